@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS ledger_entries_balanced ON ledger_entries;
+DROP FUNCTION IF EXISTS trg_ledger_journal_balanced();
+
+DROP INDEX IF EXISTS idx_ledger_entries_journal;
+DROP INDEX IF EXISTS idx_ledger_entries_account;
+
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_journals;
+DROP TABLE IF EXISTS ledger_accounts;
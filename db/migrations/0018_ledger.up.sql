@@ -0,0 +1,76 @@
+CREATE TABLE ledger_accounts (
+    id SERIAL PRIMARY KEY,
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT ledger_accounts_entity_unique UNIQUE (entity_type, entity_id)
+);
+
+CREATE TABLE ledger_journals (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id INTEGER UNIQUE REFERENCES transactions (id),
+    kind VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    journal_id BIGINT NOT NULL REFERENCES ledger_journals (id),
+    account_id INTEGER NOT NULL REFERENCES ledger_accounts (id),
+    amount DECIMAL(34, 2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ledger_entries_account ON ledger_entries (account_id, id);
+CREATE INDEX idx_ledger_entries_journal ON ledger_entries (journal_id);
+
+-- счета для уже существующих сущностей и внешний мир (ЮKassa, банки)
+INSERT INTO ledger_accounts (entity_type, entity_id) VALUES ('external', 0);
+INSERT INTO ledger_accounts (entity_type, entity_id) SELECT 'user', id FROM users;
+INSERT INTO ledger_accounts (entity_type, entity_id) SELECT 'org', id FROM organizations;
+INSERT INTO ledger_accounts (entity_type, entity_id) SELECT 'project', id FROM projects;
+
+-- входящие остатки: текущие балансы переносятся в леджер одной проводкой против внешнего счета
+INSERT INTO ledger_journals (kind) VALUES ('opening_balance');
+
+INSERT INTO ledger_entries (journal_id, account_id, amount)
+SELECT j.id, a.id, b.balance
+FROM ledger_journals j
+CROSS JOIN (
+    SELECT 'user' AS entity_type, id, COALESCE(balance, 0) AS balance FROM users
+    UNION ALL
+    SELECT 'org', id, COALESCE(balance, 0) FROM organizations
+    UNION ALL
+    SELECT 'project', id, COALESCE(current_money, 0) FROM projects
+) b
+JOIN ledger_accounts a ON a.entity_type = b.entity_type AND a.entity_id = b.id
+WHERE j.kind = 'opening_balance' AND b.balance <> 0;
+
+INSERT INTO ledger_entries (journal_id, account_id, amount)
+SELECT j.id, a.id, -COALESCE(SUM(e.amount), 0)
+FROM ledger_journals j
+JOIN ledger_accounts a ON a.entity_type = 'external' AND a.entity_id = 0
+LEFT JOIN ledger_entries e ON e.journal_id = j.id
+WHERE j.kind = 'opening_balance'
+GROUP BY j.id, a.id;
+
+-- сумма проводок по журналу должна быть нулевой, проверяется в момент коммита
+CREATE OR REPLACE FUNCTION trg_ledger_journal_balanced()
+    RETURNS trigger AS
+    $$
+    DECLARE
+        total DECIMAL(34, 2);
+    BEGIN
+        SELECT COALESCE(SUM(amount), 0) INTO total FROM ledger_entries WHERE journal_id = NEW.journal_id;
+        IF total <> 0 THEN
+            RAISE EXCEPTION 'ledger journal % is not balanced: %', NEW.journal_id, total;
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+    AFTER INSERT OR UPDATE ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION trg_ledger_journal_balanced();
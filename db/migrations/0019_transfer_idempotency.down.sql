@@ -0,0 +1 @@
+DROP TABLE IF EXISTS transfer_idempotency_keys;
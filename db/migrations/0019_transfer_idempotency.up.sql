@@ -0,0 +1,10 @@
+-- Ключи идемпотентности для POST /transfer: повтор запроса с тем же ключом
+-- возвращает исходную транзакцию вместо повторного списания
+CREATE TABLE transfer_idempotency_keys (
+    key VARCHAR(255) PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    transaction_id INT REFERENCES transactions(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_transfer_idempotency_keys_created_at ON transfer_idempotency_keys(created_at);
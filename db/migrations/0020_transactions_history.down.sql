@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_from_id;
+DROP INDEX IF EXISTS idx_transactions_reciever_id;
+CREATE INDEX idx_transactions_from_id ON transactions (from_id);
+CREATE INDEX idx_transactions_reciever_id ON transactions (reciever_id);
@@ -0,0 +1,39 @@
+-- Индексы под постраничную выдачу истории: фильтр по стороне перевода, сортировка по id
+DROP INDEX IF EXISTS idx_transactions_from_id;
+DROP INDEX IF EXISTS idx_transactions_reciever_id;
+CREATE INDEX idx_transactions_from_id ON transactions (from_id, id DESC);
+CREATE INDEX idx_transactions_reciever_id ON transactions (reciever_id, id DESC);
+
+-- Заполняем остатки после операции для старых транзакций, у которых их нет.
+-- Сторона перевода определяется по типу: "<from>_to_<to>", "<to>_deposit", "<from>_withdraw".
+-- Остаток считается нарастающим итогом по истории сущности, начиная с нуля.
+CREATE TEMP TABLE transactions_running_balance AS
+WITH movements AS (
+    SELECT id, time_at, 'sender' AS side,
+           split_part(type::text, '_', 1) AS entity_type,
+           from_id AS entity_id,
+           -amount AS delta
+    FROM transactions
+    WHERE type::text NOT LIKE '%\_deposit'
+    UNION ALL
+    SELECT id, time_at, 'reciever' AS side,
+           CASE WHEN type::text LIKE '%\_to\_%' THEN split_part(type::text, '_to_', 2)
+                ELSE split_part(type::text, '_', 1) END AS entity_type,
+           reciever_id AS entity_id,
+           amount AS delta
+    FROM transactions
+    WHERE type::text NOT LIKE '%\_withdraw'
+)
+SELECT id, side,
+       SUM(delta) OVER (PARTITION BY entity_type, entity_id ORDER BY time_at, id) AS balance
+FROM movements;
+
+UPDATE transactions t SET cum_sum_of_sender = r.balance
+FROM transactions_running_balance r
+WHERE r.id = t.id AND r.side = 'sender' AND t.cum_sum_of_sender IS NULL;
+
+UPDATE transactions t SET cum_sum_of_reciever = r.balance
+FROM transactions_running_balance r
+WHERE r.id = t.id AND r.side = 'reciever' AND t.cum_sum_of_reciever IS NULL;
+
+DROP TABLE transactions_running_balance;
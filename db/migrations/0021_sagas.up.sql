@@ -0,0 +1,34 @@
+-- Саги: многошаговые денежные операции между сервисами (выплаты инвесторам,
+-- вывод и пополнение). Состояние и журнал шагов хранятся здесь, чтобы после
+-- перезапуска сервиса воркер продолжил саги с того места, где они остановились.
+CREATE TABLE sagas (
+    id VARCHAR(36) PRIMARY KEY,
+    service VARCHAR(32) NOT NULL,
+    kind VARCHAR(64) NOT NULL,
+    ref VARCHAR(128) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL,
+    current_step INT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- одновременно по одному объекту (проекту, платежу, выводу) может идти только одна сага каждого вида
+CREATE UNIQUE INDEX idx_sagas_active_ref ON sagas (service, kind, ref) WHERE status IN ('running', 'compensating');
+CREATE INDEX idx_sagas_pending ON sagas (service, next_run_at) WHERE status IN ('running', 'compensating');
+
+CREATE TABLE saga_steps (
+    id BIGSERIAL PRIMARY KEY,
+    saga_id VARCHAR(36) NOT NULL REFERENCES sagas (id) ON DELETE CASCADE,
+    step_index INT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_saga_steps_saga ON saga_steps (saga_id, id);
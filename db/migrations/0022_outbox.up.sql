@@ -0,0 +1,15 @@
+-- Outbox: доменные события пишутся сюда в той же транзакции, что и изменение
+-- состояния, а релей каждого сервиса публикует их в Redis Stream platform:events.
+-- Пока событие не опубликовано, published_at пустой, и релей повторит отправку.
+CREATE TABLE outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    service VARCHAR(32) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events (service, id) WHERE published_at IS NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS frozen_balances;
+DROP TABLE IF EXISTS reconciliation_diffs;
+DROP TABLE IF EXISTS reconciliation_runs;
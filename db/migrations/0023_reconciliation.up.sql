@@ -0,0 +1,36 @@
+-- Сверка балансов: демон раз в сутки пересчитывает балансы из transactions
+-- (плюс входящие остатки из леджера) и сохраняет расхождения с колонками
+-- balance / current_money для разбора администратором.
+CREATE TABLE reconciliation_runs (
+    id SERIAL PRIMARY KEY,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP,
+    entities_checked INT NOT NULL DEFAULT 0,
+    drift_count INT NOT NULL DEFAULT 0,
+    total_drift DECIMAL(34, 2) NOT NULL DEFAULT 0
+);
+
+CREATE TABLE reconciliation_diffs (
+    id BIGSERIAL PRIMARY KEY,
+    run_id INT NOT NULL REFERENCES reconciliation_runs (id) ON DELETE CASCADE,
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    stored_balance DECIMAL(34, 2) NOT NULL,
+    computed_balance DECIMAL(34, 2) NOT NULL,
+    diff DECIMAL(34, 2) NOT NULL,
+    frozen BOOLEAN NOT NULL DEFAULT FALSE,
+    resolved_at TIMESTAMP,
+    resolved_by INT REFERENCES users (id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_reconciliation_diffs_run ON reconciliation_diffs (run_id);
+
+-- замороженные до проверки балансы: переводы с их участием отклоняются
+CREATE TABLE frozen_balances (
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    diff_id BIGINT REFERENCES reconciliation_diffs (id) ON DELETE SET NULL,
+    frozen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (entity_type, entity_id)
+);
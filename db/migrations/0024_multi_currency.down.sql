@@ -0,0 +1,32 @@
+ALTER TABLE reconciliation_diffs DROP COLUMN IF EXISTS currency;
+
+CREATE OR REPLACE FUNCTION trg_ledger_journal_balanced()
+    RETURNS trigger AS
+    $$
+    DECLARE
+        total DECIMAL(34, 2);
+    BEGIN
+        SELECT COALESCE(SUM(amount), 0) INTO total FROM ledger_entries WHERE journal_id = NEW.journal_id;
+        IF total <> 0 THEN
+            RAISE EXCEPTION 'ledger journal % is not balanced: %', NEW.journal_id, total;
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_entity_unique;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_entity_unique UNIQUE (entity_type, entity_id);
+ALTER TABLE ledger_accounts DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE withdrawals DROP COLUMN IF EXISTS currency;
+ALTER TABLE payments DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS to_currency,
+    DROP COLUMN IF EXISTS to_amount,
+    DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS fx_rates;
+ALTER TABLE projects DROP COLUMN IF EXISTS currency;
+DROP TABLE IF EXISTS wallet_balances;
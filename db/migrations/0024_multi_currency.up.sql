@@ -0,0 +1,64 @@
+-- Мультивалютные кошельки. Баланс в базовой валюте (RUB) по-прежнему хранится
+-- в users.balance / organizations.balance, остатки в других валютах — в wallet_balances.
+-- Деньги проекта хранятся в его целевой валюте projects.currency.
+CREATE TABLE wallet_balances (
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    balance DECIMAL(34, 2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (entity_type, entity_id, currency)
+);
+
+ALTER TABLE projects ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+
+-- курсы валют к рублю: 1 единица currency = rate RUB. Старые курсы не удаляются,
+-- по ним можно проверить конвертацию любой прошлой транзакции.
+CREATE TABLE fx_rates (
+    id BIGSERIAL PRIMARY KEY,
+    currency VARCHAR(3) NOT NULL,
+    rate DECIMAL(24, 10) NOT NULL CHECK (rate > 0),
+    source VARCHAR(32) NOT NULL,
+    fetched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fx_rates_currency ON fx_rates (currency, fetched_at DESC);
+
+-- amount списывается с отправителя в currency, получателю зачисляется to_amount в to_currency
+-- по курсу fx_rate (NULL, если валюты совпадают)
+ALTER TABLE transactions
+    ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    ADD COLUMN to_amount DECIMAL(34, 2),
+    ADD COLUMN to_currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    ADD COLUMN fx_rate DECIMAL(24, 10);
+
+ALTER TABLE payments ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE withdrawals ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+
+-- у каждой сущности свой счет в леджере на каждую валюту
+ALTER TABLE ledger_accounts ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE ledger_accounts DROP CONSTRAINT ledger_accounts_entity_unique;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_entity_unique UNIQUE (entity_type, entity_id, currency);
+
+-- конвертация проходит через счета 'fx', поэтому журнал сходится в каждой валюте отдельно
+CREATE OR REPLACE FUNCTION trg_ledger_journal_balanced()
+    RETURNS trigger AS
+    $$
+    DECLARE
+        unbalanced VARCHAR(3);
+    BEGIN
+        SELECT a.currency INTO unbalanced
+        FROM ledger_entries e
+        JOIN ledger_accounts a ON a.id = e.account_id
+        WHERE e.journal_id = NEW.journal_id
+        GROUP BY a.currency
+        HAVING SUM(e.amount) <> 0
+        LIMIT 1;
+        IF unbalanced IS NOT NULL THEN
+            RAISE EXCEPTION 'ledger journal % is not balanced in %', NEW.journal_id, unbalanced;
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+ALTER TABLE reconciliation_diffs ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'RUB';
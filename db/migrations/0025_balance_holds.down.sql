@@ -0,0 +1,2 @@
+ALTER TABLE withdrawals DROP COLUMN IF EXISTS hold_id;
+DROP TABLE IF EXISTS balance_holds;
@@ -0,0 +1,26 @@
+-- Холды: деньги резервируются на балансе и либо списываются переводом (capture),
+-- либо освобождаются (release) вручную или по истечении expires_at.
+-- Баланс не меняется, пока холд активен: доступный остаток = balance - сумма активных холдов.
+CREATE TABLE balance_holds (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    amount DECIMAL(34, 2) NOT NULL CHECK (amount > 0),
+    -- получатель, которому уйдут деньги при списании холда
+    to_type VARCHAR(16) NOT NULL,
+    to_id INT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'captured', 'released', 'expired')),
+    reference VARCHAR(255) NOT NULL UNIQUE,
+    transaction_id INT REFERENCES transactions (id),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_balance_holds_active ON balance_holds (entity_type, entity_id, currency) WHERE status = 'active';
+CREATE INDEX idx_balance_holds_pledges ON balance_holds (to_id) WHERE status = 'active' AND to_type = 'project';
+CREATE INDEX idx_balance_holds_expiry ON balance_holds (expires_at) WHERE status = 'active';
+
+ALTER TABLE withdrawals ADD COLUMN hold_id BIGINT;
@@ -0,0 +1,19 @@
+-- Входящие вебхуки ЮKassa: каждый запрос сохраняется до обработки для аудита и
+-- повторного проведения. Отклоненные проверкой источника или подписи тоже сохраняются,
+-- но повторно не обрабатываются.
+CREATE TABLE webhook_inbox (
+    id BIGSERIAL PRIMARY KEY,
+    event VARCHAR(64) NOT NULL DEFAULT '',
+    object_id VARCHAR(255) NOT NULL DEFAULT '',
+    source_ip VARCHAR(64) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL
+        CHECK (status IN ('received', 'processed', 'failed', 'rejected')),
+    error TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP
+);
+
+CREATE INDEX idx_webhook_inbox_object ON webhook_inbox (object_id);
+CREATE INDEX idx_webhook_inbox_failed ON webhook_inbox (received_at) WHERE status = 'failed';
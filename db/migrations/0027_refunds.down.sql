@@ -0,0 +1,4 @@
+ALTER TABLE projects
+    DROP COLUMN IF EXISTS investors_refunded_at,
+    DROP COLUMN IF EXISTS failed_at;
+DROP TABLE IF EXISTS refunds;
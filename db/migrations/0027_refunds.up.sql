@@ -0,0 +1,25 @@
+-- Возвраты платежей: деньги резервируются холдом на кошельке и списываются,
+-- когда провайдер подтвердит возврат
+CREATE TABLE refunds (
+    id VARCHAR(36) PRIMARY KEY,
+    payment_id UUID NOT NULL REFERENCES payments (id),
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    entity_id INT NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    amount DECIMAL(34, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    hold_id BIGINT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refunds_payment ON refunds (payment_id);
+CREATE INDEX idx_refunds_external_id ON refunds (external_id);
+
+-- проект, не собравший цель к дедлайну: failed_at ставит демон при закрытии,
+-- investors_refunded_at — когда вклады всех инвесторов вернулись на кошельки
+ALTER TABLE projects
+    ADD COLUMN failed_at TIMESTAMP,
+    ADD COLUMN investors_refunded_at TIMESTAMP;
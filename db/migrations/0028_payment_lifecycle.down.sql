@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_payments_open;
+
+UPDATE payments SET status = 'pending' WHERE status IN ('waiting_for_capture', 'expired');
+
+ALTER TABLE payments
+    DROP COLUMN cancellation_reason,
+    DROP COLUMN expires_at,
+    DROP COLUMN capture;
@@ -0,0 +1,12 @@
+-- Жизненный цикл платежа как в ЮKassa: pending -> (waiting_for_capture) -> succeeded
+-- или canceled; expired ставит сам сервис, если платеж так и не оплатили.
+-- capture = false — двухстадийный платеж, деньги сначала только блокируются на карте.
+ALTER TABLE payments
+    ADD COLUMN capture BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN expires_at TIMESTAMP,
+    ADD COLUMN cancellation_reason VARCHAR(64) NOT NULL DEFAULT '';
+
+-- старые незавершенные платежи закрываются при первом проходе чистильщика
+UPDATE payments SET expires_at = created_at + INTERVAL '1 hour' WHERE status = 'pending';
+
+CREATE INDEX idx_payments_open ON payments (expires_at) WHERE status IN ('pending', 'waiting_for_capture');
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_withdrawals_next_poll;
+DROP INDEX IF EXISTS idx_payments_next_poll;
+
+ALTER TABLE withdrawals
+    DROP COLUMN last_poll_error,
+    DROP COLUMN next_poll_at,
+    DROP COLUMN poll_attempts;
+
+ALTER TABLE payments
+    DROP COLUMN last_poll_error,
+    DROP COLUMN next_poll_at,
+    DROP COLUMN poll_attempts;
@@ -0,0 +1,14 @@
+-- Опрос провайдера по незавершенным платежам и выводам: у каждой строки свой
+-- счетчик попыток и время следующей проверки (экспоненциальная задержка)
+ALTER TABLE payments
+    ADD COLUMN poll_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN next_poll_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN last_poll_error TEXT;
+
+ALTER TABLE withdrawals
+    ADD COLUMN poll_attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN next_poll_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN last_poll_error TEXT;
+
+CREATE INDEX idx_payments_next_poll ON payments (next_poll_at) WHERE status IN ('pending', 'waiting_for_capture');
+CREATE INDEX idx_withdrawals_next_poll ON withdrawals (next_poll_at) WHERE status = 'pending';
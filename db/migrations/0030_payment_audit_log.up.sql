@@ -0,0 +1,19 @@
+-- Журнал обращений к платежным ручкам: кто, над каким кошельком, что пытался
+-- сделать и чем закончилось. Пишется и для отказов в доступе.
+CREATE TABLE payment_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    entity_type VARCHAR(16) NOT NULL DEFAULT '',
+    entity_id INT NOT NULL DEFAULT 0,
+    object_id VARCHAR(64) NOT NULL DEFAULT '',
+    amount DECIMAL(34, 2),
+    currency VARCHAR(3),
+    allowed BOOLEAN NOT NULL,
+    error TEXT,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_payment_audit_entity ON payment_audit_log (entity_type, entity_id, created_at DESC);
+CREATE INDEX idx_payment_audit_user ON payment_audit_log (user_id, created_at DESC);
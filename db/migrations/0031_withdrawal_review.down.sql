@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS withdrawal_decisions;
+
+DROP INDEX IF EXISTS idx_withdrawals_entity_created;
+DROP INDEX IF EXISTS idx_withdrawals_review;
+
+ALTER TABLE withdrawals
+    DROP COLUMN IF EXISTS required_approvals,
+    DROP COLUMN IF EXISTS review_reason,
+    DROP COLUMN IF EXISTS requested_by,
+    DROP COLUMN IF EXISTS destination;
@@ -0,0 +1,22 @@
+-- Ручная проверка выводов: крупные суммы, новые реквизиты и организации без
+-- завершенной регистрации ждут решения администратора, деньги при этом в холде.
+ALTER TABLE withdrawals
+    ADD COLUMN destination TEXT NOT NULL DEFAULT '',
+    ADD COLUMN requested_by INT NOT NULL DEFAULT 0,
+    ADD COLUMN review_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN required_approvals INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_withdrawals_review ON withdrawals (created_at) WHERE status = 'review';
+CREATE INDEX idx_withdrawals_entity_created ON withdrawals (entity_type, entity_id, created_at);
+
+-- Каждое решение администратора; один администратор голосует по выводу один раз,
+-- поэтому для крупных выводов организаций нужны два разных человека
+CREATE TABLE withdrawal_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    withdrawal_id VARCHAR(36) NOT NULL REFERENCES withdrawals (id),
+    admin_id INT NOT NULL,
+    decision VARCHAR(16) NOT NULL,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (withdrawal_id, admin_id)
+);
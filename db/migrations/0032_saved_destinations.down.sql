@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS saved_payment_methods;
+
+ALTER TABLE withdrawals DROP COLUMN IF EXISTS destination_id;
+
+DROP TABLE IF EXISTS payout_destinations;
@@ -0,0 +1,37 @@
+-- Сохраненные реквизиты для вывода. Удаление мягкое: на реквизиты ссылаются
+-- прошлые выводы, а одобренный вывод должен уйти туда, куда его одобрили.
+CREATE TABLE payout_destinations (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    masked VARCHAR(64) NOT NULL DEFAULT '',
+    payout_token TEXT NOT NULL DEFAULT '',
+    account_number VARCHAR(34) NOT NULL DEFAULT '',
+    bic VARCHAR(9) NOT NULL DEFAULT '',
+    corr_account VARCHAR(20) NOT NULL DEFAULT '',
+    holder TEXT NOT NULL DEFAULT '',
+    verified_at TIMESTAMP,
+    verified_by INT,
+    created_by INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_payout_destinations_entity ON payout_destinations (entity_type, entity_id) WHERE deleted_at IS NULL;
+
+ALTER TABLE withdrawals ADD COLUMN destination_id BIGINT REFERENCES payout_destinations (id);
+
+-- Способы оплаты, сохраненные ЮKassa по save_payment_method
+CREATE TABLE saved_payment_methods (
+    id VARCHAR(64) PRIMARY KEY,
+    entity_type VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    type VARCHAR(32) NOT NULL DEFAULT '',
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_saved_payment_methods_entity ON saved_payment_methods (entity_type, entity_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS fee_rule_id,
+    DROP COLUMN IF EXISTS fee;
+
+DROP TABLE IF EXISTS fee_rules;
@@ -0,0 +1,29 @@
+-- Комиссии платформы. Правило — процент от зачисляемой суммы плюс фиксированная часть
+-- с ограничением снизу и сверху; пустые monetization_type, org_type и currency
+-- подходят к любому переводу, из подходящих выбирается самое точное.
+CREATE TABLE fee_rules (
+    id BIGSERIAL PRIMARY KEY,
+    operation VARCHAR(16) NOT NULL CHECK (operation IN ('deposit', 'investment', 'payback')),
+    monetization_type VARCHAR(32) NOT NULL DEFAULT '',
+    org_type VARCHAR(8) NOT NULL DEFAULT '',
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    percent DECIMAL(7, 4) NOT NULL DEFAULT 0 CHECK (percent >= 0 AND percent <= 100),
+    fixed DECIMAL(34, 2) NOT NULL DEFAULT 0 CHECK (fixed >= 0),
+    min_fee DECIMAL(34, 2) NOT NULL DEFAULT 0 CHECK (min_fee >= 0),
+    max_fee DECIMAL(34, 2) NOT NULL DEFAULT 0 CHECK (max_fee >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_fee_rules_operation ON fee_rules (operation) WHERE active;
+
+-- комиссия с привлеченных в проект денег; благотворительные сборы без комиссии
+INSERT INTO fee_rules (operation, percent) VALUES ('investment', 5);
+INSERT INTO fee_rules (operation, monetization_type, percent) VALUES ('investment', 'charity', 0);
+
+-- получатель получает to_amount, комиссия fee в to_currency зачисляется на счет 'platform' в леджере
+ALTER TABLE transactions
+    ADD COLUMN fee DECIMAL(34, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN fee_rule_id BIGINT REFERENCES fee_rules (id);
+
@@ -0,0 +1,4 @@
+-- значение 'user_to_org' остается в transaction_type: из enum его не удалить,
+-- пока на него ссылаются проведенные переводы
+DROP TABLE IF EXISTS recurring_charges;
+DROP TABLE IF EXISTS recurring_pledges;
@@ -0,0 +1,41 @@
+-- Регулярные пожертвования: раз в месяц пользователь переводит сумму в проект
+-- или организацию с кошелька или с сохраненной карты
+CREATE TABLE recurring_pledges (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id),
+    target_type VARCHAR(16) NOT NULL CHECK (target_type IN ('project', 'org')),
+    target_id INT NOT NULL,
+    amount DECIMAL(34, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'RUB',
+    source VARCHAR(16) NOT NULL CHECK (source IN ('wallet', 'card')),
+    payment_method_id VARCHAR(64) REFERENCES saved_payment_methods (id),
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    stop_reason TEXT NOT NULL DEFAULT '',
+    next_charge_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_recurring_pledges_due ON recurring_pledges (next_charge_at) WHERE status = 'active';
+CREATE INDEX idx_recurring_pledges_user ON recurring_pledges (user_id);
+CREATE INDEX idx_recurring_pledges_target ON recurring_pledges (target_type, target_id) WHERE status IN ('active', 'paused');
+
+-- одно списание на пожертвование за месяц; id списания — ключ идемпотентности перевода
+CREATE TABLE recurring_charges (
+    id BIGSERIAL PRIMARY KEY,
+    pledge_id BIGINT NOT NULL REFERENCES recurring_pledges (id),
+    period DATE NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    payment_id UUID REFERENCES payments (id),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (pledge_id, period)
+);
+
+CREATE INDEX idx_recurring_charges_open ON recurring_charges (next_attempt_at) WHERE status IN ('pending', 'paying', 'paid');
+
+-- пожертвования организациям идут прямо на ее кошелек
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'user_to_org';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tags_name;
+CREATE INDEX idx_tags_name ON tags (name);
+
+ALTER TABLE project_tags DROP CONSTRAINT IF EXISTS project_tags_project_tag_key;
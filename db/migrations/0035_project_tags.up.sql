@@ -0,0 +1,9 @@
+-- Теги проектов: у проекта каждый тег не больше одного раза, имена тегов уникальны
+DELETE FROM project_tags a
+USING project_tags b
+WHERE a.project_id = b.project_id AND a.tag_id = b.tag_id AND a.id > b.id;
+
+ALTER TABLE project_tags ADD CONSTRAINT project_tags_project_tag_key UNIQUE (project_id, tag_id);
+
+DROP INDEX IF EXISTS idx_tags_name;
+CREATE UNIQUE INDEX idx_tags_name ON tags (name);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_projects_search;
+ALTER TABLE projects DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,11 @@
+-- Полнотекстовый поиск по проектам. Название весит больше краткого описания, краткое —
+-- больше полного. Каждое поле разбирается русской и английской конфигурацией, чтобы
+-- находились словоформы обоих языков; опечатки в названии ловит триграммный индекс из 0013.
+ALTER TABLE projects ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('russian', name), 'A') || setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('russian', quick_peek), 'B') || setweight(to_tsvector('english', quick_peek), 'B') ||
+    setweight(to_tsvector('russian', content), 'C') || setweight(to_tsvector('english', content), 'C')
+) STORED;
+
+CREATE INDEX idx_projects_search ON projects USING GIN (search_vector)
+WHERE is_public = true AND is_banned = false AND is_completed = false;
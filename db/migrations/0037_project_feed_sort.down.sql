@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_projects_feed_funded;
+DROP INDEX IF EXISTS idx_projects_feed_percent;
+DROP INDEX IF EXISTS idx_projects_feed_ending;
+DROP INDEX IF EXISTS idx_projects_feed_closest;
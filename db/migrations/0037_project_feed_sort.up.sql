@@ -0,0 +1,18 @@
+-- Индексы для порядков ленты и постраничного чтения по курсору. Выражения должны
+-- совпадать с ключами сортировки в services/project/repo/feed.go. Порядок «новые»
+-- обслуживает idx_projects_feed_public из 0013.
+CREATE INDEX IF NOT EXISTS idx_projects_feed_closest
+ON projects ((COALESCE(current_money, 0) / GREATEST(wanted_money, 0.01)) DESC, id ASC)
+WHERE is_public = true AND is_banned = false AND is_completed = false;
+
+CREATE INDEX IF NOT EXISTS idx_projects_feed_ending
+ON projects ((created_at + COALESCE(duration_days, 30) * interval '1 day') ASC, id ASC)
+WHERE is_public = true AND is_banned = false AND is_completed = false;
+
+CREATE INDEX IF NOT EXISTS idx_projects_feed_percent
+ON projects (COALESCE(percent, 0) DESC, id ASC)
+WHERE is_public = true AND is_banned = false AND is_completed = false;
+
+CREATE INDEX IF NOT EXISTS idx_projects_feed_funded
+ON projects (COALESCE(current_money, 0) DESC, id ASC)
+WHERE is_public = true AND is_banned = false AND is_completed = false;
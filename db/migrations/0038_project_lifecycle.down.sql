@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_projects_feed_ending;
+CREATE INDEX IF NOT EXISTS idx_projects_feed_ending
+ON projects ((created_at + COALESCE(duration_days, 30) * interval '1 day') ASC, id ASC)
+WHERE is_public = true AND is_banned = false AND is_completed = false;
+
+DROP TABLE IF EXISTS project_status_history;
+DROP INDEX IF EXISTS idx_projects_review;
+
+ALTER TABLE projects
+    DROP COLUMN activated_at,
+    DROP COLUMN status;
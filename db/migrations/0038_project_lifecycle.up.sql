@@ -0,0 +1,45 @@
+-- Жизненный цикл проекта: draft -> pending_review -> active -> funded/failed ->
+-- payback_in_progress -> closed. Блокировка (is_banned) — отдельный признак поверх
+-- статуса. is_public и is_completed остаются: их читают лента, индексы и другие сервисы,
+-- а меняет их только переход статуса. activated_at — начало сбора после одобрения
+-- модератором, от него считается срок.
+ALTER TABLE projects
+    ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'pending_review', 'active', 'funded', 'failed', 'payback_in_progress', 'closed')),
+    ADD COLUMN activated_at TIMESTAMP;
+
+UPDATE projects SET
+    status = CASE
+        WHEN failed_at IS NOT NULL AND investors_refunded_at IS NOT NULL THEN 'closed'
+        WHEN failed_at IS NOT NULL THEN 'failed'
+        WHEN payback_started THEN 'payback_in_progress'
+        WHEN is_completed THEN 'funded'
+        ELSE 'active'
+    END,
+    activated_at = created_at;
+
+CREATE INDEX idx_projects_review ON projects (id) WHERE status = 'pending_review';
+
+-- История статусов и блокировок. actor_id NULL — переход сделала система (daemon, выплаты).
+CREATE TABLE project_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    project_id INT NOT NULL REFERENCES projects (id),
+    action VARCHAR(16) NOT NULL DEFAULT 'transition',
+    from_status VARCHAR(32),
+    to_status VARCHAR(32) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor_id INT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_project_status_history_project ON project_status_history (project_id, created_at);
+
+INSERT INTO project_status_history (project_id, to_status, reason, created_at)
+SELECT id, status, 'статус восстановлен по флагам проекта', NOW() FROM projects;
+
+-- срок сбора теперь считается от одобрения, индекс порядка «скоро закончится» из 0037
+-- пересоздается под новый ключ
+DROP INDEX IF EXISTS idx_projects_feed_ending;
+CREATE INDEX idx_projects_feed_ending
+ON projects ((activated_at + COALESCE(duration_days, 30) * interval '1 day') ASC, id ASC)
+WHERE is_public = true AND is_banned = false AND is_completed = false;
@@ -0,0 +1,2 @@
+UPDATE balance_holds SET expires_at = updated_at + INTERVAL '7 days' WHERE expires_at IS NULL;
+ALTER TABLE balance_holds ALTER COLUMN expires_at SET NOT NULL;
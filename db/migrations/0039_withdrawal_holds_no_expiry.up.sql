@@ -0,0 +1,6 @@
+-- Холд под выплату держится, пока провайдер не сообщит ее результат: истекший резерв
+-- позволил бы потратить деньги, которые уже уходят на внешний счет. У таких холдов expires_at нет.
+ALTER TABLE balance_holds ALTER COLUMN expires_at DROP NOT NULL;
+
+UPDATE balance_holds SET expires_at = NULL WHERE to_type = 'external' AND status IN ('active', 'expired');
+UPDATE balance_holds SET status = 'active' WHERE to_type = 'external' AND status = 'expired';
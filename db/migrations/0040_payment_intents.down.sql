@@ -0,0 +1,5 @@
+UPDATE recurring_charges SET payment_id = NULL
+WHERE payment_id IN (SELECT id FROM payments WHERE external_id = '');
+DELETE FROM payments WHERE external_id = '';
+DROP INDEX IF EXISTS payments_external_id_key;
+ALTER TABLE payments ADD CONSTRAINT payments_external_id_key UNIQUE (external_id);
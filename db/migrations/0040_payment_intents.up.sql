@@ -0,0 +1,4 @@
+-- Платеж по регулярному пожертвованию сохраняется до обращения к провайдеру (статус creating),
+-- а external_id заполняется, когда провайдер ответил. Пустые external_id не должны конфликтовать.
+ALTER TABLE payments DROP CONSTRAINT payments_external_id_key;
+CREATE UNIQUE INDEX payments_external_id_key ON payments (external_id) WHERE external_id <> '';
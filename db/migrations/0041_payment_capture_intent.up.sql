@@ -0,0 +1,3 @@
+-- Сумма подтверждения двухстадийного платежа сохраняется до обращения к провайдеру:
+-- если ответ потерялся, вебхук или чистильщик зачислят именно ее, а не исходную сумму
+ALTER TABLE payments ADD COLUMN capture_amount NUMERIC(34, 2);
@@ -75,6 +75,9 @@ func (g *Generator) Run() error {
 	if err := g.generateTransactions(); err != nil {
 		return err
 	}
+	if err := g.generateOpeningBalances(); err != nil {
+		return err
+	}
 
 	fmt.Println("Перепись населения и национализация завершены успешно! Пятилетка - в три года!")
 	return nil
@@ -624,3 +627,69 @@ func (g *Generator) generateTransactions() error {
 	fmt.Println("Финансовые операции проведены.")
 	return nil
 }
+
+// generateOpeningBalances переносит сгенерированные балансы в леджер так же, как миграция 0018:
+// история выше пишется в transactions напрямую, без журналов, и сверка считает ее уже учтенной во входящих остатках
+func (g *Generator) generateOpeningBalances() error {
+	fmt.Println("Сводим дебет с кредитом (входящие остатки в леджер)...")
+
+	tx, err := g.conn.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	balances := `
+		SELECT 'user' AS entity_type, id, 'RUB' AS currency, COALESCE(balance, 0) AS balance FROM users WHERE id = ANY($1)
+		UNION ALL
+		SELECT 'org', id, 'RUB', COALESCE(balance, 0) FROM organizations WHERE id = ANY($2)
+		UNION ALL
+		SELECT 'project', id, currency, COALESCE(current_money, 0) FROM projects WHERE id = ANY($3)`
+
+	_, err = tx.Exec(context.Background(), `
+		INSERT INTO ledger_accounts (entity_type, entity_id, currency)
+		SELECT entity_type, id, currency FROM (`+balances+`) b
+		UNION
+		SELECT 'external', 0, 'RUB'
+		ON CONFLICT (entity_type, entity_id, currency) DO NOTHING`,
+		g.userIDs, g.organizationIDs, g.projectIDs)
+	if err != nil {
+		return err
+	}
+
+	var journalID int64
+	err = tx.QueryRow(context.Background(), `INSERT INTO ledger_journals (kind) VALUES ('opening_balance') RETURNING id`).Scan(&journalID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+		INSERT INTO ledger_entries (journal_id, account_id, amount)
+		SELECT $4, a.id, b.balance
+		FROM (`+balances+`) b
+		JOIN ledger_accounts a ON a.entity_type = b.entity_type AND a.entity_id = b.id AND a.currency = b.currency
+		WHERE b.balance <> 0`,
+		g.userIDs, g.organizationIDs, g.projectIDs, journalID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(context.Background(), `
+		INSERT INTO ledger_entries (journal_id, account_id, amount)
+		SELECT $1, ext.id, -SUM(e.amount)
+		FROM ledger_entries e
+		JOIN ledger_accounts a ON a.id = e.account_id
+		JOIN ledger_accounts ext ON ext.entity_type = 'external' AND ext.entity_id = 0 AND ext.currency = a.currency
+		WHERE e.journal_id = $1
+		GROUP BY ext.id`, journalID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return err
+	}
+
+	fmt.Println("Входящие остатки перенесены.")
+	return nil
+}
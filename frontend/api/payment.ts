@@ -34,11 +34,17 @@ export async function InitPayment(
     }
 }
 
+// status = "review": вывод ждет ручной проверки администратором, деньги зарезервированы
+export interface WithdrawResult {
+    withdrawal_id: string;
+    status: string;
+}
+
 export async function InitWithdraw(
     amount: number,
     payoutToken: string,
     setMessage: (msg: Message) => void
-): Promise<string | null> {
+): Promise<WithdrawResult | null> {
     try {
         const user = useUserStore.getState().user;
 
@@ -56,7 +62,7 @@ export async function InitWithdraw(
             payout_destination: payoutToken
         });
 
-        return res.data.id;
+        return res.data;
     } catch (e: any) {
         console.error("Withdraw init error:", e);
         DefaultErrorHandler(setMessage)(e);
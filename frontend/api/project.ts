@@ -18,11 +18,103 @@ export interface Project {
     created_at?: string;
     is_banned: boolean;
     percent?: number;
+    tags?: Tag[];
+    status?: ProjectStatus;
+    activated_at?: string;
 
     quickPeekPictureFile?: File | null;
 
 }
 
+export type ProjectStatus = "draft" | "pending_review" | "active" | "funded" | "failed" | "payback_in_progress" | "closed";
+
+export interface StatusChange {
+    id: number;
+    project_id: number;
+    action: "transition" | "ban" | "unban";
+    from_status?: ProjectStatus;
+    to_status: ProjectStatus;
+    reason: string;
+    actor_id?: number; // нет у переходов, сделанных системой
+    created_at: string;
+}
+
+export interface Tag {
+    id: number;
+    name: string;
+    description?: string;
+}
+
+export interface TagCount extends Tag {
+    project_count: number;
+}
+
+export async function GetTags(): Promise<TagCount[]> {
+    try {
+        const res = await api.get('/projects/tags');
+        return Array.isArray(res.data) ? res.data : [];
+    } catch (e) {
+        console.warn(e);
+        return [];
+    }
+}
+
+export interface ProjectSearchResult extends Project {
+    rank: number;
+    name_highlight: string; // HTML, подсвечены только совпадения в <mark>
+    snippet: string;
+}
+
+export async function SearchProjects(query: string, limit: number = 20, offset: number = 0): Promise<ProjectSearchResult[]> {
+    try {
+        const params = new URLSearchParams();
+        params.append("search", query);
+        params.append("limit", limit.toString());
+        params.append("offset", offset.toString());
+        const res = await api.get(`/projects/projects/search?${params.toString()}`);
+        return Array.isArray(res.data) ? res.data : [];
+    } catch (e) {
+        console.warn(e);
+        return [];
+    }
+}
+
+export type ProjectSort = "new" | "closest" | "ending" | "percent" | "funded" | "relevance";
+
+export interface ProjectPage {
+    projects: Project[];
+    nextCursor?: string; // нет на последней странице
+}
+
+// Постраничное чтение ленты по курсору: nextCursor передается в следующий вызов
+export async function GetProjectsPage(
+    limit: number = 20,
+    sort?: ProjectSort,
+    cursor?: string,
+    query?: string,
+    category?: string,
+    tagIds?: number[]
+): Promise<ProjectPage> {
+    try {
+        const params = new URLSearchParams();
+        params.append("limit", limit.toString());
+        if (sort) params.append("sort", sort);
+        if (cursor) params.append("cursor", cursor);
+        if (query) params.append("search", query);
+        if (category) params.append("type", category);
+        if (tagIds && tagIds.length > 0) params.append("tags", tagIds.join(","));
+
+        const res = await api.get(`/projects/projects?${params.toString()}`);
+        return {
+            projects: Array.isArray(res.data) ? res.data : [],
+            nextCursor: res.headers["x-next-cursor"] || undefined,
+        };
+    } catch (e) {
+        console.warn(e);
+        return {projects: []};
+    }
+}
+
 export async function GetProjectById(id: number): Promise<Project | null> {
     try {
         const res = await api.get(`/projects/${id}`); // Nginx: /api/projects/ -> project_service
@@ -37,7 +129,8 @@ export async function GetProjects(
     limit: number = 50,
     offset: number = 0,
     query?: string,
-    category?: string
+    category?: string,
+    tagIds?: number[]
 ): Promise<Project[]> {
     try {
         const params = new URLSearchParams();
@@ -45,6 +138,7 @@ export async function GetProjects(
         params.append("offset", offset.toString());
         if (query) params.append("search", query);
         if (category) params.append("type", category); // Используем type вместо category
+        if (tagIds && tagIds.length > 0) params.append("tags", tagIds.join(","));
 
         const res = await api.get(`/projects/projects?${params.toString()}`);
         if (Array.isArray(res.data)) {
@@ -83,7 +177,10 @@ export async function PublishProject(project: Project, setMessage: (message: Mes
             });
         }
 
-        setMessage({isError: false, message: "Проект успешно создан!"});
+        // новый проект — черновик, в ленту он попадет после одобрения модератором
+        await api.post(`/projects/${createdProject.id}/submit`);
+
+        setMessage({isError: false, message: "Проект создан и отправлен на модерацию"});
         return createdProject.id;
     } catch (e: any) {
         DefaultErrorHandler(setMessage)(e);
@@ -161,6 +258,64 @@ export async function ProjectPayback(
     }
 }
 
+export async function SubmitProject(projectId: number, setMessage: (msg: Message) => void): Promise<boolean> {
+    try {
+        await api.post(`/projects/${projectId}/submit`);
+        setMessage({isError: false, message: "Проект отправлен на модерацию"});
+        return true;
+    } catch (e: any) {
+        DefaultErrorHandler(setMessage)(e);
+        return false;
+    }
+}
+
+export async function ReviewProject(
+    projectId: number,
+    approve: boolean,
+    reason: string,
+    setMessage: (msg: Message) => void
+): Promise<boolean> {
+    try {
+        await api.post(`/projects/${projectId}/${approve ? "approve" : "reject"}`, {reason});
+        setMessage({isError: false, message: approve ? "Проект одобрен" : "Проект возвращен на доработку"});
+        return true;
+    } catch (e: any) {
+        DefaultErrorHandler(setMessage)(e);
+        return false;
+    }
+}
+
+export async function CloseProject(projectId: number, reason: string, setMessage: (msg: Message) => void): Promise<boolean> {
+    try {
+        await api.post(`/projects/${projectId}/close`, {reason});
+        setMessage({isError: false, message: "Проект закрыт"});
+        return true;
+    } catch (e: any) {
+        DefaultErrorHandler(setMessage)(e);
+        return false;
+    }
+}
+
+export async function GetProjectStatusHistory(projectId: number): Promise<StatusChange[]> {
+    try {
+        const res = await api.get(`/projects/${projectId}/history`);
+        return Array.isArray(res.data) ? res.data : [];
+    } catch (e) {
+        console.warn(e);
+        return [];
+    }
+}
+
+export async function GetReviewQueue(limit: number = 20, offset: number = 0): Promise<Project[]> {
+    try {
+        const res = await api.get(`/projects/projects/review?limit=${limit}&offset=${offset}`);
+        return Array.isArray(res.data) ? res.data : [];
+    } catch (e) {
+        console.warn(e);
+        return [];
+    }
+}
+
 export async function GetOrganisationProjects(orgId: number): Promise<Project[]> {
     try {
         const res = await api.get(`/projects/projects/org/${orgId}`);
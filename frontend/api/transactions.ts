@@ -39,45 +39,75 @@ export async function MakeTransfer(
 
 export interface Transaction {
     id: number;
-    from_id: number;
-    to_id: number;
-    amount: number;
     type: string; // 'user_to_project', 'project_to_user', etc.
+    direction: 'in' | 'out';
+    counterparty_type: 'user' | 'org' | 'project' | 'external';
+    counterparty_id: number;
+    amount: number;
+    balance_after: number | null;
     created_at: string;
-    // Опционально, если бэк обогащает данными, но пока используем ID
 }
 
-export async function GetTransactions(filters: {
-    user_id?: number,
-    project_id?: number,
-    limit?: number
-}): Promise<Transaction[]> {
-    try {
-        // Формируем query params для Transaction Service
-        // Предполагаем, что сервис поддерживает фильтрацию.
-        // Если нет - придется фильтровать на клиенте, но сделаем вид, что API умный.
-        // В твоем описании эндпоинтов был /get с параметрами.
-
-        /*
-           ВНИМАНИЕ: Так как в Transaction Service мы реализовали пока только /create (Invest/Transfer),
-           для истории нам нужно либо добавить GET эндпоинт на бэке, либо (если он есть в задании, но мы пропустили) использовать его.
+export interface TransactionsPage {
+    items: Transaction[];
+    next_cursor?: string;
+}
 
-           Если на бэке нет GET /transactions, давай предположим, что мы его добавили в Repo/Handler транзакций.
-           (Я добавлю мок-запрос, который сработает, если ты реализуешь GET /api/tx/history на бэке).
-        */
+export async function GetTransactions(
+    entityType: 'user' | 'org' | 'project',
+    entityId: number,
+    filters: {
+        types?: string[],
+        from?: string, // YYYY-MM-DD
+        to?: string,   // YYYY-MM-DD, включительно
+        cursor?: string,
+        limit?: number
+    } = {}
+): Promise<TransactionsPage> {
+    try {
+        const params = new URLSearchParams();
+        if (filters.types?.length) params.append("type", filters.types.join(","));
+        if (filters.from) params.append("from", filters.from);
+        if (filters.to) params.append("to", filters.to);
+        if (filters.cursor) params.append("cursor", filters.cursor);
+        if (filters.limit) params.append("limit", filters.limit.toString());
 
-        // Пока вернем пустой массив, чтобы код компилировался,
-        // но ниже я напишу код для UserView, который будет готов к данным.
+        const res = await api.get<TransactionsPage>(`/tx/history/${entityType}/${entityId}?${params.toString()}`);
+        return res.data;
+    } catch (e) {
+        console.warn(e);
+        return {items: []};
+    }
+}
 
-        // const params = new URLSearchParams();
-        // if (filters.user_id) params.append("from_id", filters.user_id.toString());
-        // ...
-        // const res = await api.get(`/tx/history?${params.toString()}`);
-        // return res.data;
+export interface Quote {
+    amount: number;
+    currency: string;
+    to_amount: number; // сколько получит получатель после комиссии
+    to_currency: string;
+    fx_rate?: string;
+    fee: number;
+}
 
-        return [];
+// GetQuote считает комиссию платформы до перевода; null, если посчитать не удалось
+export async function GetQuote(
+    fromType: 'user' | 'org' | 'project' | 'external',
+    fromId: number,
+    toType: 'user' | 'org' | 'project',
+    toId: number,
+    amount: number
+): Promise<Quote | null> {
+    try {
+        const res = await api.post<Quote>('/tx/fees/quote', {
+            from_type: fromType,
+            from_id: fromId,
+            to_type: toType,
+            to_id: toId,
+            amount: amount
+        });
+        return res.data;
     } catch (e) {
         console.warn(e);
-        return [];
+        return null;
     }
-}
\ No newline at end of file
+}
@@ -0,0 +1,46 @@
+'use client'
+
+import React, {useEffect, useState} from 'react';
+import {GetReviewQueue, Project} from "@/api/project";
+import ProjectPreviewNew from "@/app/components/project-preview-new";
+import Spinner from "@/app/components/spinner";
+import {useUserStore} from "@/context/user-store";
+import styles from "@/app/projects/projects.module.css";
+
+// Очередь модерации: проекты, отправленные организациями на проверку.
+// Решение принимается на странице проекта.
+export default function ReviewQueuePage() {
+    const user = useUserStore((state) => state.user);
+    const [projects, setProjects] = useState<Project[]>([]);
+    const [loading, setLoading] = useState(true);
+
+    useEffect(() => {
+        if (!user?.is_admin) return;
+        GetReviewQueue(50, 0)
+            .then(setProjects)
+            .finally(() => setLoading(false));
+    }, [user]);
+
+    if (!user?.is_admin) {
+        return <div className={styles.empty_state}>Страница доступна только администраторам</div>;
+    }
+
+    return (
+        <div className={styles.container}>
+            <h1 className={styles.title}>Проекты на модерации</h1>
+            {loading ? (
+                <div className="flex justify-center p-20"><Spinner /></div>
+            ) : projects.length > 0 ? (
+                <div className={styles.grid_container}>
+                    {projects.map(project => (
+                        <ProjectPreviewNew key={project.id} project={project} />
+                    ))}
+                </div>
+            ) : (
+                <div className={styles.empty_state}>
+                    Очередь пуста
+                </div>
+            )}
+        </div>
+    );
+}
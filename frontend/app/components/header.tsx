@@ -70,6 +70,9 @@ const Header = () => {
                 <div className={styles.actions_group}>
                     <Link href="/create-project" className={styles.create_btn}>Создать проект</Link>
                     <Link href="/projects" className={styles.invest_btn}>Инвестировать</Link>
+                    {user?.is_admin && (
+                        <Link href="/admin/review" className={styles.login_link}>Модерация</Link>
+                    )}
 
                     {!user ? (
                         <Link href="/login" className={styles.login_link}>Войти</Link>
@@ -115,6 +118,9 @@ const Header = () => {
                         <>
                             <Link href="/user-profile" onClick={() => setBurger(false)}>Мой профиль</Link>
                             <Link href="/organisation/my" onClick={() => setBurger(false)}>Мои организации</Link>
+                            {user.is_admin && (
+                                <Link href="/admin/review" onClick={() => setBurger(false)}>Модерация проектов</Link>
+                            )}
                         </>
                     )}
 
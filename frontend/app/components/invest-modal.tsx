@@ -12,6 +12,7 @@ import Link from "next/link";
 import {Wallet} from "lucide-react";
 import TopUpModal from "@/app/components/top-up-modal";
 import SimpleModal from "@/app/components/simple-modal";
+import {useFeeQuote} from "@/hooks/use-fee-quote";
 
 export default function InvestModal({projectId, projectName, onSuccess}: {projectId: number, projectName: string, onSuccess?: () => void}) {
     const [amount, setAmount] = useState<string>("1000");
@@ -24,6 +25,7 @@ export default function InvestModal({projectId, projectName, onSuccess}: {projec
     const currentBalance = user?.balance || 0;
     const investAmount = parseFloat(amount) || 0;
     const isEnough = currentBalance >= investAmount;
+    const quote = useFeeQuote('user', user?.id || 0, 'project', projectId, investAmount, open && !!user);
 
     const handleInvest = async () => {
         if (!user) {
@@ -93,6 +95,19 @@ export default function InvestModal({projectId, projectName, onSuccess}: {projec
                         />
                     </div>
 
+                    {quote && quote.fee > 0 && (
+                        <div className="p-3 bg-white/5 rounded-lg border border-white/10 text-sm space-y-1">
+                            <div className="flex justify-between">
+                                <span>Комиссия платформы (платит проект)</span>
+                                <span>{quote.fee} {quote.to_currency}</span>
+                            </div>
+                            <div className="flex justify-between">
+                                <span>Проект получит</span>
+                                <span className="font-bold">{quote.to_amount} {quote.to_currency}</span>
+                            </div>
+                        </div>
+                    )}
+
                     {/* Логика отображения кнопок */}
                     {!isEnough ? (
                         <div className="text-center space-y-2 bg-red-900/20 p-3 rounded">
@@ -174,7 +174,9 @@ export default function PaymentSection() {
         if (result) {
             setWithdrawMessage({
                 isError: false,
-                message: `Запрос на вывод ${value} ₽ отправлен. ID: ${result}`
+                message: result.status === "review"
+                    ? `Запрос на вывод ${value} ₽ отправлен на проверку. ID: ${result.withdrawal_id}`
+                    : `Запрос на вывод ${value} ₽ отправлен. ID: ${result.withdrawal_id}`
             });
         }
 
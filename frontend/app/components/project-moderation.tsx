@@ -0,0 +1,109 @@
+'use client'
+import { useEffect, useState } from "react";
+import { Button } from "@/app/components/ui/button";
+import { Send, CheckCircle, XCircle } from "lucide-react";
+import { useUserStore } from "@/context/user-store";
+import MessageComponent from "@/app/components/message";
+import { Message } from "@/api/api";
+import Spinner from "@/app/components/spinner";
+import { GetProjectStatusHistory, ProjectStatus, ReviewProject, SubmitProject } from "@/api/project";
+
+interface Props {
+    projectId: number;
+    status?: ProjectStatus;
+    onUpdate: (newStatus: ProjectStatus) => void;
+}
+
+// Черновик и проект на модерации видят только организация и администраторы,
+// поэтому кнопка отправки на модерацию показывается всем, кому виден черновик
+export default function ProjectModeration({ projectId, status, onUpdate }: Props) {
+    const { user } = useUserStore();
+    const [loading, setLoading] = useState(false);
+    const [message, setMessage] = useState<Message | null>(null);
+    const [reason, setReason] = useState("");
+    const [rejectReason, setRejectReason] = useState<string | null>(null);
+
+    // Последняя причина отказа модератора, чтобы организация знала, что исправить
+    useEffect(() => {
+        if (status !== 'draft' || !user) return;
+        GetProjectStatusHistory(projectId).then(history => {
+            const rejection = history
+                .filter(h => h.action === 'transition' && h.from_status === 'pending_review' && h.to_status === 'draft')
+                .sort((a, b) => b.created_at.localeCompare(a.created_at))[0];
+            setRejectReason(rejection?.reason ?? null);
+        });
+    }, [projectId, status, user]);
+
+    if (status !== 'draft' && status !== 'pending_review') return null;
+
+    const handleSubmit = async () => {
+        setLoading(true);
+        setMessage(null);
+        if (await SubmitProject(projectId, setMessage)) {
+            onUpdate('pending_review');
+        }
+        setLoading(false);
+    };
+
+    const handleReview = async (approve: boolean) => {
+        setLoading(true);
+        setMessage(null);
+        if (await ReviewProject(projectId, approve, approve ? "" : reason, setMessage)) {
+            setReason("");
+            onUpdate(approve ? 'active' : 'draft');
+        }
+        setLoading(false);
+    };
+
+    return (
+        <div className="mt-6 border-t border-gray-700 pt-4">
+            <p className="text-xs text-gray-500 mb-2 uppercase font-bold tracking-wider">Модерация</p>
+            <div className="flex flex-col gap-2">
+                {status === 'draft' ? (
+                    <>
+                        <p className="text-sm text-gray-300">
+                            Проект — черновик. Его видит только организация, в каталог он попадет после одобрения модератором.
+                        </p>
+                        {rejectReason && (
+                            <p className="text-sm text-red-400">Отклонен модератором: {rejectReason}</p>
+                        )}
+                        <Button onClick={handleSubmit} disabled={loading} className="w-full gap-2">
+                            {loading ? <Spinner size={20} /> : <Send />}
+                            Отправить на модерацию
+                        </Button>
+                    </>
+                ) : (
+                    <>
+                        <p className="text-sm text-gray-300">
+                            Проект на модерации. Редактирование недоступно до решения модератора.
+                        </p>
+                        {user?.is_admin && (
+                            <>
+                                <Button onClick={() => handleReview(true)} disabled={loading} className="w-full gap-2">
+                                    {loading ? <Spinner size={20} /> : <CheckCircle />}
+                                    Одобрить
+                                </Button>
+                                <textarea
+                                    value={reason}
+                                    onChange={(e) => setReason(e.target.value)}
+                                    placeholder="Причина отказа"
+                                    className="w-full p-3 bg-[#333] text-white border border-[#555] rounded min-h-[80px]"
+                                />
+                                <Button
+                                    variant="destructive"
+                                    onClick={() => handleReview(false)}
+                                    disabled={loading || reason.trim() === ""}
+                                    className="w-full gap-2"
+                                >
+                                    {loading ? <Spinner size={20} /> : <XCircle />}
+                                    Отклонить
+                                </Button>
+                            </>
+                        )}
+                    </>
+                )}
+                <MessageComponent message={message} />
+            </div>
+        </div>
+    );
+}
@@ -11,6 +11,7 @@ import {BUCKETS} from "@/lib/config";
 import InvestModal from "@/app/components/invest-modal";
 import BannedBanner from "@/app/components/banned-banner";
 import AdminBanControl from "@/app/components/admin-ban-control";
+import ProjectModeration from "@/app/components/project-moderation";
 import {GetUserById, User} from "@/api/user";
 import {toast} from "sonner";
 import { Button } from "@/app/components/ui/button";
@@ -208,7 +209,8 @@ export default function ProjectView({project: initialProject, setProject: extern
                                     Обновить обложку
                                 </Button>
 
-                                <Button
+                                {/* После отправки на модерацию проект не меняется */}
+                                {project.status === 'draft' && <Button
                                     onClick={() => setIsEditing(!isEditing)}
                                     style={{
                                         backgroundColor: isEditing ? '#ff6666' : '#825e9c',
@@ -220,7 +222,7 @@ export default function ProjectView({project: initialProject, setProject: extern
                                 >
                                     <Edit3 size={16} />
                                     {isEditing ? 'Отмена' : 'Редактировать'}
-                                </Button>
+                                </Button>}
 
                                 <Button
                                     onClick={() => {
@@ -344,10 +346,10 @@ export default function ProjectView({project: initialProject, setProject: extern
                                     <div style={{ display: 'flex', gap: '1rem' }}>
                                         <Button
                                             onClick={() => {
-                                                UpdateProject(project.id, editData, (msg) => {
-                                                    setMessage(msg);
-                                                    if (!msg.isError) {
-                                                        setProject(editData);
+                                                UpdateProject(project.id, editData, setMessage).then(updated => {
+                                                    if (updated) {
+                                                        setProject(updated);
+                                                        setEditData(updated);
                                                         setIsEditing(false);
                                                     }
                                                 });
@@ -368,6 +370,15 @@ export default function ProjectView({project: initialProject, setProject: extern
                             </div>
                         )}
 
+                        <ProjectModeration
+                            projectId={project.id}
+                            status={project.status}
+                            onUpdate={(status) => {
+                                setProject({...project, status, is_public: status === 'active'});
+                                setIsEditing(false);
+                            }}
+                        />
+
                         <AdminBanControl
                             entityType="project"
                             entityId={project.id}
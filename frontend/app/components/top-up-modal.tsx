@@ -9,12 +9,16 @@ import Spinner from "@/app/components/spinner";
 import {InitPayment} from "@/api/payment";
 import {PlusCircle} from "lucide-react";
 import SimpleModal from "@/app/components/simple-modal";
+import {useUserStore} from "@/context/user-store";
+import {useFeeQuote} from "@/hooks/use-fee-quote";
 
 export default function TopUpModal() {
     const [amount, setAmount] = useState<string>("1000");
     const [loading, setLoading] = useState(false);
     const [message, setMessage] = useState<Message | null>(null);
     const [isOpen, setIsOpen] = useState(false);
+    const {user} = useUserStore();
+    const quote = useFeeQuote('external', 0, 'user', user?.id, parseFloat(amount), isOpen);
 
     const handleTopUp = async () => {
         const value = parseFloat(amount);
@@ -73,6 +77,11 @@ export default function TopUpModal() {
                         <p className="text-3xl font-bold text-gray-900">
                             {parseFloat(amount || "0").toLocaleString()} ₽
                         </p>
+                        {quote && quote.fee > 0 && (
+                            <p className="text-sm text-gray-900 mt-2">
+                                Комиссия платформы: {quote.fee.toLocaleString()} ₽, на кошелек поступит {quote.to_amount.toLocaleString()} ₽
+                            </p>
+                        )}
                     </div>
 
                     <div className="bg-gray-50 p-4 rounded-lg border border-gray-200">
@@ -147,7 +147,9 @@ export default function WithdrawModal() {
         if (result) {
             setMessage({
                 isError: false,
-                message: `Запрос на вывод ${value} ₽ успешно отправлен. ID: ${result}`
+                message: result.status === "review"
+                    ? `Запрос на вывод ${value} ₽ отправлен на проверку. ID: ${result.withdrawal_id}`
+                    : `Запрос на вывод ${value} ₽ успешно отправлен. ID: ${result.withdrawal_id}`
             });
             setTimeout(() => {
                 setIsOpen(false);
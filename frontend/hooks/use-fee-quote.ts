@@ -0,0 +1,32 @@
+import {useEffect, useState} from "react";
+import {GetQuote, Quote} from "@/api/transactions";
+
+// Пересчитывает комиссию при изменении суммы, с задержкой, чтобы не дергать API на каждый символ
+export function useFeeQuote(
+    fromType: 'user' | 'org' | 'project' | 'external',
+    fromId: number,
+    toType: 'user' | 'org' | 'project',
+    toId: number | undefined,
+    amount: number,
+    enabled: boolean = true
+) {
+    const [quote, setQuote] = useState<Quote | null>(null);
+
+    useEffect(() => {
+        if (!enabled || !toId || !(amount > 0)) {
+            setQuote(null);
+            return;
+        }
+        let cancelled = false;
+        const timer = setTimeout(async () => {
+            const q = await GetQuote(fromType, fromId, toType, toId, amount);
+            if (!cancelled) setQuote(q);
+        }, 300);
+        return () => {
+            cancelled = true;
+            clearTimeout(timer);
+        };
+    }, [fromType, fromId, toType, toId, amount, enabled]);
+
+    return quote;
+}
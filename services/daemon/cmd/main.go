@@ -1,18 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 	"github.com/robfig/cron/v3"
 
+	"github.com/Starostina-elena/investment_platform/services/daemon/events"
 	"github.com/Starostina-elena/investment_platform/services/daemon/jobs"
+	"github.com/Starostina-elena/investment_platform/services/daemon/outbox"
 )
 
 func openDB() *sqlx.DB {
@@ -29,6 +34,21 @@ func openDB() *sqlx.DB {
 	return db
 }
 
+func openRedis() *redis.Client {
+	host := os.Getenv("REDIS_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := os.Getenv("REDIS_PORT")
+	if port == "" {
+		port = "6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr: host + ":" + port,
+	})
+	return client
+}
+
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	logger.Info("starting daemon service")
@@ -36,6 +56,9 @@ func main() {
 	db := openDB()
 	defer db.Close()
 
+	redisClient := openRedis()
+	defer redisClient.Close()
+
 	c := cron.New()
 
 	expiredJob := jobs.NewExpiredProjectsJob(db, logger)
@@ -50,13 +73,39 @@ func main() {
 		log.Fatalf("failed to add recalculate payback cron job: %v", err)
 	}
 
+	reconciliationJob := jobs.NewReconciliationJob(db, logger)
+	_, err = c.AddFunc("0 3 * * *", reconciliationJob.Run)
+	if err != nil {
+		log.Fatalf("failed to add reconciliation cron job: %v", err)
+	}
+
+	// списания повторяются раз в сутки, а очередной месяц наступает в любое время суток,
+	// поэтому запускаем ежечасно; повторный запуск ничего не списывает дважды
+	recurringJob := jobs.NewRecurringPledgesJob(logger)
+	_, err = c.AddFunc("15 * * * *", recurringJob.Run)
+	if err != nil {
+		log.Fatalf("failed to add recurring pledges cron job: %v", err)
+	}
+
 	c.Start()
 	logger.Info("daemon service started, cron jobs scheduled")
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// события об изменениях, которые daemon делает в обход других сервисов
+	relay := outbox.NewRelay(db, redisClient, outbox.Service, *logger)
+	go relay.Run(ctx, time.Second)
+
+	consumer := events.NewConsumer(redisClient, "daemon", logger)
+	consumer.Handle("org.banned", events.OrgBannedHandler(db, logger))
+	go consumer.Run(ctx)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
 
 	logger.Info("shutting down daemon service...")
+	cancel()
 	c.Stop()
 }
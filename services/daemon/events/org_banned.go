@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/Starostina-elena/investment_platform/services/daemon/outbox"
+)
+
+type orgBannedEvent struct {
+	OrgID int    `json:"org_id"`
+	Name  string `json:"name"`
+}
+
+// OrgBannedHandler снимает с публикации незавершенные проекты заблокированной
+// организации, чтобы они пропали из каталога, и останавливает регулярные пожертвования
+// ей и ее проектам. Повторная обработка безопасна.
+func OrgBannedHandler(db *sqlx.DB, log *slog.Logger) HandlerFunc {
+	return func(ctx context.Context, e Event) error {
+		var event orgBannedEvent
+		if err := json.Unmarshal(e.Payload, &event); err != nil {
+			log.Error("failed to decode org banned event", "event_id", e.EventID, "error", err)
+			return nil
+		}
+
+		hidden, err := hideProjects(ctx, db, event.OrgID)
+		if err != nil {
+			return err
+		}
+		log.Info("hid projects of banned organisation", "org_id", event.OrgID, "projects", hidden)
+
+		stopped, err := stopRecurringPledges(ctx, db, event.OrgID)
+		if err != nil {
+			return err
+		}
+		log.Info("stopped recurring pledges to banned organisation", "org_id", event.OrgID, "pledges", stopped)
+		return nil
+	}
+}
+
+// hideProjects снимает проекты с публикации и в той же транзакции сообщает проектному
+// сервису, какие из них сбросить из кеша
+func hideProjects(ctx context.Context, db *sqlx.DB, orgID int) (int, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var ids []int
+	err = tx.SelectContext(ctx, &ids, `
+		UPDATE projects SET is_public = false
+		WHERE creator_id = $1 AND is_public = true AND is_completed = false
+		RETURNING id
+	`, orgID)
+	if err != nil {
+		return 0, err
+	}
+	if err := outbox.AddProjectUpdated(ctx, tx, ids...); err != nil {
+		return 0, err
+	}
+	return len(ids), tx.Commit()
+}
+
+const stopReasonOrgBanned = "recipient is banned"
+
+// stopRecurringPledges останавливает пожертвования организации и ее проектам и закрывает
+// их незавершенные списания; деньги, уже пришедшие с карты, остаются на кошельке пользователя
+func stopRecurringPledges(ctx context.Context, db *sqlx.DB, orgID int) (int64, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var ids []int64
+	err = tx.SelectContext(ctx, &ids, `
+		UPDATE recurring_pledges SET status = 'stopped', stop_reason = $1, updated_at = NOW()
+		WHERE status IN ('active', 'paused') AND (
+			(target_type = 'org' AND target_id = $2) OR
+			(target_type = 'project' AND target_id IN (SELECT id FROM projects WHERE creator_id = $2))
+		)
+		RETURNING id
+	`, stopReasonOrgBanned, orgID)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE recurring_charges SET status = 'skipped', last_error = $1, updated_at = NOW()
+		WHERE pledge_id = ANY($2) AND status IN ('pending', 'paying', 'paid')
+	`, stopReasonOrgBanned, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), tx.Commit()
+}
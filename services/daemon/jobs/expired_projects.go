@@ -2,6 +2,7 @@ package jobs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -9,6 +10,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/Starostina-elena/investment_platform/services/daemon/money"
+	"github.com/Starostina-elena/investment_platform/services/daemon/outbox"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -16,21 +19,36 @@ type ExpiredProjectsJob struct {
 	db              *sqlx.DB
 	log             *slog.Logger
 	notificationURL string
+	txClient        *transactionClient
 }
 
 type Investment struct {
-	UserID      int     `db:"user_id"`
-	UserEmail   string  `db:"user_email"`
-	Amount      float64 `db:"amount"`
-	ProjectName string  `db:"project_name"`
+	UserID      int          `db:"user_id"`
+	UserEmail   string       `db:"user_email"`
+	Amount      money.Amount `db:"amount"`
+	ProjectName string       `db:"project_name"`
 }
 
 type Project struct {
-	ID          int       `db:"id"`
-	Name        string    `db:"name"`
-	OwnerEmail  string    `db:"owner_email"`
-	CreatedAt   time.Time `db:"created_at"`
-	DurationDays int      `db:"duration_days"`
+	ID               int          `db:"id"`
+	Name             string       `db:"name"`
+	OwnerEmail       string       `db:"owner_email"`
+	CreatedAt        time.Time    `db:"created_at"`
+	DurationDays     int          `db:"duration_days"`
+	MonetizationType string       `db:"monetization_type"`
+	CurrentMoney     money.Amount `db:"current_money"`
+	WantedMoney      money.Amount `db:"wanted_money"`
+	Currency         string       `db:"currency"`
+}
+
+// Contribution — чистый вклад инвестора в проект: вложения вместе с удержанной с них
+// комиссией минус уже возвращенное. Fee — часть вклада, которая осталась у платформы.
+type Contribution struct {
+	InvestorType string       `db:"investor_type"`
+	InvestorID   int          `db:"investor_id"`
+	Email        string       `db:"email"`
+	Amount       money.Amount `db:"amount"`
+	Fee          money.Amount `db:"fee"`
 }
 
 func NewExpiredProjectsJob(db *sqlx.DB, log *slog.Logger) *ExpiredProjectsJob {
@@ -42,6 +60,7 @@ func NewExpiredProjectsJob(db *sqlx.DB, log *slog.Logger) *ExpiredProjectsJob {
 		db:              db,
 		log:             log,
 		notificationURL: notifURL,
+		txClient:        newTransactionClient(),
 	}
 }
 
@@ -50,11 +69,12 @@ func (j *ExpiredProjectsJob) Run() {
 
 	var expiredProjects []Project
 	query := `
-		SELECT p.id, p.name, u.email as owner_email, p.created_at, p.duration_days
+		SELECT p.id, p.name, u.email as owner_email, p.created_at, p.duration_days,
+		       p.monetization_type, COALESCE(p.current_money, 0) as current_money, p.wanted_money, p.currency
 		FROM projects p
 		JOIN organizations o ON p.creator_id = o.id
 		JOIN users u ON o.owner = u.id
-		WHERE p.created_at + (p.duration_days || ' days')::interval < NOW() AND p.is_completed = false
+		WHERE p.status = 'active' AND p.activated_at + (p.duration_days || ' days')::interval < NOW()
 	`
 	err := j.db.Select(&expiredProjects, query)
 	if err != nil {
@@ -70,6 +90,8 @@ func (j *ExpiredProjectsJob) Run() {
 		}
 	}
 
+	j.refundFailedProjects()
+
 	j.log.Info("expired projects job completed")
 }
 
@@ -92,12 +114,41 @@ func (j *ExpiredProjectsJob) processExpiredProject(project Project) error {
 		return fmt.Errorf("fetch investments: %w", err)
 	}
 
-	_, err = tx.Exec(`
-		UPDATE projects SET is_completed = true, is_public = false WHERE id = $1
-	`, project.ID)
+	// благотворительный проект оставляет себе все собранное, остальные при недоборе
+	// цели считаются провалившимися, и вклады возвращаются инвесторам
+	failed := project.MonetizationType != "charity" && project.CurrentMoney < project.WantedMoney
+	status, reason := "funded", "срок сбора истек"
+	if failed {
+		status, reason = "failed", "срок сбора истек, цель не достигнута"
+	}
+	res, err := tx.Exec(`
+		UPDATE projects SET status = $2, is_completed = true, is_public = false,
+		                    failed_at = CASE WHEN $3 THEN NOW() ELSE failed_at END
+		WHERE id = $1 AND status = 'active'
+	`, project.ID, status, failed)
 	if err != nil {
 		return fmt.Errorf("update project: %w", err)
 	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// проект успели завершить вручную после выборки
+		return nil
+	}
+	if err := addStatusChange(tx, project.ID, "active", status, reason); err != nil {
+		return fmt.Errorf("add status history: %w", err)
+	}
+	if err := outbox.AddProjectUpdated(context.Background(), tx, project.ID); err != nil {
+		return fmt.Errorf("add project updated event: %w", err)
+	}
+
+	// обещания инвестиций не набрали цель до дедлайна, резерв с кошельков снимается
+	res, err = tx.Exec(`
+		UPDATE balance_holds SET status = 'released', updated_at = NOW()
+		WHERE to_type = 'project' AND to_id = $1 AND status = 'active'
+	`, project.ID)
+	if err != nil {
+		return fmt.Errorf("release pledges: %w", err)
+	}
+	releasedPledges, _ := res.RowsAffected()
 
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit tx: %w", err)
@@ -109,11 +160,120 @@ func (j *ExpiredProjectsJob) processExpiredProject(project Project) error {
 
 	j.sendEmailToOwner(project.OwnerEmail, project.Name)
 
-	j.log.Info("processed expired project", "project_id", project.ID, "investors", len(investments))
+	j.log.Info("processed expired project", "project_id", project.ID, "investors", len(investments), "released_pledges", releasedPledges, "failed", failed)
+	return nil
+}
+
+// refundFailedProjects возвращает вклады инвесторам провалившихся проектов. Проекты,
+// где часть переводов не прошла (например, организация уже вывела деньги), остаются
+// в выборке и повторяются при следующем запуске: ключ идемпотентности не даст
+// вернуть одному инвестору дважды.
+func (j *ExpiredProjectsJob) refundFailedProjects() {
+	var projects []Project
+	err := j.db.Select(&projects, `
+		SELECT p.id, p.name, p.currency
+		FROM projects p
+		WHERE p.failed_at IS NOT NULL AND p.investors_refunded_at IS NULL
+	`)
+	if err != nil {
+		j.log.Error("failed to fetch failed projects", "error", err)
+		return
+	}
+
+	for _, project := range projects {
+		if err := j.refundInvestors(project); err != nil {
+			j.log.Error("failed to refund investors", "project_id", project.ID, "error", err)
+		}
+	}
+}
+
+func (j *ExpiredProjectsJob) refundInvestors(project Project) error {
+	// инвестор получает обратно все, что вложил: проект отдает зачисленное ему,
+	// а платформа — удержанную комиссию. У возврата комиссия отрицательная (ее доплатила
+	// платформа), поэтому из вклада вычитается списанное с проекта вместе с ней.
+	var contributions []Contribution
+	err := j.db.Select(&contributions, `
+		WITH flows AS (
+			SELECT 'user' AS investor_type, from_id AS investor_id, COALESCE(to_amount, amount) + fee AS amount, fee
+			FROM transactions WHERE reciever_id = $1 AND type = 'user_to_project'
+			UNION ALL
+			SELECT 'org', from_id, COALESCE(to_amount, amount) + fee, fee
+			FROM transactions WHERE reciever_id = $1 AND type = 'org_to_project'
+			UNION ALL
+			SELECT 'user', reciever_id, LEAST(fee, 0) - amount, LEAST(fee, 0)
+			FROM transactions WHERE from_id = $1 AND type = 'project_to_user'
+			UNION ALL
+			SELECT 'org', reciever_id, LEAST(fee, 0) - amount, LEAST(fee, 0)
+			FROM transactions WHERE from_id = $1 AND type = 'project_to_org'
+		)
+		SELECT f.investor_type, f.investor_id, COALESCE(u.email, ou.email, '') AS email,
+		       SUM(f.amount) AS amount, GREATEST(SUM(f.fee), 0) AS fee
+		FROM flows f
+		LEFT JOIN users u ON f.investor_type = 'user' AND u.id = f.investor_id
+		LEFT JOIN organizations o ON f.investor_type = 'org' AND o.id = f.investor_id
+		LEFT JOIN users ou ON ou.id = o.owner
+		GROUP BY f.investor_type, f.investor_id, u.email, ou.email
+		HAVING SUM(f.amount) > 0
+	`, project.ID)
+	if err != nil {
+		return fmt.Errorf("fetch contributions: %w", err)
+	}
+
+	failures := 0
+	for _, c := range contributions {
+		key := fmt.Sprintf("project:%d:refund:%s:%d", project.ID, c.InvestorType, c.InvestorID)
+		replayed, err := j.txClient.Refund(key, project.ID, c.InvestorType, c.InvestorID, c.Amount, c.Fee, project.Currency)
+		if err != nil {
+			j.log.Error("failed to refund investor", "project_id", project.ID, "investor_type", c.InvestorType, "investor_id", c.InvestorID, "amount", c.Amount, "fee", c.Fee, "error", err)
+			failures++
+			continue
+		}
+		if !replayed && c.Email != "" {
+			j.sendEmail(c.Email, "project_refund", project.Name, c.Amount)
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d of %d refunds failed", failures, len(contributions))
+	}
+
+	tx, err := j.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE projects SET investors_refunded_at = NOW() WHERE id = $1`, project.ID); err != nil {
+		return fmt.Errorf("mark refunded: %w", err)
+	}
+	res, err := tx.Exec(`UPDATE projects SET status = 'closed' WHERE id = $1 AND status = 'failed'`, project.ID)
+	if err != nil {
+		return fmt.Errorf("close project: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if err := addStatusChange(tx, project.ID, "failed", "closed", "вклады возвращены инвесторам"); err != nil {
+			return fmt.Errorf("add status history: %w", err)
+		}
+		if err := outbox.AddProjectUpdated(context.Background(), tx, project.ID); err != nil {
+			return fmt.Errorf("add project updated event: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	j.log.Info("refunded investors of failed project", "project_id", project.ID, "investors", len(contributions))
 	return nil
 }
 
-func (j *ExpiredProjectsJob) sendEmail(email, notifType, projectName string, amount float64) {
+// addStatusChange пишет переход статуса, сделанный daemon, в историю проекта
+func addStatusChange(tx *sqlx.Tx, projectID int, from, to, reason string) error {
+	_, err := tx.Exec(`
+		INSERT INTO project_status_history (project_id, from_status, to_status, reason)
+		VALUES ($1, $2, $3, $4)
+	`, projectID, from, to, reason)
+	return err
+}
+
+func (j *ExpiredProjectsJob) sendEmail(email, notifType, projectName string, amount money.Amount) {
 	payload := map[string]interface{}{
 		"email":        email,
 		"type":         notifType,
@@ -1,9 +1,13 @@
 package jobs
 
 import (
+	"context"
 	"log/slog"
+	"math/big"
 	"time"
 
+	"github.com/Starostina-elena/investment_platform/services/daemon/money"
+	"github.com/Starostina-elena/investment_platform/services/daemon/outbox"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -18,9 +22,9 @@ type ProjectInfo struct {
 }
 
 type InvestorTransaction struct {
-	UserID     int       `db:"user_id"`
-	Amount     float64   `db:"amount"`
-	InvestedAt time.Time `db:"invested_at"`
+	UserID     int          `db:"user_id"`
+	Amount     money.Amount `db:"amount"`
+	InvestedAt time.Time    `db:"invested_at"`
 }
 
 func NewRecalculatePaybackJob(db *sqlx.DB, log *slog.Logger) *RecalculatePaybackJob {
@@ -62,33 +66,48 @@ func (j *RecalculatePaybackJob) recalculateForProject(project ProjectInfo) error
 		return err
 	}
 
-	totalPayback := 0.0
+	totalPayback := money.Zero
 	for _, payback := range investorPaybacks {
 		totalPayback += payback
 	}
 
-	_, err = j.db.Exec(`
+	tx, err := j.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
 		UPDATE projects SET money_required_to_payback = $1 WHERE id = $2
 	`, totalPayback, project.ID)
 	if err != nil {
 		j.log.Error("failed to update money_required_to_payback", "project_id", project.ID, "error", err)
 		return err
 	}
+	// проектный сервис отдает сумму из кеша, а сервис транзакций прибавляет к ней новые вложения
+	if err := outbox.AddProjectUpdated(context.Background(), tx, project.ID); err != nil {
+		j.log.Error("failed to add project updated event", "project_id", project.ID, "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
 	j.log.Info("updated money_required_to_payback", "project_id", project.ID, "amount", totalPayback)
 	return nil
 }
 
-func (j *RecalculatePaybackJob) calculateInvestorPaybacks(projectID int, percent float64) (map[int]float64, error) {
+func (j *RecalculatePaybackJob) calculateInvestorPaybacks(projectID int, percent float64) (map[int]money.Amount, error) {
 	var creatorID int
 	err := j.db.Get(&creatorID, `SELECT creator_id FROM projects WHERE id = $1`, projectID)
 	if err != nil {
 		return nil, err
 	}
 
+	// доход считается от всего вложения: комиссию платформы платит проект
 	var investorTxs []InvestorTransaction
 	query := `
-		SELECT from_id as user_id, amount, time_at as invested_at
+		SELECT from_id as user_id, COALESCE(to_amount, amount) + fee as amount, time_at as invested_at
 		FROM transactions
 		WHERE reciever_id = $1 AND type = 'user_to_project' AND from_id != $2
 		ORDER BY time_at ASC
@@ -121,19 +140,30 @@ func (j *RecalculatePaybackJob) calculateInvestorPaybacks(projectID int, percent
 		excludeUsers[userID] = true
 	}
 
-	result := make(map[int]float64)
+	rate, err := money.PercentRate(percent)
+	if err != nil {
+		j.log.Error("invalid project percent", "project_id", projectID, "percent", percent, "error", err)
+		return nil, err
+	}
+	result := make(map[int]money.Amount)
 	now := time.Now()
 	for userID, transactions := range investorMap {
 		if excludeUsers[userID] {
 			continue
 		}
 
-		paybackAmount := 0.0
+		// проценты считаются точно и округляются один раз на инвестора
+		paybackAmount := new(big.Rat)
 		for _, tx := range transactions {
-			days := int(now.Sub(tx.InvestedAt).Hours() / 24)
-			paybackAmount += tx.Amount * (percent / 100) * float64(days)
+			days := int64(now.Sub(tx.InvestedAt).Hours() / 24)
+			interest := new(big.Rat).Mul(tx.Amount.Rat(), rate)
+			paybackAmount.Add(paybackAmount, interest.Mul(interest, big.NewRat(days, 1)))
+		}
+		result[userID], err = money.FromRat(paybackAmount, money.RoundHalfUp)
+		if err != nil {
+			j.log.Error("payback amount is out of range", "project_id", projectID, "user_id", userID, "error", err)
+			return nil, err
 		}
-		result[userID] = paybackAmount
 	}
 
 	return result, nil
@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Starostina-elena/investment_platform/services/daemon/money"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReconciliationJob сверяет сохраненные балансы пользователей, организаций и
+// проектов с пересчитанными по журналу transactions. Расхождения сохраняются
+// для администраторов и отправляются им письмом; при RECONCILIATION_FREEZE=true
+// балансы с расхождениями замораживаются до разбора.
+type ReconciliationJob struct {
+	db              *sqlx.DB
+	log             *slog.Logger
+	notificationURL string
+	adminEmails     []string
+	freeze          bool
+}
+
+type BalanceDrift struct {
+	EntityType      string       `db:"entity_type" json:"entity_type"`
+	EntityID        int          `db:"entity_id" json:"entity_id"`
+	Currency        string       `db:"currency" json:"currency"`
+	StoredBalance   money.Amount `db:"stored_balance" json:"stored_balance"`
+	ComputedBalance money.Amount `db:"computed_balance" json:"computed_balance"`
+	Diff            money.Amount `db:"diff" json:"diff"`
+}
+
+// balanceDriftQuery — пересчет балансов по каждой валюте. Исходящие суммы берутся
+// в валюте списания, входящие — в валюте зачисления (после конвертации и за вычетом комиссии).
+// Точка отсчета — входящие остатки, перенесенные в леджер при его введении (журнал opening_balance).
+// Они уже включают всю прежнюю историю, поэтому из transactions берутся только переводы,
+// проведенные через леджер (у них есть свой журнал), иначе старые переводы учитывались бы дважды.
+// Базовая валюта пользователей и организаций хранится в balance, остальные — в wallet_balances.
+const balanceDriftQuery = `
+	WITH ledgered AS (
+		SELECT t.* FROM transactions t JOIN ledger_journals lj ON lj.transaction_id = t.id
+	),
+	movements AS (
+		SELECT 'user' AS entity_type, reciever_id AS entity_id, to_currency AS currency, COALESCE(to_amount, amount) AS amount
+		FROM ledgered WHERE type IN ('project_to_user', 'user_deposit')
+		UNION ALL
+		SELECT 'user', from_id, currency, -amount FROM ledgered WHERE type IN ('user_to_project', 'user_to_org', 'user_withdraw')
+		UNION ALL
+		SELECT 'org', reciever_id, to_currency, COALESCE(to_amount, amount) FROM ledgered WHERE type IN ('project_to_org', 'user_to_org', 'org_deposit')
+		UNION ALL
+		SELECT 'org', from_id, currency, -amount FROM ledgered WHERE type IN ('org_to_project', 'org_withdraw')
+		UNION ALL
+		SELECT 'project', reciever_id, to_currency, COALESCE(to_amount, amount) FROM ledgered WHERE type IN ('user_to_project', 'org_to_project')
+		UNION ALL
+		SELECT 'project', from_id, currency, -amount FROM ledgered WHERE type IN ('project_to_user', 'project_to_org')
+		UNION ALL
+		SELECT a.entity_type, a.entity_id, a.currency, e.amount
+		FROM ledger_entries e
+		JOIN ledger_journals j ON j.id = e.journal_id AND j.kind = 'opening_balance'
+		JOIN ledger_accounts a ON a.id = e.account_id
+	),
+	computed AS (
+		SELECT entity_type, entity_id, currency, SUM(amount) AS balance FROM movements GROUP BY entity_type, entity_id, currency
+	),
+	stored AS (
+		SELECT 'user' AS entity_type, id AS entity_id, 'RUB' AS currency, COALESCE(balance, 0) AS balance FROM users
+		UNION ALL
+		SELECT 'org', id, 'RUB', COALESCE(balance, 0) FROM organizations
+		UNION ALL
+		SELECT 'project', id, currency, COALESCE(current_money, 0) FROM projects
+		UNION ALL
+		SELECT entity_type, entity_id, currency, balance FROM wallet_balances
+	)
+	SELECT s.entity_type, s.entity_id, s.currency, s.balance AS stored_balance,
+	       COALESCE(c.balance, 0) AS computed_balance,
+	       s.balance - COALESCE(c.balance, 0) AS diff
+	FROM stored s
+	LEFT JOIN computed c ON c.entity_type = s.entity_type AND c.entity_id = s.entity_id AND c.currency = s.currency
+	WHERE s.balance <> COALESCE(c.balance, 0)
+	ORDER BY s.entity_type, s.entity_id, s.currency
+`
+
+func NewReconciliationJob(db *sqlx.DB, log *slog.Logger) *ReconciliationJob {
+	notifURL := os.Getenv("NOTIFICATION_SERVICE_URL")
+	if notifURL == "" {
+		notifURL = "http://notification:8083"
+	}
+	var adminEmails []string
+	for _, email := range strings.Split(os.Getenv("RECONCILIATION_ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			adminEmails = append(adminEmails, email)
+		}
+	}
+	return &ReconciliationJob{
+		db:              db,
+		log:             log,
+		notificationURL: notifURL,
+		adminEmails:     adminEmails,
+		freeze:          os.Getenv("RECONCILIATION_FREEZE") == "true",
+	}
+}
+
+func (j *ReconciliationJob) Run() {
+	j.log.Info("starting reconciliation job")
+
+	drifts, checked, err := j.findDrifts()
+	if err != nil {
+		j.log.Error("failed to compute balance drifts", "error", err)
+		return
+	}
+
+	runID, err := j.saveRun(drifts, checked)
+	if err != nil {
+		j.log.Error("failed to save reconciliation run", "error", err)
+		return
+	}
+
+	if len(drifts) == 0 {
+		j.log.Info("reconciliation job completed, no drift", "run_id", runID, "entities", checked)
+		return
+	}
+
+	for _, d := range drifts {
+		j.log.Warn("balance drift", "run_id", runID, "entity_type", d.EntityType, "entity_id", d.EntityID, "currency", d.Currency,
+			"stored", d.StoredBalance, "computed", d.ComputedBalance, "diff", d.Diff)
+	}
+	j.sendReport(drifts)
+
+	j.log.Info("reconciliation job completed", "run_id", runID, "entities", checked, "drifts", len(drifts), "frozen", j.freeze)
+}
+
+// findDrifts читает балансы и журнал из одного снимка БД, чтобы переводы,
+// идущие во время сверки, не давали ложных расхождений
+func (j *ReconciliationJob) findDrifts() ([]BalanceDrift, int, error) {
+	tx, err := j.db.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var checked int
+	err = tx.Get(&checked, `SELECT (SELECT COUNT(*) FROM users) + (SELECT COUNT(*) FROM organizations) + (SELECT COUNT(*) FROM projects)`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("count entities: %w", err)
+	}
+
+	var drifts []BalanceDrift
+	if err := tx.Select(&drifts, balanceDriftQuery); err != nil {
+		return nil, 0, fmt.Errorf("select drifts: %w", err)
+	}
+	return drifts, checked, tx.Commit()
+}
+
+func (j *ReconciliationJob) saveRun(drifts []BalanceDrift, checked int) (int, error) {
+	tx, err := j.db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var runID int
+	err = tx.QueryRowx(`
+		INSERT INTO reconciliation_runs (finished_at, entities_checked, drift_count, total_drift)
+		VALUES (NOW(), $1, $2, $3) RETURNING id
+	`, checked, len(drifts), totalDrift(drifts)).Scan(&runID)
+	if err != nil {
+		return 0, fmt.Errorf("insert run: %w", err)
+	}
+
+	for _, d := range drifts {
+		var diffID int64
+		err = tx.QueryRowx(`
+			INSERT INTO reconciliation_diffs (run_id, entity_type, entity_id, currency, stored_balance, computed_balance, diff, frozen)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
+		`, runID, d.EntityType, d.EntityID, d.Currency, d.StoredBalance, d.ComputedBalance, d.Diff, j.freeze).Scan(&diffID)
+		if err != nil {
+			return 0, fmt.Errorf("insert diff: %w", err)
+		}
+
+		if j.freeze {
+			_, err = tx.Exec(`
+				INSERT INTO frozen_balances (entity_type, entity_id, diff_id) VALUES ($1, $2, $3)
+				ON CONFLICT (entity_type, entity_id) DO NOTHING
+			`, d.EntityType, d.EntityID, diffID)
+			if err != nil {
+				return 0, fmt.Errorf("freeze balance: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return runID, nil
+}
+
+func (j *ReconciliationJob) sendReport(drifts []BalanceDrift) {
+	if len(j.adminEmails) == 0 {
+		j.log.Warn("no admin emails configured for reconciliation report")
+		return
+	}
+
+	total := totalDrift(drifts)
+	for _, email := range j.adminEmails {
+		payload := map[string]interface{}{
+			"email":  email,
+			"type":   "reconciliation_drift",
+			"amount": total,
+			"drifts": drifts,
+		}
+		body, _ := json.Marshal(payload)
+
+		resp, err := http.Post(j.notificationURL+"/send", "application/json", bytes.NewReader(body))
+		if err != nil {
+			j.log.Error("failed to send reconciliation report", "error", err, "to", email)
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			j.log.Error("email service returned error", "status", resp.StatusCode, "to", email)
+		}
+	}
+}
+
+// totalDrift — сумма расхождений по модулю. Суммы в разных валютах складываются
+// без пересчета: это индикатор масштаба, точные значения — в самих расхождениях.
+func totalDrift(drifts []BalanceDrift) money.Amount {
+	total := money.Zero
+	for _, d := range drifts {
+		total += d.Diff.Abs()
+	}
+	return total
+}
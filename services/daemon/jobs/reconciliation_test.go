@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/daemon/money"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// минимальная схема для balanceDriftQuery: запрос не использует ничего специфичного для Postgres
+const reconciliationSchema = `
+	CREATE TABLE users (id INTEGER PRIMARY KEY, balance DECIMAL);
+	CREATE TABLE organizations (id INTEGER PRIMARY KEY, balance DECIMAL);
+	CREATE TABLE projects (id INTEGER PRIMARY KEY, currency TEXT, current_money DECIMAL);
+	CREATE TABLE wallet_balances (entity_type TEXT, entity_id INTEGER, currency TEXT, balance DECIMAL);
+	CREATE TABLE transactions (
+		id INTEGER PRIMARY KEY, from_id INTEGER, reciever_id INTEGER, type TEXT,
+		amount DECIMAL, currency TEXT DEFAULT 'RUB', to_amount DECIMAL, to_currency TEXT DEFAULT 'RUB'
+	);
+	CREATE TABLE ledger_accounts (id INTEGER PRIMARY KEY, entity_type TEXT, entity_id INTEGER, currency TEXT);
+	CREATE TABLE ledger_journals (id INTEGER PRIMARY KEY, transaction_id INTEGER, kind TEXT);
+	CREATE TABLE ledger_entries (id INTEGER PRIMARY KEY, journal_id INTEGER, account_id INTEGER, amount DECIMAL);
+`
+
+func newReconciliationDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(reconciliationSchema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	return db
+}
+
+func mustExec(t *testing.T, db *sqlx.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// seedPreLedgerHistory — состояние после миграции 0018: пользователь пополнил счет на 700 и 300
+// и вложил 400 в проект до введения леджера, входящие остатки (600 и 400) перенесены журналом opening_balance
+func seedPreLedgerHistory(t *testing.T, db *sqlx.DB) {
+	mustExec(t, db, `INSERT INTO users (id, balance) VALUES (1, 600)`)
+	mustExec(t, db, `INSERT INTO projects (id, currency, current_money) VALUES (1, 'RUB', 400)`)
+	mustExec(t, db, `INSERT INTO transactions (id, from_id, reciever_id, type, amount) VALUES
+		(1, 0, 1, 'user_deposit', 700),
+		(2, 0, 1, 'user_deposit', 300),
+		(3, 1, 1, 'user_to_project', 400)`)
+
+	mustExec(t, db, `INSERT INTO ledger_accounts (id, entity_type, entity_id, currency) VALUES
+		(1, 'external', 0, 'RUB'), (2, 'user', 1, 'RUB'), (3, 'project', 1, 'RUB')`)
+	mustExec(t, db, `INSERT INTO ledger_journals (id, transaction_id, kind) VALUES (1, NULL, 'opening_balance')`)
+	mustExec(t, db, `INSERT INTO ledger_entries (journal_id, account_id, amount) VALUES (1, 2, 600), (1, 3, 400), (1, 1, -1000)`)
+}
+
+func findTestDrifts(t *testing.T, db *sqlx.DB) []BalanceDrift {
+	t.Helper()
+	var drifts []BalanceDrift
+	if err := db.Select(&drifts, balanceDriftQuery); err != nil {
+		t.Fatalf("select drifts: %v", err)
+	}
+	return drifts
+}
+
+func TestBalanceDriftPreLedgerHistory(t *testing.T) {
+	db := newReconciliationDB(t)
+	seedPreLedgerHistory(t, db)
+
+	if drifts := findTestDrifts(t, db); len(drifts) != 0 {
+		t.Fatalf("pre-ledger history reported as drift: %+v", drifts)
+	}
+}
+
+func TestBalanceDriftLedgeredTransfers(t *testing.T) {
+	db := newReconciliationDB(t)
+	seedPreLedgerHistory(t, db)
+
+	// перевод после введения леджера: у него есть свой журнал
+	mustExec(t, db, `INSERT INTO transactions (id, from_id, reciever_id, type, amount) VALUES (4, 1, 1, 'user_to_project', 100)`)
+	mustExec(t, db, `INSERT INTO ledger_journals (id, transaction_id, kind) VALUES (2, 4, 'transfer')`)
+	mustExec(t, db, `INSERT INTO ledger_entries (journal_id, account_id, amount) VALUES (2, 2, -100), (2, 3, 100)`)
+	mustExec(t, db, `UPDATE users SET balance = 500 WHERE id = 1`)
+	mustExec(t, db, `UPDATE projects SET current_money = 500 WHERE id = 1`)
+
+	if drifts := findTestDrifts(t, db); len(drifts) != 0 {
+		t.Fatalf("unexpected drift after ledgered transfer: %+v", drifts)
+	}
+
+	// баланс изменен в обход журнала
+	mustExec(t, db, `UPDATE users SET balance = 550 WHERE id = 1`)
+
+	drifts := findTestDrifts(t, db)
+	if len(drifts) != 1 {
+		t.Fatalf("expected 1 drift, got %+v", drifts)
+	}
+	d := drifts[0]
+	if d.EntityType != "user" || d.EntityID != 1 || d.Currency != "RUB" {
+		t.Errorf("drift for %s %d %s, want user 1 RUB", d.EntityType, d.EntityID, d.Currency)
+	}
+	if d.StoredBalance != money.FromRubles(550) || d.ComputedBalance != money.FromRubles(500) || d.Diff != money.FromRubles(50) {
+		t.Errorf("drift = stored %v computed %v diff %v, want 550/500/50", d.StoredBalance, d.ComputedBalance, d.Diff)
+	}
+}
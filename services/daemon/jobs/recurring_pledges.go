@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RecurringPledgesJob запускает списания по регулярным пожертвованиям. Сами списания
+// проводит сервис платежей: ему нужны сохраненные карты и платежный провайдер.
+type RecurringPledgesJob struct {
+	log        *slog.Logger
+	paymentURL string
+	http       *http.Client
+}
+
+func NewRecurringPledgesJob(log *slog.Logger) *RecurringPledgesJob {
+	url := os.Getenv("PAYMENT_SERVICE_URL")
+	if url == "" {
+		url = "http://payment:8106"
+	}
+	return &RecurringPledgesJob{log: log, paymentURL: url, http: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+func (j *RecurringPledgesJob) Run() {
+	j.log.Info("starting recurring pledges job")
+	if err := j.run(); err != nil {
+		j.log.Error("recurring pledges job failed", "error", err)
+		return
+	}
+	j.log.Info("recurring pledges job completed")
+}
+
+func (j *RecurringPledgesJob) run() error {
+	req, err := http.NewRequest("POST", j.paymentURL+"/internal/recurring/run", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := j.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("payment service error: %d", resp.StatusCode)
+	}
+	return nil
+}
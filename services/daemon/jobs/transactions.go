@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/daemon/money"
+)
+
+// transactionClient — перевод между кошельками через сервис транзакций
+type transactionClient struct {
+	url  string
+	http *http.Client
+}
+
+func newTransactionClient() *transactionClient {
+	url := os.Getenv("TRANSACTION_SERVICE_URL")
+	if url == "" {
+		url = "http://transactions:8103"
+	}
+	return &transactionClient{url: url, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Refund возвращает инвестору вклад из проекта с ключом идемпотентности: amount получит
+// инвестор, из них feeReturned доплатит платформа из удержанной комиссии. Второе
+// значение — true, если возврат с этим ключом уже был проведен раньше.
+func (c *transactionClient) Refund(idempotencyKey string, projectID int, toType string, toID int, amount, feeReturned money.Amount, currency string) (bool, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"project_id":   projectID,
+		"to_type":      toType,
+		"to_id":        toID,
+		"amount":       amount,
+		"fee_returned": feeReturned,
+		"currency":     currency,
+	})
+
+	req, err := http.NewRequest("POST", c.url+"/internal/refunds", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return false, nil
+	case http.StatusOK:
+		return true, nil
+	default:
+		return false, fmt.Errorf("transaction service error: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,26 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Service — имя daemon в outbox_events и в event_id публикуемых событий
+const Service = "daemon"
+
+// ProjectUpdatedEvent — daemon изменил проект в обход проектного сервиса
+// (статус, сумму к выплате, публикацию); тот сбрасывает проект из кеша
+type ProjectUpdatedEvent struct {
+	ProjectID int `json:"project_id"`
+}
+
+// AddProjectUpdated записывает project.updated в транзакции, изменившей проект
+func AddProjectUpdated(ctx context.Context, tx *sqlx.Tx, projectIDs ...int) error {
+	for _, id := range projectIDs {
+		if err := Add(ctx, tx, Service, TypeProjectUpdated, ProjectUpdatedEvent{ProjectID: id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -8,14 +8,35 @@ import (
 	"os/signal"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Starostina-elena/investment_platform/services/notification/events"
 	"github.com/Starostina-elena/investment_platform/services/notification/handler"
 	"github.com/Starostina-elena/investment_platform/services/notification/service"
 )
 
+func openRedis() *redis.Client {
+	host := os.Getenv("REDIS_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := os.Getenv("REDIS_PORT")
+	if port == "" {
+		port = "6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr: host + ":" + port,
+	})
+	return client
+}
+
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 	logger.Info("starting notification service")
 
+	redisClient := openRedis()
+	defer redisClient.Close()
+
 	emailService := service.NewEmailService(*logger)
 	h := handler.NewHandler(emailService, *logger)
 	router := getRouter(h)
@@ -30,6 +51,10 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	consumer := events.NewConsumer(redisClient, "notification", *logger)
+	consumer.Handle("project.goal_reached", events.GoalReachedHandler(emailService, redisClient, *logger))
+	go consumer.Run(ctx)
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("listen", "error", err)
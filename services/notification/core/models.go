@@ -1,13 +1,29 @@
 package core
 
+import "github.com/Starostina-elena/investment_platform/services/notification/money"
+
 const (
-	NotifTypeDividends     = "dividends"
-	NotifTypeProjectClosed = "project_closed"
+	NotifTypeDividends           = "dividends"
+	NotifTypeProjectClosed       = "project_closed"
+	NotifTypeProjectGoalReached  = "project_goal_reached"
+	NotifTypeReconciliationDrift = "reconciliation_drift"
+	NotifTypeProjectRefund       = "project_refund"
 )
 
 type EmailRequest struct {
-	Email       string  `json:"email"`
-	Type        string  `json:"type"`
-	ProjectName string  `json:"project_name"`
-	Amount      float64 `json:"amount"`
+	Email       string       `json:"email"`
+	Type        string       `json:"type"`
+	ProjectName string       `json:"project_name"`
+	Amount      money.Amount `json:"amount"`
+	// Drifts — расхождения балансов для отчета о сверке
+	Drifts []BalanceDrift `json:"drifts,omitempty"`
+}
+
+type BalanceDrift struct {
+	EntityType      string       `json:"entity_type"`
+	EntityID        int          `json:"entity_id"`
+	Currency        string       `json:"currency"`
+	StoredBalance   money.Amount `json:"stored_balance"`
+	ComputedBalance money.Amount `json:"computed_balance"`
+	Diff            money.Amount `json:"diff"`
 }
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Stream — общий поток доменных событий, куда публикуют outbox-релеи сервисов
+const Stream = "platform:events"
+
+const (
+	readBlock = 5 * time.Second
+	readCount = 20
+	// retryInterval — как часто перечитываются свои неподтвержденные сообщения
+	retryInterval = 30 * time.Second
+)
+
+type Event struct {
+	StreamID   string
+	EventID    string
+	Type       string
+	Service    string
+	Payload    []byte
+	OccurredAt string
+}
+
+// HandlerFunc обрабатывает событие. Если вернуть ошибку, сообщение останется
+// неподтвержденным и будет обработано повторно.
+type HandlerFunc func(ctx context.Context, e Event) error
+
+// Consumer читает поток в своей группе потребителей. Группа запоминает
+// позицию чтения, поэтому события, пришедшие пока сервис лежал, не теряются.
+type Consumer struct {
+	rdb      *redis.Client
+	group    string
+	name     string
+	handlers map[string]HandlerFunc
+	log      slog.Logger
+}
+
+func NewConsumer(rdb *redis.Client, group string, log slog.Logger) *Consumer {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		name = group
+	}
+	return &Consumer{
+		rdb:      rdb,
+		group:    group,
+		name:     name,
+		handlers: make(map[string]HandlerFunc),
+		log:      log,
+	}
+}
+
+func (c *Consumer) Handle(eventType string, h HandlerFunc) {
+	c.handlers[eventType] = h
+}
+
+// Run читает события, пока не отменен ctx
+func (c *Consumer) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := c.ensureGroup(ctx); err != nil {
+			c.log.Error("failed to create consumer group", "group", c.group, "error", err)
+			c.sleep(ctx, time.Second)
+			continue
+		}
+		break
+	}
+
+	lastRetry := time.Time{}
+	for ctx.Err() == nil {
+		if time.Since(lastRetry) >= retryInterval {
+			// "0" — сообщения, выданные этому потребителю, но так и не подтвержденные
+			if err := c.read(ctx, "0"); err != nil {
+				c.log.Error("failed to read pending events", "group", c.group, "error", err)
+			}
+			lastRetry = time.Now()
+		}
+		if err := c.read(ctx, ">"); err != nil {
+			c.log.Error("failed to read events", "group", c.group, "error", err)
+			c.sleep(ctx, time.Second)
+		}
+	}
+}
+
+func (c *Consumer) ensureGroup(ctx context.Context) error {
+	// группа создается с начала потока, чтобы прочитать и события, опубликованные до первого запуска
+	err := c.rdb.XGroupCreateMkStream(ctx, Stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+func (c *Consumer) read(ctx context.Context, id string) error {
+	block := readBlock
+	if id != ">" {
+		block = -1
+	}
+	streams, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.name,
+		Streams:  []string{Stream, id},
+		Count:    readCount,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			c.process(ctx, msg)
+		}
+	}
+	return nil
+}
+
+func (c *Consumer) process(ctx context.Context, msg redis.XMessage) {
+	e := Event{
+		StreamID:   msg.ID,
+		EventID:    value(msg, "event_id"),
+		Type:       value(msg, "type"),
+		Service:    value(msg, "service"),
+		Payload:    []byte(value(msg, "payload")),
+		OccurredAt: value(msg, "occurred_at"),
+	}
+
+	if h, ok := c.handlers[e.Type]; ok {
+		if err := h(ctx, e); err != nil {
+			c.log.Error("failed to handle event", "event_id", e.EventID, "type", e.Type, "error", err)
+			return
+		}
+		c.log.Info("event handled", "event_id", e.EventID, "type", e.Type)
+	}
+
+	if err := c.rdb.XAck(ctx, Stream, c.group, msg.ID).Err(); err != nil {
+		c.log.Error("failed to ack event", "event_id", e.EventID, "error", err)
+	}
+}
+
+func (c *Consumer) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+func value(msg redis.XMessage, key string) string {
+	v, _ := msg.Values[key].(string)
+	return v
+}
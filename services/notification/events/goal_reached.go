@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Starostina-elena/investment_platform/services/notification/core"
+	"github.com/Starostina-elena/investment_platform/services/notification/service"
+)
+
+// sentTTL — сколько помнить об отправленном письме; за это время событие точно будет подтверждено
+const sentTTL = 7 * 24 * time.Hour
+
+type goalReachedEvent struct {
+	ProjectID   int    `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	Investors   []struct {
+		UserID int    `json:"user_id"`
+		Email  string `json:"email"`
+	} `json:"investors"`
+}
+
+// GoalReachedHandler рассылает инвесторам письмо о том, что проект собрал нужную сумму.
+// Адреса, на которые письмо уже ушло, отмечаются в Redis: при повторной обработке
+// события после частичного сбоя письмо получат только оставшиеся инвесторы.
+func GoalReachedHandler(emails *service.EmailService, rdb *redis.Client, log slog.Logger) HandlerFunc {
+	return func(ctx context.Context, e Event) error {
+		var event goalReachedEvent
+		if err := json.Unmarshal(e.Payload, &event); err != nil {
+			// битое событие повторять бессмысленно
+			log.Error("failed to decode goal reached event", "event_id", e.EventID, "error", err)
+			return nil
+		}
+
+		var failed int
+		for _, inv := range event.Investors {
+			key := fmt.Sprintf("notification:sent:%s:%s", e.EventID, inv.Email)
+			sent, err := rdb.Exists(ctx, key).Result()
+			if err != nil {
+				return err
+			}
+			if sent > 0 {
+				continue
+			}
+
+			req := &core.EmailRequest{
+				Email:       inv.Email,
+				Type:        core.NotifTypeProjectGoalReached,
+				ProjectName: event.ProjectName,
+			}
+			if err := emails.SendNotification(req); err != nil {
+				failed++
+				continue
+			}
+			if err := rdb.Set(ctx, key, 1, sentTTL).Err(); err != nil {
+				log.Error("failed to mark email as sent", "key", key, "error", err)
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("failed to send %d of %d goal reached emails", failed, len(event.Investors))
+		}
+		log.Info("goal reached notifications sent", "project_id", event.ProjectID, "investors", len(event.Investors))
+		return nil
+	}
+}
@@ -35,17 +35,24 @@ func SendEmailHandler(h *Handler) http.HandlerFunc {
 			http.Error(w, "invalid email", http.StatusBadRequest)
 			return
 		}
-		if req.Type != core.NotifTypeDividends && req.Type != core.NotifTypeProjectClosed {
+		switch req.Type {
+		case core.NotifTypeDividends, core.NotifTypeProjectClosed, core.NotifTypeProjectGoalReached, core.NotifTypeReconciliationDrift, core.NotifTypeProjectRefund:
+		default:
 			h.log.Error("unknown notification type", "type", req.Type)
 			http.Error(w, "unknown notification type", http.StatusBadRequest)
 			return
 		}
-		if req.ProjectName == "" {
+		if req.Type == core.NotifTypeReconciliationDrift && len(req.Drifts) == 0 {
+			h.log.Error("empty reconciliation report")
+			http.Error(w, "empty drifts", http.StatusBadRequest)
+			return
+		}
+		if req.Type != core.NotifTypeReconciliationDrift && req.ProjectName == "" {
 			h.log.Error("invalid project name")
 			http.Error(w, "invalid project name", http.StatusBadRequest)
 			return
 		}
-		if req.Type != core.NotifTypeProjectClosed && req.Amount <= 0 {
+		if (req.Type == core.NotifTypeDividends || req.Type == core.NotifTypeProjectRefund) && !req.Amount.IsPositive() {
 			h.log.Error("invalid amount", "amount", req.Amount, "type", req.Type)
 			http.Error(w, "invalid amount", http.StatusBadRequest)
 			return
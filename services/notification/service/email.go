@@ -78,6 +78,12 @@ func (s *EmailService) buildEmail(req *core.EmailRequest) (string, string, error
 		return s.buildDividendsEmail(req)
 	case core.NotifTypeProjectClosed:
 		return s.buildProjectClosedEmail(req)
+	case core.NotifTypeProjectGoalReached:
+		return s.buildProjectGoalReachedEmail(req)
+	case core.NotifTypeReconciliationDrift:
+		return s.buildReconciliationDriftEmail(req)
+	case core.NotifTypeProjectRefund:
+		return s.buildProjectRefundEmail(req)
 	default:
 		return "", "", core.ErrUnknownNotifType
 	}
@@ -155,3 +161,128 @@ func (s *EmailService) buildProjectClosedEmail(req *core.EmailRequest) (string,
 
 	return subject, buf.String(), nil
 }
+
+func (s *EmailService) buildProjectGoalReachedEmail(req *core.EmailRequest) (string, string, error) {
+	subject := "Проект собрал нужную сумму"
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h2 style="color: #4CAF50;">Цель достигнута</h2>
+        <p>Здравствуйте!</p>
+        <p>Проект <strong>{{.ProjectName}}</strong>, в который вы инвестировали, собрал требуемую сумму.</p>
+        <p>Мы сообщим, когда начнутся выплаты.</p>
+        <hr style="border: none; border-top: 1px solid #ddd; margin: 20px 0;">
+        <p style="font-size: 12px; color: #888;">
+            Это автоматическое уведомление, не отвечайте на него.
+        </p>
+    </div>
+</body>
+</html>
+`
+	t, err := template.New("project_goal_reached").Parse(tmpl)
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, req); err != nil {
+		return "", "", err
+	}
+
+	return subject, buf.String(), nil
+}
+
+func (s *EmailService) buildProjectRefundEmail(req *core.EmailRequest) (string, string, error) {
+	subject := "Возврат средств по проекту"
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h2 style="color: #FF5722;">Проект не собрал нужную сумму</h2>
+        <p>Здравствуйте!</p>
+        <p>Проект <strong>{{.ProjectName}}</strong> не достиг цели к установленному сроку, и ваши вложения возвращены.</p>
+        <p style="font-size: 18px; color: #4CAF50;">
+            <strong>Сумма: {{.Amount}}</strong>
+        </p>
+        <p>Средства зачислены на ваш кошелек на платформе. Их можно вывести или вернуть на карту, с которой была оплата.</p>
+        <hr style="border: none; border-top: 1px solid #ddd; margin: 20px 0;">
+        <p style="font-size: 12px; color: #888;">
+            Это автоматическое уведомление, не отвечайте на него.
+        </p>
+    </div>
+</body>
+</html>
+`
+	t, err := template.New("project_refund").Parse(tmpl)
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, req); err != nil {
+		return "", "", err
+	}
+
+	return subject, buf.String(), nil
+}
+
+func (s *EmailService) buildReconciliationDriftEmail(req *core.EmailRequest) (string, string, error) {
+	subject := "Расхождение балансов"
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h2 style="color: #FF5722;">Расхождение балансов</h2>
+        <p>Ночная сверка нашла балансы, которые не совпадают с журналом транзакций.</p>
+        <p style="font-size: 18px; color: #FF5722;">
+            <strong>Сумма расхождений: {{.Amount}}</strong>
+        </p>
+        <table style="border-collapse: collapse; width: 100%;">
+            <tr>
+                <th style="text-align: left; border-bottom: 1px solid #ddd;">Счет</th>
+                <th style="text-align: right; border-bottom: 1px solid #ddd;">Баланс</th>
+                <th style="text-align: right; border-bottom: 1px solid #ddd;">По журналу</th>
+                <th style="text-align: right; border-bottom: 1px solid #ddd;">Разница</th>
+            </tr>
+            {{range .Drifts}}
+            <tr>
+                <td>{{.EntityType}} #{{.EntityID}} ({{.Currency}})</td>
+                <td style="text-align: right;">{{.StoredBalance}}</td>
+                <td style="text-align: right;">{{.ComputedBalance}}</td>
+                <td style="text-align: right;">{{.Diff}}</td>
+            </tr>
+            {{end}}
+        </table>
+        <hr style="border: none; border-top: 1px solid #ddd; margin: 20px 0;">
+        <p style="font-size: 12px; color: #888;">
+            Это автоматическое уведомление, не отвечайте на него.
+        </p>
+    </div>
+</body>
+</html>
+`
+	t, err := template.New("reconciliation_drift").Parse(tmpl)
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, req); err != nil {
+		return "", "", err
+	}
+
+	return subject, buf.String(), nil
+}
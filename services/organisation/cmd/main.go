@@ -12,8 +12,10 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/Starostina-elena/investment_platform/services/organisation/handler"
+	"github.com/Starostina-elena/investment_platform/services/organisation/outbox"
 	"github.com/Starostina-elena/investment_platform/services/organisation/repo"
 	"github.com/Starostina-elena/investment_platform/services/organisation/service"
 	"github.com/Starostina-elena/investment_platform/services/organisation/storage"
@@ -33,6 +35,21 @@ func openDB() *sqlx.DB {
 	return db
 }
 
+func openRedis() *redis.Client {
+	host := os.Getenv("REDIS_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := os.Getenv("REDIS_PORT")
+	if port == "" {
+		port = "6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr: host + ":" + port,
+	})
+	return client
+}
+
 func openMinio() *storage.MinioStorage {
 	endpoint := os.Getenv("MINIO_ENDPOINT")
 	accessKey := os.Getenv("MINIO_ACCESS_KEY")
@@ -68,6 +85,9 @@ func main() {
 
 	minioStorage := openMinio()
 
+	redisClient := openRedis()
+	defer redisClient.Close()
+
 	repo := repo.NewRepo(db, *logger)
 	service := service.NewService(repo, *minioStorage, *logger)
 	handler := handler.NewHandler(service, *logger)
@@ -81,6 +101,9 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	relay := outbox.NewRelay(db, redisClient, "organisation", *logger)
+	go relay.Run(ctx, time.Second)
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("listen", "error", err)
@@ -1,6 +1,10 @@
 package core
 
-import "time"
+import (
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/organisation/money"
+)
 
 type OrgType string
 
@@ -29,17 +33,17 @@ type OrgEmployee struct {
 }
 
 type OrgBase struct {
-	ID                    int       `json:"id"`
-	Name                  string    `json:"name"`
-	OwnerId               int       `json:"owner_id" db:"owner"`
-	AvatarPath            *string   `json:"-" db:"avatar_path"`
-	Email                 string    `json:"email"`
-	Balance               float64   `json:"balance,omitempty"`
-	OrgType               OrgType   `json:"org_type" db:"type"`
-	OrgTypeId             int       `json:"-" db:"org_type_id"`
-	CreatedAt             time.Time `json:"created_at" db:"created_at"`
-	IsBanned              bool      `json:"is_banned" db:"is_banned"`
-	RegistrationCompleted bool      `json:"registration_completed"`
+	ID                    int          `json:"id"`
+	Name                  string       `json:"name"`
+	OwnerId               int          `json:"owner_id" db:"owner"`
+	AvatarPath            *string      `json:"-" db:"avatar_path"`
+	Email                 string       `json:"email"`
+	Balance               money.Amount `json:"balance,omitempty"`
+	OrgType               OrgType      `json:"org_type" db:"type"`
+	OrgTypeId             int          `json:"-" db:"org_type_id"`
+	CreatedAt             time.Time    `json:"created_at" db:"created_at"`
+	IsBanned              bool         `json:"is_banned" db:"is_banned"`
+	RegistrationCompleted bool         `json:"registration_completed"`
 }
 
 type PhysFace struct {
@@ -451,6 +451,10 @@ func BanOrgHandler(h *Handler) http.HandlerFunc {
 		}
 
 		err = h.service.BanOrg(r.Context(), orgID, ban)
+		if err == core.ErrOrgNotFound {
+			http.Error(w, "Организация не найдена", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			h.log.Error("failed to ban/unban org", "org_id", orgID, "banned", ban, "error", err)
 			http.Error(w, "internal error", http.StatusInternalServerError)
@@ -0,0 +1,124 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	relayBatch = 100
+	// streamMaxLen — примерная длина, до которой обрезается поток; старые события уже прочитаны
+	streamMaxLen = 100000
+)
+
+// Relay публикует неопубликованные события своего сервиса в Redis Stream.
+// Доставка «хотя бы один раз»: при падении между XADD и отметкой в БД событие
+// уйдет повторно, поэтому потребители отсеивают дубли по event_id.
+type Relay struct {
+	db      *sqlx.DB
+	rdb     *redis.Client
+	service string
+	log     slog.Logger
+}
+
+func NewRelay(db *sqlx.DB, rdb *redis.Client, service string, log slog.Logger) *Relay {
+	return &Relay{db: db, rdb: rdb, service: service, log: log}
+}
+
+// PublishPending отправляет очередную пачку событий и возвращает число опубликованных.
+// Строки блокируются через SKIP LOCKED, так что несколько реплик сервиса не мешают друг другу.
+func (r *Relay) PublishPending(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var events []Event
+	err = tx.SelectContext(ctx, &events, `
+		SELECT id, service, event_type, payload, attempts, last_error, created_at, published_at
+		FROM outbox_events
+		WHERE service = $1 AND published_at IS NULL
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`, r.service, relayBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	published := make([]int64, 0, len(events))
+	var publishErr error
+	for _, e := range events {
+		// события публикуются строго по порядку: на первой ошибке пачка прерывается
+		if publishErr = r.publish(ctx, e); publishErr != nil {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE outbox_events SET attempts = attempts + 1, last_error = $1 WHERE id = $2`,
+				publishErr.Error(), e.ID)
+			if err != nil {
+				return 0, err
+			}
+			break
+		}
+		published = append(published, e.ID)
+	}
+
+	if len(published) > 0 {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE outbox_events SET published_at = NOW() WHERE id = ANY($1)`, pq.Array(published))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(published), publishErr
+}
+
+func (r *Relay) publish(ctx context.Context, e Event) error {
+	return r.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: Stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"event_id":    fmt.Sprintf("%s:%d", e.Service, e.ID),
+			"type":        e.Type,
+			"service":     e.Service,
+			"payload":     string(e.Payload),
+			"occurred_at": e.CreatedAt.UTC().Format(time.RFC3339),
+		},
+	}).Err()
+}
+
+// Run раз в interval публикует накопившиеся события, пока не отменен ctx
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := r.PublishPending(ctx)
+				if err != nil {
+					r.log.Error("failed to publish outbox events", "service", r.service, "error", err)
+					break
+				}
+				if n < relayBatch {
+					break
+				}
+			}
+		}
+	}
+}
@@ -12,8 +12,18 @@ import (
 	_ "github.com/lib/pq"
 
 	"github.com/Starostina-elena/investment_platform/services/organisation/core"
+	"github.com/Starostina-elena/investment_platform/services/organisation/outbox"
 )
 
+const outboxService = "organisation"
+
+type OrgBannedEvent struct {
+	OrgID      int    `json:"org_id"`
+	Name       string `json:"name"`
+	OwnerID    int    `json:"owner_id"`
+	OwnerEmail string `json:"owner_email"`
+}
+
 type Repo struct {
 	db  *sqlx.DB
 	log slog.Logger
@@ -34,7 +44,6 @@ type RepoInterface interface {
 	UpdateEmployeePermissions(ctx context.Context, orgID int, userID int, orgAccMgmt, moneyMgmt, projMgmt bool) error
 	DeleteEmployee(ctx context.Context, orgID int, userID int) error
 	TransferOwnership(ctx context.Context, orgID int, oldOwnerID int, newOwnerID int) error
-	ChangeBalance(ctx context.Context, orgID int, delta float64) error
 }
 
 func NewRepo(db *sqlx.DB, log slog.Logger) RepoInterface {
@@ -427,9 +436,54 @@ func (r *Repo) GetUsersOrgs(ctx context.Context, userID int) ([]core.Org, error)
 	return orgs, nil
 }
 
+// BanOrg меняет флаг блокировки. При блокировке ранее активной организации
+// в той же транзакции пишется событие org.banned.
 func (r *Repo) BanOrg(ctx context.Context, orgID int, banned bool) error {
-	_, err := r.db.ExecContext(ctx, `UPDATE organizations SET is_banned = $1 WHERE id = $2`, banned, orgID)
-	return err
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var org struct {
+		Name       string `db:"name"`
+		OwnerID    int    `db:"owner"`
+		OwnerEmail string `db:"owner_email"`
+		IsBanned   bool   `db:"is_banned"`
+	}
+	err = tx.GetContext(ctx, &org, `
+		SELECT o.name, o.owner, u.email AS owner_email, o.is_banned
+		FROM organizations o
+		JOIN users u ON u.id = o.owner
+		WHERE o.id = $1
+		FOR UPDATE OF o`, orgID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrOrgNotFound
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE organizations SET is_banned = $1 WHERE id = $2`, banned, orgID); err != nil {
+		return err
+	}
+
+	if banned && !org.IsBanned {
+		err = outbox.Add(ctx, tx, outboxService, outbox.TypeOrgBanned, OrgBannedEvent{
+			OrgID:      orgID,
+			Name:       org.Name,
+			OwnerID:    org.OwnerID,
+			OwnerEmail: org.OwnerEmail,
+		})
+		if err != nil {
+			r.log.Error("failed to add org banned event", "org_id", orgID, "error", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 func (r *Repo) GetUserOrgPermissions(ctx context.Context, orgID int, userID int) (map[string]bool, error) {
@@ -618,30 +672,3 @@ func (r *Repo) TransferOwnership(ctx context.Context, orgID int, oldOwnerID int,
 	r.log.Info("ownership transferred successfully", "org_id", orgID, "old_owner", oldOwnerID, "new_owner", newOwnerID)
 	return nil
 }
-
-func (r *Repo) ChangeBalance(ctx context.Context, orgID int, delta float64) error {
-	tx, err := r.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	var balance float64
-	err = tx.QueryRowxContext(ctx, "SELECT balance FROM organizations WHERE id = $1 FOR UPDATE", orgID).Scan(&balance)
-	if err != nil {
-		r.log.Error("failed to get org balance", "org_id", orgID, "error", err)
-		return err
-	}
-
-	if balance+delta < 0 {
-		return fmt.Errorf("insufficient funds in organization %d", orgID)
-	}
-
-	_, err = tx.ExecContext(ctx, "UPDATE organizations SET balance = balance + $1 WHERE id = $2", delta, orgID)
-	if err != nil {
-		r.log.Error("failed to update org balance", "org_id", orgID, "error", err)
-		return err
-	}
-
-	return tx.Commit()
-}
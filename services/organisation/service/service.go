@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Starostina-elena/investment_platform/services/organisation/core"
+	"github.com/Starostina-elena/investment_platform/services/organisation/money"
 	"github.com/Starostina-elena/investment_platform/services/organisation/repo"
 	"github.com/Starostina-elena/investment_platform/services/organisation/storage"
 )
@@ -30,7 +31,6 @@ type Service interface {
 	UpdateEmployeePermissions(ctx context.Context, orgID int, userRequested int, userID int, orgAccMgmt, moneyMgmt, projMgmt bool) error
 	DeleteEmployee(ctx context.Context, orgID int, userRequested int, userID int) error
 	TransferOwnership(ctx context.Context, orgID int, userRequested int, newOwnerID int) error
-	ChangeBalance(ctx context.Context, orgID int, delta float64) error
 }
 
 type service struct {
@@ -44,7 +44,7 @@ func NewService(r repo.RepoInterface, minio storage.MinioStorage, log slog.Logge
 }
 
 func (s *service) Create(ctx context.Context, org core.Org) (*core.Org, error) {
-	org.Balance = 0.0
+	org.Balance = money.Zero
 	org.CreatedAt = time.Now()
 	org.IsBanned = false
 	id, err := s.repo.Create(ctx, &org)
@@ -77,7 +77,7 @@ func (s *service) GetPublicInfoOrg(ctx context.Context, id int) (*core.Org, erro
 
 	org.SetIsRegistrationCompleted()
 
-	org.Balance = 0.0
+	org.Balance = money.Zero
 	switch org.OrgType {
 	case core.OrgTypePhys:
 		org.PhysFace.INN = ""
@@ -159,7 +159,3 @@ func (s *service) BanOrg(ctx context.Context, orgID int, banned bool) error {
 	}
 	return nil
 }
-
-func (s *service) ChangeBalance(ctx context.Context, orgID int, delta float64) error {
-	return s.repo.ChangeBalance(ctx, orgID, delta)
-}
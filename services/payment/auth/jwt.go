@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"errors"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var jwtSecret = []byte(getEnv("JWT_SECRET", "dev-secret"))
+
+type Claims struct {
+	UserID int  `json:"user_id"`
+	Admin  bool `json:"admin"`
+	Banned bool `json:"banned"`
+	jwt.RegisteredClaims
+}
+
+func getEnv(k, d string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return d
+}
+
+func ParseAndVerify(tokenStr string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return jwtSecret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := token.Claims.(*Claims); ok && token.Valid {
+		return c, nil
+	}
+	return nil, errors.New("invalid token")
+}
@@ -0,0 +1,150 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+type OrgClient struct {
+	url    string
+	client *http.Client
+}
+
+func NewOrgClient() *OrgClient {
+	url := os.Getenv("ORG_SERVICE_URL")
+	if url == "" {
+		url = "http://organisation:8102"
+	}
+	return &OrgClient{
+		url:    url,
+		client: &http.Client{},
+	}
+}
+
+// CheckUserOrgPermission проверяет право сотрудника организации, например money_management
+func (oc *OrgClient) CheckUserOrgPermission(ctx context.Context, orgID int, userID int, permission string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%d/rights/%d/%s", oc.url, orgID, userID, permission), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := oc.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New("failed to get organisation permissions")
+	}
+
+	var perms map[string]bool
+	if err := json.NewDecoder(resp.Body).Decode(&perms); err != nil {
+		return false, err
+	}
+
+	allowed, exists := perms["allowed"]
+	if !exists {
+		return false, errors.New("incorrect response format")
+	}
+
+	return allowed, nil
+}
+
+// IsRegistrationCompleted — загрузила ли организация все документы для своего типа
+func (oc *OrgClient) IsRegistrationCompleted(ctx context.Context, orgID int) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%d", oc.url, orgID), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := oc.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New("failed to get organisation")
+	}
+
+	var org struct {
+		RegistrationCompleted bool `json:"registration_completed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&org); err != nil {
+		return false, err
+	}
+	return org.RegistrationCompleted, nil
+}
+
+// ErrOrgAccessDenied — у пользователя нет доступа к полным данным организации
+var ErrOrgAccessDenied = errors.New("access to organisation data denied")
+
+// OrgBankDetails — банковские реквизиты из регистрационных данных организации
+type OrgBankDetails struct {
+	Holder                string
+	BIC                   string
+	Account               string
+	CorrAccount           string
+	RegistrationCompleted bool
+}
+
+// GetBankDetails читает реквизиты организации от имени пользователя: полные данные
+// организации видят только ее управляющие и администраторы, поэтому передается его токен
+func (oc *OrgClient) GetBankDetails(ctx context.Context, authorization string, orgID int) (*OrgBankDetails, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%d/full", oc.url, orgID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := oc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrOrgAccessDenied
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("failed to get organisation")
+	}
+
+	type account struct {
+		BIC                  string `json:"bic"`
+		CheckingAccount      string `json:"checking_account"`
+		CorrespondentAccount string `json:"correspondent_account"`
+		RasSchot             string `json:"ras_schot"`
+		KorSchot             string `json:"kor_schot"`
+		FIO                  string `json:"fio"`
+		FullName             string `json:"full_organisation_name"`
+	}
+	var org struct {
+		RegistrationCompleted bool     `json:"registration_completed"`
+		PhysFace              *account `json:"phys_face"`
+		JurFace               *account `json:"jur_face"`
+		IPFace                *account `json:"ip_face"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&org); err != nil {
+		return nil, err
+	}
+
+	details := &OrgBankDetails{RegistrationCompleted: org.RegistrationCompleted}
+	switch {
+	case org.JurFace != nil:
+		a := org.JurFace
+		details.Holder, details.BIC, details.Account, details.CorrAccount = a.FullName, a.BIC, a.CheckingAccount, a.CorrespondentAccount
+	case org.IPFace != nil:
+		a := org.IPFace
+		details.Holder, details.BIC, details.Account, details.CorrAccount = a.FIO, a.BIC, a.RasSchot, a.KorSchot
+	case org.PhysFace != nil:
+		a := org.PhysFace
+		details.Holder, details.BIC, details.Account, details.CorrAccount = a.FIO, a.BIC, a.CheckingAccount, a.CorrespondentAccount
+	}
+	return details, nil
+}
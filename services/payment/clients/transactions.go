@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
 )
 
+// ErrTransferRejected — сервис транзакций отклонил перевод (недостаточно средств,
+// неверные параметры); повтор того же запроса не поможет
+var ErrTransferRejected = errors.New("transfer rejected by transaction service")
+
 type TransactionClient struct {
 	url    string
 	client *http.Client
@@ -25,13 +32,16 @@ func NewTransactionClient() *TransactionClient {
 	}
 }
 
-func (tc *TransactionClient) Deposit(ctx context.Context, toType string, toID int, amount float64) error {
+// Deposit передает idempotencyKey в заголовке Idempotency-Key, поэтому
+// повторный вызов с тем же ключом не проводит перевод второй раз.
+func (tc *TransactionClient) Deposit(ctx context.Context, idempotencyKey string, toType string, toID int, amount money.Amount, currency money.Currency) error {
 	reqBody, _ := json.Marshal(map[string]interface{}{
 		"from_type": "external",
 		"from_id":   0,
 		"to_type":   toType,     // "user" или "org"
 		"to_id":     toID,
 		"amount":    amount,
+		"currency":  currency,
 	})
 
 	req, err := http.NewRequestWithContext(ctx, "POST", tc.url+"/transfer", bytes.NewBuffer(reqBody))
@@ -39,6 +49,7 @@ func (tc *TransactionClient) Deposit(ctx context.Context, toType string, toID in
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
 
 	resp, err := tc.client.Do(req)
 	if err != nil {
@@ -46,19 +57,25 @@ func (tc *TransactionClient) Deposit(ctx context.Context, toType string, toID in
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return fmt.Errorf("%w: %d", ErrTransferRejected, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("transaction service error: %d", resp.StatusCode)
 	}
 	return nil
 }
 
-func (tc *TransactionClient) Withdraw(ctx context.Context, fromType string, fromID int, amount float64) error {
+// Transfer переводит деньги между кошельками платформы (пользователь — проект или организация);
+// как и Deposit, с тем же idempotencyKey перевод проводится один раз.
+func (tc *TransactionClient) Transfer(ctx context.Context, idempotencyKey string, fromType string, fromID int, toType string, toID int, amount money.Amount, currency money.Currency) error {
 	reqBody, _ := json.Marshal(map[string]interface{}{
-		"from_type": fromType,   // "user" или "org"
+		"from_type": fromType,
 		"from_id":   fromID,
-		"to_type":   "external",
-		"to_id":     0,
+		"to_type":   toType,
+		"to_id":     toID,
 		"amount":    amount,
+		"currency":  currency,
 	})
 
 	req, err := http.NewRequestWithContext(ctx, "POST", tc.url+"/transfer", bytes.NewBuffer(reqBody))
@@ -66,6 +83,7 @@ func (tc *TransactionClient) Withdraw(ctx context.Context, fromType string, from
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
 
 	resp, err := tc.client.Do(req)
 	if err != nil {
@@ -73,8 +91,85 @@ func (tc *TransactionClient) Withdraw(ctx context.Context, fromType string, from
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return fmt.Errorf("%w: %d", ErrTransferRejected, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("transaction service error: %d", resp.StatusCode)
 	}
 	return nil
 }
+
+// Hold резервирует деньги под вывод на внешний счет и возвращает id холда.
+// idempotencyKey служит reference холда: повторный вызов вернет тот же холд.
+// Такой холд не истекает: он держится, пока его не спишут или не освободят.
+func (tc *TransactionClient) Hold(ctx context.Context, idempotencyKey string, fromType string, fromID int, amount money.Amount, currency money.Currency) (int64, error) {
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"from_type": fromType, // "user" или "org"
+		"from_id":   fromID,
+		"to_type":   "external",
+		"to_id":     0,
+		"amount":    amount,
+		"currency":  currency,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tc.url+"/internal/holds", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return 0, fmt.Errorf("%w: %d", ErrTransferRejected, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("transaction service error: %d", resp.StatusCode)
+	}
+
+	var hold struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&hold); err != nil {
+		return 0, err
+	}
+	return hold.ID, nil
+}
+
+// CaptureHold списывает холд; повторное списание не проводит перевод второй раз
+func (tc *TransactionClient) CaptureHold(ctx context.Context, holdID int64) error {
+	return tc.holdAction(ctx, holdID, "capture")
+}
+
+// ReleaseHold освобождает холд; освобождать можно повторно
+func (tc *TransactionClient) ReleaseHold(ctx context.Context, holdID int64) error {
+	return tc.holdAction(ctx, holdID, "release")
+}
+
+func (tc *TransactionClient) holdAction(ctx context.Context, holdID int64, action string) error {
+	url := fmt.Sprintf("%s/internal/holds/%d/%s", tc.url, holdID, action)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return fmt.Errorf("%w: %d", ErrTransferRejected, resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("transaction service error: %d", resp.StatusCode)
+	}
+	return nil
+}
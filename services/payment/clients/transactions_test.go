@@ -0,0 +1,59 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+)
+
+func TestHoldHasNoExpiry(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/internal/holds" {
+			t.Errorf("request %s %s, want POST /internal/holds", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Idempotency-Key"); got != "withdrawal-hold:w1" {
+			t.Errorf("Idempotency-Key = %q, want %q", got, "withdrawal-hold:w1")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 42}`))
+	}))
+	defer srv.Close()
+
+	tc := &TransactionClient{url: srv.URL, client: srv.Client()}
+	id, err := tc.Hold(context.Background(), "withdrawal-hold:w1", "user", 7, money.FromRubles(100), money.RUB)
+	if err != nil {
+		t.Fatalf("Hold() error = %v", err)
+	}
+	if id != 42 {
+		t.Errorf("Hold() = %d, want 42", id)
+	}
+	if body["to_type"] != "external" {
+		t.Errorf("to_type = %v, want external", body["to_type"])
+	}
+	// срок не передается: холд под выплату держится, пока провайдер не ответит
+	if _, ok := body["ttl_seconds"]; ok {
+		t.Errorf("ttl_seconds = %v, want it omitted", body["ttl_seconds"])
+	}
+}
+
+func TestHoldRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Недостаточно средств", http.StatusUnprocessableEntity)
+	}))
+	defer srv.Close()
+
+	tc := &TransactionClient{url: srv.URL, client: srv.Client()}
+	_, err := tc.Hold(context.Background(), "withdrawal-hold:w2", "user", 7, money.FromRubles(100), money.RUB)
+	if !errors.Is(err, ErrTransferRejected) {
+		t.Errorf("Hold() error = %v, want ErrTransferRejected", err)
+	}
+}
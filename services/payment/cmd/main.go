@@ -11,11 +11,19 @@ import (
 
 	"github.com/Starostina-elena/investment_platform/services/payment/clients"
 	"github.com/Starostina-elena/investment_platform/services/payment/handler"
+	"github.com/Starostina-elena/investment_platform/services/payment/metrics"
+	"github.com/Starostina-elena/investment_platform/services/payment/middleware"
+	"github.com/Starostina-elena/investment_platform/services/payment/outbox"
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
 	"github.com/Starostina-elena/investment_platform/services/payment/repo"
+	"github.com/Starostina-elena/investment_platform/services/payment/saga"
+	"github.com/Starostina-elena/investment_platform/services/payment/scheduler"
 	"github.com/Starostina-elena/investment_platform/services/payment/service"
+	"github.com/Starostina-elena/investment_platform/services/payment/webhook"
 	"github.com/Starostina-elena/investment_platform/services/payment/yookassa"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -32,35 +40,109 @@ func main() {
 		log.Fatalf("failed to connect to db: %v", err)
 	}
 
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		redisHost = "localhost"
+	}
+	redisPort := os.Getenv("REDIS_PORT")
+	if redisPort == "" {
+		redisPort = "6379"
+	}
+	redisClient := redis.NewClient(&redis.Options{Addr: redisHost + ":" + redisPort})
+	defer redisClient.Close()
+
 	r := repo.NewRepo(db)
-	yc := yookassa.NewClient()
 	tc := clients.NewTransactionClient()
-	svc := service.NewService(r, yc, tc, *logger)
-	h := handler.NewHandler(svc)
+	oc := clients.NewOrgClient()
+	sagas := saga.NewOrchestrator(db, "payment", *logger)
 
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
+	verifier, err := webhook.NewVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("invalid webhook config: %v", err)
+	}
+	// без секрета вебхук о платеже мог бы прислать любой, кто подделает адрес
+	if !verifier.HasSecret() {
+		log.Fatalf("YOOKASSA_WEBHOOK_SECRET is not set, refusing to accept unsigned webhooks")
+	}
 
-		for range ticker.C {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			if err := svc.ProcessPendingPayments(ctx); err != nil {
-				logger.Error("error processing pending payments", "error", err)
-			}
-			if err := svc.ProcessPendingWithdrawals(ctx); err != nil {
-				logger.Error("error processing pending withdrawals", "error", err)
-			}
-			cancel()
+	// PAYMENT_PROVIDER=fake включает локальный шлюз: платежи подтверждаются на
+	// /fakepay/confirm/{id}, а вебхуки приходят с этого же хоста
+	var pp provider.PaymentProvider
+	var fake *provider.Fake
+	switch os.Getenv("PAYMENT_PROVIDER") {
+	case "", "yookassa":
+		pp = yookassa.NewClient()
+	case "fake":
+		if fake, err = provider.NewFakeFromEnv(logger); err != nil {
+			log.Fatalf("invalid fake provider config: %v", err)
 		}
-	}()
+		if err := verifier.Allow("127.0.0.1", "::1"); err != nil {
+			log.Fatalf("invalid webhook config: %v", err)
+		}
+		pp = fake
+	default:
+		log.Fatalf("unknown PAYMENT_PROVIDER %q", os.Getenv("PAYMENT_PROVIDER"))
+	}
+	logger.Info("payment provider selected", "provider", pp.Name())
+
+	policy, err := service.WithdrawalPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("invalid withdrawal limits: %v", err)
+	}
+
+	m := metrics.NewRegistry()
+	svc := service.NewService(r, pp, tc, oc, sagas, policy, m, *logger)
+	h := handler.NewHandler(svc, verifier)
+
+	// продолжает саги пополнения и вывода, прерванные сбоем или отложенные на повтор
+	go sagas.RunWorker(context.Background(), 10*time.Second)
+
+	relay := outbox.NewRelay(db, redisClient, "payment", *logger)
+	go relay.Run(context.Background(), time.Second)
+
+	// опрос провайдера и чистка идут только на реплике-лидере, остальные ждут
+	// своей очереди; саги и outbox закрепляют записи сами и работают на всех
+	sched := scheduler.New(db, "payment", m, *logger)
+	sched.Add(scheduler.Task{Name: "poll_payments", Every: 30 * time.Second, Run: svc.ProcessPendingPayments})
+	sched.Add(scheduler.Task{Name: "expire_payments", Every: time.Minute, Run: svc.ExpireStalePayments})
+	sched.Add(scheduler.Task{Name: "poll_withdrawals", Every: 30 * time.Second, Run: svc.ProcessPendingWithdrawals})
+	sched.Add(scheduler.Task{Name: "poll_refunds", Every: time.Minute, Run: svc.ProcessPendingRefunds})
+	sched.Add(scheduler.Task{Name: "replay_webhooks", Every: 30 * time.Second, Run: svc.ReplayFailedWebhooks})
+	go sched.Run(context.Background(), 10*time.Second)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("POST /pay/init", h.InitPaymentHandler)
+	// метрики под /internal/: nginx закрывает этот префикс снаружи и для /api/payment/, и для /withdraw/
+	mux.HandleFunc("GET /internal/metrics", m.Handler())
+	mux.Handle("POST /pay/init", middleware.AuthMiddleware(http.HandlerFunc(h.InitPaymentHandler)))
 	mux.HandleFunc("POST /pay/webhook", h.WebhookHandler)
-	mux.HandleFunc("POST /pay/check", h.CheckPaymentHandler)
-	mux.HandleFunc("POST /withdraw/init", h.InitWithdrawalHandler)
+	mux.Handle("POST /pay/check", middleware.AuthMiddleware(http.HandlerFunc(h.CheckPaymentHandler)))
+	mux.Handle("POST /pay/capture", middleware.AuthMiddleware(http.HandlerFunc(h.CapturePaymentHandler)))
+	mux.Handle("POST /pay/cancel", middleware.AuthMiddleware(http.HandlerFunc(h.CancelPaymentHandler)))
+	mux.Handle("POST /pay/refund", middleware.AuthMiddleware(http.HandlerFunc(h.RefundHandler)))
+	mux.Handle("POST /withdraw/init", middleware.AuthMiddleware(http.HandlerFunc(h.InitWithdrawalHandler)))
 	mux.HandleFunc("POST /withdraw/webhook", h.WebhookHandler)
-	mux.HandleFunc("POST /withdraw/check", h.CheckWithdrawalHandler)
+	mux.Handle("POST /withdraw/check", middleware.AuthMiddleware(http.HandlerFunc(h.CheckWithdrawalHandler)))
+	mux.Handle("GET /withdraw/review", middleware.AuthMiddleware(http.HandlerFunc(h.WithdrawalReviewHandler)))
+	mux.Handle("POST /withdraw/decisions", middleware.AuthMiddleware(http.HandlerFunc(h.WithdrawalDecisionsHandler)))
+	mux.Handle("POST /withdraw/approve", middleware.AuthMiddleware(http.HandlerFunc(h.ApproveWithdrawalHandler)))
+	mux.Handle("POST /withdraw/reject", middleware.AuthMiddleware(http.HandlerFunc(h.RejectWithdrawalHandler)))
+	mux.Handle("POST /pay/methods", middleware.AuthMiddleware(http.HandlerFunc(h.PaymentMethodsHandler)))
+	mux.Handle("POST /pay/methods/delete", middleware.AuthMiddleware(http.HandlerFunc(h.DeletePaymentMethodHandler)))
+	mux.Handle("POST /destinations", middleware.AuthMiddleware(http.HandlerFunc(h.DestinationsHandler)))
+	mux.Handle("POST /destinations/create", middleware.AuthMiddleware(http.HandlerFunc(h.SaveDestinationHandler)))
+	mux.Handle("POST /destinations/rename", middleware.AuthMiddleware(http.HandlerFunc(h.RenameDestinationHandler)))
+	mux.Handle("POST /destinations/verify", middleware.AuthMiddleware(http.HandlerFunc(h.VerifyDestinationHandler)))
+	mux.Handle("POST /destinations/delete", middleware.AuthMiddleware(http.HandlerFunc(h.DeleteDestinationHandler)))
+	mux.Handle("POST /recurring", middleware.AuthMiddleware(http.HandlerFunc(h.RecurringHandler)))
+	mux.Handle("POST /recurring/create", middleware.AuthMiddleware(http.HandlerFunc(h.CreateRecurringHandler)))
+	mux.Handle("POST /recurring/pause", middleware.AuthMiddleware(http.HandlerFunc(h.PauseRecurringHandler)))
+	mux.Handle("POST /recurring/resume", middleware.AuthMiddleware(http.HandlerFunc(h.ResumeRecurringHandler)))
+	mux.Handle("POST /recurring/cancel", middleware.AuthMiddleware(http.HandlerFunc(h.CancelRecurringHandler)))
+	mux.Handle("POST /recurring/charges", middleware.AuthMiddleware(http.HandlerFunc(h.RecurringChargesHandler)))
+	mux.HandleFunc("POST /internal/recurring/run", h.RunRecurringHandler)
+	if fake != nil {
+		mux.HandleFunc("GET /fakepay/confirm/{id}", fake.ConfirmHandler())
+	}
 
 	logger.Info("payment service listening on :8106")
 	http.ListenAndServe(":8106", mux)
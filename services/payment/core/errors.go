@@ -0,0 +1,30 @@
+package core
+
+import "errors"
+
+var (
+	ErrForbidden            = errors.New("access to the wallet is forbidden")
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrWithdrawalNotFound   = errors.New("withdrawal not found")
+	ErrPaymentNotRefundable = errors.New("only succeeded payments can be refunded")
+	ErrRefundTooLarge       = errors.New("refund exceeds the not yet refunded payment amount")
+	ErrPaymentNotCapturable = errors.New("only payments waiting for capture can be captured or canceled")
+	ErrCaptureTooLarge      = errors.New("capture amount exceeds the payment amount")
+
+	ErrWithdrawalLimitExceeded = errors.New("withdrawal limit exceeded")
+	ErrRateUnavailable         = errors.New("no fresh fx rate to check withdrawal limits")
+	ErrWithdrawalNotInReview   = errors.New("withdrawal is not waiting for review")
+	ErrAlreadyDecided          = errors.New("administrator has already decided on this withdrawal")
+	ErrSelfApproval            = errors.New("withdrawal cannot be approved by the user who requested it")
+
+	ErrDestinationNotFound    = errors.New("payout destination not found")
+	ErrDestinationNotVerified = errors.New("payout destination is not verified")
+	ErrDestinationMismatch    = errors.New("bank details differ from the organisation registration data")
+	ErrInvalidDestination     = errors.New("invalid payout destination")
+	ErrPaymentMethodNotFound  = errors.New("saved payment method not found")
+
+	ErrRecurringNotFound     = errors.New("recurring pledge not found")
+	ErrRecurringFinished     = errors.New("recurring pledge is canceled or stopped")
+	ErrInvalidRecurring      = errors.New("invalid recurring pledge")
+	ErrRecurringTargetClosed = errors.New("project is completed or recipient is banned")
+)
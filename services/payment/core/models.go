@@ -1,32 +1,56 @@
 package core
 
-import "time"
+import (
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+)
 
 type PaymentStatus string
 
 const (
-	StatusPending   PaymentStatus = "pending"
-	StatusSucceeded PaymentStatus = "succeeded"
-	StatusCanceled  PaymentStatus = "canceled"
+	StatusCreating          PaymentStatus = "creating" // сохранен до обращения к провайдеру, external_id еще нет
+	StatusPending           PaymentStatus = "pending"
+	StatusWaitingForCapture PaymentStatus = "waiting_for_capture"
+	StatusSucceeded         PaymentStatus = "succeeded"
+	StatusCanceled          PaymentStatus = "canceled"
+	StatusExpired           PaymentStatus = "expired" // не оплачен до ExpiresAt
 )
 
 type Payment struct {
-	ID         string        `db:"id"`          // Внутренний UUID
-	ExternalID string        `db:"external_id"` // ID в ЮKassa
-	Amount     float64       `db:"amount"`
-	EntityID   int           `db:"entity_id"`
-	EntityType string        `db:"entity_type"` // "user" или "org"
-	Status     PaymentStatus `db:"status"`
-	CreatedAt  time.Time     `db:"created_at"`
-	UpdatedAt  time.Time     `db:"updated_at"`
+	ID         string         `db:"id"`          // Внутренний UUID
+	ExternalID string         `db:"external_id"` // ID в ЮKassa
+	Amount     money.Amount   `db:"amount"`
+	Currency   money.Currency `db:"currency"`
+	EntityID   int            `db:"entity_id"`
+	EntityType string         `db:"entity_type"` // "user" или "org"
+	Status     PaymentStatus  `db:"status"`
+	Capture    bool           `db:"capture"` // false — двухстадийный платеж
+	// ExpiresAt — до какого момента ждать оплаты (pending) или подтверждения (waiting_for_capture)
+	ExpiresAt          *time.Time `db:"expires_at"`
+	CancellationReason string     `db:"cancellation_reason"`
+	// CaptureAmount — сумма, которую попросили подтвердить; становится Amount, когда платеж прошел
+	CaptureAmount *money.Amount `db:"capture_amount"`
+	Poll
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// Poll — состояние опроса провайдера по незавершенной операции
+type Poll struct {
+	PollAttempts  int       `db:"poll_attempts" json:"-"`
+	NextPollAt    time.Time `db:"next_poll_at" json:"-"`
+	LastPollError *string   `db:"last_poll_error" json:"-"`
 }
 
 type WithdrawalStatus string
 
 const (
+	WithdrawalReview    WithdrawalStatus = "review" // ждет решения администратора, деньги в холде
 	WithdrawalPending   WithdrawalStatus = "pending"
 	WithdrawalSucceeded WithdrawalStatus = "succeeded"
 	WithdrawalFailed    WithdrawalStatus = "failed"
+	WithdrawalRejected  WithdrawalStatus = "rejected" // отклонен при ручной проверке
 )
 
 type Withdrawal struct {
@@ -34,8 +58,197 @@ type Withdrawal struct {
 	ExternalID string           `db:"external_id"` // ID выплаты в ЮKassa
 	EntityID   int              `db:"entity_id"`
 	EntityType string           `db:"entity_type"` // "user" или "org"
-	Amount     float64          `db:"amount"`
+	Amount     money.Amount     `db:"amount"`
+	Currency   money.Currency   `db:"currency"`
 	Status     WithdrawalStatus `db:"status"`
-	CreatedAt  time.Time        `db:"created_at"`
-	UpdatedAt  time.Time        `db:"updated_at"`
+	HoldID     *int64           `db:"hold_id"` // холд в сервисе транзакций; nil у выводов, списанных сразу
+	// Destination — payout token получателя для вывода без сохраненных реквизитов;
+	// пусто у выводов, созданных до ручной проверки
+	Destination   string `db:"destination" json:"-"`
+	DestinationID *int64 `db:"destination_id"` // сохраненные реквизиты
+	RequestedBy   int    `db:"requested_by"`
+	// ReviewReason — почему вывод отправлен на проверку, через запятую
+	ReviewReason      string `db:"review_reason"`
+	RequiredApprovals int    `db:"required_approvals"`
+	Approvals         int    `db:"approvals"` // заполняется только в очереди на проверку
+	Poll
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type WebhookStatus string
+
+const (
+	WebhookReceived  WebhookStatus = "received"
+	WebhookProcessed WebhookStatus = "processed"
+	WebhookFailed    WebhookStatus = "failed"
+	WebhookRejected  WebhookStatus = "rejected" // не прошел проверку источника или подписи
+)
+
+// WebhookEvent — запись входящего вебхука в webhook_inbox
+type WebhookEvent struct {
+	ID          int64         `db:"id" json:"id"`
+	Event       string        `db:"event" json:"event"`
+	ObjectID    string        `db:"object_id" json:"object_id"`
+	SourceIP    string        `db:"source_ip" json:"source_ip"`
+	Body        string        `db:"body" json:"body"`
+	Status      WebhookStatus `db:"status" json:"status"`
+	Error       *string       `db:"error" json:"error,omitempty"`
+	Attempts    int           `db:"attempts" json:"attempts"`
+	ReceivedAt  time.Time     `db:"received_at" json:"received_at"`
+	ProcessedAt *time.Time    `db:"processed_at" json:"processed_at,omitempty"`
+}
+
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"
+	RefundSucceeded RefundStatus = "succeeded"
+	RefundFailed    RefundStatus = "failed"
+)
+
+type Refund struct {
+	ID         string         `db:"id" json:"id"`
+	PaymentID  string         `db:"payment_id" json:"payment_id"`
+	ExternalID string         `db:"external_id" json:"external_id"` // ID возврата у провайдера
+	EntityID   int            `db:"entity_id" json:"entity_id"`
+	EntityType string         `db:"entity_type" json:"entity_type"`
+	Amount     money.Amount   `db:"amount" json:"amount"`
+	Currency   money.Currency `db:"currency" json:"currency"`
+	Status     RefundStatus   `db:"status" json:"status"`
+	Reason     string         `db:"reason" json:"reason"`
+	HoldID     *int64         `db:"hold_id" json:"-"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// Caller — пользователь из JWT, от имени которого идет запрос
+type Caller struct {
+	UserID int
+	Admin  bool
+	Banned bool
+	IP     string
+	// Authorization — заголовок запроса, передается в сервисы, которые проверяют доступ сами
+	Authorization string
+}
+
+type AuditEntry struct {
+	ID         int64           `db:"id"`
+	UserID     int             `db:"user_id"`
+	Action     string          `db:"action"`
+	EntityType string          `db:"entity_type"`
+	EntityID   int             `db:"entity_id"`
+	ObjectID   string          `db:"object_id"` // id платежа, вывода или возврата
+	Amount     *money.Amount   `db:"amount"`
+	Currency   *money.Currency `db:"currency"`
+	Allowed    bool            `db:"allowed"`
+	Error      *string         `db:"error"`
+	IP         string          `db:"ip"`
+	CreatedAt  time.Time       `db:"created_at"`
+}
+
+type WithdrawalDecisionType string
+
+const (
+	DecisionApprove WithdrawalDecisionType = "approve"
+	DecisionReject  WithdrawalDecisionType = "reject"
+)
+
+// WithdrawalDecision — решение администратора по выводу на ручной проверке
+type WithdrawalDecision struct {
+	ID           int64                  `db:"id" json:"id"`
+	WithdrawalID string                 `db:"withdrawal_id" json:"withdrawal_id"`
+	AdminID      int                    `db:"admin_id" json:"admin_id"`
+	Decision     WithdrawalDecisionType `db:"decision" json:"decision"`
+	Comment      string                 `db:"comment" json:"comment"`
+	CreatedAt    time.Time              `db:"created_at" json:"created_at"`
+}
+
+// PayoutDestination — сохраненные реквизиты для вывода: карта, кошелек ЮMoney
+// или расчетный счет организации из ее регистрационных данных
+type PayoutDestination struct {
+	ID            int64      `db:"id" json:"id"`
+	EntityType    string     `db:"entity_type" json:"entity_type"`
+	EntityID      int        `db:"entity_id" json:"entity_id"`
+	Kind          string     `db:"kind" json:"kind"` // bank_card, yoo_money или bank_account
+	Name          string     `db:"name" json:"name"`
+	Masked        string     `db:"masked" json:"masked"` // для показа, например «220220******1234»
+	PayoutToken   string     `db:"payout_token" json:"-"`
+	AccountNumber string     `db:"account_number" json:"-"`
+	BIC           string     `db:"bic" json:"bic,omitempty"`
+	CorrAccount   string     `db:"corr_account" json:"-"`
+	Holder        string     `db:"holder" json:"holder,omitempty"`
+	VerifiedAt    *time.Time `db:"verified_at" json:"verified_at"`
+	VerifiedBy    *int       `db:"verified_by" json:"-"` // nil — проверены автоматически
+	CreatedBy     int        `db:"created_by" json:"created_by"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	DeletedAt     *time.Time `db:"deleted_at" json:"-"`
+}
+
+// SavedPaymentMethod — способ оплаты, сохраненный провайдером для пополнений без ввода карты
+type SavedPaymentMethod struct {
+	ID         string     `db:"id" json:"id"` // id способа оплаты у провайдера
+	EntityType string     `db:"entity_type" json:"entity_type"`
+	EntityID   int        `db:"entity_id" json:"entity_id"`
+	Type       string     `db:"type" json:"type"`
+	Title      string     `db:"title" json:"title"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	DeletedAt  *time.Time `db:"deleted_at" json:"-"`
+}
+
+type RecurringStatus string
+
+const (
+	RecurringActive   RecurringStatus = "active"
+	RecurringPaused   RecurringStatus = "paused"
+	RecurringCanceled RecurringStatus = "canceled" // отменено пользователем
+	RecurringStopped  RecurringStatus = "stopped"  // проект завершен или получатель заблокирован
+)
+
+// Источники денег для регулярного пожертвования
+const (
+	RecurringSourceWallet = "wallet"
+	RecurringSourceCard   = "card" // сохраненная карта, деньги сначала зачисляются на кошелек
+)
+
+// RecurringPledge — ежемесячный перевод пользователя в проект или организацию
+type RecurringPledge struct {
+	ID              int64           `db:"id" json:"id"`
+	UserID          int             `db:"user_id" json:"user_id"`
+	TargetType      string          `db:"target_type" json:"target_type"` // project или org
+	TargetID        int             `db:"target_id" json:"target_id"`
+	Amount          money.Amount    `db:"amount" json:"amount"`
+	Currency        money.Currency  `db:"currency" json:"currency"`
+	Source          string          `db:"source" json:"source"`
+	PaymentMethodID *string         `db:"payment_method_id" json:"payment_method_id,omitempty"`
+	Status          RecurringStatus `db:"status" json:"status"`
+	StopReason      string          `db:"stop_reason" json:"stop_reason,omitempty"`
+	NextChargeAt    time.Time       `db:"next_charge_at" json:"next_charge_at"`
+	CreatedAt       time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+type RecurringChargeStatus string
+
+const (
+	ChargePending   RecurringChargeStatus = "pending"   // ждет перевода или оплаты картой
+	ChargePaying    RecurringChargeStatus = "paying"    // платеж по карте создан, ждем зачисления на кошелек
+	ChargePaid      RecurringChargeStatus = "paid"      // деньги с карты на кошельке, осталось перевести получателю
+	ChargeSucceeded RecurringChargeStatus = "succeeded" // переведено получателю
+	ChargeFailed    RecurringChargeStatus = "failed"    // попытки исчерпаны
+	ChargeSkipped   RecurringChargeStatus = "skipped"   // пожертвование отменено или остановлено
+)
+
+// RecurringCharge — списание по регулярному пожертвованию за один месяц
+type RecurringCharge struct {
+	ID            int64                 `db:"id" json:"id"`
+	PledgeID      int64                 `db:"pledge_id" json:"pledge_id"`
+	Period        time.Time             `db:"period" json:"period"` // первое число месяца
+	Status        RecurringChargeStatus `db:"status" json:"status"`
+	PaymentID     *string               `db:"payment_id" json:"payment_id,omitempty"`
+	Attempts      int                   `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time             `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     string                `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt     time.Time             `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time             `db:"updated_at" json:"updated_at"`
 }
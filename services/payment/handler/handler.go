@@ -1,25 +1,58 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
+	"github.com/Starostina-elena/investment_platform/services/payment/clients"
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/middleware"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
 	"github.com/Starostina-elena/investment_platform/services/payment/service"
+	"github.com/Starostina-elena/investment_platform/services/payment/webhook"
 )
 
+// maxWebhookBody — предельный размер тела вебхука
+const maxWebhookBody = 1 << 20
+
 type Handler struct {
-	service *service.Service
+	service  *service.Service
+	verifier *webhook.Verifier
+}
+
+func NewHandler(s *service.Service, v *webhook.Verifier) *Handler {
+	return &Handler{service: s, verifier: v}
 }
 
-func NewHandler(s *service.Service) *Handler {
-	return &Handler{service: s}
+// caller собирает данные о пользователе из JWT для проверки доступа и аудита
+func (h *Handler) caller(r *http.Request) core.Caller {
+	var c core.Caller
+	if uc := middleware.FromContext(r.Context()); uc != nil {
+		c.UserID, c.Admin, c.Banned = uc.UserID, uc.Admin, uc.Banned
+	}
+	if ip := h.verifier.SourceIP(r); ip != nil {
+		c.IP = ip.String()
+	}
+	c.Authorization = r.Header.Get("Authorization")
+	return c
 }
 
 type InitRequest struct {
-	EntityType string  `json:"entity_type"`
-	EntityID   int     `json:"entity_id"`
-	Amount     float64 `json:"amount"`
-	ReturnURL  string  `json:"return_url"`
+	EntityType string       `json:"entity_type"`
+	EntityID   int          `json:"entity_id"`
+	Amount     money.Amount `json:"amount"`
+	Currency   string       `json:"currency"` // по умолчанию RUB
+	ReturnURL  string       `json:"return_url"`
+	// Capture = false — двухстадийный платеж, деньги зачисляются после POST /pay/capture
+	Capture *bool `json:"capture"`
+	// SavePaymentMethod — сохранить карту для следующих пополнений
+	SavePaymentMethod bool `json:"save_payment_method"`
+	// PaymentMethodID — оплатить сохраненной картой, без перехода на страницу оплаты
+	PaymentMethodID string `json:"payment_method_id"`
 }
 
 func (h *Handler) InitPaymentHandler(w http.ResponseWriter, r *http.Request) {
@@ -39,9 +72,16 @@ func (h *Handler) InitPaymentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	url, err := h.service.InitPayment(r.Context(), entityType, entityID, req.Amount, req.ReturnURL)
+	currency, err := money.ParseCurrency(req.Currency)
 	if err != nil {
-		http.Error(w, "failed to init payment", http.StatusInternalServerError)
+		http.Error(w, "unsupported currency", http.StatusBadRequest)
+		return
+	}
+
+	capture := req.Capture == nil || *req.Capture
+	url, err := h.service.InitPayment(r.Context(), h.caller(r), entityType, entityID, req.Amount, currency, req.ReturnURL, capture, req.SavePaymentMethod, req.PaymentMethodID)
+	if err != nil {
+		writePaymentError(w, err, "failed to init payment")
 		return
 	}
 
@@ -49,31 +89,38 @@ func (h *Handler) InitPaymentHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"confirmation_url": url})
 }
 
-type WebhookRequest struct {
-	Type   string                 `json:"type"`
-	Event  string                 `json:"event"`
-	Object map[string]interface{} `json:"object"`
-}
-
+// WebhookHandler принимает уведомления ЮKassa о платежах и выплатах. Запрос с чужого
+// адреса или с неверной подписью только сохраняется в inbox и отклоняется.
 func (h *Handler) WebhookHandler(w http.ResponseWriter, r *http.Request) {
-	var req WebhookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
-	if req.Event == "payment.succeeded" || req.Event == "payment.failed" {
-		if err := h.service.ProcessWebhook(r.Context(), req.Event, req.Object); err != nil {
-			http.Error(w, "internal error", http.StatusInternalServerError)
-			return
+	sourceIP := ""
+	if ip := h.verifier.SourceIP(r); ip != nil {
+		sourceIP = ip.String()
+	}
+
+	if err := h.verifier.Verify(r, body); err != nil {
+		h.service.RecordRejectedWebhook(r.Context(), sourceIP, body, err)
+		if errors.Is(err, webhook.ErrSourceNotAllowed) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+		} else {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
 		}
+		return
 	}
 
-	if req.Event == "payout.succeeded" || req.Event == "payout.failed" {
-		if err := h.service.ProcessWithdrawalWebhook(r.Context(), req.Event, req.Object); err != nil {
-			http.Error(w, "internal error", http.StatusInternalServerError)
+	if err := h.service.ReceiveWebhook(r.Context(), sourceIP, body); err != nil {
+		if errors.Is(err, service.ErrBadWebhook) {
+			http.Error(w, "bad request", http.StatusBadRequest)
 			return
 		}
+		// ЮKassa повторит уведомление, а запись в inbox будет повторена и у нас
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -95,9 +142,40 @@ func (h *Handler) CheckPaymentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	payment, err := h.service.CheckPayment(r.Context(), req.PaymentID)
+	payment, err := h.service.CheckPayment(r.Context(), h.caller(r), req.PaymentID)
+	if err != nil {
+		writePaymentError(w, err, "failed to check payment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+type CapturePaymentRequest struct {
+	PaymentID string       `json:"payment_id"`
+	Amount    money.Amount `json:"amount"` // 0 — подтвердить всю сумму
+}
+
+func (h *Handler) CapturePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	var req CapturePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.PaymentID == "" {
+		http.Error(w, "payment_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.Amount.IsNegative() {
+		http.Error(w, "amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.service.CapturePayment(r.Context(), h.caller(r), req.PaymentID, req.Amount)
 	if err != nil {
-		http.Error(w, "payment not found", http.StatusNotFound)
+		writePaymentError(w, err, "failed to capture payment")
 		return
 	}
 
@@ -105,11 +183,114 @@ func (h *Handler) CheckPaymentHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(payment)
 }
 
+func (h *Handler) CancelPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	var req CheckPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.PaymentID == "" {
+		http.Error(w, "payment_id is required", http.StatusBadRequest)
+		return
+	}
+
+	payment, err := h.service.CancelPayment(r.Context(), h.caller(r), req.PaymentID)
+	if err != nil {
+		writePaymentError(w, err, "failed to cancel payment")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+func writePaymentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, core.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, core.ErrPaymentNotFound), errors.Is(err, core.ErrWithdrawalNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, core.ErrPaymentNotCapturable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, core.ErrCaptureTooLarge), errors.Is(err, core.ErrWithdrawalLimitExceeded):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, core.ErrRateUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, core.ErrWithdrawalNotInReview), errors.Is(err, core.ErrAlreadyDecided):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, core.ErrSelfApproval):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, core.ErrDestinationNotFound), errors.Is(err, core.ErrPaymentMethodNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, core.ErrDestinationNotVerified), errors.Is(err, core.ErrDestinationMismatch):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, core.ErrInvalidDestination), errors.Is(err, provider.ErrDestinationNotSupported):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, core.ErrRecurringNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, core.ErrRecurringFinished), errors.Is(err, core.ErrRecurringTargetClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, core.ErrInvalidRecurring):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+type RefundRequest struct {
+	PaymentID string       `json:"payment_id"`
+	Amount    money.Amount `json:"amount"` // 0 — вернуть весь остаток платежа
+	Reason    string       `json:"reason"`
+}
+
+func (h *Handler) RefundHandler(w http.ResponseWriter, r *http.Request) {
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.PaymentID == "" {
+		http.Error(w, "payment_id is required", http.StatusBadRequest)
+		return
+	}
+	if req.Amount.IsNegative() {
+		http.Error(w, "amount must be positive", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.service.InitRefund(r.Context(), h.caller(r), req.PaymentID, req.Amount, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, core.ErrForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, core.ErrPaymentNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, core.ErrPaymentNotRefundable):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, core.ErrRefundTooLarge):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, clients.ErrTransferRejected):
+			http.Error(w, "insufficient funds to refund", http.StatusConflict)
+		default:
+			http.Error(w, "failed to init refund", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
 type InitWithdrawalRequest struct {
-	EntityType        string  `json:"entity_type"`
-	EntityID          int     `json:"entity_id"`
-	Amount            float64 `json:"amount"`
-	PayoutDestination string  `json:"payout_destination"`
+	EntityType        string       `json:"entity_type"`
+	EntityID          int          `json:"entity_id"`
+	Amount            money.Amount `json:"amount"`
+	Currency          string       `json:"currency"`           // по умолчанию RUB
+	PayoutDestination string       `json:"payout_destination"` // токен карты из виджета выплат
+	DestinationID     int64        `json:"destination_id"`     // или сохраненные реквизиты
 }
 
 func (h *Handler) InitWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
@@ -129,23 +310,29 @@ func (h *Handler) InitWithdrawalHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if req.Amount <= 0 {
+	if !req.Amount.IsPositive() {
 		http.Error(w, "amount must be positive", http.StatusBadRequest)
 		return
 	}
 
-	if req.PayoutDestination == "" {
-		http.Error(w, "payout_destination is required", http.StatusBadRequest)
+	if req.PayoutDestination == "" && req.DestinationID == 0 {
+		http.Error(w, "payout_destination or destination_id is required", http.StatusBadRequest)
 		return
 	}
 
-	withdrawalID, err := h.service.InitWithdrawal(r.Context(), entityType, entityID, req.Amount, req.PayoutDestination)
+	currency, err := money.ParseCurrency(req.Currency)
 	if err != nil {
-		http.Error(w, "failed to init withdrawal", http.StatusInternalServerError)
+		http.Error(w, "unsupported currency", http.StatusBadRequest)
+		return
+	}
+
+	withdrawal, err := h.service.InitWithdrawal(r.Context(), h.caller(r), entityType, entityID, req.Amount, currency, req.PayoutDestination, req.DestinationID)
+	if err != nil {
+		writePaymentError(w, err, "failed to init withdrawal")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"withdrawal_id": withdrawalID})
+	json.NewEncoder(w).Encode(map[string]string{"withdrawal_id": withdrawal.ID, "status": string(withdrawal.Status)})
 }
 
 type CheckWithdrawalRequest struct {
@@ -164,12 +351,405 @@ func (h *Handler) CheckWithdrawalHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	withdrawal, err := h.service.CheckWithdrawal(r.Context(), req.WithdrawalID)
+	withdrawal, err := h.service.CheckWithdrawal(r.Context(), h.caller(r), req.WithdrawalID)
 	if err != nil {
-		http.Error(w, "withdrawal not found", http.StatusNotFound)
+		writePaymentError(w, err, "failed to check withdrawal")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(withdrawal)
 }
+
+// WithdrawalReviewHandler — очередь выводов на ручной проверке, только для администраторов
+func (h *Handler) WithdrawalReviewHandler(w http.ResponseWriter, r *http.Request) {
+	withdrawals, err := h.service.GetWithdrawalsForReview(r.Context(), h.caller(r))
+	if err != nil {
+		writePaymentError(w, err, "failed to get withdrawals for review")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(withdrawals)
+}
+
+func (h *Handler) WithdrawalDecisionsHandler(w http.ResponseWriter, r *http.Request) {
+	var req CheckWithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.WithdrawalID == "" {
+		http.Error(w, "withdrawal_id is required", http.StatusBadRequest)
+		return
+	}
+
+	decisions, err := h.service.GetWithdrawalDecisions(r.Context(), h.caller(r), req.WithdrawalID)
+	if err != nil {
+		writePaymentError(w, err, "failed to get withdrawal decisions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(decisions)
+}
+
+type WithdrawalDecisionRequest struct {
+	WithdrawalID string `json:"withdrawal_id"`
+	Comment      string `json:"comment"`
+}
+
+func (h *Handler) ApproveWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	h.decideWithdrawal(w, r, h.service.ApproveWithdrawal)
+}
+
+func (h *Handler) RejectWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	h.decideWithdrawal(w, r, h.service.RejectWithdrawal)
+}
+
+func (h *Handler) decideWithdrawal(w http.ResponseWriter, r *http.Request, decide func(context.Context, core.Caller, string, string) (*core.Withdrawal, error)) {
+	var req WithdrawalDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.WithdrawalID == "" {
+		http.Error(w, "withdrawal_id is required", http.StatusBadRequest)
+		return
+	}
+
+	withdrawal, err := decide(r.Context(), h.caller(r), req.WithdrawalID, req.Comment)
+	if err != nil {
+		writePaymentError(w, err, "failed to save withdrawal decision")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(withdrawal)
+}
+
+type WalletRequest struct {
+	EntityType string `json:"entity_type"` // по умолчанию user
+	EntityID   int    `json:"entity_id"`
+}
+
+func (req *WalletRequest) validate(w http.ResponseWriter) bool {
+	if req.EntityType == "" {
+		req.EntityType = "user"
+	}
+	if req.EntityID == 0 {
+		http.Error(w, "entity_id must be set", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+type SaveDestinationRequest struct {
+	EntityType string `json:"entity_type"`
+	EntityID   int    `json:"entity_id"`
+	Kind       string `json:"kind"` // bank_card, yoo_money или bank_account
+	Name       string `json:"name"`
+	// PayoutToken и CardLast4 — из виджета выплат ЮKassa, для kind = bank_card
+	PayoutToken string `json:"payout_token"`
+	CardLast4   string `json:"card_last4"`
+	// AccountNumber — номер кошелька ЮMoney; расчетный счет берется из данных организации
+	AccountNumber string `json:"account_number"`
+}
+
+func (h *Handler) SaveDestinationHandler(w http.ResponseWriter, r *http.Request) {
+	var req SaveDestinationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	wallet := WalletRequest{EntityType: req.EntityType, EntityID: req.EntityID}
+	if !wallet.validate(w) {
+		return
+	}
+
+	d := &core.PayoutDestination{
+		EntityType:    wallet.EntityType,
+		EntityID:      wallet.EntityID,
+		Kind:          req.Kind,
+		Name:          req.Name,
+		PayoutToken:   req.PayoutToken,
+		AccountNumber: req.AccountNumber,
+	}
+	if req.CardLast4 != "" {
+		d.Masked = "**** " + req.CardLast4
+	}
+
+	d, err := h.service.SaveDestination(r.Context(), h.caller(r), d)
+	if err != nil {
+		writePaymentError(w, err, "failed to save destination")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(d)
+}
+
+func (h *Handler) DestinationsHandler(w http.ResponseWriter, r *http.Request) {
+	var req WalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !req.validate(w) {
+		return
+	}
+
+	destinations, err := h.service.GetDestinations(r.Context(), h.caller(r), req.EntityType, req.EntityID)
+	if err != nil {
+		writePaymentError(w, err, "failed to get destinations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(destinations)
+}
+
+type DestinationRequest struct {
+	DestinationID int64  `json:"destination_id"`
+	Name          string `json:"name"`
+}
+
+func (h *Handler) RenameDestinationHandler(w http.ResponseWriter, r *http.Request) {
+	var req DestinationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.DestinationID == 0 || req.Name == "" {
+		http.Error(w, "destination_id and name are required", http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.service.RenameDestination(r.Context(), h.caller(r), req.DestinationID, req.Name)
+	if err != nil {
+		writePaymentError(w, err, "failed to rename destination")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+func (h *Handler) VerifyDestinationHandler(w http.ResponseWriter, r *http.Request) {
+	var req DestinationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.DestinationID == 0 {
+		http.Error(w, "destination_id is required", http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.service.VerifyDestination(r.Context(), h.caller(r), req.DestinationID)
+	if err != nil {
+		writePaymentError(w, err, "failed to verify destination")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+func (h *Handler) DeleteDestinationHandler(w http.ResponseWriter, r *http.Request) {
+	var req DestinationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	if req.DestinationID == 0 {
+		http.Error(w, "destination_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteDestination(r.Context(), h.caller(r), req.DestinationID); err != nil {
+		writePaymentError(w, err, "failed to delete destination")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PaymentMethodsHandler(w http.ResponseWriter, r *http.Request) {
+	var req WalletRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !req.validate(w) {
+		return
+	}
+
+	methods, err := h.service.GetPaymentMethods(r.Context(), h.caller(r), req.EntityType, req.EntityID)
+	if err != nil {
+		writePaymentError(w, err, "failed to get payment methods")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(methods)
+}
+
+type DeletePaymentMethodRequest struct {
+	EntityType      string `json:"entity_type"`
+	EntityID        int    `json:"entity_id"`
+	PaymentMethodID string `json:"payment_method_id"`
+}
+
+func (h *Handler) DeletePaymentMethodHandler(w http.ResponseWriter, r *http.Request) {
+	var req DeletePaymentMethodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	wallet := WalletRequest{EntityType: req.EntityType, EntityID: req.EntityID}
+	if !wallet.validate(w) {
+		return
+	}
+	if req.PaymentMethodID == "" {
+		http.Error(w, "payment_method_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeletePaymentMethod(r.Context(), h.caller(r), wallet.EntityType, wallet.EntityID, req.PaymentMethodID); err != nil {
+		writePaymentError(w, err, "failed to delete payment method")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type CreateRecurringRequest struct {
+	TargetType string       `json:"target_type"` // project или org
+	TargetID   int          `json:"target_id"`
+	Amount     money.Amount `json:"amount"`
+	Currency   string       `json:"currency"` // по умолчанию RUB
+	Source     string       `json:"source"`   // wallet или card
+	// PaymentMethodID — сохраненная карта пользователя, для source = card
+	PaymentMethodID string `json:"payment_method_id"`
+}
+
+func (h *Handler) CreateRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateRecurringRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	currency, err := money.ParseCurrency(req.Currency)
+	if err != nil {
+		http.Error(w, "unsupported currency", http.StatusBadRequest)
+		return
+	}
+
+	p := &core.RecurringPledge{
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		Amount:     req.Amount,
+		Currency:   currency,
+		Source:     req.Source,
+	}
+	if req.PaymentMethodID != "" {
+		p.PaymentMethodID = &req.PaymentMethodID
+	}
+
+	p, err = h.service.CreateRecurring(r.Context(), h.caller(r), p)
+	if err != nil {
+		writePaymentError(w, err, "failed to create recurring pledge")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *Handler) RecurringHandler(w http.ResponseWriter, r *http.Request) {
+	pledges, err := h.service.GetRecurring(r.Context(), h.caller(r))
+	if err != nil {
+		writePaymentError(w, err, "failed to get recurring pledges")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pledges)
+}
+
+type RecurringRequest struct {
+	PledgeID int64 `json:"pledge_id"`
+}
+
+func (h *Handler) PauseRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	h.recurringAction(w, r, h.service.PauseRecurring, "failed to pause recurring pledge")
+}
+
+func (h *Handler) ResumeRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	h.recurringAction(w, r, h.service.ResumeRecurring, "failed to resume recurring pledge")
+}
+
+func (h *Handler) CancelRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	h.recurringAction(w, r, h.service.CancelRecurring, "failed to cancel recurring pledge")
+}
+
+func (h *Handler) recurringAction(w http.ResponseWriter, r *http.Request, action func(context.Context, core.Caller, int64) (*core.RecurringPledge, error), fallback string) {
+	var req RecurringRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.PledgeID == 0 {
+		http.Error(w, "pledge_id is required", http.StatusBadRequest)
+		return
+	}
+
+	p, err := action(r.Context(), h.caller(r), req.PledgeID)
+	if err != nil {
+		writePaymentError(w, err, fallback)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *Handler) RecurringChargesHandler(w http.ResponseWriter, r *http.Request) {
+	var req RecurringRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if req.PledgeID == 0 {
+		http.Error(w, "pledge_id is required", http.StatusBadRequest)
+		return
+	}
+
+	charges, err := h.service.GetRecurringCharges(r.Context(), h.caller(r), req.PledgeID)
+	if err != nil {
+		writePaymentError(w, err, "failed to get recurring charges")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(charges)
+}
+
+// RunRecurringHandler запускает списания по регулярным пожертвованиям; вызывается демоном
+// по расписанию и снаружи не доступен
+func (h *Handler) RunRecurringHandler(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.RunRecurring(r.Context()); err != nil {
+		http.Error(w, "failed to run recurring charges", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry — счетчики и показатели в текстовом формате Prometheus. Серия задается
+// именем и парами меток: Add("payment_poll_total", 1, "kind", "payment").
+type Registry struct {
+	mu     sync.Mutex
+	series map[string]float64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{series: map[string]float64{}}
+}
+
+// Add увеличивает счетчик
+func (r *Registry) Add(name string, v float64, labels ...string) {
+	key := seriesKey(name, labels)
+	r.mu.Lock()
+	r.series[key] += v
+	r.mu.Unlock()
+}
+
+// Set задает текущее значение показателя
+func (r *Registry) Set(name string, v float64, labels ...string) {
+	key := seriesKey(name, labels)
+	r.mu.Lock()
+	r.series[key] = v
+	r.mu.Unlock()
+}
+
+// Get возвращает значение серии, 0 — если ее нет
+func (r *Registry) Get(name string, labels ...string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.series[seriesKey(name, labels)]
+}
+
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		r.mu.Lock()
+		keys := make([]string, 0, len(r.series))
+		for k := range r.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s %g\n", k, r.series[k])
+		}
+		r.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(b.String()))
+	}
+}
+
+func seriesKey(name string, labels []string) string {
+	if len(labels) < 2 {
+		return name
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	return name + "{" + strings.Join(parts, ",") + "}"
+}
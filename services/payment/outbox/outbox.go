@@ -0,0 +1,46 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Stream — общий Redis Stream, в который релеи всех сервисов публикуют события
+const Stream = "platform:events"
+
+const (
+	TypeProjectCreated     = "project.created"
+	TypeProjectGoalReached = "project.goal_reached"
+	TypeProjectUpdated     = "project.updated"
+	TypeTransferCompleted  = "transfer.completed"
+	TypePaymentSucceeded   = "payment.succeeded"
+	TypeOrgBanned          = "org.banned"
+)
+
+// Event — доменное событие в таблице outbox_events
+type Event struct {
+	ID          int64      `db:"id"`
+	Service     string     `db:"service"`
+	Type        string     `db:"event_type"`
+	Payload     []byte     `db:"payload"`
+	Attempts    int        `db:"attempts"`
+	LastError   *string    `db:"last_error"`
+	CreatedAt   time.Time  `db:"created_at"`
+	PublishedAt *time.Time `db:"published_at"`
+}
+
+// Add записывает событие в транзакции вызывающего: событие появится в outbox
+// только если изменение состояния закоммичено, и не потеряется, если Redis недоступен.
+func Add(ctx context.Context, tx *sqlx.Tx, service, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO outbox_events (service, event_type, payload) VALUES ($1, $2, $3)`,
+		service, eventType, data)
+	return err
+}
@@ -0,0 +1,418 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/webhook"
+	"github.com/google/uuid"
+)
+
+// Fake — локальный платежный шлюз для демо и сквозных тестов без ЮKassa. Хранит
+// объекты в памяти, подтверждение платежа имитирует страницей ConfirmHandler,
+// статусы меняет через Delay и шлет вебхуки в формате ЮKassa на WebhookURL.
+// С вероятностью FailRate платеж отменяется, а выплата не проходит.
+type Fake struct {
+	PublicURL  string
+	WebhookURL string
+	Secret     string
+	Delay      time.Duration
+	FailRate   float64
+
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+	payouts  map[string]*Payout
+	refunds  map[string]*Refund
+	keys     map[string]string // idempotenceKey -> id платежа, выплаты или возврата
+	http     *http.Client
+	log      *slog.Logger
+}
+
+type fakePayment struct {
+	Payment
+	Amount    string
+	Currency  string
+	ReturnURL string
+	Capture   bool
+	Save      bool
+	confirmed bool
+}
+
+func NewFake(publicURL, webhookURL, secret string, delay time.Duration, failRate float64, log *slog.Logger) *Fake {
+	return &Fake{
+		PublicURL:  publicURL,
+		WebhookURL: webhookURL,
+		Secret:     secret,
+		Delay:      delay,
+		FailRate:   failRate,
+		payments:   map[string]*fakePayment{},
+		payouts:    map[string]*Payout{},
+		refunds:    map[string]*Refund{},
+		keys:       map[string]string{},
+		http:       &http.Client{Timeout: 5 * time.Second},
+		log:        log,
+	}
+}
+
+// NewFakeFromEnv читает FAKEPAY_PUBLIC_URL, FAKEPAY_WEBHOOK_URL, FAKEPAY_DELAY,
+// FAKEPAY_FAIL_RATE и секрет вебхуков YOOKASSA_WEBHOOK_SECRET
+func NewFakeFromEnv(log *slog.Logger) (*Fake, error) {
+	publicURL := os.Getenv("FAKEPAY_PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost/api/payment"
+	}
+	webhookURL := os.Getenv("FAKEPAY_WEBHOOK_URL")
+	if webhookURL == "" {
+		webhookURL = "http://127.0.0.1:8106/pay/webhook"
+	}
+
+	delay := 2 * time.Second
+	if env := os.Getenv("FAKEPAY_DELAY"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FAKEPAY_DELAY: %w", err)
+		}
+		delay = d
+	}
+
+	var failRate float64
+	if env := os.Getenv("FAKEPAY_FAIL_RATE"); env != "" {
+		r, err := strconv.ParseFloat(env, 64)
+		if err != nil || r < 0 || r > 1 {
+			return nil, fmt.Errorf("invalid FAKEPAY_FAIL_RATE %q", env)
+		}
+		failRate = r
+	}
+
+	return NewFake(publicURL, webhookURL, os.Getenv("YOOKASSA_WEBHOOK_SECRET"), delay, failRate, log), nil
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+// CreatePayment создает платеж, который ждет подтверждения на /fakepay/confirm/{id}.
+// Платеж сохраненным способом подтверждается сразу, как автоплатеж ЮKassa.
+// Повтор с тем же opts.IdempotenceKey возвращает уже созданный платеж.
+func (f *Fake) CreatePayment(amount, currency, description, returnURL string, opts PaymentOptions) (*Payment, error) {
+	if opts.IdempotenceKey != "" {
+		f.mu.Lock()
+		if id, ok := f.keys[opts.IdempotenceKey]; ok {
+			if p, ok := f.payments[id]; ok {
+				result := p.Payment
+				f.mu.Unlock()
+				return &result, nil
+			}
+		}
+		f.mu.Unlock()
+	}
+
+	id := "fake-" + uuid.New().String()
+	p := &fakePayment{
+		Payment: Payment{
+			ID:     id,
+			Status: StatusPending,
+		},
+		Amount:    amount,
+		Currency:  currency,
+		ReturnURL: returnURL,
+		Capture:   opts.Capture,
+		Save:      opts.SavePaymentMethod,
+	}
+	if opts.PaymentMethodID != "" {
+		p.PaymentMethod = &PaymentMethod{ID: opts.PaymentMethodID, Type: DestinationBankCard, Title: "Bank card *4242", Saved: true}
+	} else {
+		p.ConfirmationURL = f.PublicURL + "/fakepay/confirm/" + id
+	}
+
+	f.mu.Lock()
+	f.payments[id] = p
+	if opts.IdempotenceKey != "" {
+		f.keys[opts.IdempotenceKey] = id
+	}
+	f.mu.Unlock()
+
+	f.log.Info("fake payment created", "id", id, "amount", amount, "currency", currency, "description", description)
+	result := p.Payment
+	if opts.PaymentMethodID != "" {
+		f.confirm(p, false)
+	}
+	return &result, nil
+}
+
+func (f *Fake) GetPayment(paymentID string) (*Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[paymentID]
+	if !ok {
+		return nil, fmt.Errorf("fake provider: payment %s not found", paymentID)
+	}
+	result := p.Payment
+	return &result, nil
+}
+
+// CapturePayment подтверждает двухстадийный платеж сразу, без задержки
+func (f *Fake) CapturePayment(paymentID, amount, currency, idempotenceKey string) (*Payment, error) {
+	f.mu.Lock()
+	p, ok := f.payments[paymentID]
+	if !ok {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("fake provider: payment %s not found", paymentID)
+	}
+	if p.Status == StatusSucceeded {
+		result := p.Payment
+		f.mu.Unlock()
+		return &result, nil
+	}
+	if p.Status != StatusWaitingForCapture {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("fake provider: payment %s is %s, not waiting for capture", paymentID, p.Status)
+	}
+	p.Status = StatusSucceeded
+	p.Paid = true
+	p.Amount = amount
+	result := p.Payment
+	f.mu.Unlock()
+
+	f.log.Info("fake payment captured", "id", paymentID, "amount", amount, "currency", currency)
+	f.later(func() { f.notify("payment.succeeded", result) })
+	return &result, nil
+}
+
+// CancelPayment отменяет неоплаченный или ожидающий подтверждения платеж
+func (f *Fake) CancelPayment(paymentID, idempotenceKey string) (*Payment, error) {
+	f.mu.Lock()
+	p, ok := f.payments[paymentID]
+	if !ok {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("fake provider: payment %s not found", paymentID)
+	}
+	if p.Status == StatusCanceled {
+		result := p.Payment
+		f.mu.Unlock()
+		return &result, nil
+	}
+	if p.Status == StatusSucceeded {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("fake provider: payment %s is already succeeded", paymentID)
+	}
+	p.Status = StatusCanceled
+	p.Paid = false
+	p.CancellationReason = ReasonCanceledByMerchant
+	p.confirmed = true
+	result := p.Payment
+	f.mu.Unlock()
+
+	f.log.Info("fake payment canceled", "id", paymentID)
+	f.later(func() { f.notify("payment.canceled", result) })
+	return &result, nil
+}
+
+// CreatePayout принимает реквизиты любого типа, в отличие от ЮKassa
+func (f *Fake) CreatePayout(amount, currency, description string, dest PayoutDestination, idempotenceKey string) (*Payout, error) {
+	if dest.PayoutToken == "" && dest.AccountNumber == "" {
+		return nil, fmt.Errorf("%w: fake provider: empty payout destination", ErrPayoutRejected)
+	}
+	f.mu.Lock()
+	if id, ok := f.keys[idempotenceKey]; ok {
+		if payout, ok := f.payouts[id]; ok {
+			result := *payout
+			f.mu.Unlock()
+			return &result, nil
+		}
+	}
+	payout := &Payout{ID: "fake-payout-" + uuid.New().String(), Status: StatusPending}
+	f.payouts[payout.ID] = payout
+	f.keys[idempotenceKey] = payout.ID
+	result := *payout
+	f.mu.Unlock()
+
+	f.log.Info("fake payout created", "id", payout.ID, "amount", amount, "currency", currency, "description", description, "destination", dest.Type)
+
+	status := StatusSucceeded
+	if f.fails() {
+		status = StatusFailed
+	}
+	f.later(func() {
+		f.mu.Lock()
+		payout.Status = status
+		object := *payout
+		f.mu.Unlock()
+		f.notify("payout."+status, object)
+	})
+	return &result, nil
+}
+
+func (f *Fake) GetPayout(payoutID string) (*Payout, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payout, ok := f.payouts[payoutID]
+	if !ok {
+		return nil, fmt.Errorf("fake provider: payout %s not found", payoutID)
+	}
+	result := *payout
+	return &result, nil
+}
+
+func (f *Fake) CreateRefund(paymentID, amount, currency, description, idempotenceKey string) (*Refund, error) {
+	f.mu.Lock()
+	if id, ok := f.keys[idempotenceKey]; ok {
+		if refund, ok := f.refunds[id]; ok {
+			result := *refund
+			f.mu.Unlock()
+			return &result, nil
+		}
+	}
+	p, ok := f.payments[paymentID]
+	if !ok || p.Status != StatusSucceeded {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("fake provider: payment %s is not succeeded", paymentID)
+	}
+	refund := &Refund{ID: "fake-refund-" + uuid.New().String(), PaymentID: paymentID, Status: StatusPending}
+	f.refunds[refund.ID] = refund
+	f.keys[idempotenceKey] = refund.ID
+	result := *refund
+	f.mu.Unlock()
+
+	f.log.Info("fake refund created", "id", refund.ID, "payment_id", paymentID, "amount", amount, "currency", currency, "description", description)
+
+	f.later(func() {
+		f.mu.Lock()
+		refund.Status = StatusSucceeded
+		object := *refund
+		f.mu.Unlock()
+		f.notify("refund.succeeded", object)
+	})
+	return &result, nil
+}
+
+func (f *Fake) GetRefund(refundID string) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	refund, ok := f.refunds[refundID]
+	if !ok {
+		return nil, fmt.Errorf("fake provider: refund %s not found", refundID)
+	}
+	result := *refund
+	return &result, nil
+}
+
+// ConfirmHandler — страница подтверждения, на которую ведет ConfirmationURL.
+// ?result=cancel отменяет платеж, без параметра он оплачивается (или отменяется
+// с вероятностью FailRate); платеж с capture = false переходит в waiting_for_capture.
+// Пользователь сразу уходит на return_url, а статус меняется и вебхук
+// отправляется через Delay.
+func (f *Fake) ConfirmHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		f.mu.Lock()
+		p, ok := f.payments[id]
+		if !ok {
+			f.mu.Unlock()
+			http.Error(w, "payment not found", http.StatusNotFound)
+			return
+		}
+		returnURL := p.ReturnURL
+		f.mu.Unlock()
+
+		f.confirm(p, r.URL.Query().Get("result") == "cancel")
+
+		if returnURL == "" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte("Платеж обрабатывается тестовым шлюзом"))
+			return
+		}
+		http.Redirect(w, r, returnURL, http.StatusFound)
+	}
+}
+
+// confirm проводит оплату через Delay и отправляет вебхук; повторное подтверждение ничего не делает
+func (f *Fake) confirm(p *fakePayment, canceledByUser bool) {
+	f.mu.Lock()
+	if p.confirmed {
+		f.mu.Unlock()
+		return
+	}
+	p.confirmed = true
+	capture, save := p.Capture, p.Save
+	f.mu.Unlock()
+
+	status := StatusSucceeded
+	if !capture {
+		status = StatusWaitingForCapture
+	}
+	reason := ""
+	if canceledByUser {
+		status, reason = StatusCanceled, "canceled_by_user"
+	} else if f.fails() {
+		status, reason = StatusCanceled, "general_decline"
+	}
+	f.later(func() {
+		f.mu.Lock()
+		if p.Status != StatusPending {
+			// платеж успели отменить, пока шла задержка
+			f.mu.Unlock()
+			return
+		}
+		p.Status = status
+		p.Paid = status == StatusSucceeded || status == StatusWaitingForCapture
+		p.CancellationReason = reason
+		if save && p.Paid && p.PaymentMethod == nil {
+			p.PaymentMethod = &PaymentMethod{ID: "fake-pm-" + uuid.New().String(), Type: DestinationBankCard, Title: "Bank card *4242", Saved: true}
+		}
+		object := p.Payment
+		f.mu.Unlock()
+		f.notify("payment."+status, object)
+	})
+}
+
+func (f *Fake) fails() bool {
+	return f.FailRate > 0 && rand.Float64() < f.FailRate
+}
+
+func (f *Fake) later(fn func()) {
+	if f.Delay <= 0 {
+		fn()
+		return
+	}
+	time.AfterFunc(f.Delay, fn)
+}
+
+// notify шлет вебхук в формате ЮKassa, подписанный секретом, если он задан
+func (f *Fake) notify(event string, object interface{}) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"type":   "notification",
+		"event":  event,
+		"object": object,
+	})
+
+	req, err := http.NewRequest("POST", f.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		f.log.Error("fake webhook request failed", "error", err, "event", event)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.Secret != "" {
+		req.Header.Set(webhook.SignatureHeader, hex.EncodeToString(webhook.Sign([]byte(f.Secret), body)))
+	}
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		f.log.Error("fake webhook delivery failed", "error", err, "event", event)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		f.log.Error("fake webhook rejected", "status", resp.StatusCode, "event", event)
+	}
+}
@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/webhook"
+)
+
+func TestFakePaymentLifecycle(t *testing.T) {
+	events := make(chan string, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		want := hex.EncodeToString(webhook.Sign([]byte("s3cret"), body))
+		if got := r.Header.Get(webhook.SignatureHeader); got != want {
+			t.Errorf("webhook signature = %q, want %q", got, want)
+		}
+		var n struct {
+			Event string `json:"event"`
+		}
+		_ = json.Unmarshal(body, &n)
+		events <- n.Event
+	}))
+	defer hook.Close()
+
+	f := NewFake("http://pay.local", hook.URL, "s3cret", 0, 0, slog.Default())
+
+	p, err := f.CreatePayment("100.00", "RUB", "test", "http://shop.local/return", PaymentOptions{Capture: true})
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	if p.Status != StatusPending || p.ConfirmationURL != "http://pay.local/fakepay/confirm/"+p.ID {
+		t.Errorf("CreatePayment() = %+v", p)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /fakepay/confirm/{id}", f.ConfirmHandler())
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/fakepay/confirm/"+p.ID, nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "http://shop.local/return" {
+		t.Errorf("confirm = %d %q, want redirect to return url", rec.Code, rec.Header().Get("Location"))
+	}
+
+	if got := <-events; got != "payment.succeeded" {
+		t.Errorf("webhook event = %q, want payment.succeeded", got)
+	}
+	if got, _ := f.GetPayment(p.ID); got.Status != StatusSucceeded || !got.Paid {
+		t.Errorf("GetPayment() = %+v, want succeeded", got)
+	}
+
+	r1, err := f.CreateRefund(p.ID, "40.00", "RUB", "refund", "key-1")
+	if err != nil {
+		t.Fatalf("CreateRefund() error = %v", err)
+	}
+	r2, _ := f.CreateRefund(p.ID, "40.00", "RUB", "refund", "key-1")
+	if r1.ID != r2.ID {
+		t.Errorf("CreateRefund() with same key created %q and %q", r1.ID, r2.ID)
+	}
+	if got := <-events; got != "refund.succeeded" {
+		t.Errorf("webhook event = %q, want refund.succeeded", got)
+	}
+}
+
+func TestFakeTwoStagePayment(t *testing.T) {
+	events := make(chan string, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n struct {
+			Event string `json:"event"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		events <- n.Event
+	}))
+	defer hook.Close()
+
+	f := NewFake("http://pay.local", hook.URL, "", 0, 0, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /fakepay/confirm/{id}", f.ConfirmHandler())
+
+	p, _ := f.CreatePayment("100.00", "RUB", "test", "", PaymentOptions{})
+	if _, err := f.CapturePayment(p.ID, "100.00", "RUB", "c-1"); err == nil {
+		t.Errorf("CapturePayment() before confirmation error = nil, want error")
+	}
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fakepay/confirm/"+p.ID, nil))
+	if got := <-events; got != "payment.waiting_for_capture" {
+		t.Errorf("webhook event = %q, want payment.waiting_for_capture", got)
+	}
+
+	captured, err := f.CapturePayment(p.ID, "60.00", "RUB", "c-1")
+	if err != nil {
+		t.Fatalf("CapturePayment() error = %v", err)
+	}
+	if captured.Status != StatusSucceeded {
+		t.Errorf("CapturePayment() status = %q, want succeeded", captured.Status)
+	}
+	if got := <-events; got != "payment.succeeded" {
+		t.Errorf("webhook event = %q, want payment.succeeded", got)
+	}
+
+	p2, _ := f.CreatePayment("50.00", "RUB", "test", "", PaymentOptions{})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fakepay/confirm/"+p2.ID, nil))
+	<-events
+	canceled, err := f.CancelPayment(p2.ID, "x-1")
+	if err != nil {
+		t.Fatalf("CancelPayment() error = %v", err)
+	}
+	if canceled.Status != StatusCanceled || canceled.CancellationReason != ReasonCanceledByMerchant {
+		t.Errorf("CancelPayment() = %+v, want canceled by merchant", canceled)
+	}
+	if got := <-events; got != "payment.canceled" {
+		t.Errorf("webhook event = %q, want payment.canceled", got)
+	}
+}
+
+func TestFakePayoutFailure(t *testing.T) {
+	events := make(chan string, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n struct {
+			Event string `json:"event"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		events <- n.Event
+	}))
+	defer hook.Close()
+
+	f := NewFake("http://pay.local", hook.URL, "", 0, 1, slog.Default())
+	payout, err := f.CreatePayout("10.00", "RUB", "test", PayoutDestination{Type: DestinationBankCard, PayoutToken: "token"}, "w-1")
+	if err != nil {
+		t.Fatalf("CreatePayout() error = %v", err)
+	}
+	if got := <-events; got != "payout.failed" {
+		t.Errorf("webhook event = %q, want payout.failed", got)
+	}
+	if got, _ := f.GetPayout(payout.ID); got.Status != StatusFailed {
+		t.Errorf("GetPayout() status = %q, want failed", got.Status)
+	}
+}
+
+func TestFakeSavedPaymentMethod(t *testing.T) {
+	events := make(chan string, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n struct {
+			Event string `json:"event"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		events <- n.Event
+	}))
+	defer hook.Close()
+
+	f := NewFake("http://pay.local", hook.URL, "", 0, 0, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /fakepay/confirm/{id}", f.ConfirmHandler())
+
+	p, _ := f.CreatePayment("100.00", "RUB", "test", "", PaymentOptions{Capture: true, SavePaymentMethod: true})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fakepay/confirm/"+p.ID, nil))
+	<-events
+	paid, _ := f.GetPayment(p.ID)
+	if paid.PaymentMethod == nil || !paid.PaymentMethod.Saved {
+		t.Fatalf("GetPayment() payment method = %+v, want saved", paid.PaymentMethod)
+	}
+
+	again, err := f.CreatePayment("30.00", "RUB", "test", "", PaymentOptions{Capture: true, PaymentMethodID: paid.PaymentMethod.ID})
+	if err != nil {
+		t.Fatalf("CreatePayment() with saved method error = %v", err)
+	}
+	if again.ConfirmationURL != "" {
+		t.Errorf("CreatePayment() with saved method confirmation_url = %q, want empty", again.ConfirmationURL)
+	}
+	if got := <-events; got != "payment.succeeded" {
+		t.Errorf("webhook event = %q, want payment.succeeded", got)
+	}
+}
+
+func TestFakeCreatePaymentIdempotent(t *testing.T) {
+	f := NewFake("http://pay.local", "", "", 0, 0, slog.Default())
+
+	opts := PaymentOptions{Capture: true, PaymentMethodID: "pm-1", IdempotenceKey: "recurring:1:payment:0"}
+	p1, err := f.CreatePayment("100.00", "RUB", "test", "", opts)
+	if err != nil {
+		t.Fatalf("CreatePayment() error = %v", err)
+	}
+	p2, _ := f.CreatePayment("100.00", "RUB", "test", "", opts)
+	if p1.ID != p2.ID {
+		t.Errorf("CreatePayment() with same key created %q and %q", p1.ID, p2.ID)
+	}
+
+	opts.IdempotenceKey = "recurring:1:payment:1"
+	p3, _ := f.CreatePayment("100.00", "RUB", "test", "", opts)
+	if p3.ID == p1.ID {
+		t.Errorf("CreatePayment() with a new key returned the old payment %q", p3.ID)
+	}
+}
@@ -0,0 +1,103 @@
+package provider
+
+import "errors"
+
+// Статусы объектов провайдера, совпадают со статусами ЮKassa
+const (
+	StatusPending           = "pending"
+	StatusWaitingForCapture = "waiting_for_capture"
+	StatusSucceeded         = "succeeded"
+	StatusCanceled          = "canceled"
+	StatusFailed            = "failed"
+)
+
+// Причины отмены платежа, которые ставит сам сервис
+const (
+	ReasonCanceledByMerchant    = "canceled_by_merchant"
+	ReasonExpiredOnConfirmation = "expired_on_confirmation"
+	ReasonExpiredOnCapture      = "expired_on_capture"
+)
+
+type Payment struct {
+	ID              string `json:"id"`
+	Status          string `json:"status"`
+	Paid            bool   `json:"paid"`
+	ConfirmationURL string `json:"confirmation_url"`
+	// CancellationReason — причина отмены из cancellation_details ЮKassa
+	CancellationReason string `json:"cancellation_reason,omitempty"`
+	// PaymentMethod — способ оплаты; Saved = true, если его можно использовать повторно
+	PaymentMethod *PaymentMethod `json:"payment_method,omitempty"`
+}
+
+type PaymentMethod struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"` // например, «Bank card *4444»
+	Saved bool   `json:"saved"`
+}
+
+// PaymentOptions — параметры создания платежа
+type PaymentOptions struct {
+	// Capture = false — двухстадийный платеж, ждет CapturePayment или CancelPayment
+	Capture bool
+	// SavePaymentMethod просит провайдера сохранить способ оплаты для следующих платежей
+	SavePaymentMethod bool
+	// PaymentMethodID — оплата сохраненным способом, без перехода пользователя на страницу оплаты
+	PaymentMethodID string
+	// IdempotenceKey — ключ для повторов создания того же платежа; пусто — случайный
+	IdempotenceKey string
+}
+
+// Типы реквизитов для выплаты
+const (
+	DestinationBankCard    = "bank_card"
+	DestinationYooMoney    = "yoo_money"
+	DestinationBankAccount = "bank_account"
+)
+
+var ErrDestinationNotSupported = errors.New("payout destination type is not supported by the provider")
+
+// ErrPayoutRejected — провайдер ответил отказом, выплата точно не создана.
+// Любая другая ошибка CreatePayout (таймаут, 5xx) не говорит, создана ли выплата.
+var ErrPayoutRejected = errors.New("payout is rejected by the provider")
+
+// PayoutDestination — куда отправить выплату
+type PayoutDestination struct {
+	Type string
+	// PayoutToken — токен карты из виджета выплат ЮKassa (bank_card)
+	PayoutToken string
+	// AccountNumber — номер кошелька ЮMoney (yoo_money) или расчетный счет (bank_account)
+	AccountNumber string
+	BIC           string
+	CorrAccount   string
+	Holder        string // получатель: ФИО или название организации
+}
+
+type Payout struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type Refund struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"payment_id"`
+	Status    string `json:"status"`
+}
+
+// PaymentProvider — платежный шлюз: прием платежей, выплаты и возвраты.
+// Суммы передаются строкой с двумя знаками после точки, как их ждет ЮKassa.
+// idempotenceKey должен совпадать при повторах одной и той же операции.
+// Платеж с Capture = false после оплаты ждет CapturePayment или CancelPayment
+// в статусе waiting_for_capture. Если провайдер не умеет выплачивать на реквизиты
+// такого типа, CreatePayout возвращает ErrDestinationNotSupported, при отказе — ErrPayoutRejected.
+type PaymentProvider interface {
+	Name() string
+	CreatePayment(amount, currency, description, returnURL string, opts PaymentOptions) (*Payment, error)
+	GetPayment(paymentID string) (*Payment, error)
+	CapturePayment(paymentID, amount, currency, idempotenceKey string) (*Payment, error)
+	CancelPayment(paymentID, idempotenceKey string) (*Payment, error)
+	CreatePayout(amount, currency, description string, dest PayoutDestination, idempotenceKey string) (*Payout, error)
+	GetPayout(payoutID string) (*Payout, error)
+	CreateRefund(paymentID, amount, currency, description, idempotenceKey string) (*Refund, error)
+	GetRefund(refundID string) (*Refund, error)
+}
@@ -0,0 +1,15 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+)
+
+func (r *Repo) SaveAudit(ctx context.Context, e *core.AuditEntry) error {
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO payment_audit_log (user_id, action, entity_type, entity_id, object_id, amount, currency, allowed, error, ip)
+		VALUES (:user_id, :action, :entity_type, :entity_id, :object_id, :amount, :currency, :allowed, :error, :ip)
+	`, e)
+	return err
+}
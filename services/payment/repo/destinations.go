@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+)
+
+func (r *Repo) CreateDestination(ctx context.Context, d *core.PayoutDestination) error {
+	d.CreatedAt = time.Now()
+	rows, err := r.db.NamedQueryContext(ctx, `
+		INSERT INTO payout_destinations (entity_type, entity_id, kind, name, masked, payout_token, account_number,
+			bic, corr_account, holder, verified_at, created_by, created_at)
+		VALUES (:entity_type, :entity_id, :kind, :name, :masked, :payout_token, :account_number,
+			:bic, :corr_account, :holder, :verified_at, :created_by, :created_at)
+		RETURNING id
+	`, d)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return rows.Scan(&d.ID)
+	}
+	return rows.Err()
+}
+
+// GetDestination возвращает реквизиты, в том числе удаленные: по ним могут идти старые выводы
+func (r *Repo) GetDestination(ctx context.Context, id int64) (*core.PayoutDestination, error) {
+	var d core.PayoutDestination
+	err := r.db.GetContext(ctx, &d, "SELECT * FROM payout_destinations WHERE id = $1", id)
+	return &d, err
+}
+
+func (r *Repo) GetDestinations(ctx context.Context, entityType string, entityID int) ([]core.PayoutDestination, error) {
+	destinations := []core.PayoutDestination{}
+	err := r.db.SelectContext(ctx, &destinations, `
+		SELECT * FROM payout_destinations
+		WHERE entity_type = $1 AND entity_id = $2 AND deleted_at IS NULL
+		ORDER BY created_at`,
+		entityType, entityID)
+	return destinations, err
+}
+
+func (r *Repo) RenameDestination(ctx context.Context, id int64, name string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payout_destinations SET name = $1 WHERE id = $2 AND deleted_at IS NULL
+	`, name, id)
+	return err
+}
+
+// VerifyDestination отмечает реквизиты проверенными; verifiedBy = nil — проверка автоматическая
+func (r *Repo) VerifyDestination(ctx context.Context, id int64, verifiedBy *int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payout_destinations SET verified_at = NOW(), verified_by = $1 WHERE id = $2 AND deleted_at IS NULL
+	`, verifiedBy, id)
+	return err
+}
+
+func (r *Repo) DeleteDestination(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payout_destinations SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+	`, id)
+	return err
+}
+
+// SavePaymentMethod запоминает способ оплаты; повторное сохранение того же способа ничего не меняет
+func (r *Repo) SavePaymentMethod(ctx context.Context, m *core.SavedPaymentMethod) error {
+	m.CreatedAt = time.Now()
+	_, err := r.db.NamedExecContext(ctx, `
+		INSERT INTO saved_payment_methods (id, entity_type, entity_id, type, title, created_at)
+		VALUES (:id, :entity_type, :entity_id, :type, :title, :created_at)
+		ON CONFLICT (id) DO NOTHING
+	`, m)
+	return err
+}
+
+func (r *Repo) GetPaymentMethod(ctx context.Context, id string) (*core.SavedPaymentMethod, error) {
+	var m core.SavedPaymentMethod
+	err := r.db.GetContext(ctx, &m, "SELECT * FROM saved_payment_methods WHERE id = $1", id)
+	return &m, err
+}
+
+func (r *Repo) GetPaymentMethods(ctx context.Context, entityType string, entityID int) ([]core.SavedPaymentMethod, error) {
+	methods := []core.SavedPaymentMethod{}
+	err := r.db.SelectContext(ctx, &methods, `
+		SELECT * FROM saved_payment_methods
+		WHERE entity_type = $1 AND entity_id = $2 AND deleted_at IS NULL
+		ORDER BY created_at`,
+		entityType, entityID)
+	return methods, err
+}
+
+func (r *Repo) DeletePaymentMethod(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE saved_payment_methods SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL
+	`, id)
+	return err
+}
@@ -0,0 +1,214 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/lib/pq"
+)
+
+func (r *Repo) CreateRecurring(ctx context.Context, p *core.RecurringPledge) error {
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	rows, err := r.db.NamedQueryContext(ctx, `
+		INSERT INTO recurring_pledges (user_id, target_type, target_id, amount, currency, source, payment_method_id,
+			status, next_charge_at, created_at, updated_at)
+		VALUES (:user_id, :target_type, :target_id, :amount, :currency, :source, :payment_method_id,
+			:status, :next_charge_at, :created_at, :updated_at)
+		RETURNING id
+	`, p)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return rows.Scan(&p.ID)
+	}
+	return rows.Err()
+}
+
+func (r *Repo) GetRecurring(ctx context.Context, id int64) (*core.RecurringPledge, error) {
+	var p core.RecurringPledge
+	err := r.db.GetContext(ctx, &p, "SELECT * FROM recurring_pledges WHERE id = $1", id)
+	return &p, err
+}
+
+func (r *Repo) GetUserRecurring(ctx context.Context, userID int) ([]core.RecurringPledge, error) {
+	pledges := []core.RecurringPledge{}
+	err := r.db.SelectContext(ctx, &pledges,
+		"SELECT * FROM recurring_pledges WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	return pledges, err
+}
+
+// SetRecurringStatus меняет статус, если текущий входит в from. Отмена и остановка
+// закрывают незавершенные списания; деньги, уже пришедшие с карты, остаются на кошельке.
+func (r *Repo) SetRecurringStatus(ctx context.Context, id int64, status core.RecurringStatus, reason string, nextChargeAt *time.Time, from ...core.RecurringStatus) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	allowed := make([]string, len(from))
+	for i, s := range from {
+		allowed[i] = string(s)
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE recurring_pledges
+		SET status = $1, stop_reason = $2, next_charge_at = COALESCE($3, next_charge_at), updated_at = NOW()
+		WHERE id = $4 AND status = ANY($5)
+	`, status, reason, nextChargeAt, id, pq.Array(allowed))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	if status == core.RecurringCanceled || status == core.RecurringStopped {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE recurring_charges SET status = $1, last_error = $2, updated_at = NOW()
+			WHERE pledge_id = $3 AND status IN ($4, $5, $6)
+		`, core.ChargeSkipped, reason, id, core.ChargePending, core.ChargePaying, core.ChargePaid)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// ScheduleDueCharges создает списания за текущий период по всем активным пожертвованиям,
+// у которых подошла дата, и переносит дату на следующий месяц. Повторный вызов не создаст
+// второе списание за тот же месяц.
+func (r *Repo) ScheduleDueCharges(ctx context.Context, now time.Time, limit int) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var due []core.RecurringPledge
+	err = tx.SelectContext(ctx, &due, `
+		SELECT * FROM recurring_pledges
+		WHERE status = $1 AND next_charge_at <= $2
+		ORDER BY next_charge_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	`, core.RecurringActive, now, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range due {
+		period := chargePeriod(p.NextChargeAt)
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO recurring_charges (pledge_id, period, status, next_attempt_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (pledge_id, period) DO NOTHING
+		`, p.ID, period, core.ChargePending, now)
+		if err != nil {
+			return 0, err
+		}
+		next := nextChargeAt(p.NextChargeAt, now)
+		_, err = tx.ExecContext(ctx, `
+			UPDATE recurring_pledges SET next_charge_at = $1, updated_at = NOW() WHERE id = $2
+		`, next, p.ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(due), tx.Commit()
+}
+
+// chargePeriod — месяц, за который делается списание с датой at
+func chargePeriod(at time.Time) time.Time {
+	return time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextChargeAt — дата следующего списания после prev, не раньше now. Пропущенные месяцы
+// (сервис не работал, пожертвование было на паузе) не доначисляются.
+func nextChargeAt(prev, now time.Time) time.Time {
+	next := prev.AddDate(0, 1, 0)
+	for !next.After(now) {
+		next = next.AddDate(0, 1, 0)
+	}
+	return next
+}
+
+// OpenCharge — незавершенное списание вместе с пожертвованием
+type OpenCharge struct {
+	core.RecurringCharge
+	Pledge core.RecurringPledge `db:"pledge"`
+}
+
+// GetOpenCharges — списания активных пожертвований, по которым подошло время следующей попытки
+func (r *Repo) GetOpenCharges(ctx context.Context, now time.Time, limit int) ([]OpenCharge, error) {
+	var charges []OpenCharge
+	err := r.db.SelectContext(ctx, &charges, `
+		SELECT c.*,
+		       p.id AS "pledge.id", p.user_id AS "pledge.user_id", p.target_type AS "pledge.target_type",
+		       p.target_id AS "pledge.target_id", p.amount AS "pledge.amount", p.currency AS "pledge.currency",
+		       p.source AS "pledge.source", p.payment_method_id AS "pledge.payment_method_id",
+		       p.status AS "pledge.status", p.stop_reason AS "pledge.stop_reason",
+		       p.next_charge_at AS "pledge.next_charge_at", p.created_at AS "pledge.created_at",
+		       p.updated_at AS "pledge.updated_at"
+		FROM recurring_charges c
+		JOIN recurring_pledges p ON p.id = c.pledge_id
+		WHERE c.status IN ($1, $2, $3) AND c.next_attempt_at <= $4 AND p.status = $5
+		ORDER BY c.next_attempt_at
+		LIMIT $6
+	`, core.ChargePending, core.ChargePaying, core.ChargePaid, now, core.RecurringActive, limit)
+	return charges, err
+}
+
+// UpdateCharge переводит списание из статуса from; false — статус уже сменил параллельный запуск
+func (r *Repo) UpdateCharge(ctx context.Context, c *core.RecurringCharge, from core.RecurringChargeStatus) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE recurring_charges
+		SET status = $1, payment_id = $2, attempts = $3, next_attempt_at = $4, last_error = $5, updated_at = NOW()
+		WHERE id = $6 AND status = $7
+	`, c.Status, c.PaymentID, c.Attempts, c.NextAttemptAt, c.LastError, c.ID, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *Repo) GetRecurringCharges(ctx context.Context, pledgeID int64) ([]core.RecurringCharge, error) {
+	charges := []core.RecurringCharge{}
+	err := r.db.SelectContext(ctx, &charges,
+		"SELECT * FROM recurring_charges WHERE pledge_id = $1 ORDER BY period DESC", pledgeID)
+	return charges, err
+}
+
+// RecurringTargetOpen — можно ли еще переводить деньги получателю: у проекта идет сбор
+// и он не заблокирован, организация (или владелец проекта) не заблокирована
+func (r *Repo) RecurringTargetOpen(ctx context.Context, targetType string, targetID int) (bool, error) {
+	var open bool
+	var err error
+	switch targetType {
+	case "project":
+		err = r.db.GetContext(ctx, &open, `
+			SELECT p.status = 'active' AND NOT COALESCE(p.is_banned, false) AND NOT COALESCE(o.is_banned, false)
+			FROM projects p JOIN organizations o ON o.id = p.creator_id
+			WHERE p.id = $1`, targetID)
+	case "org":
+		err = r.db.GetContext(ctx, &open,
+			"SELECT NOT COALESCE(is_banned, false) FROM organizations WHERE id = $1", targetID)
+	default:
+		return false, nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return open, err
+}
@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChargePeriod(t *testing.T) {
+	at := time.Date(2026, 5, 17, 9, 30, 0, 0, time.UTC)
+	if got, want := chargePeriod(at), time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("chargePeriod() = %v, want %v", got, want)
+	}
+}
+
+func TestNextChargeAt(t *testing.T) {
+	prev := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"on time", time.Date(2026, 1, 10, 0, 5, 0, 0, time.UTC), time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC)},
+		{"missed months are skipped", time.Date(2026, 4, 20, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC)},
+		{"exactly on the next date", time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextChargeAt(prev, tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextChargeAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
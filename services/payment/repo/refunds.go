@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+)
+
+// RefundedAmount — сумма возвратов по платежу, кроме неудавшихся
+func (r *Repo) RefundedAmount(ctx context.Context, paymentID string) (money.Amount, error) {
+	var refunded money.Amount
+	err := r.db.GetContext(ctx, &refunded, `
+		SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE payment_id = $1 AND status <> $2`,
+		paymentID, core.RefundFailed)
+	return refunded, err
+}
+
+// CreateRefund сохраняет возврат, если платеж оплачен и вместе с прошлыми возвратами
+// сумма не превышает платеж. Строка платежа блокируется, чтобы параллельные возвраты
+// не превысили его вдвоем. Повторное создание того же возврата ничего не делает.
+func (r *Repo) CreateRefund(ctx context.Context, rf *core.Refund) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM refunds WHERE id = $1)`, rf.ID); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	var p core.Payment
+	err = tx.GetContext(ctx, &p, `SELECT * FROM payments WHERE id = $1 FOR UPDATE`, rf.PaymentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return core.ErrPaymentNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if p.Status != core.StatusSucceeded {
+		return core.ErrPaymentNotRefundable
+	}
+
+	var refunded money.Amount
+	err = tx.GetContext(ctx, &refunded, `
+		SELECT COALESCE(SUM(amount), 0) FROM refunds WHERE payment_id = $1 AND status <> $2`,
+		rf.PaymentID, core.RefundFailed)
+	if err != nil {
+		return err
+	}
+	if refunded+rf.Amount > p.Amount {
+		return core.ErrRefundTooLarge
+	}
+
+	rf.CreatedAt = time.Now()
+	rf.UpdatedAt = time.Now()
+	_, err = tx.NamedExecContext(ctx, `
+		INSERT INTO refunds (id, payment_id, external_id, entity_id, entity_type, amount, currency, status, reason, created_at, updated_at)
+		VALUES (:id, :payment_id, :external_id, :entity_id, :entity_type, :amount, :currency, :status, :reason, :created_at, :updated_at)
+	`, rf)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repo) GetRefund(ctx context.Context, id string) (*core.Refund, error) {
+	var rf core.Refund
+	err := r.db.GetContext(ctx, &rf, "SELECT * FROM refunds WHERE id = $1", id)
+	return &rf, err
+}
+
+func (r *Repo) GetRefundByExternalID(ctx context.Context, externalID string) (*core.Refund, error) {
+	var rf core.Refund
+	err := r.db.GetContext(ctx, &rf, "SELECT * FROM refunds WHERE external_id = $1", externalID)
+	return &rf, err
+}
+
+func (r *Repo) GetPendingRefunds(ctx context.Context) ([]core.Refund, error) {
+	var refunds []core.Refund
+	err := r.db.SelectContext(ctx, &refunds, "SELECT * FROM refunds WHERE status = $1 AND external_id <> ''", core.RefundPending)
+	return refunds, err
+}
+
+func (r *Repo) SetRefundHoldID(ctx context.Context, id string, holdID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refunds SET hold_id = $1, updated_at = NOW() WHERE id = $2
+	`, holdID, id)
+	return err
+}
+
+func (r *Repo) SetRefundExternalID(ctx context.Context, id string, externalID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refunds SET external_id = $1, updated_at = NOW() WHERE id = $2
+	`, externalID, id)
+	return err
+}
+
+func (r *Repo) UpdateRefundStatus(ctx context.Context, id string, status core.RefundStatus) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE refunds SET status = $1, updated_at = NOW() WHERE id = $2
+	`, status, id)
+	return err
+}
@@ -2,12 +2,28 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+	"github.com/Starostina-elena/investment_platform/services/payment/outbox"
 	"github.com/jmoiron/sqlx"
 )
 
+const outboxService = "payment"
+
+type PaymentSucceededEvent struct {
+	PaymentID  string         `json:"payment_id"`
+	ExternalID string         `json:"external_id"`
+	EntityType string         `json:"entity_type"`
+	EntityID   int            `json:"entity_id"`
+	Amount     money.Amount   `json:"amount"`
+	Currency   money.Currency `json:"currency"`
+}
+
 type Repo struct {
 	db *sqlx.DB
 }
@@ -20,8 +36,8 @@ func (r *Repo) Create(ctx context.Context, p *core.Payment) error {
 	p.CreatedAt = time.Now()
 	p.UpdatedAt = time.Now()
 	_, err := r.db.NamedExecContext(ctx, `
-		INSERT INTO payments (id, external_id, amount, entity_id, entity_type, status, created_at, updated_at)
-		VALUES (:id, :external_id, :amount, :entity_id, :entity_type, :status, :created_at, :updated_at)
+		INSERT INTO payments (id, external_id, amount, currency, entity_id, entity_type, status, capture, expires_at, created_at, updated_at)
+		VALUES (:id, :external_id, :amount, :currency, :entity_id, :entity_type, :status, :capture, :expires_at, :created_at, :updated_at)
 	`, p)
 	return err
 }
@@ -33,6 +49,43 @@ func (r *Repo) UpdateStatus(ctx context.Context, externalID string, status core.
 	return err
 }
 
+// MarkSucceeded переводит платеж в succeeded и в той же транзакции пишет
+// событие payment.succeeded. Повторный вызов для уже успешного платежа ничего не делает.
+func (r *Repo) MarkSucceeded(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var p core.Payment
+	err = tx.GetContext(ctx, &p, `
+		UPDATE payments SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status <> $1
+		RETURNING *`, core.StatusSucceeded, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = outbox.Add(ctx, tx, outboxService, outbox.TypePaymentSucceeded, PaymentSucceededEvent{
+		PaymentID:  p.ID,
+		ExternalID: p.ExternalID,
+		EntityType: p.EntityType,
+		EntityID:   p.EntityID,
+		Amount:     p.Amount,
+		Currency:   p.Currency,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (r *Repo) GetByExternalID(ctx context.Context, externalID string) (*core.Payment, error) {
 	var p core.Payment
 	err := r.db.GetContext(ctx, &p, "SELECT * FROM payments WHERE external_id = $1", externalID)
@@ -45,20 +98,134 @@ func (r *Repo) GetByID(ctx context.Context, id string) (*core.Payment, error) {
 	return &p, err
 }
 
-func (r *Repo) GetPendingPayments(ctx context.Context) ([]core.Payment, error) {
+// GetPendingPayments — незавершенные платежи, срок которых еще не вышел, а время
+// следующей проверки подошло. Платежи, исчерпавшие maxAttempts, не возвращаются.
+func (r *Repo) GetPendingPayments(ctx context.Context, maxAttempts, limit int) ([]core.Payment, error) {
+	var payments []core.Payment
+	err := r.db.SelectContext(ctx, &payments, `
+		SELECT * FROM payments
+		WHERE status IN ($1, $2) AND (expires_at IS NULL OR expires_at > NOW())
+		  AND next_poll_at <= NOW() AND poll_attempts < $3
+		ORDER BY next_poll_at
+		LIMIT $4`,
+		core.StatusPending, core.StatusWaitingForCapture, maxAttempts, limit)
+	return payments, err
+}
+
+// RecordPaymentPoll сохраняет результат проверки платежа и время следующей
+func (r *Repo) RecordPaymentPoll(ctx context.Context, id string, attempts int, next time.Time, pollErr *string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payments SET poll_attempts = $1, next_poll_at = $2, last_poll_error = $3 WHERE id = $4
+	`, attempts, next, pollErr, id)
+	return err
+}
+
+// GetStalePayments — незавершенные платежи с истекшим сроком, которым подошло время
+// проверки: после ошибки провайдера платеж откладывается так же, как при опросе
+func (r *Repo) GetStalePayments(ctx context.Context, limit int) ([]core.Payment, error) {
 	var payments []core.Payment
-	err := r.db.SelectContext(ctx, &payments, "SELECT * FROM payments WHERE status = $1", core.StatusPending)
+	err := r.db.SelectContext(ctx, &payments, `
+		SELECT * FROM payments
+		WHERE status IN ($1, $2) AND expires_at <= NOW() AND next_poll_at <= NOW()
+		ORDER BY expires_at
+		LIMIT $3`,
+		core.StatusPending, core.StatusWaitingForCapture, limit)
 	return payments, err
 }
 
-func (r *Repo) CreateWithdrawal(ctx context.Context, w *core.Withdrawal) error {
+// MarkWaitingForCapture переводит оплаченный двухстадийный платеж в ожидание
+// подтверждения до expiresAt
+func (r *Repo) MarkWaitingForCapture(ctx context.Context, id string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payments SET status = $1, expires_at = $2, updated_at = NOW()
+		WHERE id = $3 AND status = $4
+	`, core.StatusWaitingForCapture, expiresAt, id, core.StatusPending)
+	return err
+}
+
+// SetPaymentCreated сохраняет ответ провайдера на платеж, записанный до обращения к нему,
+// и переводит платеж в pending до expiresAt
+func (r *Repo) SetPaymentCreated(ctx context.Context, id, externalID string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payments SET external_id = $1, status = $2, expires_at = $3, updated_at = NOW()
+		WHERE id = $4 AND status = $5
+	`, externalID, core.StatusPending, expiresAt, id, core.StatusCreating)
+	return err
+}
+
+// ClosePayment завершает незавершенный платеж без зачисления (canceled или expired).
+// Успешный платеж не трогает.
+func (r *Repo) ClosePayment(ctx context.Context, id string, status core.PaymentStatus, reason string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payments SET status = $1, cancellation_reason = $2, updated_at = NOW()
+		WHERE id = $3 AND status IN ($4, $5, $6)
+	`, status, reason, id, core.StatusCreating, core.StatusPending, core.StatusWaitingForCapture)
+	return err
+}
+
+// SetCaptureAmount сохраняет сумму подтверждения до обращения к провайдеру. Вернет false,
+// если платеж уже не ждет подтверждения или подтверждается на другую сумму.
+func (r *Repo) SetCaptureAmount(ctx context.Context, id string, amount money.Amount) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE payments SET capture_amount = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3 AND (capture_amount IS NULL OR capture_amount = $1)
+	`, amount, id, core.StatusWaitingForCapture)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ApplyCaptureAmount делает сумму подтверждения суммой платежа перед зачислением
+func (r *Repo) ApplyCaptureAmount(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE payments SET amount = capture_amount, updated_at = NOW()
+		WHERE id = $1 AND status = $2 AND capture_amount IS NOT NULL
+	`, id, core.StatusWaitingForCapture)
+	return err
+}
+
+// CreateWithdrawal сохраняет вывод, если его пропускает check. Выводы одного кошелька
+// сериализуются блокировкой, поэтому два одновременных вывода не пройдут лимит вместе.
+// Повтор с тем же id ничего не делает и лимиты заново не проверяет.
+func (r *Repo) CreateWithdrawal(ctx context.Context, w *core.Withdrawal, check WithdrawalCheck) error {
 	w.CreatedAt = time.Now()
 	w.UpdatedAt = time.Now()
-	_, err := r.db.NamedExecContext(ctx, `
-		INSERT INTO withdrawals (id, external_id, entity_id, entity_type, amount, status, created_at, updated_at)
-		VALUES (:id, :external_id, :entity_id, :entity_type, :amount, :status, :created_at, :updated_at)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	lockKey := fmt.Sprintf("withdrawal:%s:%d", w.EntityType, w.EntityID)
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, lockKey); err != nil {
+		return err
+	}
+
+	res, err := tx.NamedExecContext(ctx, `
+		INSERT INTO withdrawals (id, external_id, entity_id, entity_type, amount, currency, status, destination,
+			destination_id, requested_by, review_reason, required_approvals, created_at, updated_at)
+		VALUES (:id, :external_id, :entity_id, :entity_type, :amount, :currency, :status, :destination,
+			:destination_id, :requested_by, :review_reason, :required_approvals, :created_at, :updated_at)
+		ON CONFLICT (id) DO NOTHING
 	`, w)
-	return err
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 1 && check != nil {
+		totals, err := withdrawalTotals(ctx, tx, w, w.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if err := check(totals); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *Repo) UpdateWithdrawalStatus(ctx context.Context, externalID string, status core.WithdrawalStatus) error {
@@ -68,6 +235,27 @@ func (r *Repo) UpdateWithdrawalStatus(ctx context.Context, externalID string, st
 	return err
 }
 
+func (r *Repo) UpdateWithdrawalStatusByID(ctx context.Context, id string, status core.WithdrawalStatus) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE withdrawals SET status = $1, updated_at = NOW() WHERE id = $2
+	`, status, id)
+	return err
+}
+
+func (r *Repo) SetWithdrawalExternalID(ctx context.Context, id string, externalID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE withdrawals SET external_id = $1, updated_at = NOW() WHERE id = $2
+	`, externalID, id)
+	return err
+}
+
+func (r *Repo) SetWithdrawalHoldID(ctx context.Context, id string, holdID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE withdrawals SET hold_id = $1, updated_at = NOW() WHERE id = $2
+	`, holdID, id)
+	return err
+}
+
 func (r *Repo) GetWithdrawalByExternalID(ctx context.Context, externalID string) (*core.Withdrawal, error) {
 	var w core.Withdrawal
 	err := r.db.GetContext(ctx, &w, "SELECT * FROM withdrawals WHERE external_id = $1", externalID)
@@ -80,12 +268,26 @@ func (r *Repo) GetWithdrawalByID(ctx context.Context, id string) (*core.Withdraw
 	return &w, err
 }
 
-func (r *Repo) GetPendingWithdrawals(ctx context.Context) ([]core.Withdrawal, error) {
+// GetPendingWithdrawals — выводы с созданной выплатой, которые пора проверить у провайдера
+func (r *Repo) GetPendingWithdrawals(ctx context.Context, maxAttempts, limit int) ([]core.Withdrawal, error) {
 	var withdrawals []core.Withdrawal
-	err := r.db.SelectContext(ctx, &withdrawals, "SELECT * FROM withdrawals WHERE status = $1", core.WithdrawalPending)
+	err := r.db.SelectContext(ctx, &withdrawals, `
+		SELECT * FROM withdrawals
+		WHERE status = $1 AND external_id <> '' AND next_poll_at <= NOW() AND poll_attempts < $2
+		ORDER BY next_poll_at
+		LIMIT $3`,
+		core.WithdrawalPending, maxAttempts, limit)
 	return withdrawals, err
 }
 
+// RecordWithdrawalPoll сохраняет результат проверки вывода и время следующей
+func (r *Repo) RecordWithdrawalPoll(ctx context.Context, id string, attempts int, next time.Time, pollErr *string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE withdrawals SET poll_attempts = $1, next_poll_at = $2, last_poll_error = $3 WHERE id = $4
+	`, attempts, next, pollErr, id)
+	return err
+}
+
 func (r *Repo) GetWithdrawalsByEntity(ctx context.Context, entityType string, entityID int) ([]core.Withdrawal, error) {
 	var withdrawals []core.Withdrawal
 	err := r.db.SelectContext(ctx, &withdrawals,
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+)
+
+func (r *Repo) SaveWebhook(ctx context.Context, e *core.WebhookEvent) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO webhook_inbox (event, object_id, source_ip, body, status, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, received_at`,
+		e.Event, e.ObjectID, e.SourceIP, e.Body, e.Status, e.Error,
+	).Scan(&e.ID, &e.ReceivedAt)
+}
+
+// FinishWebhook сохраняет результат очередной попытки обработки
+func (r *Repo) FinishWebhook(ctx context.Context, id int64, status core.WebhookStatus, errText *string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_inbox
+		SET status = $1, error = $2, attempts = attempts + 1, processed_at = NOW()
+		WHERE id = $3`, status, errText, id)
+	return err
+}
+
+func (r *Repo) GetWebhook(ctx context.Context, id int64) (*core.WebhookEvent, error) {
+	var e core.WebhookEvent
+	err := r.db.GetContext(ctx, &e, "SELECT * FROM webhook_inbox WHERE id = $1", id)
+	return &e, err
+}
+
+// GetFailedWebhooks возвращает вебхуки, обработка которых упала меньше maxAttempts раз
+func (r *Repo) GetFailedWebhooks(ctx context.Context, maxAttempts, limit int) ([]core.WebhookEvent, error) {
+	var events []core.WebhookEvent
+	err := r.db.SelectContext(ctx, &events, `
+		SELECT * FROM webhook_inbox
+		WHERE status = $1 AND attempts < $2
+		ORDER BY received_at
+		LIMIT $3`, core.WebhookFailed, maxAttempts, limit)
+	return events, err
+}
@@ -0,0 +1,225 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxRateAge — как fx.MaxRateAge сервиса транзакций: по более старому курсу лимиты не считаются
+const maxRateAge = 72 * time.Hour
+
+// WithdrawalTotals — суммы для проверки лимитов в базовой валюте: новый вывод и уже
+// выведенное кошельком с начала дня и месяца, кроме неудавшихся и отклоненных выводов
+type WithdrawalTotals struct {
+	Amount  money.Amount
+	Daily   money.Amount
+	Monthly money.Amount
+}
+
+// WithdrawalCheck решает, можно ли создать вывод при таких суммах
+type WithdrawalCheck func(t WithdrawalTotals) error
+
+// WithdrawalTotals считает суммы для вывода w на момент now; сам w в них не входит
+func (r *Repo) WithdrawalTotals(ctx context.Context, w *core.Withdrawal, now time.Time) (WithdrawalTotals, error) {
+	return withdrawalTotals(ctx, r.db, w, now)
+}
+
+// withdrawalTotals переводит выводы во всех валютах в базовую по последнему курсу из fx_rates:
+// лимиты политики заданы в рублях и ограничивают кошелек целиком
+func withdrawalTotals(ctx context.Context, q sqlx.QueryerContext, w *core.Withdrawal, now time.Time) (WithdrawalTotals, error) {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var rows []struct {
+		Currency money.Currency `db:"currency"`
+		Daily    money.Amount   `db:"daily"`
+		Monthly  money.Amount   `db:"monthly"`
+	}
+	err := sqlx.SelectContext(ctx, q, &rows, `
+		SELECT currency,
+			COALESCE(SUM(amount) FILTER (WHERE created_at >= $1), 0) AS daily,
+			COALESCE(SUM(amount), 0) AS monthly
+		FROM withdrawals
+		WHERE entity_type = $2 AND entity_id = $3 AND created_at >= $4 AND status NOT IN ($5, $6) AND id <> $7
+		GROUP BY currency`,
+		dayStart, w.EntityType, w.EntityID, monthStart, core.WithdrawalFailed, core.WithdrawalRejected, w.ID)
+	if err != nil {
+		return WithdrawalTotals{}, err
+	}
+
+	var t WithdrawalTotals
+	if t.Amount, err = toBase(ctx, q, w.Amount, w.Currency); err != nil {
+		return WithdrawalTotals{}, err
+	}
+	for _, row := range rows {
+		daily, err := toBase(ctx, q, row.Daily, row.Currency)
+		if err != nil {
+			return WithdrawalTotals{}, err
+		}
+		monthly, err := toBase(ctx, q, row.Monthly, row.Currency)
+		if err != nil {
+			return WithdrawalTotals{}, err
+		}
+		t.Daily += daily
+		t.Monthly += monthly
+	}
+	return t, nil
+}
+
+// toBase пересчитывает сумму в базовую валюту. Доли копейки округляются вверх,
+// чтобы пересчет не помогал обойти лимит.
+func toBase(ctx context.Context, q sqlx.QueryerContext, amount money.Amount, currency money.Currency) (money.Amount, error) {
+	if currency == money.BaseCurrency || amount.IsZero() {
+		return amount, nil
+	}
+	var rate struct {
+		Rate      string    `db:"rate"`
+		FetchedAt time.Time `db:"fetched_at"`
+	}
+	err := sqlx.GetContext(ctx, q, &rate, `
+		SELECT rate, fetched_at FROM fx_rates WHERE currency = $1 ORDER BY fetched_at DESC, id DESC LIMIT 1`, currency)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && time.Since(rate.FetchedAt) > maxRateAge) {
+		return 0, fmt.Errorf("%w: %s", core.ErrRateUnavailable, currency)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return convert(amount, rate.Rate)
+}
+
+// convert умножает сумму на курс, записанный десятичной строкой
+func convert(amount money.Amount, rate string) (money.Amount, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid fx rate %q", rate)
+	}
+	return amount.MulRat(r, money.RoundUp)
+}
+
+// HasSucceededWithdrawalTo — был ли у кошелька успешный вывод на эти реквизиты:
+// сохраненные (destinationID) или переданные строкой
+func (r *Repo) HasSucceededWithdrawalTo(ctx context.Context, entityType string, entityID int, destinationID *int64, destination string) (bool, error) {
+	column, value := "destination", interface{}(destination)
+	if destinationID != nil {
+		column, value = "destination_id", *destinationID
+	}
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `
+		SELECT EXISTS (
+			SELECT 1 FROM withdrawals
+			WHERE entity_type = $1 AND entity_id = $2 AND `+column+` = $3 AND status = $4
+		)`,
+		entityType, entityID, value, core.WithdrawalSucceeded)
+	return exists, err
+}
+
+// GetWithdrawalsInReview — очередь ручной проверки, старые выводы первыми
+func (r *Repo) GetWithdrawalsInReview(ctx context.Context) ([]core.Withdrawal, error) {
+	var withdrawals []core.Withdrawal
+	err := r.db.SelectContext(ctx, &withdrawals, `
+		SELECT w.*, (
+			SELECT COUNT(*) FROM withdrawal_decisions d WHERE d.withdrawal_id = w.id AND d.decision = $1
+		) AS approvals
+		FROM withdrawals w
+		WHERE w.status = $2
+		ORDER BY w.created_at`,
+		core.DecisionApprove, core.WithdrawalReview)
+	return withdrawals, err
+}
+
+// ReturnWithdrawalToReview возвращает на ручную проверку вывод, еще не переданный провайдеру.
+// Прежние одобрения остаются в истории, поэтому выплату разрешит только еще одно одобрение
+// администратора, который по этому выводу еще не решал.
+func (r *Repo) ReturnWithdrawalToReview(ctx context.Context, id, reason string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE withdrawals SET status = $1, review_reason = $2,
+			required_approvals = (
+				SELECT COUNT(*) + 1 FROM withdrawal_decisions d WHERE d.withdrawal_id = withdrawals.id AND d.decision = $3
+			),
+			updated_at = NOW()
+		WHERE id = $4 AND status IN ($1, $5) AND external_id = ''`,
+		core.WithdrawalReview, reason, core.DecisionApprove, id, core.WithdrawalPending)
+	return err
+}
+
+func (r *Repo) GetWithdrawalDecisions(ctx context.Context, withdrawalID string) ([]core.WithdrawalDecision, error) {
+	var decisions []core.WithdrawalDecision
+	err := r.db.SelectContext(ctx, &decisions,
+		"SELECT * FROM withdrawal_decisions WHERE withdrawal_id = $1 ORDER BY created_at", withdrawalID)
+	return decisions, err
+}
+
+// DecideWithdrawal сохраняет решение администратора и переводит вывод дальше:
+// отказ сразу делает его rejected, одобрение — pending, когда набралось
+// required_approvals одобрений разных администраторов. Строка вывода блокируется,
+// чтобы два одновременных решения не разошлись в подсчете.
+func (r *Repo) DecideWithdrawal(ctx context.Context, d *core.WithdrawalDecision) (*core.Withdrawal, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var w core.Withdrawal
+	err = tx.GetContext(ctx, &w, `SELECT * FROM withdrawals WHERE id = $1 FOR UPDATE`, d.WithdrawalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrWithdrawalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if w.Status != core.WithdrawalReview {
+		return nil, core.ErrWithdrawalNotInReview
+	}
+	if d.Decision == core.DecisionApprove && w.RequestedBy == d.AdminID {
+		return nil, core.ErrSelfApproval
+	}
+
+	d.CreatedAt = time.Now()
+	res, err := tx.NamedExecContext(ctx, `
+		INSERT INTO withdrawal_decisions (withdrawal_id, admin_id, decision, comment, created_at)
+		VALUES (:withdrawal_id, :admin_id, :decision, :comment, :created_at)
+		ON CONFLICT (withdrawal_id, admin_id) DO NOTHING
+	`, d)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, core.ErrAlreadyDecided
+	}
+
+	if err := tx.GetContext(ctx, &w.Approvals, `
+		SELECT COUNT(*) FROM withdrawal_decisions WHERE withdrawal_id = $1 AND decision = $2`,
+		w.ID, core.DecisionApprove); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case d.Decision == core.DecisionReject:
+		w.Status = core.WithdrawalRejected
+	case w.Approvals >= w.RequiredApprovals:
+		w.Status = core.WithdrawalPending
+	}
+	if w.Status != core.WithdrawalReview {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE withdrawals SET status = $1, updated_at = NOW() WHERE id = $2
+		`, w.Status, w.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount  money.Amount
+		rate    string
+		want    money.Amount
+		wantErr bool
+	}{
+		{money.FromRubles(100), "92.5", money.FromRubles(9250), false},
+		{money.MustParse("0.01"), "0.333", money.MustParse("0.01"), false},
+		{money.MustParse("10.00"), "0.0101", money.MustParse("0.11"), false},
+		{money.FromRubles(1), "0", 0, true},
+		{money.FromRubles(1), "-1", 0, true},
+		{money.FromRubles(1), "abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := convert(tt.amount, tt.rate)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("convert(%s, %q) error = %v, wantErr %v", tt.amount, tt.rate, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("convert(%s, %q) = %s, want %s", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
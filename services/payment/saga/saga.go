@@ -0,0 +1,116 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	StatusRunning      = "running"
+	StatusCompensating = "compensating"
+	StatusCompleted    = "completed"
+	StatusFailed       = "failed"
+)
+
+const (
+	stepDone        = "done"
+	stepFailed      = "failed"
+	stepCompensated = "compensated"
+)
+
+var (
+	ErrAlreadyRunning = errors.New("saga with this ref is already running")
+	ErrUnknownKind    = errors.New("unknown saga kind")
+)
+
+// Saga — экземпляр многошаговой операции. Payload хранит входные данные и
+// промежуточные результаты шагов, CurrentStep — число успешно выполненных шагов.
+type Saga struct {
+	ID          string     `db:"id"`
+	Service     string     `db:"service"`
+	Kind        string     `db:"kind"`
+	Ref         string     `db:"ref"`
+	Payload     []byte     `db:"payload"`
+	Status      string     `db:"status"`
+	CurrentStep int        `db:"current_step"`
+	Attempts    int        `db:"attempts"`
+	LastError   *string    `db:"last_error"`
+	NextRunAt   time.Time  `db:"next_run_at"`
+	LockedUntil *time.Time `db:"locked_until"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at"`
+
+	store *store
+}
+
+// Decode разбирает payload саги в v
+func (s *Saga) Decode(v interface{}) error {
+	return json.Unmarshal(s.Payload, v)
+}
+
+// Save сразу сохраняет v как новый payload. Шаги, которые делают несколько
+// внешних вызовов, отмечают так свой прогресс, чтобы после сбоя не повторять сделанное.
+func (s *Saga) Save(ctx context.Context, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := s.store.savePayload(ctx, s.ID, payload); err != nil {
+		return err
+	}
+	s.Payload = payload
+	return nil
+}
+
+// Step — шаг саги. Action должен быть идемпотентным: после сбоя шаг,
+// не отмеченный выполненным, запускается заново. Compensate откатывает
+// результат шага, nil — если откатывать нечего.
+type Step struct {
+	Name       string
+	Action     func(ctx context.Context, s *Saga) error
+	Compensate func(ctx context.Context, s *Saga) error
+}
+
+type Definition struct {
+	Kind  string
+	Steps []Step
+	// MaxAttempts — сколько раз подряд шаг может упасть до запуска компенсаций
+	MaxAttempts int
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent помечает ошибку шага как неустранимую: повторов не будет,
+// сага сразу переходит к компенсациям
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// backoff — пауза перед следующей попыткой: 5s, 10s, 20s ... но не больше 10 минут
+func backoff(attempts int) time.Duration {
+	d := 5 * time.Second
+	for i := 1; i < attempts && d < 10*time.Minute; i++ {
+		d *= 2
+	}
+	return min(d, 10*time.Minute)
+}
@@ -0,0 +1,110 @@
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const sagaColumns = `id, service, kind, ref, payload, status, current_step, attempts,
+	last_error, next_run_at, locked_until, created_at, updated_at`
+
+type store struct {
+	db *sqlx.DB
+}
+
+func (st *store) create(ctx context.Context, s *Saga) error {
+	_, err := st.db.ExecContext(ctx,
+		`INSERT INTO sagas (id, service, kind, ref, payload, status, next_run_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		s.ID, s.Service, s.Kind, s.Ref, s.Payload, s.Status, s.NextRunAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrAlreadyRunning
+	}
+	return err
+}
+
+func (st *store) get(ctx context.Context, id string) (*Saga, error) {
+	var s Saga
+	if err := st.db.GetContext(ctx, &s, `SELECT `+sagaColumns+` FROM sagas WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+	s.store = st
+	return &s, nil
+}
+
+// claim берет сагу в работу на время lease. Если ее уже выполняет другой
+// процесс или она завершена, возвращает nil без ошибки.
+func (st *store) claim(ctx context.Context, id string, lease time.Duration) (*Saga, error) {
+	var s Saga
+	err := st.db.GetContext(ctx, &s, `
+		UPDATE sagas SET locked_until = NOW() + $2 * INTERVAL '1 second'
+		WHERE id = $1 AND status IN ('running', 'compensating')
+		  AND (locked_until IS NULL OR locked_until < NOW())
+		RETURNING `+sagaColumns, id, lease.Seconds())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.store = st
+	return &s, nil
+}
+
+func (st *store) pendingIDs(ctx context.Context, service string, limit int) ([]string, error) {
+	var ids []string
+	err := st.db.SelectContext(ctx, &ids, `
+		SELECT id FROM sagas
+		WHERE service = $1 AND status IN ('running', 'compensating') AND next_run_at <= NOW()
+		  AND (locked_until IS NULL OR locked_until < NOW())
+		ORDER BY next_run_at LIMIT $2`, service, limit)
+	return ids, err
+}
+
+func (st *store) savePayload(ctx context.Context, id string, payload []byte) error {
+	_, err := st.db.ExecContext(ctx,
+		`UPDATE sagas SET payload = $1, updated_at = NOW() WHERE id = $2`, payload, id)
+	return err
+}
+
+// update сохраняет состояние саги и пишет событие шага в журнал одной транзакцией
+func (st *store) update(ctx context.Context, s *Saga, stepIndex int, stepName, stepStatus string, stepErr error) error {
+	tx, err := st.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE sagas SET status = $1, current_step = $2, attempts = $3, last_error = $4,
+		       next_run_at = $5, locked_until = $6, updated_at = NOW()
+		WHERE id = $7`,
+		s.Status, s.CurrentStep, s.Attempts, s.LastError, s.NextRunAt, s.LockedUntil, s.ID)
+	if err != nil {
+		return err
+	}
+
+	if stepName != "" {
+		var errText *string
+		if stepErr != nil {
+			msg := stepErr.Error()
+			errText = &msg
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO saga_steps (saga_id, step_index, name, status, error) VALUES ($1, $2, $3, $4, $5)`,
+			s.ID, stepIndex, stepName, stepStatus, errText)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,149 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"log/slog"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/metrics"
+	"github.com/jmoiron/sqlx"
+)
+
+// Task — периодическая задача планировщика
+type Task struct {
+	Name    string
+	Every   time.Duration
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+
+	lastRun time.Time
+}
+
+// Scheduler запускает задачи только на одной реплике сервиса. Лидер держит
+// advisory lock Postgres на отдельном соединении: если процесс упал или соединение
+// оборвалось, блокировка снимается сама и лидером становится другая реплика.
+type Scheduler struct {
+	db      *sqlx.DB
+	name    string
+	lockKey int64
+	tasks   []*Task
+	metrics *metrics.Registry
+	log     slog.Logger
+
+	conn *sql.Conn
+}
+
+func New(db *sqlx.DB, name string, m *metrics.Registry, log slog.Logger) *Scheduler {
+	h := fnv.New64a()
+	h.Write([]byte("scheduler:" + name))
+	return &Scheduler{db: db, name: name, lockKey: int64(h.Sum64()), metrics: m, log: log}
+}
+
+func (s *Scheduler) Add(t Task) {
+	if t.Timeout == 0 {
+		t.Timeout = t.Every
+	}
+	s.tasks = append(s.tasks, &t)
+}
+
+// Run каждые tick проверяет лидерство и запускает задачи, которым подошел срок
+func (s *Scheduler) Run(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	defer s.resign()
+
+	for {
+		if s.ensureLeader(ctx) {
+			s.runDue(ctx, time.Now())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	for _, t := range s.tasks {
+		if now.Sub(t.lastRun) < t.Every {
+			continue
+		}
+		t.lastRun = now
+
+		taskCtx, cancel := context.WithTimeout(ctx, t.Timeout)
+		start := time.Now()
+		err := t.Run(taskCtx)
+		cancel()
+
+		s.metrics.Add("payment_scheduler_runs_total", 1, "task", t.Name)
+		s.metrics.Set("payment_scheduler_last_duration_seconds", time.Since(start).Seconds(), "task", t.Name)
+		if err != nil {
+			s.metrics.Add("payment_scheduler_errors_total", 1, "task", t.Name)
+			s.log.Error("scheduled task failed", "task", t.Name, "error", err)
+			continue
+		}
+		s.metrics.Set("payment_scheduler_last_success_timestamp_seconds", float64(time.Now().Unix()), "task", t.Name)
+	}
+}
+
+// ensureLeader проверяет, что блокировка еще за нами, или пытается ее взять
+func (s *Scheduler) ensureLeader(ctx context.Context) bool {
+	if s.conn != nil {
+		if err := s.conn.PingContext(ctx); err == nil {
+			return true
+		}
+		s.log.Warn("scheduler lost leadership", "scheduler", s.name)
+		_ = s.conn.Close()
+		s.conn = nil
+		s.metrics.Set("payment_scheduler_leader", 0)
+	}
+
+	conn, err := s.db.DB.Conn(ctx)
+	if err != nil {
+		s.log.Error("scheduler failed to get connection", "error", err)
+		return false
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", s.lockKey).Scan(&locked); err != nil || !locked {
+		if err != nil {
+			s.log.Error("scheduler failed to take lock", "error", err)
+		}
+		_ = conn.Close()
+		return false
+	}
+
+	s.log.Info("scheduler became leader", "scheduler", s.name)
+	s.conn = conn
+	s.metrics.Set("payment_scheduler_leader", 1)
+	return true
+}
+
+func (s *Scheduler) resign() {
+	if s.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _ = s.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", s.lockKey)
+	_ = s.conn.Close()
+	s.conn = nil
+	s.metrics.Set("payment_scheduler_leader", 0)
+}
+
+// Backoff — задержка перед попыткой attempt (с нуля): base, 2*base, 4*base... не больше max
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 0; i < attempt; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+)
+
+// Действия в журнале аудита
+const (
+	auditInitPayment     = "payment.init"
+	auditCheckPayment    = "payment.check"
+	auditCapturePayment  = "payment.capture"
+	auditCancelPayment   = "payment.cancel"
+	auditInitRefund      = "refund.init"
+	auditInitWithdrawal  = "withdrawal.init"
+	auditCheckWithdrawal = "withdrawal.check"
+
+	auditApproveWithdrawal = "withdrawal.approve"
+	auditRejectWithdrawal  = "withdrawal.reject"
+
+	auditSaveDestination   = "destination.create"
+	auditDeleteDestination = "destination.delete"
+	auditVerifyDestination = "destination.verify"
+	auditDeletePayMethod   = "payment_method.delete"
+
+	auditCreateRecurring = "recurring.create"
+	auditPauseRecurring  = "recurring.pause"
+	auditResumeRecurring = "recurring.resume"
+	auditCancelRecurring = "recurring.cancel"
+)
+
+// authorize проверяет доступ к кошельку: пользователь работает только со своим,
+// с кошельком организации — сотрудник с правом money_management. Администратор
+// проходит проверку, если adminAllowed (просмотр, возвраты), но не может пополнять
+// и выводить деньги чужого кошелька. Заблокированным пользователям доступ закрыт.
+func (s *Service) authorize(ctx context.Context, caller core.Caller, entityType string, entityID int, adminAllowed bool) error {
+	if caller.Admin && adminAllowed {
+		return nil
+	}
+	if caller.Banned {
+		return core.ErrForbidden
+	}
+
+	switch entityType {
+	case "user":
+		if caller.UserID != entityID {
+			return core.ErrForbidden
+		}
+		return nil
+	case "org":
+		allowed, err := s.orgClient.CheckUserOrgPermission(ctx, entityID, caller.UserID, "money_management")
+		if err != nil {
+			s.log.Error("failed to check money_management permission", "error", err, "org_id", entityID, "user_id", caller.UserID)
+			return err
+		}
+		if !allowed {
+			return core.ErrForbidden
+		}
+		return nil
+	default:
+		return core.ErrForbidden
+	}
+}
+
+// audit пишет обращение в журнал. Ошибка записи не мешает самой операции.
+func (s *Service) audit(ctx context.Context, caller core.Caller, action, entityType string, entityID int, objectID string, amount *money.Amount, currency *money.Currency, opErr error) {
+	e := &core.AuditEntry{
+		UserID:     caller.UserID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		ObjectID:   objectID,
+		Amount:     amount,
+		Currency:   currency,
+		Allowed:    opErr != core.ErrForbidden,
+		Error:      errText(opErr),
+		IP:         caller.IP,
+	}
+	if err := s.repo.SaveAudit(context.WithoutCancel(ctx), e); err != nil {
+		s.log.Error("failed to save audit entry", "error", err, "action", action, "user_id", caller.UserID)
+	}
+	s.log.Info("payment audit", "action", action, "user_id", caller.UserID, "entity_type", entityType, "entity_id", entityID, "object_id", objectID, "allowed", e.Allowed, "error", opErr)
+}
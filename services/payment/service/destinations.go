@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/clients"
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
+)
+
+// SaveDestination сохраняет реквизиты для вывода. Карта проверена ЮKassa при выдаче
+// токена в виджете выплат и считается проверенной сразу. Расчетный счет берется из
+// регистрационных данных организации и проверен, если регистрация завершена.
+// Кошелек ЮMoney проверяет администратор.
+func (s *Service) SaveDestination(ctx context.Context, caller core.Caller, d *core.PayoutDestination) (_ *core.PayoutDestination, err error) {
+	defer func() {
+		s.audit(ctx, caller, auditSaveDestination, d.EntityType, d.EntityID, strconv.FormatInt(d.ID, 10), nil, nil, err)
+	}()
+	if err = s.authorize(ctx, caller, d.EntityType, d.EntityID, false); err != nil {
+		return nil, err
+	}
+
+	d.CreatedBy = caller.UserID
+	now := time.Now()
+	switch d.Kind {
+	case provider.DestinationBankCard:
+		if d.PayoutToken == "" {
+			return nil, fmt.Errorf("%w: payout_token is required", core.ErrInvalidDestination)
+		}
+		if d.Masked == "" {
+			d.Masked = "Банковская карта"
+		}
+		d.VerifiedAt = &now
+	case provider.DestinationYooMoney:
+		if !isDigits(d.AccountNumber, 11, 20) {
+			return nil, fmt.Errorf("%w: account_number must be 11-20 digits", core.ErrInvalidDestination)
+		}
+		d.Masked = mask(d.AccountNumber, 5, 4)
+	case provider.DestinationBankAccount:
+		if d.EntityType != "org" {
+			return nil, fmt.Errorf("%w: bank accounts are available only for organisations", core.ErrInvalidDestination)
+		}
+		details, err := s.orgBankDetails(ctx, caller, d.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		if !isDigits(details.Account, 20, 20) || !isDigits(details.BIC, 9, 9) {
+			return nil, fmt.Errorf("%w: organisation has no bank details", core.ErrInvalidDestination)
+		}
+		d.AccountNumber, d.BIC, d.CorrAccount, d.Holder = details.Account, details.BIC, details.CorrAccount, details.Holder
+		d.Masked = "р/с " + mask(d.AccountNumber, 0, 4)
+		if details.RegistrationCompleted {
+			d.VerifiedAt = &now
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", core.ErrInvalidDestination, d.Kind)
+	}
+	if d.Name == "" {
+		d.Name = d.Masked
+	}
+
+	if err := s.repo.CreateDestination(ctx, d); err != nil {
+		s.log.Error("failed to save payout destination", "error", err, "entity_type", d.EntityType, "entity_id", d.EntityID)
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *Service) GetDestinations(ctx context.Context, caller core.Caller, entityType string, entityID int) ([]core.PayoutDestination, error) {
+	if err := s.authorize(ctx, caller, entityType, entityID, true); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDestinations(ctx, entityType, entityID)
+}
+
+func (s *Service) RenameDestination(ctx context.Context, caller core.Caller, id int64, name string) (*core.PayoutDestination, error) {
+	d, err := s.destination(ctx, caller, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RenameDestination(ctx, d.ID, name); err != nil {
+		return nil, err
+	}
+	d.Name = name
+	return d, nil
+}
+
+// DeleteDestination скрывает реквизиты; выводы, уже созданные на них, завершатся как обычно
+func (s *Service) DeleteDestination(ctx context.Context, caller core.Caller, id int64) (err error) {
+	var entityType string
+	var entityID int
+	defer func() {
+		s.audit(ctx, caller, auditDeleteDestination, entityType, entityID, strconv.FormatInt(id, 10), nil, nil, err)
+	}()
+
+	d, err := s.destination(ctx, caller, id, false)
+	if err != nil {
+		return err
+	}
+	entityType, entityID = d.EntityType, d.EntityID
+	return s.repo.DeleteDestination(ctx, d.ID)
+}
+
+// VerifyDestination подтверждает реквизиты. Администратор подтверждает любые;
+// пользователь — только расчетный счет, совпадающий с данными организации
+// после завершения ее регистрации.
+func (s *Service) VerifyDestination(ctx context.Context, caller core.Caller, id int64) (_ *core.PayoutDestination, err error) {
+	var entityType string
+	var entityID int
+	defer func() {
+		s.audit(ctx, caller, auditVerifyDestination, entityType, entityID, strconv.FormatInt(id, 10), nil, nil, err)
+	}()
+
+	d, err := s.destination(ctx, caller, id, true)
+	if err != nil {
+		return nil, err
+	}
+	entityType, entityID = d.EntityType, d.EntityID
+	if d.VerifiedAt != nil {
+		return d, nil
+	}
+
+	var verifiedBy *int
+	switch {
+	case caller.Admin && !caller.Banned:
+		verifiedBy = &caller.UserID
+	case d.Kind == provider.DestinationBankAccount:
+		details, err := s.orgBankDetails(ctx, caller, d.EntityID)
+		if err != nil {
+			return nil, err
+		}
+		if !details.RegistrationCompleted {
+			return nil, fmt.Errorf("%w: organisation registration is not completed", core.ErrDestinationNotVerified)
+		}
+		if details.Account != d.AccountNumber || details.BIC != d.BIC {
+			return nil, core.ErrDestinationMismatch
+		}
+	default:
+		return nil, core.ErrForbidden
+	}
+
+	if err := s.repo.VerifyDestination(ctx, d.ID, verifiedBy); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDestination(ctx, d.ID)
+}
+
+// destination загружает неудаленные реквизиты и проверяет доступ к их кошельку
+func (s *Service) destination(ctx context.Context, caller core.Caller, id int64, adminAllowed bool) (*core.PayoutDestination, error) {
+	d, err := s.repo.GetDestination(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && d.DeletedAt != nil) {
+		return nil, core.ErrDestinationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, caller, d.EntityType, d.EntityID, adminAllowed); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *Service) orgBankDetails(ctx context.Context, caller core.Caller, orgID int) (*clients.OrgBankDetails, error) {
+	details, err := s.orgClient.GetBankDetails(ctx, caller.Authorization, orgID)
+	if errors.Is(err, clients.ErrOrgAccessDenied) {
+		return nil, core.ErrForbidden
+	}
+	if err != nil {
+		s.log.Error("failed to get organisation bank details", "error", err, "org_id", orgID)
+		return nil, err
+	}
+	return details, nil
+}
+
+// GetPaymentMethods — сохраненные способы оплаты кошелька
+func (s *Service) GetPaymentMethods(ctx context.Context, caller core.Caller, entityType string, entityID int) ([]core.SavedPaymentMethod, error) {
+	if err := s.authorize(ctx, caller, entityType, entityID, true); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPaymentMethods(ctx, entityType, entityID)
+}
+
+// DeletePaymentMethod отвязывает сохраненный способ оплаты; у провайдера он остается,
+// но пополнить им кошелек уже нельзя
+func (s *Service) DeletePaymentMethod(ctx context.Context, caller core.Caller, entityType string, entityID int, id string) (err error) {
+	defer func() {
+		s.audit(ctx, caller, auditDeletePayMethod, entityType, entityID, id, nil, nil, err)
+	}()
+	if err = s.authorize(ctx, caller, entityType, entityID, false); err != nil {
+		return err
+	}
+	if _, err = s.paymentMethod(ctx, entityType, entityID, id); err != nil {
+		return err
+	}
+	return s.repo.DeletePaymentMethod(ctx, id)
+}
+
+// paymentMethod загружает неудаленный способ оплаты, привязанный к этому кошельку
+func (s *Service) paymentMethod(ctx context.Context, entityType string, entityID int, id string) (*core.SavedPaymentMethod, error) {
+	m, err := s.repo.GetPaymentMethod(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrPaymentMethodNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.DeletedAt != nil || m.EntityType != entityType || m.EntityID != entityID {
+		return nil, core.ErrPaymentMethodNotFound
+	}
+	return m, nil
+}
+
+// savePaymentMethod запоминает способ оплаты, который провайдер сохранил по просьбе плательщика.
+// Ошибка не мешает зачислению: в худшем случае карту придется ввести еще раз.
+func (s *Service) savePaymentMethod(ctx context.Context, payment *core.Payment, method *provider.PaymentMethod) {
+	if method == nil || !method.Saved || method.ID == "" {
+		return
+	}
+	err := s.repo.SavePaymentMethod(ctx, &core.SavedPaymentMethod{
+		ID:         method.ID,
+		EntityType: payment.EntityType,
+		EntityID:   payment.EntityID,
+		Type:       method.Type,
+		Title:      method.Title,
+	})
+	if err != nil {
+		s.log.Error("failed to save payment method", "error", err, "payment_id", payment.ID)
+	}
+}
+
+// withdrawalDestination проверяет, что по сохраненным реквизитам можно выводить с этого кошелька
+func (s *Service) withdrawalDestination(ctx context.Context, entityType string, entityID int, id int64) (*core.PayoutDestination, error) {
+	d, err := s.repo.GetDestination(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrDestinationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if d.DeletedAt != nil || d.EntityType != entityType || d.EntityID != entityID {
+		return nil, core.ErrDestinationNotFound
+	}
+	if d.VerifiedAt == nil {
+		return nil, core.ErrDestinationNotVerified
+	}
+	return d, nil
+}
+
+// payoutDestination собирает реквизиты выплаты: сохраненные или токен карты, переданный строкой
+func (s *Service) payoutDestination(ctx context.Context, destinationID *int64, token string) (provider.PayoutDestination, error) {
+	if destinationID == nil {
+		return provider.PayoutDestination{Type: provider.DestinationBankCard, PayoutToken: token}, nil
+	}
+	d, err := s.repo.GetDestination(ctx, *destinationID)
+	if err != nil {
+		return provider.PayoutDestination{}, err
+	}
+	return provider.PayoutDestination{
+		Type:          d.Kind,
+		PayoutToken:   d.PayoutToken,
+		AccountNumber: d.AccountNumber,
+		BIC:           d.BIC,
+		CorrAccount:   d.CorrAccount,
+		Holder:        d.Holder,
+	}, nil
+}
+
+// payoutRejected — выплата точно не создана и повторять бесполезно: провайдер не выплачивает
+// на такие реквизиты или отклонил запрос
+func payoutRejected(err error) bool {
+	return errors.Is(err, provider.ErrDestinationNotSupported) || errors.Is(err, provider.ErrPayoutRejected)
+}
+
+func isDigits(s string, minLen, maxLen int) bool {
+	if len(s) < minLen || len(s) > maxLen {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// mask оставляет первые head и последние tail символов
+func mask(s string, head, tail int) string {
+	if len(s) <= head+tail {
+		return s
+	}
+	masked := []byte(s)
+	for i := head; i < len(s)-tail; i++ {
+		masked[i] = '*'
+	}
+	return string(masked)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		s          string
+		head, tail int
+		want       string
+	}{
+		{"410011234567890", 5, 4, "41001******7890"},
+		{"40702810900000000001", 0, 4, "****************0001"},
+		{"1234", 2, 2, "1234"},
+	}
+	for _, tt := range tests {
+		if got := mask(tt.s, tt.head, tt.tail); got != tt.want {
+			t.Errorf("mask(%q, %d, %d) = %q, want %q", tt.s, tt.head, tt.tail, got, tt.want)
+		}
+	}
+}
+
+func TestIsDigits(t *testing.T) {
+	if !isDigits("044525225", 9, 9) {
+		t.Errorf("isDigits() BIC = false, want true")
+	}
+	if isDigits("4100 1234567", 11, 20) {
+		t.Errorf("isDigits() with space = true, want false")
+	}
+	if isDigits("4100", 11, 20) {
+		t.Errorf("isDigits() too short = true, want false")
+	}
+}
+
+func TestPayoutRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"destination not supported", provider.ErrDestinationNotSupported, true},
+		{"rejected", fmt.Errorf("%w: invalid payout token", provider.ErrPayoutRejected), true},
+		{"timeout", errors.New("context deadline exceeded"), false},
+		{"server error", errors.New("yookassa payout error: internal error"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := payoutRejected(tt.err); got != tt.want {
+				t.Errorf("payoutRejected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
+	"github.com/Starostina-elena/investment_platform/services/payment/scheduler"
+)
+
+const (
+	// paymentTTL — сколько ждать оплаты. Потом платеж помечается expired и больше
+	// не опрашивается; если он все же будет оплачен, зачисление придет с вебхуком.
+	paymentTTL = time.Hour
+	// captureTTL — сколько держать двухстадийный платеж до автоматической отмены.
+	// ЮKassa сама отменяет его через 7 дней, отменяем раньше, чтобы статус не расходился.
+	captureTTL = 6 * 24 * time.Hour
+)
+
+func captureKey(paymentID string) string {
+	return "payment:" + paymentID + ":capture"
+}
+
+func cancelKey(paymentID string) string {
+	return "payment:" + paymentID + ":cancel"
+}
+
+// syncPayment переводит платеж в статус, который вернул провайдер
+func (s *Service) syncPayment(ctx context.Context, payment *core.Payment, remotePayment *provider.Payment) error {
+	if remotePayment.Status == provider.StatusSucceeded || remotePayment.Status == provider.StatusWaitingForCapture {
+		s.savePaymentMethod(ctx, payment, remotePayment.PaymentMethod)
+	}
+	switch remotePayment.Status {
+	case provider.StatusSucceeded:
+		if payment.CaptureAmount != nil && *payment.CaptureAmount != payment.Amount && payment.Status == core.StatusWaitingForCapture {
+			// частичное подтверждение: зачисляется подтвержденная сумма, остаток вернется плательщику
+			if err := s.repo.ApplyCaptureAmount(ctx, payment.ID); err != nil {
+				s.log.Error("CRITICAL: failed to save captured amount", "error", err, "payment_id", payment.ID, "amount", *payment.CaptureAmount)
+				return err
+			}
+			payment.Amount = *payment.CaptureAmount
+		}
+		s.log.Info("payment succeeded, crediting wallet", "payment_id", payment.ID, "external_id", payment.ExternalID)
+		if err := s.runDeposit(ctx, payment); err != nil {
+			s.log.Error("CRITICAL: failed to deposit money after success payment", "error", err, "payment_id", payment.ID)
+			return err
+		}
+	case provider.StatusWaitingForCapture:
+		if payment.Status != core.StatusPending {
+			return nil
+		}
+		s.log.Info("payment is waiting for capture", "payment_id", payment.ID)
+		return s.repo.MarkWaitingForCapture(ctx, payment.ID, time.Now().Add(captureTTL))
+	case provider.StatusCanceled:
+		if payment.Status == core.StatusCanceled {
+			return nil
+		}
+		s.log.Info("payment canceled", "payment_id", payment.ID, "reason", remotePayment.CancellationReason)
+		return s.repo.ClosePayment(ctx, payment.ID, core.StatusCanceled, remotePayment.CancellationReason)
+	}
+	return nil
+}
+
+// CapturePayment подтверждает двухстадийный платеж и зачисляет деньги на кошелек.
+// amount = 0 подтверждает всю сумму, меньшая сумма — частичное подтверждение,
+// остаток ЮKassa вернет плательщику.
+func (s *Service) CapturePayment(ctx context.Context, caller core.Caller, paymentID string, amount money.Amount) (_ *core.Payment, err error) {
+	var entityType string
+	var entityID int
+	defer func() {
+		s.audit(ctx, caller, auditCapturePayment, entityType, entityID, paymentID, &amount, nil, err)
+	}()
+
+	payment, err := s.repo.GetByID(ctx, paymentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	entityType, entityID = payment.EntityType, payment.EntityID
+	if err = s.authorize(ctx, caller, entityType, entityID, true); err != nil {
+		return nil, err
+	}
+	if payment.Status != core.StatusWaitingForCapture {
+		return nil, core.ErrPaymentNotCapturable
+	}
+	if amount.IsZero() {
+		amount = payment.Amount
+		if payment.CaptureAmount != nil {
+			amount = *payment.CaptureAmount
+		}
+	}
+	if amount > payment.Amount {
+		return nil, core.ErrCaptureTooLarge
+	}
+
+	// сумма сохраняется до запроса: если ответ провайдера потеряется, вебхук или
+	// чистильщик зачислят ее, а повтор запроса пойдет с тем же ключом и той же суммой
+	ok, err := s.repo.SetCaptureAmount(ctx, payment.ID, amount)
+	if err != nil {
+		s.log.Error("failed to save capture amount", "error", err, "payment_id", payment.ID)
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: capture for another amount is already requested", core.ErrPaymentNotCapturable)
+	}
+	payment.CaptureAmount = &amount
+
+	if err := s.capture(ctx, payment); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, payment.ID)
+}
+
+// capture подтверждает у провайдера сумму payment.CaptureAmount
+func (s *Service) capture(ctx context.Context, payment *core.Payment) error {
+	remotePayment, err := s.provider.CapturePayment(payment.ExternalID, payment.CaptureAmount.String(), payment.Currency.String(), captureKey(payment.ID))
+	if err != nil {
+		s.log.Error("payment capture failed", "error", err, "payment_id", payment.ID)
+		return err
+	}
+	return s.syncPayment(ctx, payment, remotePayment)
+}
+
+// CancelPayment отменяет двухстадийный платеж, деньги разблокируются на карте плательщика
+func (s *Service) CancelPayment(ctx context.Context, caller core.Caller, paymentID string) (_ *core.Payment, err error) {
+	var entityType string
+	var entityID int
+	defer func() {
+		s.audit(ctx, caller, auditCancelPayment, entityType, entityID, paymentID, nil, nil, err)
+	}()
+
+	payment, err := s.repo.GetByID(ctx, paymentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	entityType, entityID = payment.EntityType, payment.EntityID
+	if err = s.authorize(ctx, caller, entityType, entityID, true); err != nil {
+		return nil, err
+	}
+	if payment.Status != core.StatusWaitingForCapture {
+		return nil, core.ErrPaymentNotCapturable
+	}
+
+	if err := s.cancelAtProvider(ctx, payment, provider.ReasonCanceledByMerchant); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, payment.ID)
+}
+
+func (s *Service) cancelAtProvider(ctx context.Context, payment *core.Payment, reason string) error {
+	remotePayment, err := s.provider.CancelPayment(payment.ExternalID, cancelKey(payment.ID))
+	if err != nil {
+		s.log.Error("payment cancel failed", "error", err, "payment_id", payment.ID)
+		return err
+	}
+	if remotePayment.Status != provider.StatusCanceled {
+		return s.syncPayment(ctx, payment, remotePayment)
+	}
+	s.log.Info("payment canceled", "payment_id", payment.ID, "reason", reason)
+	return s.repo.ClosePayment(ctx, payment.ID, core.StatusCanceled, reason)
+}
+
+// ExpireStalePayments закрывает платежи с истекшим сроком. Перед закрытием статус
+// перечитывается у провайдера: оплаченный платеж зачисляется, неоплаченный помечается
+// expired, а неподтвержденный двухстадийный отменяется, чтобы разблокировать деньги.
+// Если подтверждение уже запрошено, оно повторяется вместо отмены. После ошибки
+// платеж откладывается с растущей задержкой, как при опросе.
+func (s *Service) ExpireStalePayments(ctx context.Context) error {
+	payments, err := s.repo.GetStalePayments(ctx, 100)
+	if err != nil {
+		s.log.Error("failed to get stale payments", "error", err)
+		return err
+	}
+
+	for i := range payments {
+		payment := &payments[i]
+		if err := s.closeStalePayment(ctx, payment); err != nil {
+			s.log.Error("failed to close stale payment", "error", err, "payment_id", payment.ID, "attempt", payment.PollAttempts+1)
+			next := time.Now().Add(scheduler.Backoff(payment.PollAttempts, pollBase, pollMax))
+			if err := s.repo.RecordPaymentPoll(ctx, payment.ID, payment.PollAttempts+1, next, errText(err)); err != nil {
+				s.log.Error("failed to save payment poll", "error", err, "payment_id", payment.ID)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) closeStalePayment(ctx context.Context, payment *core.Payment) error {
+	remotePayment, err := s.provider.GetPayment(payment.ExternalID)
+	if err != nil {
+		return err
+	}
+
+	switch remotePayment.Status {
+	case provider.StatusPending:
+		s.log.Info("payment expired", "payment_id", payment.ID, "created_at", payment.CreatedAt)
+		return s.repo.ClosePayment(ctx, payment.ID, core.StatusExpired, provider.ReasonExpiredOnConfirmation)
+	case provider.StatusWaitingForCapture:
+		if payment.CaptureAmount != nil {
+			return s.capture(ctx, payment)
+		}
+		return s.cancelAtProvider(ctx, payment, provider.ReasonExpiredOnCapture)
+	}
+	return s.syncPayment(ctx, payment, remotePayment)
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
+	"github.com/Starostina-elena/investment_platform/services/payment/scheduler"
+)
+
+// Опрос провайдера на случай потерянного вебхука. Каждая строка проверяется
+// с растущей задержкой (30с, 1м, 2м ... до часа) и не больше maxAttempts раз.
+// Платеж к тому времени закроет ExpireStalePayments, а зависший вывод требует ручной проверки.
+const (
+	pollBase               = 30 * time.Second
+	pollMax                = time.Hour
+	pollBatch              = 100
+	paymentPollAttempts    = 20
+	withdrawalPollAttempts = 30
+)
+
+// ProcessPendingPayments проверяет у провайдера незавершенные платежи, которым подошло время проверки
+func (s *Service) ProcessPendingPayments(ctx context.Context) error {
+	payments, err := s.repo.GetPendingPayments(ctx, paymentPollAttempts, pollBatch)
+	if err != nil {
+		s.log.Error("failed to get pending payments", "error", err)
+		return err
+	}
+
+	for i := range payments {
+		payment := &payments[i]
+		remotePayment, err := s.provider.GetPayment(payment.ExternalID)
+		if err == nil {
+			err = s.syncPayment(ctx, payment, remotePayment)
+		}
+
+		result := pollResult(err, remotePayment == nil || remotePayment.Status == provider.StatusPending ||
+			remotePayment.Status == provider.StatusWaitingForCapture)
+		s.metrics.Add("payment_poll_total", 1, "kind", "payment", "result", result)
+		if err != nil {
+			s.log.Error("failed to check payment status", "error", err, "payment_id", payment.ID, "attempt", payment.PollAttempts+1)
+		}
+
+		attempts, next := s.nextPoll(payment.PollAttempts, paymentPollAttempts, "payment", payment.ID, result)
+		if err := s.repo.RecordPaymentPoll(ctx, payment.ID, attempts, next, errText(err)); err != nil {
+			s.log.Error("failed to save payment poll", "error", err, "payment_id", payment.ID)
+		}
+	}
+
+	return nil
+}
+
+// ProcessPendingWithdrawals проверяет у провайдера выплаты по незавершенным выводам
+func (s *Service) ProcessPendingWithdrawals(ctx context.Context) error {
+	withdrawals, err := s.repo.GetPendingWithdrawals(ctx, withdrawalPollAttempts, pollBatch)
+	if err != nil {
+		s.log.Error("failed to get pending withdrawals", "error", err)
+		return err
+	}
+
+	for i := range withdrawals {
+		withdrawal := &withdrawals[i]
+		remotePayout, err := s.provider.GetPayout(withdrawal.ExternalID)
+		if err == nil {
+			switch remotePayout.Status {
+			case provider.StatusSucceeded:
+				s.log.Info("payout succeeded, updating status", "withdrawal_id", withdrawal.ID)
+				err = s.completeWithdrawal(ctx, withdrawal)
+			case provider.StatusFailed:
+				s.log.Warn("payout failed, releasing funds", "withdrawal_id", withdrawal.ID)
+				err = s.failWithdrawal(ctx, withdrawal)
+			}
+		}
+
+		result := pollResult(err, remotePayout == nil || remotePayout.Status == provider.StatusPending)
+		s.metrics.Add("payment_poll_total", 1, "kind", "withdrawal", "result", result)
+		if err != nil {
+			s.log.Error("failed to check withdrawal status", "error", err, "withdrawal_id", withdrawal.ID, "attempt", withdrawal.PollAttempts+1)
+		}
+
+		attempts, next := s.nextPoll(withdrawal.PollAttempts, withdrawalPollAttempts, "withdrawal", withdrawal.ID, result)
+		if err := s.repo.RecordWithdrawalPoll(ctx, withdrawal.ID, attempts, next, errText(err)); err != nil {
+			s.log.Error("failed to save withdrawal poll", "error", err, "withdrawal_id", withdrawal.ID)
+		}
+	}
+
+	return nil
+}
+
+// nextPoll считает номер попытки и время следующей проверки. Если последняя попытка
+// не завершила операцию, пишет об этом в лог и метрику: дальше строку никто не опрашивает.
+func (s *Service) nextPoll(prevAttempts, maxAttempts int, kind, id, result string) (int, time.Time) {
+	attempts := prevAttempts + 1
+	if attempts >= maxAttempts && result != "settled" {
+		s.metrics.Add("payment_poll_exhausted_total", 1, "kind", kind)
+		s.log.Error("CRITICAL: provider polling gave up, manual check required", "kind", kind, "id", id, "attempts", attempts)
+	}
+	return attempts, time.Now().Add(scheduler.Backoff(prevAttempts, pollBase, pollMax))
+}
+
+func pollResult(err error, stillPending bool) string {
+	switch {
+	case err != nil:
+		return "error"
+	case stillPending:
+		return "pending"
+	default:
+		return "settled"
+	}
+}
+
+func errText(err error) *string {
+	if err == nil {
+		return nil
+	}
+	text := err.Error()
+	return &text
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/clients"
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
+	"github.com/Starostina-elena/investment_platform/services/payment/repo"
+	"github.com/google/uuid"
+)
+
+// Регулярные пожертвования списываются раз в месяц. Если денег на кошельке не хватило
+// или карта отклонена, списание повторяется раз в сутки, после recurringMaxAttempts
+// попыток месяц пропускается, а пожертвование остается активным.
+const (
+	recurringBatch       = 100
+	recurringMaxAttempts = 3
+	recurringRetryDelay  = 24 * time.Hour
+	// recurringClaimTTL — через сколько списание, застрявшее в paying без платежа
+	// (сбой до записи платежа), снова считается неоплаченным. Столько же повторяется
+	// создание платежа у провайдера, если он не ответил.
+	recurringClaimTTL = time.Hour
+)
+
+const (
+	stopReasonTargetClosed   = "project is completed or recipient is banned"
+	stopReasonMethodDeleted  = "saved payment method is deleted"
+	stopReasonCanceledByUser = "canceled by user"
+)
+
+func recurringKey(chargeID int64) string {
+	return "recurring:" + strconv.FormatInt(chargeID, 10)
+}
+
+// chargePaymentKey — ключ идемпотентности платежа по карте. Он один на попытку: повтор
+// после таймаута вернет уже созданный платеж, а следующая попытка через сутки создаст новый.
+func chargePaymentKey(c *core.RecurringCharge) string {
+	return recurringKey(c.ID) + ":payment:" + strconv.Itoa(c.Attempts)
+}
+
+// CreateRecurring оформляет ежемесячное пожертвование от имени вызывающего пользователя.
+// Первое списание проходит при ближайшем запуске расписания.
+func (s *Service) CreateRecurring(ctx context.Context, caller core.Caller, p *core.RecurringPledge) (_ *core.RecurringPledge, err error) {
+	defer func() {
+		s.audit(ctx, caller, auditCreateRecurring, "user", caller.UserID, strconv.FormatInt(p.ID, 10), &p.Amount, &p.Currency, err)
+	}()
+	if err = s.authorize(ctx, caller, "user", caller.UserID, false); err != nil {
+		return nil, err
+	}
+
+	if p.TargetType != "project" && p.TargetType != "org" {
+		return nil, fmt.Errorf("%w: target_type must be project or org", core.ErrInvalidRecurring)
+	}
+	if p.TargetID <= 0 || !p.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: target_id and amount must be positive", core.ErrInvalidRecurring)
+	}
+	switch p.Source {
+	case core.RecurringSourceWallet:
+		p.PaymentMethodID = nil
+	case core.RecurringSourceCard:
+		if p.PaymentMethodID == nil || *p.PaymentMethodID == "" {
+			return nil, fmt.Errorf("%w: payment_method_id is required", core.ErrInvalidRecurring)
+		}
+		if _, err = s.paymentMethod(ctx, "user", caller.UserID, *p.PaymentMethodID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: source must be wallet or card", core.ErrInvalidRecurring)
+	}
+
+	open, err := s.repo.RecurringTargetOpen(ctx, p.TargetType, p.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if !open {
+		return nil, core.ErrRecurringTargetClosed
+	}
+
+	p.UserID = caller.UserID
+	p.Status = core.RecurringActive
+	p.NextChargeAt = time.Now()
+	if err = s.repo.CreateRecurring(ctx, p); err != nil {
+		s.log.Error("failed to save recurring pledge", "error", err, "user_id", caller.UserID)
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetRecurring возвращает пожертвования вызывающего пользователя
+func (s *Service) GetRecurring(ctx context.Context, caller core.Caller) ([]core.RecurringPledge, error) {
+	if err := s.authorize(ctx, caller, "user", caller.UserID, false); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUserRecurring(ctx, caller.UserID)
+}
+
+// GetRecurringCharges возвращает историю списаний по пожертвованию
+func (s *Service) GetRecurringCharges(ctx context.Context, caller core.Caller, id int64) ([]core.RecurringCharge, error) {
+	if _, err := s.recurring(ctx, caller, id, true); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRecurringCharges(ctx, id)
+}
+
+func (s *Service) PauseRecurring(ctx context.Context, caller core.Caller, id int64) (*core.RecurringPledge, error) {
+	return s.setRecurringStatus(ctx, caller, auditPauseRecurring, id, core.RecurringPaused, "", core.RecurringActive)
+}
+
+func (s *Service) ResumeRecurring(ctx context.Context, caller core.Caller, id int64) (*core.RecurringPledge, error) {
+	return s.setRecurringStatus(ctx, caller, auditResumeRecurring, id, core.RecurringActive, "", core.RecurringPaused)
+}
+
+// CancelRecurring отменяет пожертвование насовсем. Незавершенное списание тоже отменяется;
+// если деньги с карты уже пришли, они остаются на кошельке пользователя.
+func (s *Service) CancelRecurring(ctx context.Context, caller core.Caller, id int64) (*core.RecurringPledge, error) {
+	return s.setRecurringStatus(ctx, caller, auditCancelRecurring, id, core.RecurringCanceled, stopReasonCanceledByUser,
+		core.RecurringActive, core.RecurringPaused)
+}
+
+func (s *Service) setRecurringStatus(ctx context.Context, caller core.Caller, action string, id int64, status core.RecurringStatus, reason string, from ...core.RecurringStatus) (_ *core.RecurringPledge, err error) {
+	defer func() {
+		s.audit(ctx, caller, action, "user", caller.UserID, strconv.FormatInt(id, 10), nil, nil, err)
+	}()
+	p, err := s.recurring(ctx, caller, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// после паузы пропущенные месяцы не списываются: ближайшее списание — сразу
+	// или в ранее назначенную дату, если она еще не прошла
+	var next *time.Time
+	if status == core.RecurringActive && p.NextChargeAt.Before(time.Now()) {
+		now := time.Now()
+		next = &now
+	}
+
+	ok, err := s.repo.SetRecurringStatus(ctx, id, status, reason, next, from...)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, core.ErrRecurringFinished
+	}
+	return s.repo.GetRecurring(ctx, id)
+}
+
+// recurring загружает пожертвование и проверяет, что оно принадлежит вызывающему
+func (s *Service) recurring(ctx context.Context, caller core.Caller, id int64, adminAllowed bool) (*core.RecurringPledge, error) {
+	p, err := s.repo.GetRecurring(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrRecurringNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, caller, "user", p.UserID, adminAllowed); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// RunRecurring создает списания за наступивший месяц и продвигает незавершенные.
+// Запускается по расписанию демоном; повторный или параллельный запуск безопасен:
+// списания закрепляются условным обновлением статуса, а переводы идемпотентны.
+func (s *Service) RunRecurring(ctx context.Context) error {
+	now := time.Now()
+	scheduled, err := s.repo.ScheduleDueCharges(ctx, now, recurringBatch)
+	if err != nil {
+		s.log.Error("failed to schedule recurring charges", "error", err)
+		return err
+	}
+	if scheduled > 0 {
+		s.log.Info("recurring charges scheduled", "count", scheduled)
+	}
+
+	charges, err := s.repo.GetOpenCharges(ctx, now, recurringBatch)
+	if err != nil {
+		s.log.Error("failed to get open recurring charges", "error", err)
+		return err
+	}
+	for i := range charges {
+		c := &charges[i]
+		if err := s.processCharge(ctx, c); err != nil {
+			s.log.Error("failed to process recurring charge", "error", err, "charge_id", c.ID, "pledge_id", c.PledgeID)
+		}
+	}
+	return nil
+}
+
+func (s *Service) processCharge(ctx context.Context, c *repo.OpenCharge) error {
+	p := &c.Pledge
+	open, err := s.repo.RecurringTargetOpen(ctx, p.TargetType, p.TargetID)
+	if err != nil {
+		return err
+	}
+	if reason := stopReason(p, open); reason != "" {
+		return s.stopRecurring(ctx, p, reason)
+	}
+
+	switch c.Status {
+	case core.ChargePending:
+		if p.Source == core.RecurringSourceCard {
+			return s.payCharge(ctx, c)
+		}
+		return s.transferCharge(ctx, c)
+	case core.ChargePaying:
+		return s.checkChargePayment(ctx, c)
+	case core.ChargePaid:
+		return s.transferCharge(ctx, c)
+	}
+	return nil
+}
+
+// transferCharge переводит сумму с кошелька пользователя получателю
+func (s *Service) transferCharge(ctx context.Context, c *repo.OpenCharge) error {
+	p := &c.Pledge
+	from := c.Status
+	err := s.txClient.Transfer(ctx, recurringKey(c.ID), "user", p.UserID, p.TargetType, p.TargetID, p.Amount, p.Currency)
+	if errors.Is(err, clients.ErrTransferRejected) {
+		return s.retryCharge(ctx, c, from, from, err)
+	}
+	if err != nil {
+		// сервис транзакций недоступен: попробуем при следующем запуске, попытку не считаем
+		return err
+	}
+
+	c.Status = core.ChargeSucceeded
+	c.LastError = ""
+	if _, err := s.repo.UpdateCharge(ctx, &c.RecurringCharge, from); err != nil {
+		return err
+	}
+	s.metrics.Add("recurring_charges_total", 1, "source", p.Source, "result", "succeeded")
+	s.log.Info("recurring charge succeeded", "charge_id", c.ID, "pledge_id", p.ID, "amount", p.Amount, "currency", p.Currency)
+	return nil
+}
+
+// payCharge списывает сумму с сохраненной карты на кошелек пользователя; перевод
+// получателю будет сделан, когда платеж пройдет
+func (s *Service) payCharge(ctx context.Context, c *repo.OpenCharge) error {
+	p := &c.Pledge
+	if _, err := s.paymentMethod(ctx, "user", p.UserID, *p.PaymentMethodID); err != nil {
+		if errors.Is(err, core.ErrPaymentMethodNotFound) {
+			return s.stopRecurring(ctx, p, stopReasonMethodDeleted)
+		}
+		return err
+	}
+
+	// закрепляем списание до обращения к провайдеру, чтобы параллельный запуск не создал второй платеж
+	c.Status = core.ChargePaying
+	ok, err := s.repo.UpdateCharge(ctx, &c.RecurringCharge, core.ChargePending)
+	if err != nil || !ok {
+		return err
+	}
+
+	// платеж записывается до обращения к провайдеру: если ответ не придет, следующий
+	// запуск повторит запрос с тем же ключом, а не создаст второй платеж
+	payment := &core.Payment{
+		ID:         uuid.New().String(),
+		Amount:     p.Amount,
+		Currency:   p.Currency,
+		EntityID:   p.UserID,
+		EntityType: "user",
+		Status:     core.StatusCreating,
+		Capture:    true,
+	}
+	if err := s.repo.Create(ctx, payment); err != nil {
+		s.log.Error("failed to save recurring payment", "error", err, "charge_id", c.ID)
+		return err
+	}
+	c.PaymentID = &payment.ID
+	if _, err := s.repo.UpdateCharge(ctx, &c.RecurringCharge, core.ChargePaying); err != nil {
+		return err
+	}
+	return s.submitChargePayment(ctx, c, payment)
+}
+
+// submitChargePayment создает у провайдера платеж, уже записанный в БД. Пока не вышел
+// recurringClaimTTL, ошибка только откладывает запрос до следующего запуска.
+func (s *Service) submitChargePayment(ctx context.Context, c *repo.OpenCharge, payment *core.Payment) error {
+	p := &c.Pledge
+	if p.PaymentMethodID == nil {
+		if err := s.repo.ClosePayment(ctx, payment.ID, core.StatusCanceled, stopReasonMethodDeleted); err != nil {
+			return err
+		}
+		return s.stopRecurring(ctx, p, stopReasonMethodDeleted)
+	}
+
+	desc := fmt.Sprintf("Регулярное пожертвование #%d", p.ID)
+	created, err := s.provider.CreatePayment(payment.Amount.String(), payment.Currency.String(), desc, "", provider.PaymentOptions{
+		Capture:         true,
+		PaymentMethodID: *p.PaymentMethodID,
+		IdempotenceKey:  chargePaymentKey(&c.RecurringCharge),
+	})
+	if err != nil {
+		if time.Since(payment.CreatedAt) < recurringClaimTTL {
+			s.log.Warn("recurring payment creation failed, will retry with the same key", "error", err, "charge_id", c.ID, "payment_id", payment.ID)
+			return err
+		}
+		if err := s.repo.ClosePayment(ctx, payment.ID, core.StatusCanceled, err.Error()); err != nil {
+			return err
+		}
+		return s.retryCharge(ctx, c, core.ChargePaying, core.ChargePending, err)
+	}
+
+	if err := s.repo.SetPaymentCreated(ctx, payment.ID, created.ID, time.Now().Add(paymentTTL)); err != nil {
+		s.log.Error("failed to save recurring payment external id", "error", err, "payment_id", payment.ID)
+		return err
+	}
+	s.log.Info("recurring card payment created", "charge_id", c.ID, "pledge_id", p.ID, "payment_id", payment.ID)
+	return nil
+}
+
+// checkChargePayment ждет зачисления платежа по карте. Статус платежа обновляют
+// вебхук и опрос провайдера, здесь он только читается.
+func (s *Service) checkChargePayment(ctx context.Context, c *repo.OpenCharge) error {
+	if c.PaymentID == nil {
+		if time.Since(c.UpdatedAt) > recurringClaimTTL {
+			return s.retryCharge(ctx, c, core.ChargePaying, core.ChargePending, errors.New("payment was not created"))
+		}
+		return nil
+	}
+
+	payment, err := s.repo.GetByID(ctx, *c.PaymentID)
+	if err != nil {
+		return err
+	}
+	switch payment.Status {
+	case core.StatusCreating:
+		return s.submitChargePayment(ctx, c, payment)
+	case core.StatusSucceeded:
+		c.Status = core.ChargePaid
+		ok, err := s.repo.UpdateCharge(ctx, &c.RecurringCharge, core.ChargePaying)
+		if err != nil || !ok {
+			return err
+		}
+		return s.transferCharge(ctx, c)
+	case core.StatusCanceled, core.StatusExpired:
+		reason := "card payment " + string(payment.Status)
+		if payment.CancellationReason != "" {
+			reason += ": " + payment.CancellationReason
+		}
+		return s.retryCharge(ctx, c, core.ChargePaying, core.ChargePending, errors.New(reason))
+	}
+	return nil
+}
+
+// retryCharge откладывает списание на сутки, а после последней попытки закрывает его как failed
+func (s *Service) retryCharge(ctx context.Context, c *repo.OpenCharge, from, to core.RecurringChargeStatus, reason error) error {
+	failAttempt(&c.RecurringCharge, to, reason, time.Now())
+	if _, err := s.repo.UpdateCharge(ctx, &c.RecurringCharge, from); err != nil {
+		return err
+	}
+
+	result := "retry"
+	if c.Status == core.ChargeFailed {
+		result = "failed"
+	}
+	s.metrics.Add("recurring_charges_total", 1, "source", c.Pledge.Source, "result", result)
+	s.log.Warn("recurring charge attempt failed", "charge_id", c.ID, "pledge_id", c.PledgeID, "attempt", c.Attempts, "status", c.Status, "reason", reason)
+	return nil
+}
+
+// stopReason — почему пожертвование больше нельзя исполнить; пусто, если можно
+func stopReason(p *core.RecurringPledge, targetOpen bool) string {
+	if !targetOpen {
+		return stopReasonTargetClosed
+	}
+	if p.Source == core.RecurringSourceCard && p.PaymentMethodID == nil {
+		return stopReasonMethodDeleted
+	}
+	return ""
+}
+
+// failAttempt засчитывает неудачную попытку: следующая через recurringRetryDelay в статусе to,
+// после recurringMaxAttempts списание закрывается как failed
+func failAttempt(c *core.RecurringCharge, to core.RecurringChargeStatus, reason error, now time.Time) {
+	c.Attempts++
+	c.LastError = reason.Error()
+	c.NextAttemptAt = now.Add(recurringRetryDelay)
+	c.Status = to
+	if c.Attempts >= recurringMaxAttempts {
+		c.Status = core.ChargeFailed
+	}
+}
+
+// stopRecurring останавливает пожертвование, которое больше нельзя исполнить
+func (s *Service) stopRecurring(ctx context.Context, p *core.RecurringPledge, reason string) error {
+	if _, err := s.repo.SetRecurringStatus(ctx, p.ID, core.RecurringStopped, reason, nil, core.RecurringActive, core.RecurringPaused); err != nil {
+		return err
+	}
+	s.log.Info("recurring pledge stopped", "pledge_id", p.ID, "reason", reason)
+	return nil
+}
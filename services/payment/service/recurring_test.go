@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+)
+
+func TestChargePaymentKey(t *testing.T) {
+	c := &core.RecurringCharge{ID: 7}
+	first := chargePaymentKey(c)
+	if again := chargePaymentKey(c); again != first {
+		t.Errorf("chargePaymentKey() = %q, then %q; want the same key for a retry", first, again)
+	}
+	c.Attempts++
+	if next := chargePaymentKey(c); next == first {
+		t.Errorf("chargePaymentKey() = %q for the next attempt, want a new key", next)
+	}
+}
+
+func TestFailAttempt(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := &core.RecurringCharge{Status: core.ChargePaying}
+	reason := errors.New("card declined")
+
+	for attempt := 1; attempt < recurringMaxAttempts; attempt++ {
+		failAttempt(c, core.ChargePending, reason, now)
+		if c.Status != core.ChargePending || c.Attempts != attempt {
+			t.Fatalf("attempt %d: status = %s, attempts = %d; want pending, %d", attempt, c.Status, c.Attempts, attempt)
+		}
+		if want := now.Add(recurringRetryDelay); !c.NextAttemptAt.Equal(want) {
+			t.Errorf("attempt %d: next attempt = %v, want %v", attempt, c.NextAttemptAt, want)
+		}
+		if c.LastError != reason.Error() {
+			t.Errorf("attempt %d: last error = %q", attempt, c.LastError)
+		}
+	}
+
+	failAttempt(c, core.ChargePending, reason, now)
+	if c.Status != core.ChargeFailed {
+		t.Errorf("after %d attempts status = %s, want failed", c.Attempts, c.Status)
+	}
+}
+
+func TestStopReason(t *testing.T) {
+	method := "pm-1"
+	tests := []struct {
+		name   string
+		pledge core.RecurringPledge
+		open   bool
+		want   string
+	}{
+		{"wallet", core.RecurringPledge{Source: core.RecurringSourceWallet}, true, ""},
+		{"card", core.RecurringPledge{Source: core.RecurringSourceCard, PaymentMethodID: &method}, true, ""},
+		{"target closed", core.RecurringPledge{Source: core.RecurringSourceWallet}, false, stopReasonTargetClosed},
+		{"card deleted", core.RecurringPledge{Source: core.RecurringSourceCard}, true, stopReasonMethodDeleted},
+		{"closed wins", core.RecurringPledge{Source: core.RecurringSourceCard}, false, stopReasonTargetClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stopReason(&tt.pledge, tt.open); got != tt.want {
+				t.Errorf("stopReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
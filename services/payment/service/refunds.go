@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
+	"github.com/Starostina-elena/investment_platform/services/payment/saga"
+	"github.com/google/uuid"
+)
+
+const (
+	sagaRefund        = "refund"
+	sagaRefundCapture = "refund_capture"
+	sagaRefundRelease = "refund_release"
+)
+
+type refundPayload struct {
+	RefundID   string         `json:"refund_id"`
+	PaymentID  string         `json:"payment_id"`
+	EntityType string         `json:"entity_type"`
+	EntityID   int            `json:"entity_id"`
+	Amount     money.Amount   `json:"amount"`
+	Currency   money.Currency `json:"currency"`
+	Reason     string         `json:"reason"`
+}
+
+type refundIDPayload struct {
+	RefundID string `json:"refund_id"`
+}
+
+func refundHoldKey(refundID string) string {
+	return "refund:" + refundID + ":hold"
+}
+
+func (s *Service) registerRefundSagas() {
+	s.sagas.Register(saga.Definition{
+		Kind: sagaRefund,
+		Steps: []saga.Step{
+			{Name: "create_refund", Action: s.createRefund, Compensate: s.markRefundFailed},
+			{Name: "hold_funds", Action: s.holdRefundFunds, Compensate: s.releaseRefundHold},
+			{Name: "create_provider_refund", Action: s.createProviderRefund},
+		},
+	})
+
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaRefundCapture,
+		MaxAttempts: 20,
+		Steps: []saga.Step{
+			{Name: "capture_hold", Action: s.captureRefundHold},
+			{Name: "mark_succeeded", Action: s.markRefundSucceeded},
+		},
+	})
+
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaRefundRelease,
+		MaxAttempts: 20,
+		Steps: []saga.Step{
+			{Name: "release_hold", Action: s.releaseRefundHold},
+			{Name: "mark_failed", Action: s.markRefundFailed},
+		},
+	})
+}
+
+// InitRefund возвращает оплаченный платеж целиком (amount = 0) или частично.
+// Как и при выводе, деньги сначала резервируются на кошельке и списываются,
+// только когда провайдер подтвердит возврат.
+func (s *Service) InitRefund(ctx context.Context, caller core.Caller, paymentID string, amount money.Amount, reason string) (_ *core.Refund, err error) {
+	var entityType string
+	var entityID int
+	defer func() {
+		s.audit(ctx, caller, auditInitRefund, entityType, entityID, paymentID, &amount, nil, err)
+	}()
+
+	payment, err := s.repo.GetByID(ctx, paymentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	entityType, entityID = payment.EntityType, payment.EntityID
+	if err = s.authorize(ctx, caller, entityType, entityID, true); err != nil {
+		return nil, err
+	}
+	if payment.Status != core.StatusSucceeded {
+		return nil, core.ErrPaymentNotRefundable
+	}
+
+	refunded, err := s.repo.RefundedAmount(ctx, payment.ID)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		amount = payment.Amount - refunded
+		if amount <= 0 {
+			return nil, core.ErrRefundTooLarge
+		}
+	}
+	if refunded+amount > payment.Amount {
+		return nil, core.ErrRefundTooLarge
+	}
+
+	payload := refundPayload{
+		RefundID:   uuid.New().String(),
+		PaymentID:  payment.ID,
+		EntityType: payment.EntityType,
+		EntityID:   payment.EntityID,
+		Amount:     amount,
+		Currency:   payment.Currency,
+		Reason:     reason,
+	}
+
+	sg, err := s.sagas.StartAndRun(ctx, sagaRefund, payload.RefundID, payload)
+	if sg == nil || sg.Status == saga.StatusFailed || sg.Status == saga.StatusCompensating {
+		s.log.Error("refund failed", "error", err, "refund_id", payload.RefundID, "payment_id", payment.ID)
+		if err == nil {
+			err = fmt.Errorf("refund %s failed", payload.RefundID)
+		}
+		return nil, err
+	}
+	if err != nil {
+		s.log.Warn("refund step failed, will retry", "error", err, "refund_id", payload.RefundID)
+	}
+
+	return s.repo.GetRefund(ctx, payload.RefundID)
+}
+
+// ProcessRefundWebhook обрабатывает уведомление о возврате, перечитывая его статус у провайдера
+func (s *Service) ProcessRefundWebhook(ctx context.Context, eventType string, object map[string]interface{}) error {
+	if eventType != "refund.succeeded" && eventType != "refund.canceled" {
+		return nil
+	}
+
+	externalID, _ := object["id"].(string)
+	if externalID == "" {
+		return fmt.Errorf("empty refund id in webhook")
+	}
+
+	refund, err := s.repo.GetRefundByExternalID(ctx, externalID)
+	if err != nil {
+		s.log.Error("refund not found", "id", externalID)
+		return err
+	}
+	return s.syncRefund(ctx, refund)
+}
+
+func (s *Service) ProcessPendingRefunds(ctx context.Context) error {
+	refunds, err := s.repo.GetPendingRefunds(ctx)
+	if err != nil {
+		s.log.Error("failed to get pending refunds", "error", err)
+		return err
+	}
+
+	for i := range refunds {
+		if err := s.syncRefund(ctx, &refunds[i]); err != nil {
+			s.log.Error("failed to check refund status", "error", err, "refund_id", refunds[i].ID)
+		}
+	}
+	return nil
+}
+
+// syncRefund списывает или освобождает холд возврата по статусу у провайдера
+func (s *Service) syncRefund(ctx context.Context, refund *core.Refund) error {
+	if refund.Status != core.RefundPending {
+		return nil
+	}
+
+	remoteRefund, err := s.provider.GetRefund(refund.ExternalID)
+	if err != nil {
+		s.log.Error("failed to get refund from provider", "error", err, "external_id", refund.ExternalID)
+		return err
+	}
+
+	switch remoteRefund.Status {
+	case provider.StatusSucceeded:
+		s.log.Info("refund succeeded", "refund_id", refund.ID)
+		err = s.runRefundSaga(ctx, sagaRefundCapture, refund)
+	case provider.StatusCanceled, provider.StatusFailed:
+		s.log.Warn("refund canceled", "refund_id", refund.ID)
+		err = s.runRefundSaga(ctx, sagaRefundRelease, refund)
+	}
+	if err != nil {
+		s.log.Error("CRITICAL: failed to settle refund", "error", err, "refund_id", refund.ID, "status", remoteRefund.Status)
+	}
+	return err
+}
+
+func (s *Service) runRefundSaga(ctx context.Context, kind string, refund *core.Refund) error {
+	sg, err := s.sagas.StartAndRun(ctx, kind, refund.ID, refundIDPayload{RefundID: refund.ID})
+	if errors.Is(err, saga.ErrAlreadyRunning) {
+		s.log.Info("refund saga already in progress", "kind", kind, "refund_id", refund.ID)
+		return nil
+	}
+	if sg != nil && sg.Status == saga.StatusRunning {
+		return nil
+	}
+	return err
+}
+
+func (s *Service) createRefund(ctx context.Context, sg *saga.Saga) error {
+	var p refundPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	err := s.repo.CreateRefund(ctx, &core.Refund{
+		ID:         p.RefundID,
+		PaymentID:  p.PaymentID,
+		EntityID:   p.EntityID,
+		EntityType: p.EntityType,
+		Amount:     p.Amount,
+		Currency:   p.Currency,
+		Status:     core.RefundPending,
+		Reason:     p.Reason,
+	})
+	if errors.Is(err, core.ErrPaymentNotRefundable) || errors.Is(err, core.ErrRefundTooLarge) {
+		return saga.Permanent(err)
+	}
+	return err
+}
+
+func (s *Service) holdRefundFunds(ctx context.Context, sg *saga.Saga) error {
+	var p refundPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	holdID, err := s.txClient.Hold(ctx, refundHoldKey(p.RefundID), p.EntityType, p.EntityID, p.Amount, p.Currency)
+	if err != nil {
+		s.log.Error("failed to hold refund funds", "error", err, "refund_id", p.RefundID)
+		return transferError(err)
+	}
+	return s.repo.SetRefundHoldID(ctx, p.RefundID, holdID)
+}
+
+func (s *Service) createProviderRefund(ctx context.Context, sg *saga.Saga) error {
+	var p refundPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	payment, err := s.repo.GetByID(ctx, p.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	desc := p.Reason
+	if desc == "" {
+		desc = fmt.Sprintf("Возврат платежа %s", payment.ID)
+	}
+	created, err := s.provider.CreateRefund(payment.ExternalID, p.Amount.String(), p.Currency.String(), desc, p.RefundID)
+	if err != nil {
+		s.log.Error("refund creation failed", "error", err, "refund_id", p.RefundID)
+		return err
+	}
+	return s.repo.SetRefundExternalID(ctx, p.RefundID, created.ID)
+}
+
+// releaseRefundHold снимает резерв под возврат; если холд не успел создаться, освобождать нечего
+func (s *Service) releaseRefundHold(ctx context.Context, sg *saga.Saga) error {
+	var p refundIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	refund, err := s.repo.GetRefund(ctx, p.RefundID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if refund.HoldID == nil {
+		return nil
+	}
+
+	s.log.Info("releasing refund hold", "refund_id", refund.ID, "hold_id", *refund.HoldID)
+	if err := s.txClient.ReleaseHold(ctx, *refund.HoldID); err != nil {
+		s.log.Error("failed to release refund hold", "error", err, "refund_id", refund.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) captureRefundHold(ctx context.Context, sg *saga.Saga) error {
+	var p refundIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	refund, err := s.repo.GetRefund(ctx, p.RefundID)
+	if err != nil {
+		return err
+	}
+	if refund.HoldID == nil {
+		return saga.Permanent(fmt.Errorf("refund %s has no hold", refund.ID))
+	}
+
+	s.log.Info("capturing refund hold", "refund_id", refund.ID, "hold_id", *refund.HoldID)
+	if err := s.txClient.CaptureHold(ctx, *refund.HoldID); err != nil {
+		s.log.Error("failed to capture refund hold", "error", err, "refund_id", refund.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) markRefundSucceeded(ctx context.Context, sg *saga.Saga) error {
+	var p refundIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	return s.repo.UpdateRefundStatus(ctx, p.RefundID, core.RefundSucceeded)
+}
+
+func (s *Service) markRefundFailed(ctx context.Context, sg *saga.Saga) error {
+	var p refundIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	return s.repo.UpdateRefundStatus(ctx, p.RefundID, core.RefundFailed)
+}
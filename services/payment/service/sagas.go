@@ -0,0 +1,418 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/clients"
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+	"github.com/Starostina-elena/investment_platform/services/payment/saga"
+)
+
+const (
+	sagaDeposit           = "deposit"
+	sagaWithdrawal        = "withdrawal"
+	sagaWithdrawalCapture = "withdrawal_capture"
+	sagaWithdrawalRelease = "withdrawal_release"
+	sagaWithdrawalRefund  = "withdrawal_refund"
+	sagaWithdrawalPayout  = "withdrawal_payout"
+	sagaWithdrawalReject  = "withdrawal_reject"
+)
+
+// payoutMaxAttempts — сколько раз повторяется создание выплаты без определенного ответа
+// провайдера. Повтор идет с тем же ключом идемпотентности и вернет уже созданную выплату.
+const payoutMaxAttempts = 10
+
+type depositPayload struct {
+	PaymentID string `json:"payment_id"`
+}
+
+type withdrawalPayload struct {
+	WithdrawalID string         `json:"withdrawal_id"`
+	EntityType   string         `json:"entity_type"`
+	EntityID     int            `json:"entity_id"`
+	Amount       money.Amount   `json:"amount"`
+	Currency     money.Currency `json:"currency"`
+	Destination  string         `json:"destination"`
+	// DestinationID — сохраненные реквизиты; если пусто, Destination — токен карты
+	DestinationID *int64 `json:"destination_id,omitempty"`
+	RequestedBy   int    `json:"requested_by"`
+	// Review — причины ручной проверки; если не пусто, выплата ждет решения администратора
+	Review    []string `json:"review,omitempty"`
+	Approvals int      `json:"approvals,omitempty"`
+}
+
+type withdrawalIDPayload struct {
+	WithdrawalID string `json:"withdrawal_id"`
+}
+
+// transferError помечает отказ сервиса транзакций как неустранимую ошибку шага
+func transferError(err error) error {
+	if errors.Is(err, clients.ErrTransferRejected) {
+		return saga.Permanent(err)
+	}
+	return err
+}
+
+func (s *Service) registerSagas() {
+	// деньги от пользователя уже получены, поэтому зачисление повторяем долго
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaDeposit,
+		MaxAttempts: 20,
+		Steps: []saga.Step{
+			{Name: "credit_wallet", Action: s.creditWallet},
+			{Name: "mark_succeeded", Action: s.markPaymentSucceeded},
+		},
+	})
+
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaWithdrawal,
+		MaxAttempts: payoutMaxAttempts,
+		Steps: []saga.Step{
+			{Name: "create_withdrawal", Action: s.createWithdrawal, Compensate: s.markWithdrawalFailed},
+			{Name: "hold_funds", Action: s.holdFunds, Compensate: s.releaseHold},
+			{Name: "create_payout", Action: s.createPayout},
+		},
+	})
+
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaWithdrawalCapture,
+		MaxAttempts: 20,
+		Steps: []saga.Step{
+			{Name: "capture_hold", Action: s.captureHold},
+			{Name: "mark_succeeded", Action: s.markWithdrawalSucceeded},
+		},
+	})
+
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaWithdrawalRelease,
+		MaxAttempts: 20,
+		Steps: []saga.Step{
+			{Name: "release_hold", Action: s.releaseHold},
+			{Name: "mark_failed", Action: s.markWithdrawalFailed},
+		},
+	})
+
+	// выплата по выводу, одобренному при ручной проверке; если провайдер отказал,
+	// холд освобождается, как и у обычного вывода
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaWithdrawalPayout,
+		MaxAttempts: payoutMaxAttempts,
+		Steps: []saga.Step{
+			{Name: "check_approved", Action: s.checkWithdrawalApproved, Compensate: s.releaseAndFailWithdrawal},
+			{Name: "create_payout", Action: s.createApprovedPayout},
+		},
+	})
+
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaWithdrawalReject,
+		MaxAttempts: 20,
+		Steps: []saga.Step{
+			{Name: "release_hold", Action: s.releaseHold},
+			{Name: "mark_rejected", Action: s.markWithdrawalRejected},
+		},
+	})
+
+	// выводы, созданные до холдов, списывались сразу; при неудачной выплате им нужен возврат
+	s.sagas.Register(saga.Definition{
+		Kind:        sagaWithdrawalRefund,
+		MaxAttempts: 20,
+		Steps: []saga.Step{
+			{Name: "refund_wallet", Action: s.refundWithdrawal},
+			{Name: "mark_failed", Action: s.markWithdrawalFailed},
+		},
+	})
+
+	s.registerRefundSagas()
+}
+
+// runDeposit зачисляет оплаченный платеж на кошелек. Если зачисление уже идет
+// (например, одновременно пришли вебхук и проверка статуса), ничего не делает.
+func (s *Service) runDeposit(ctx context.Context, payment *core.Payment) error {
+	sg, err := s.sagas.StartAndRun(ctx, sagaDeposit, payment.ID, depositPayload{PaymentID: payment.ID})
+	if errors.Is(err, saga.ErrAlreadyRunning) {
+		s.log.Info("deposit already in progress", "payment_id", payment.ID)
+		return nil
+	}
+	if sg != nil && sg.Status == saga.StatusRunning {
+		// шаг упал, но будет повторен воркером
+		return nil
+	}
+	return err
+}
+
+// completeWithdrawal списывает холд после успешной выплаты и помечает вывод успешным
+func (s *Service) completeWithdrawal(ctx context.Context, withdrawal *core.Withdrawal) error {
+	if withdrawal.HoldID == nil {
+		return s.repo.UpdateWithdrawalStatusByID(ctx, withdrawal.ID, core.WithdrawalSucceeded)
+	}
+	return s.runWithdrawalSaga(ctx, sagaWithdrawalCapture, withdrawal)
+}
+
+// failWithdrawal освобождает холд после неудачной выплаты. Деньги все время оставались
+// на кошельке, поэтому возвращать их не нужно.
+func (s *Service) failWithdrawal(ctx context.Context, withdrawal *core.Withdrawal) error {
+	if withdrawal.HoldID == nil {
+		return s.runWithdrawalSaga(ctx, sagaWithdrawalRefund, withdrawal)
+	}
+	return s.runWithdrawalSaga(ctx, sagaWithdrawalRelease, withdrawal)
+}
+
+func (s *Service) runWithdrawalSaga(ctx context.Context, kind string, withdrawal *core.Withdrawal) error {
+	sg, err := s.sagas.StartAndRun(ctx, kind, withdrawal.ID, withdrawalIDPayload{WithdrawalID: withdrawal.ID})
+	if errors.Is(err, saga.ErrAlreadyRunning) {
+		s.log.Info("withdrawal saga already in progress", "kind", kind, "withdrawal_id", withdrawal.ID)
+		return nil
+	}
+	if sg != nil && sg.Status == saga.StatusRunning {
+		return nil
+	}
+	return err
+}
+
+func (s *Service) creditWallet(ctx context.Context, sg *saga.Saga) error {
+	var p depositPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	payment, err := s.repo.GetByID(ctx, p.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("crediting wallet", "entity_type", payment.EntityType, "entity_id", payment.EntityID, "amount", payment.Amount, "payment_id", payment.ID)
+	if err := s.txClient.Deposit(ctx, depositKey(payment.ID), payment.EntityType, payment.EntityID, payment.Amount, payment.Currency); err != nil {
+		s.log.Error("failed to deposit money", "error", err, "payment_id", payment.ID)
+		return transferError(err)
+	}
+	return nil
+}
+
+func (s *Service) markPaymentSucceeded(ctx context.Context, sg *saga.Saga) error {
+	var p depositPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	return s.repo.MarkSucceeded(ctx, p.PaymentID)
+}
+
+func (s *Service) createWithdrawal(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	status := core.WithdrawalPending
+	if len(p.Review) > 0 {
+		status = core.WithdrawalReview
+	}
+	err := s.repo.CreateWithdrawal(ctx, &core.Withdrawal{
+		ID:                p.WithdrawalID,
+		Amount:            p.Amount,
+		Currency:          p.Currency,
+		EntityID:          p.EntityID,
+		EntityType:        p.EntityType,
+		Status:            status,
+		Destination:       p.Destination,
+		DestinationID:     p.DestinationID,
+		RequestedBy:       p.RequestedBy,
+		ReviewReason:      joinReasons(p.Review),
+		RequiredApprovals: p.Approvals,
+	}, s.withdrawalCheck(p.EntityType))
+	if errors.Is(err, core.ErrWithdrawalLimitExceeded) || errors.Is(err, core.ErrRateUnavailable) {
+		return saga.Permanent(err)
+	}
+	return err
+}
+
+func (s *Service) holdFunds(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	// холд не истекает: деньги остаются в резерве, пока выплата на проверке или у провайдера
+	holdID, err := s.txClient.Hold(ctx, withdrawalHoldKey(p.WithdrawalID), p.EntityType, p.EntityID, p.Amount, p.Currency)
+	if err != nil {
+		s.log.Error("failed to hold funds", "error", err, "withdrawal_id", p.WithdrawalID)
+		return transferError(err)
+	}
+	return s.repo.SetWithdrawalHoldID(ctx, p.WithdrawalID, holdID)
+}
+
+func (s *Service) createPayout(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	if len(p.Review) > 0 {
+		s.log.Info("withdrawal is waiting for review", "withdrawal_id", p.WithdrawalID, "reasons", joinReasons(p.Review))
+		return nil
+	}
+
+	dest, err := s.payoutDestination(ctx, p.DestinationID, p.Destination)
+	if err != nil {
+		return err
+	}
+	amountStr := p.Amount.String()
+	desc := fmt.Sprintf("Вывод средств %s #%d", p.EntityType, p.EntityID)
+	created, err := s.provider.CreatePayout(amountStr, p.Currency.String(), desc, dest, p.WithdrawalID)
+	if err != nil {
+		return s.payoutFailed(ctx, sg, p.WithdrawalID, err)
+	}
+	return s.repo.SetWithdrawalExternalID(ctx, p.WithdrawalID, created.ID)
+}
+
+// payoutFailed решает, что делать с неудачным созданием выплаты. Отказ провайдера
+// запускает компенсации (холд освобождается). Без ответа запрос повторяется, а после
+// последней попытки вывод уходит на ручную проверку с холдом: выплата могла создаться,
+// и освобождать деньги нельзя.
+func (s *Service) payoutFailed(ctx context.Context, sg *saga.Saga, withdrawalID string, err error) error {
+	s.log.Error("payout creation failed", "error", err, "withdrawal_id", withdrawalID, "attempt", sg.Attempts+1)
+	if payoutRejected(err) {
+		return saga.Permanent(err)
+	}
+	if sg.Attempts+1 < payoutMaxAttempts {
+		return err
+	}
+	s.log.Warn("payout status is unknown, withdrawal sent to review", "withdrawal_id", withdrawalID)
+	return s.repo.ReturnWithdrawalToReview(ctx, withdrawalID, reviewPayoutUnknown)
+}
+
+// releaseHold снимает резерв под вывод. Если id холда не успел сохраниться,
+// освобождать нечего: холд истечет сам.
+func (s *Service) releaseHold(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	withdrawal, err := s.repo.GetWithdrawalByID(ctx, p.WithdrawalID)
+	if err != nil {
+		return err
+	}
+	if withdrawal.HoldID == nil {
+		return nil
+	}
+
+	s.log.Info("releasing withdrawal hold", "withdrawal_id", withdrawal.ID, "hold_id", *withdrawal.HoldID)
+	if err := s.txClient.ReleaseHold(ctx, *withdrawal.HoldID); err != nil {
+		s.log.Error("failed to release withdrawal hold", "error", err, "withdrawal_id", withdrawal.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) captureHold(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	withdrawal, err := s.repo.GetWithdrawalByID(ctx, p.WithdrawalID)
+	if err != nil {
+		return err
+	}
+	if withdrawal.HoldID == nil {
+		return saga.Permanent(fmt.Errorf("withdrawal %s has no hold", withdrawal.ID))
+	}
+
+	s.log.Info("capturing withdrawal hold", "withdrawal_id", withdrawal.ID, "hold_id", *withdrawal.HoldID)
+	if err := s.txClient.CaptureHold(ctx, *withdrawal.HoldID); err != nil {
+		s.log.Error("failed to capture withdrawal hold", "error", err, "withdrawal_id", withdrawal.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) markWithdrawalSucceeded(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	return s.repo.UpdateWithdrawalStatusByID(ctx, p.WithdrawalID, core.WithdrawalSucceeded)
+}
+
+// refundWithdrawal возвращает на кошелек деньги вывода, списанного без холда.
+// Ключ идемпотентности не дает вернуть их дважды.
+func (s *Service) refundWithdrawal(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	withdrawal, err := s.repo.GetWithdrawalByID(ctx, p.WithdrawalID)
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("refunding withdrawal", "withdrawal_id", withdrawal.ID, "amount", withdrawal.Amount)
+	if err := s.txClient.Deposit(ctx, withdrawalRefundKey(withdrawal.ID), withdrawal.EntityType, withdrawal.EntityID, withdrawal.Amount, withdrawal.Currency); err != nil {
+		s.log.Error("failed to refund withdrawal", "error", err, "withdrawal_id", withdrawal.ID)
+		return err
+	}
+	return nil
+}
+
+// checkWithdrawalApproved не дает выплатить вывод, который не прошел ручную проверку
+func (s *Service) checkWithdrawalApproved(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	withdrawal, err := s.repo.GetWithdrawalByID(ctx, p.WithdrawalID)
+	if err != nil {
+		return err
+	}
+	if withdrawal.Status != core.WithdrawalPending {
+		return saga.Permanent(fmt.Errorf("withdrawal %s is %s, not approved", withdrawal.ID, withdrawal.Status))
+	}
+	return nil
+}
+
+// createApprovedPayout создает выплату по реквизитам, сохраненным при создании вывода
+func (s *Service) createApprovedPayout(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	withdrawal, err := s.repo.GetWithdrawalByID(ctx, p.WithdrawalID)
+	if err != nil {
+		return err
+	}
+	if withdrawal.ExternalID != "" {
+		return nil
+	}
+
+	dest, err := s.payoutDestination(ctx, withdrawal.DestinationID, withdrawal.Destination)
+	if err != nil {
+		return err
+	}
+	desc := fmt.Sprintf("Вывод средств %s #%d", withdrawal.EntityType, withdrawal.EntityID)
+	created, err := s.provider.CreatePayout(withdrawal.Amount.String(), withdrawal.Currency.String(), desc, dest, withdrawal.ID)
+	if err != nil {
+		return s.payoutFailed(ctx, sg, withdrawal.ID, err)
+	}
+	return s.repo.SetWithdrawalExternalID(ctx, withdrawal.ID, created.ID)
+}
+
+// releaseAndFailWithdrawal освобождает холд одобренного вывода, если выплату так и не удалось создать
+func (s *Service) releaseAndFailWithdrawal(ctx context.Context, sg *saga.Saga) error {
+	if err := s.releaseHold(ctx, sg); err != nil {
+		return err
+	}
+	return s.markWithdrawalFailed(ctx, sg)
+}
+
+func (s *Service) markWithdrawalRejected(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	return s.repo.UpdateWithdrawalStatusByID(ctx, p.WithdrawalID, core.WithdrawalRejected)
+}
+
+func (s *Service) markWithdrawalFailed(ctx context.Context, sg *saga.Saga) error {
+	var p withdrawalIDPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	return s.repo.UpdateWithdrawalStatusByID(ctx, p.WithdrawalID, core.WithdrawalFailed)
+}
@@ -2,56 +2,116 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/Starostina-elena/investment_platform/services/payment/clients"
 	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/metrics"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
 	"github.com/Starostina-elena/investment_platform/services/payment/repo"
-	"github.com/Starostina-elena/investment_platform/services/payment/yookassa"
+	"github.com/Starostina-elena/investment_platform/services/payment/saga"
 	"github.com/google/uuid"
 )
 
 type Service struct {
-	repo     *repo.Repo
-	yookassa *yookassa.Client
-	txClient *clients.TransactionClient
-	log      slog.Logger
+	repo      *repo.Repo
+	provider  provider.PaymentProvider
+	txClient  *clients.TransactionClient
+	sagas     *saga.Orchestrator
+	orgClient *clients.OrgClient
+	policy    WithdrawalPolicy
+	metrics   *metrics.Registry
+	log       slog.Logger
 }
 
-func NewService(repo *repo.Repo, yc *yookassa.Client, tc *clients.TransactionClient, log slog.Logger) *Service {
-	return &Service{repo: repo, yookassa: yc, txClient: tc, log: log}
+func NewService(repo *repo.Repo, pp provider.PaymentProvider, tc *clients.TransactionClient, oc *clients.OrgClient, sagas *saga.Orchestrator, policy WithdrawalPolicy, m *metrics.Registry, log slog.Logger) *Service {
+	s := &Service{repo: repo, provider: pp, txClient: tc, orgClient: oc, sagas: sagas, policy: policy, metrics: m, log: log}
+	s.registerSagas()
+	return s
 }
 
-func (s *Service) InitPayment(ctx context.Context, entityType string, entityID int, amount float64, returnURL string) (string, error) {
-	amountStr := fmt.Sprintf("%.2f", amount)
-	desc := fmt.Sprintf("Пополнение кошелька %s #%d", entityType, entityID)
+// Ключи идемпотентности для переводов: по одному на каждое движение денег,
+// чтобы повторная обработка вебхука или проверки статуса не зачисляла деньги дважды
+func depositKey(paymentID string) string {
+	return "payment:" + paymentID + ":deposit"
+}
 
-	yooResp, err := s.yookassa.CreatePayment(amountStr, desc, returnURL)
-	if err != nil {
-		s.log.Error("yookassa create failed", "error", err)
+func withdrawalHoldKey(withdrawalID string) string {
+	return "withdrawal:" + withdrawalID + ":hold"
+}
+
+func withdrawalRefundKey(withdrawalID string) string {
+	return "withdrawal:" + withdrawalID + ":refund"
+}
+
+// InitPayment создает платеж у платежного провайдера; после оплаты сумма зачисляется на кошелек в той же валюте.
+// При capture = false платеж двухстадийный: после оплаты деньги только блокируются, а зачисляются после CapturePayment.
+// savePaymentMethod просит провайдера сохранить карту для следующих пополнений; с paymentMethodID
+// платеж проводится сохраненным способом без перехода на страницу оплаты, и url пустой.
+func (s *Service) InitPayment(ctx context.Context, caller core.Caller, entityType string, entityID int, amount money.Amount, currency money.Currency, returnURL string, capture, savePaymentMethod bool, paymentMethodID string) (url string, err error) {
+	paymentID := uuid.New().String()
+	defer func() {
+		s.audit(ctx, caller, auditInitPayment, entityType, entityID, paymentID, &amount, &currency, err)
+	}()
+	if err = s.authorize(ctx, caller, entityType, entityID, false); err != nil {
 		return "", err
 	}
 
+	if paymentMethodID != "" {
+		if _, err = s.paymentMethod(ctx, entityType, entityID, paymentMethodID); err != nil {
+			return "", err
+		}
+	}
+
+	desc := fmt.Sprintf("Пополнение кошелька %s #%d", entityType, entityID)
 	payment := &core.Payment{
-		ID:         uuid.New().String(),
-		ExternalID: yooResp.ID,
+		ID:         paymentID,
 		Amount:     amount,
+		Currency:   currency,
 		EntityID:   entityID,
 		EntityType: entityType,
-		Status:     core.StatusPending,
+		Capture:    capture,
+	}
+	return s.createPayment(ctx, payment, desc, returnURL, provider.PaymentOptions{
+		Capture:           capture,
+		SavePaymentMethod: savePaymentMethod && paymentMethodID == "",
+		PaymentMethodID:   paymentMethodID,
+	})
+}
+
+// createPayment создает платеж у провайдера и сохраняет его в статусе pending;
+// возвращает ссылку на страницу оплаты
+func (s *Service) createPayment(ctx context.Context, payment *core.Payment, desc, returnURL string, opts provider.PaymentOptions) (string, error) {
+	created, err := s.provider.CreatePayment(payment.Amount.String(), payment.Currency.String(), desc, returnURL, opts)
+	if err != nil {
+		s.log.Error("payment provider create failed", "error", err)
+		return "", err
 	}
 
+	expiresAt := time.Now().Add(paymentTTL)
+	payment.ExternalID = created.ID
+	payment.Status = core.StatusPending
+	payment.ExpiresAt = &expiresAt
+
 	if err := s.repo.Create(ctx, payment); err != nil {
 		s.log.Error("db save failed", "error", err)
 		return "", err
 	}
 
-	return yooResp.Confirmation.ConfirmationURL, nil
+	return created.ConfirmationURL, nil
 }
 
+// ProcessWebhook обрабатывает уведомление о платеже. Статусу из тела вебхука не доверяем:
+// платеж перечитывается у провайдера, и деньги зачисляются, только если он действительно оплачен.
 func (s *Service) ProcessWebhook(ctx context.Context, eventType string, object map[string]interface{}) error {
-	if eventType != "payment.succeeded" {
+	switch eventType {
+	case "payment.succeeded", "payment.waiting_for_capture", "payment.canceled":
+	default:
 		return nil
 	}
 
@@ -70,114 +130,119 @@ func (s *Service) ProcessWebhook(ctx context.Context, eventType string, object m
 		return nil
 	}
 
-	if err := s.repo.UpdateStatus(ctx, externalID, core.StatusSucceeded); err != nil {
+	remotePayment, err := s.provider.GetPayment(externalID)
+	if err != nil {
+		s.log.Error("failed to get payment from provider", "error", err, "external_id", externalID)
 		return err
 	}
-
-	s.log.Info("crediting wallet", "entity_type", payment.EntityType, "entity_id", payment.EntityID, "amount", payment.Amount)
-	if err := s.txClient.Deposit(ctx, payment.EntityType, payment.EntityID, payment.Amount); err != nil {
-		s.log.Error("CRITICAL: failed to deposit money after success payment", "error", err, "payment_id", payment.ID)
-		return err
+	if "payment."+remotePayment.Status != eventType {
+		s.log.Warn("webhook status is not confirmed by provider", "event", eventType, "external_id", externalID, "status", remotePayment.Status)
 	}
 
-	return nil
+	return s.syncPayment(ctx, payment, remotePayment)
 }
 
-func (s *Service) CheckPayment(ctx context.Context, paymentID string) (*core.Payment, error) {
+func (s *Service) CheckPayment(ctx context.Context, caller core.Caller, paymentID string) (_ *core.Payment, err error) {
+	var entityType string
+	var entityID int
+	defer func() {
+		s.audit(ctx, caller, auditCheckPayment, entityType, entityID, paymentID, nil, nil, err)
+	}()
+
 	payment, err := s.repo.GetByID(ctx, paymentID)
-	if err != nil {
-		s.log.Error("payment not found", "id", paymentID)
-		return nil, fmt.Errorf("payment not found")
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrPaymentNotFound
 	}
-
-	yooPayment, err := s.yookassa.GetPayment(payment.ExternalID)
 	if err != nil {
-		s.log.Error("failed to get payment from yookassa", "error", err, "external_id", payment.ExternalID)
 		return nil, err
 	}
-
-	if yooPayment.Status == "succeeded" && payment.Status != core.StatusSucceeded {
-		if err := s.repo.UpdateStatus(ctx, payment.ExternalID, core.StatusSucceeded); err != nil {
-			s.log.Error("failed to update payment status", "error", err)
-			return nil, err
-		}
-
-		s.log.Info("crediting wallet from check", "entity_type", payment.EntityType, "entity_id", payment.EntityID, "amount", payment.Amount)
-		if err := s.txClient.Deposit(ctx, payment.EntityType, payment.EntityID, payment.Amount); err != nil {
-			s.log.Error("CRITICAL: failed to deposit money after check", "error", err, "payment_id", payment.ID)
-			return nil, err
-		}
-
-		payment.Status = core.StatusSucceeded
+	entityType, entityID = payment.EntityType, payment.EntityID
+	if err = s.authorize(ctx, caller, entityType, entityID, true); err != nil {
+		return nil, err
 	}
 
-	return payment, nil
-}
+	if payment.Status == core.StatusSucceeded {
+		return payment, nil
+	}
 
-func (s *Service) ProcessPendingPayments(ctx context.Context) error {
-	payments, err := s.repo.GetPendingPayments(ctx)
+	remotePayment, err := s.provider.GetPayment(payment.ExternalID)
 	if err != nil {
-		s.log.Error("failed to get pending payments", "error", err)
-		return err
+		s.log.Error("failed to get payment from provider", "error", err, "external_id", payment.ExternalID)
+		return nil, err
 	}
 
-	for _, payment := range payments {
-		yooPayment, err := s.yookassa.GetPayment(payment.ExternalID)
-		if err != nil {
-			s.log.Error("failed to check payment status", "error", err, "payment_id", payment.ID)
-			continue
-		}
-
-		if yooPayment.Status == "succeeded" {
-			s.log.Info("payment succeeded, crediting wallet", "payment_id", payment.ID, "external_id", payment.ExternalID)
-
-			if err := s.repo.UpdateStatus(ctx, payment.ExternalID, core.StatusSucceeded); err != nil {
-				s.log.Error("failed to update status", "error", err)
-				continue
-			}
-
-			if err := s.txClient.Deposit(ctx, payment.EntityType, payment.EntityID, payment.Amount); err != nil {
-				s.log.Error("failed to deposit money", "error", err, "payment_id", payment.ID)
-				continue
-			}
-		}
+	if remotePayment.Status == provider.StatusPending {
+		return payment, nil
 	}
-
-	return nil
+	if err := s.syncPayment(ctx, payment, remotePayment); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, payment.ID)
 }
 
-func (s *Service) InitWithdrawal(ctx context.Context, entityType string, entityID int, amount float64, destination string) (string, error) {
-	amountStr := fmt.Sprintf("%.2f", amount)
-	desc := fmt.Sprintf("Вывод средств %s #%d", entityType, entityID)
-
-	yooResp, err := s.yookassa.CreatePayout(amountStr, desc, destination)
+// InitWithdrawal запускает сагу вывода: запись о выводе, холд на кошельке,
+// создание выплаты у платежного провайдера. Деньги списываются только после успешной выплаты,
+// а если выплату создать не удалось или она не прошла, холд освобождается.
+// Вывод сверх лимитов отклоняется, а подозрительный (см. reviewReasons) только резервируется
+// и ждет решения администратора в статусе review.
+// Выводить можно по токену карты destination или на сохраненные подтвержденные реквизиты destinationID.
+func (s *Service) InitWithdrawal(ctx context.Context, caller core.Caller, entityType string, entityID int, amount money.Amount, currency money.Currency, destination string, destinationID int64) (_ *core.Withdrawal, err error) {
+	payload := withdrawalPayload{
+		WithdrawalID: uuid.New().String(),
+		EntityType:   entityType,
+		EntityID:     entityID,
+		Amount:       amount,
+		Currency:     currency,
+		Destination:  destination,
+		RequestedBy:  caller.UserID,
+	}
+	defer func() {
+		s.audit(ctx, caller, auditInitWithdrawal, entityType, entityID, payload.WithdrawalID, &amount, &currency, err)
+	}()
+	if err = s.authorize(ctx, caller, entityType, entityID, false); err != nil {
+		return nil, err
+	}
+	if destinationID != 0 {
+		if _, err = s.withdrawalDestination(ctx, entityType, entityID, destinationID); err != nil {
+			return nil, err
+		}
+		payload.DestinationID = &destinationID
+		payload.Destination = ""
+	}
+	baseAmount, err := s.checkWithdrawalLimits(ctx, &core.Withdrawal{ID: payload.WithdrawalID, EntityType: entityType, EntityID: entityID, Amount: amount, Currency: currency})
 	if err != nil {
-		s.log.Error("yookassa payout creation failed", "error", err)
-		return "", err
+		return nil, err
 	}
-
-	withdrawal := &core.Withdrawal{
-		ID:         uuid.New().String(),
-		ExternalID: yooResp.ID,
-		Amount:     amount,
-		EntityID:   entityID,
-		EntityType: entityType,
-		Status:     core.WithdrawalPending,
+	if payload.Review, err = s.reviewReasons(ctx, entityType, entityID, baseAmount, payload.DestinationID, payload.Destination); err != nil {
+		return nil, err
 	}
-
-	if err := s.repo.CreateWithdrawal(ctx, withdrawal); err != nil {
-		s.log.Error("db save withdrawal failed", "error", err)
-		return "", err
+	if len(payload.Review) > 0 {
+		payload.Approvals = s.policy.RequiredApprovals(entityType, baseAmount)
+		s.log.Info("withdrawal sent to review", "withdrawal_id", payload.WithdrawalID, "reasons", joinReasons(payload.Review), "approvals", payload.Approvals)
 	}
 
-	if err := s.txClient.Withdraw(ctx, entityType, entityID, amount); err != nil {
-		s.log.Error("failed to withdraw funds", "error", err)
-		return "", err
+	sg, err := s.sagas.StartAndRun(ctx, sagaWithdrawal, payload.WithdrawalID, payload)
+	if sg == nil || sg.Status == saga.StatusFailed || sg.Status == saga.StatusCompensating {
+		s.log.Error("withdrawal failed", "error", err, "withdrawal_id", payload.WithdrawalID)
+		if err == nil {
+			err = fmt.Errorf("withdrawal %s failed", payload.WithdrawalID)
+		}
+		return nil, err
+	}
+	if err != nil {
+		// шаг будет повторен воркером, вывод остается в статусе pending
+		s.log.Warn("withdrawal step failed, will retry", "error", err, "withdrawal_id", payload.WithdrawalID)
 	}
 
-	return withdrawal.ID, nil
+	withdrawal, err := s.repo.GetWithdrawalByID(ctx, payload.WithdrawalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// запись о выводе создаст воркер при повторе саги
+		return &core.Withdrawal{ID: payload.WithdrawalID, EntityType: entityType, EntityID: entityID, Amount: amount, Currency: currency, Status: core.WithdrawalPending}, nil
+	}
+	return withdrawal, err
 }
 
+// ProcessWithdrawalWebhook обрабатывает уведомление о выплате, перечитывая ее статус у провайдера
 func (s *Service) ProcessWithdrawalWebhook(ctx context.Context, eventType string, object map[string]interface{}) error {
 	if eventType != "payout.succeeded" && eventType != "payout.failed" {
 		return nil
@@ -194,91 +259,79 @@ func (s *Service) ProcessWithdrawalWebhook(ctx context.Context, eventType string
 		return err
 	}
 
-	var status core.WithdrawalStatus
-	if eventType == "payout.succeeded" {
-		status = core.WithdrawalSucceeded
-		s.log.Info("payout succeeded", "withdrawal_id", withdrawal.ID)
-	} else {
-		status = core.WithdrawalFailed
-		s.log.Warn("payout failed", "withdrawal_id", withdrawal.ID)
-
-		if err := s.txClient.Deposit(ctx, withdrawal.EntityType, withdrawal.EntityID, withdrawal.Amount); err != nil {
-			s.log.Error("CRITICAL: failed to refund after failed payout", "error", err, "withdrawal_id", withdrawal.ID)
-			return err
-		}
+	if withdrawal.Status != core.WithdrawalPending {
+		return nil
 	}
 
-	if err := s.repo.UpdateWithdrawalStatus(ctx, externalID, status); err != nil {
+	remotePayout, err := s.provider.GetPayout(externalID)
+	if err != nil {
+		s.log.Error("failed to get payout from provider", "error", err, "external_id", externalID)
 		return err
 	}
 
+	switch remotePayout.Status {
+	case provider.StatusFailed:
+		s.log.Warn("payout failed", "withdrawal_id", withdrawal.ID)
+		if err := s.failWithdrawal(ctx, withdrawal); err != nil {
+			s.log.Error("CRITICAL: failed to release funds after failed payout", "error", err, "withdrawal_id", withdrawal.ID)
+			return err
+		}
+	case provider.StatusSucceeded:
+		s.log.Info("payout succeeded", "withdrawal_id", withdrawal.ID)
+		if err := s.completeWithdrawal(ctx, withdrawal); err != nil {
+			s.log.Error("CRITICAL: failed to capture funds after successful payout", "error", err, "withdrawal_id", withdrawal.ID)
+			return err
+		}
+	default:
+		s.log.Warn("webhook status is not confirmed by provider", "event", eventType, "external_id", externalID, "status", remotePayout.Status)
+	}
 	return nil
 }
 
-func (s *Service) CheckWithdrawal(ctx context.Context, withdrawalID string) (*core.Withdrawal, error) {
+func (s *Service) CheckWithdrawal(ctx context.Context, caller core.Caller, withdrawalID string) (_ *core.Withdrawal, err error) {
+	var entityType string
+	var entityID int
+	defer func() {
+		s.audit(ctx, caller, auditCheckWithdrawal, entityType, entityID, withdrawalID, nil, nil, err)
+	}()
+
 	withdrawal, err := s.repo.GetWithdrawalByID(ctx, withdrawalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrWithdrawalNotFound
+	}
 	if err != nil {
-		s.log.Error("withdrawal not found", "id", withdrawalID)
-		return nil, fmt.Errorf("withdrawal not found")
+		return nil, err
+	}
+	entityType, entityID = withdrawal.EntityType, withdrawal.EntityID
+	if err = s.authorize(ctx, caller, entityType, entityID, true); err != nil {
+		return nil, err
+	}
+	if withdrawal.ExternalID == "" {
+		// выплата еще не создана: вывод на проверке, отклонен или сага не дошла до провайдера
+		return withdrawal, nil
 	}
 
-	yooPayout, err := s.yookassa.GetPayout(withdrawal.ExternalID)
+	remotePayout, err := s.provider.GetPayout(withdrawal.ExternalID)
 	if err != nil {
-		s.log.Error("failed to get payout from yookassa", "error", err, "external_id", withdrawal.ExternalID)
+		s.log.Error("failed to get payout from provider", "error", err, "external_id", withdrawal.ExternalID)
 		return nil, err
 	}
 
-	if yooPayout.Status == "succeeded" && withdrawal.Status != core.WithdrawalSucceeded {
-		if err := s.repo.UpdateWithdrawalStatus(ctx, withdrawal.ExternalID, core.WithdrawalSucceeded); err != nil {
-			return nil, err
-		}
-		withdrawal.Status = core.WithdrawalSucceeded
-	} else if yooPayout.Status == "failed" && withdrawal.Status != core.WithdrawalFailed {
-		if err := s.repo.UpdateWithdrawalStatus(ctx, withdrawal.ExternalID, core.WithdrawalFailed); err != nil {
+	if remotePayout.Status == provider.StatusSucceeded && withdrawal.Status != core.WithdrawalSucceeded {
+		if err := s.completeWithdrawal(ctx, withdrawal); err != nil {
+			s.log.Error("failed to capture funds after payout", "error", err, "withdrawal_id", withdrawal.ID)
 			return nil, err
 		}
 
-		if err := s.txClient.Deposit(ctx, withdrawal.EntityType, withdrawal.EntityID, withdrawal.Amount); err != nil {
-			s.log.Error("failed to refund after failed payout", "error", err, "withdrawal_id", withdrawal.ID)
+		return s.repo.GetWithdrawalByID(ctx, withdrawal.ID)
+	} else if remotePayout.Status == provider.StatusFailed && withdrawal.Status != core.WithdrawalFailed {
+		if err := s.failWithdrawal(ctx, withdrawal); err != nil {
+			s.log.Error("failed to release funds after failed payout", "error", err, "withdrawal_id", withdrawal.ID)
+			return nil, err
 		}
 
-		withdrawal.Status = core.WithdrawalFailed
+		return s.repo.GetWithdrawalByID(ctx, withdrawal.ID)
 	}
 
 	return withdrawal, nil
 }
-
-func (s *Service) ProcessPendingWithdrawals(ctx context.Context) error {
-	withdrawals, err := s.repo.GetPendingWithdrawals(ctx)
-	if err != nil {
-		s.log.Error("failed to get pending withdrawals", "error", err)
-		return err
-	}
-
-	for _, withdrawal := range withdrawals {
-		yooPayout, err := s.yookassa.GetPayout(withdrawal.ExternalID)
-		if err != nil {
-			s.log.Error("failed to check payout status", "error", err, "withdrawal_id", withdrawal.ID)
-			continue
-		}
-
-		if yooPayout.Status == "succeeded" {
-			s.log.Info("payout succeeded, updating status", "withdrawal_id", withdrawal.ID)
-			if err := s.repo.UpdateWithdrawalStatus(ctx, withdrawal.ExternalID, core.WithdrawalSucceeded); err != nil {
-				s.log.Error("failed to update withdrawal status", "error", err)
-			}
-		} else if yooPayout.Status == "failed" {
-			s.log.Warn("payout failed, refunding", "withdrawal_id", withdrawal.ID)
-			if err := s.repo.UpdateWithdrawalStatus(ctx, withdrawal.ExternalID, core.WithdrawalFailed); err != nil {
-				s.log.Error("failed to update withdrawal status", "error", err)
-				continue
-			}
-
-			if err := s.txClient.Deposit(ctx, withdrawal.EntityType, withdrawal.EntityID, withdrawal.Amount); err != nil {
-				s.log.Error("failed to refund after failed payout", "error", err, "withdrawal_id", withdrawal.ID)
-			}
-		}
-	}
-
-	return nil
-}
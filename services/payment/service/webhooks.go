@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+)
+
+// maxWebhookAttempts — сколько раз повторять обработку упавшего вебхука
+const maxWebhookAttempts = 10
+
+var (
+	ErrBadWebhook      = errors.New("malformed webhook body")
+	ErrWebhookRejected = errors.New("webhook was rejected and cannot be replayed")
+)
+
+type webhookBody struct {
+	Type   string                 `json:"type"`
+	Event  string                 `json:"event"`
+	Object map[string]interface{} `json:"object"`
+}
+
+// RecordRejectedWebhook сохраняет вебхук, не прошедший проверку источника или подписи
+func (s *Service) RecordRejectedWebhook(ctx context.Context, sourceIP string, body []byte, reason error) {
+	errText := reason.Error()
+	e := &core.WebhookEvent{SourceIP: sourceIP, Body: string(body), Status: core.WebhookRejected, Error: &errText}
+	var parsed webhookBody
+	if json.Unmarshal(body, &parsed) == nil {
+		e.Event = parsed.Event
+		e.ObjectID, _ = parsed.Object["id"].(string)
+	}
+	if err := s.repo.SaveWebhook(ctx, e); err != nil {
+		s.log.Error("failed to save rejected webhook", "error", err, "source_ip", sourceIP)
+	}
+	s.log.Warn("webhook rejected", "reason", reason, "source_ip", sourceIP, "event", e.Event, "object_id", e.ObjectID)
+}
+
+// ReceiveWebhook сохраняет проверенный вебхук в inbox и сразу обрабатывает его.
+// Если обработка упала, запись остается в статусе failed и будет повторена.
+func (s *Service) ReceiveWebhook(ctx context.Context, sourceIP string, body []byte) error {
+	var parsed webhookBody
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		s.RecordRejectedWebhook(ctx, sourceIP, body, ErrBadWebhook)
+		return ErrBadWebhook
+	}
+
+	e := &core.WebhookEvent{SourceIP: sourceIP, Body: string(body), Status: core.WebhookReceived}
+	e.Event = parsed.Event
+	e.ObjectID, _ = parsed.Object["id"].(string)
+	if err := s.repo.SaveWebhook(ctx, e); err != nil {
+		s.log.Error("failed to save webhook", "error", err, "event", e.Event, "object_id", e.ObjectID)
+		return err
+	}
+
+	return s.processWebhook(ctx, e)
+}
+
+// ReplayWebhook повторно обрабатывает сохраненный вебхук. Это безопасно:
+// статус объекта все равно перечитывается у провайдера, а зачисления идемпотентны.
+func (s *Service) ReplayWebhook(ctx context.Context, id int64) error {
+	e, err := s.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+	if e.Status == core.WebhookRejected {
+		return ErrWebhookRejected
+	}
+	s.log.Info("replaying webhook", "webhook_id", id, "event", e.Event, "object_id", e.ObjectID)
+	return s.processWebhook(ctx, e)
+}
+
+// ReplayFailedWebhooks повторяет обработку вебхуков, упавших на прошлых попытках
+func (s *Service) ReplayFailedWebhooks(ctx context.Context) error {
+	events, err := s.repo.GetFailedWebhooks(ctx, maxWebhookAttempts, 50)
+	if err != nil {
+		s.log.Error("failed to get failed webhooks", "error", err)
+		return err
+	}
+	for i := range events {
+		if err := s.processWebhook(ctx, &events[i]); err != nil {
+			s.log.Error("webhook replay failed", "error", err, "webhook_id", events[i].ID, "attempts", events[i].Attempts+1)
+		}
+	}
+	return nil
+}
+
+func (s *Service) processWebhook(ctx context.Context, e *core.WebhookEvent) error {
+	var parsed webhookBody
+	if err := json.Unmarshal([]byte(e.Body), &parsed); err != nil {
+		return s.finishWebhook(ctx, e, fmt.Errorf("%w: %v", ErrBadWebhook, err))
+	}
+
+	var err error
+	switch parsed.Event {
+	case "payment.succeeded", "payment.waiting_for_capture", "payment.canceled":
+		err = s.ProcessWebhook(ctx, parsed.Event, parsed.Object)
+	case "payout.succeeded", "payout.failed":
+		err = s.ProcessWithdrawalWebhook(ctx, parsed.Event, parsed.Object)
+	case "refund.succeeded", "refund.canceled":
+		err = s.ProcessRefundWebhook(ctx, parsed.Event, parsed.Object)
+	}
+	return s.finishWebhook(ctx, e, err)
+}
+
+func (s *Service) finishWebhook(ctx context.Context, e *core.WebhookEvent, procErr error) error {
+	status := core.WebhookProcessed
+	var errText *string
+	if procErr != nil {
+		status = core.WebhookFailed
+		text := procErr.Error()
+		errText = &text
+	}
+	if err := s.repo.FinishWebhook(ctx, e.ID, status, errText); err != nil {
+		s.log.Error("failed to update webhook status", "error", err, "webhook_id", e.ID)
+	}
+	return procErr
+}
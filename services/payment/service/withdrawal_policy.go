@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+)
+
+// Причины отправки вывода на ручную проверку
+const (
+	reviewAmount         = "amount"
+	reviewNewDestination = "new_destination"
+	reviewKYCIncomplete  = "kyc_incomplete"
+	// reviewPayoutUnknown — провайдер так и не ответил на создание выплаты; перед решением
+	// администратор проверяет выплату в кабинете провайдера
+	reviewPayoutUnknown = "payout_unknown"
+)
+
+// WithdrawalLimits — ограничения выводов для одного типа кошелька. Суммы в базовой валюте:
+// выводы в других валютах пересчитываются по последнему курсу и считаются вместе; ноль — без ограничения.
+type WithdrawalLimits struct {
+	PerTransaction money.Amount
+	Daily          money.Amount // с начала календарного дня
+	Monthly        money.Amount // с начала календарного месяца
+	ReviewFrom     money.Amount // с этой суммы вывод идет на ручную проверку
+}
+
+type WithdrawalPolicy struct {
+	User WithdrawalLimits
+	Org  WithdrawalLimits
+	// FourEyesFrom — с этой суммы вывод организации одобряют два разных администратора
+	FourEyesFrom money.Amount
+}
+
+func DefaultWithdrawalPolicy() WithdrawalPolicy {
+	return WithdrawalPolicy{
+		User: WithdrawalLimits{
+			PerTransaction: money.FromRubles(600_000),
+			Daily:          money.FromRubles(1_000_000),
+			Monthly:        money.FromRubles(3_000_000),
+			ReviewFrom:     money.FromRubles(100_000),
+		},
+		Org: WithdrawalLimits{
+			PerTransaction: money.FromRubles(10_000_000),
+			Daily:          money.FromRubles(20_000_000),
+			Monthly:        money.FromRubles(100_000_000),
+			ReviewFrom:     money.FromRubles(1_000_000),
+		},
+		FourEyesFrom: money.FromRubles(3_000_000),
+	}
+}
+
+// WithdrawalPolicyFromEnv переопределяет ограничения по умолчанию переменными
+// WITHDRAWAL_{USER,ORG}_{MAX_TX,MAX_DAILY,MAX_MONTHLY,REVIEW_FROM} и WITHDRAWAL_FOUR_EYES_FROM
+func WithdrawalPolicyFromEnv() (WithdrawalPolicy, error) {
+	p := DefaultWithdrawalPolicy()
+	vars := map[string]*money.Amount{
+		"WITHDRAWAL_USER_MAX_TX":      &p.User.PerTransaction,
+		"WITHDRAWAL_USER_MAX_DAILY":   &p.User.Daily,
+		"WITHDRAWAL_USER_MAX_MONTHLY": &p.User.Monthly,
+		"WITHDRAWAL_USER_REVIEW_FROM": &p.User.ReviewFrom,
+		"WITHDRAWAL_ORG_MAX_TX":       &p.Org.PerTransaction,
+		"WITHDRAWAL_ORG_MAX_DAILY":    &p.Org.Daily,
+		"WITHDRAWAL_ORG_MAX_MONTHLY":  &p.Org.Monthly,
+		"WITHDRAWAL_ORG_REVIEW_FROM":  &p.Org.ReviewFrom,
+		"WITHDRAWAL_FOUR_EYES_FROM":   &p.FourEyesFrom,
+	}
+	for name, dst := range vars {
+		env := os.Getenv(name)
+		if env == "" {
+			continue
+		}
+		a, err := money.Parse(env)
+		if err != nil || a.IsNegative() {
+			return p, fmt.Errorf("invalid %s %q", name, env)
+		}
+		*dst = a
+	}
+	return p, nil
+}
+
+func (p WithdrawalPolicy) limits(entityType string) WithdrawalLimits {
+	if entityType == "org" {
+		return p.Org
+	}
+	return p.User
+}
+
+// CheckLimits проверяет вывод amount с учетом уже выведенного за день и за месяц.
+// Все суммы в базовой валюте.
+func (p WithdrawalPolicy) CheckLimits(entityType string, amount, daily, monthly money.Amount) error {
+	l := p.limits(entityType)
+	base := money.BaseCurrency
+	switch {
+	case l.PerTransaction.IsPositive() && amount > l.PerTransaction:
+		return fmt.Errorf("%w: at most %s %s per withdrawal", core.ErrWithdrawalLimitExceeded, l.PerTransaction, base)
+	case l.Daily.IsPositive() && daily+amount > l.Daily:
+		return fmt.Errorf("%w: at most %s %s per day, %s already withdrawn", core.ErrWithdrawalLimitExceeded, l.Daily, base, daily)
+	case l.Monthly.IsPositive() && monthly+amount > l.Monthly:
+		return fmt.Errorf("%w: at most %s %s per month, %s already withdrawn", core.ErrWithdrawalLimitExceeded, l.Monthly, base, monthly)
+	}
+	return nil
+}
+
+// RequiredApprovals — сколько администраторов должны одобрить вывод на проверке;
+// amount здесь и в ReviewReasons — в базовой валюте
+func (p WithdrawalPolicy) RequiredApprovals(entityType string, amount money.Amount) int {
+	if entityType == "org" && p.FourEyesFrom.IsPositive() && amount >= p.FourEyesFrom {
+		return 2
+	}
+	return 1
+}
+
+// ReviewReasons — почему вывод нужно проверить вручную; пусто, если можно выплачивать сразу
+func (p WithdrawalPolicy) ReviewReasons(entityType string, amount money.Amount, newDestination, kycIncomplete bool) []string {
+	var reasons []string
+	l := p.limits(entityType)
+	if (l.ReviewFrom.IsPositive() && amount >= l.ReviewFrom) || p.RequiredApprovals(entityType, amount) > 1 {
+		reasons = append(reasons, reviewAmount)
+	}
+	if newDestination {
+		reasons = append(reasons, reviewNewDestination)
+	}
+	if kycIncomplete {
+		reasons = append(reasons, reviewKYCIncomplete)
+	}
+	return reasons
+}
+
+func joinReasons(reasons []string) string {
+	return strings.Join(reasons, ",")
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+)
+
+func TestCheckLimits(t *testing.T) {
+	p := DefaultWithdrawalPolicy()
+	tests := []struct {
+		name       string
+		entityType string
+		amount     money.Amount
+		daily      money.Amount
+		monthly    money.Amount
+		wantErr    bool
+	}{
+		{"within limits", "user", money.FromRubles(50_000), 0, 0, false},
+		{"per transaction", "user", money.FromRubles(600_001), 0, 0, true},
+		{"daily", "user", money.FromRubles(500_000), money.FromRubles(500_001), money.FromRubles(500_001), true},
+		{"monthly", "user", money.FromRubles(100_000), 0, money.FromRubles(2_950_000), true},
+		{"org has own limits", "org", money.FromRubles(5_000_000), 0, 0, false},
+	}
+	for _, tt := range tests {
+		err := p.CheckLimits(tt.entityType, tt.amount, tt.daily, tt.monthly)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckLimits() %s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, core.ErrWithdrawalLimitExceeded) {
+			t.Errorf("CheckLimits() %s: error = %v, want ErrWithdrawalLimitExceeded", tt.name, err)
+		}
+	}
+}
+
+func TestCheckLimitsZeroIsUnlimited(t *testing.T) {
+	p := WithdrawalPolicy{}
+	if err := p.CheckLimits("user", money.FromRubles(1_000_000_000), 0, 0); err != nil {
+		t.Errorf("CheckLimits() error = %v, want nil", err)
+	}
+}
+
+func TestReviewReasons(t *testing.T) {
+	p := DefaultWithdrawalPolicy()
+	tests := []struct {
+		name           string
+		entityType     string
+		amount         money.Amount
+		newDestination bool
+		kycIncomplete  bool
+		want           []string
+	}{
+		{"small to known destination", "user", money.FromRubles(1_000), false, false, nil},
+		{"large amount", "user", money.FromRubles(100_000), false, false, []string{reviewAmount}},
+		{"new destination", "user", money.FromRubles(1_000), true, false, []string{reviewNewDestination}},
+		{"org without documents", "org", money.FromRubles(1_000), false, true, []string{reviewKYCIncomplete}},
+		{"everything", "org", money.FromRubles(5_000_000), true, true, []string{reviewAmount, reviewNewDestination, reviewKYCIncomplete}},
+	}
+	for _, tt := range tests {
+		got := p.ReviewReasons(tt.entityType, tt.amount, tt.newDestination, tt.kycIncomplete)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReviewReasons() %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequiredApprovals(t *testing.T) {
+	p := DefaultWithdrawalPolicy()
+	if got := p.RequiredApprovals("org", money.FromRubles(3_000_000)); got != 2 {
+		t.Errorf("RequiredApprovals() large org payout = %d, want 2", got)
+	}
+	if got := p.RequiredApprovals("org", money.FromRubles(1_000_000)); got != 1 {
+		t.Errorf("RequiredApprovals() org payout = %d, want 1", got)
+	}
+	if got := p.RequiredApprovals("user", money.FromRubles(3_000_000)); got != 1 {
+		t.Errorf("RequiredApprovals() user payout = %d, want 1", got)
+	}
+}
+
+func TestWithdrawalPolicyFromEnv(t *testing.T) {
+	t.Setenv("WITHDRAWAL_USER_MAX_TX", "1000.50")
+	t.Setenv("WITHDRAWAL_FOUR_EYES_FROM", "0")
+
+	p, err := WithdrawalPolicyFromEnv()
+	if err != nil {
+		t.Fatalf("WithdrawalPolicyFromEnv() error = %v", err)
+	}
+	if p.User.PerTransaction != money.MustParse("1000.50") {
+		t.Errorf("WithdrawalPolicyFromEnv() User.PerTransaction = %s, want 1000.50", p.User.PerTransaction)
+	}
+	if !p.FourEyesFrom.IsZero() {
+		t.Errorf("WithdrawalPolicyFromEnv() FourEyesFrom = %s, want 0", p.FourEyesFrom)
+	}
+
+	t.Setenv("WITHDRAWAL_ORG_MAX_DAILY", "-1")
+	if _, err := WithdrawalPolicyFromEnv(); err == nil {
+		t.Errorf("WithdrawalPolicyFromEnv() with negative limit: error = nil, want error")
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/payment/core"
+	"github.com/Starostina-elena/investment_platform/services/payment/money"
+	"github.com/Starostina-elena/investment_platform/services/payment/repo"
+)
+
+// checkWithdrawalLimits заранее сверяет вывод с лимитами на операцию, день и месяц, чтобы
+// не запускать сагу зря, и возвращает сумму вывода в базовой валюте. Окончательно лимиты
+// проверяются при записи вывода (withdrawalCheck).
+func (s *Service) checkWithdrawalLimits(ctx context.Context, w *core.Withdrawal) (money.Amount, error) {
+	totals, err := s.repo.WithdrawalTotals(ctx, w, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return totals.Amount, s.withdrawalCheck(w.EntityType)(totals)
+}
+
+// withdrawalCheck — проверка лимитов, которую repo.CreateWithdrawal выполняет под блокировкой кошелька
+func (s *Service) withdrawalCheck(entityType string) repo.WithdrawalCheck {
+	return func(t repo.WithdrawalTotals) error {
+		return s.policy.CheckLimits(entityType, t.Amount, t.Daily, t.Monthly)
+	}
+}
+
+// reviewReasons собирает причины ручной проверки: крупная сумма (baseAmount — в базовой валюте),
+// первый вывод на эти реквизиты, незавершенная регистрация организации (документы не загружены)
+func (s *Service) reviewReasons(ctx context.Context, entityType string, entityID int, baseAmount money.Amount, destinationID *int64, destination string) ([]string, error) {
+	known, err := s.repo.HasSucceededWithdrawalTo(ctx, entityType, entityID, destinationID, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	kycIncomplete := false
+	if entityType == "org" {
+		completed, err := s.orgClient.IsRegistrationCompleted(ctx, entityID)
+		if err != nil {
+			s.log.Error("failed to check organisation registration", "error", err, "org_id", entityID)
+			return nil, err
+		}
+		kycIncomplete = !completed
+	}
+
+	return s.policy.ReviewReasons(entityType, baseAmount, !known, kycIncomplete), nil
+}
+
+// GetWithdrawalsForReview — очередь выводов, ждущих решения администратора
+func (s *Service) GetWithdrawalsForReview(ctx context.Context, caller core.Caller) ([]core.Withdrawal, error) {
+	if !caller.Admin || caller.Banned {
+		return nil, core.ErrForbidden
+	}
+	return s.repo.GetWithdrawalsInReview(ctx)
+}
+
+// GetWithdrawalDecisions — история решений по выводу
+func (s *Service) GetWithdrawalDecisions(ctx context.Context, caller core.Caller, withdrawalID string) ([]core.WithdrawalDecision, error) {
+	if !caller.Admin || caller.Banned {
+		return nil, core.ErrForbidden
+	}
+	return s.repo.GetWithdrawalDecisions(ctx, withdrawalID)
+}
+
+// ApproveWithdrawal одобряет вывод. Когда набирается нужное число одобрений
+// разных администраторов, создается выплата.
+func (s *Service) ApproveWithdrawal(ctx context.Context, caller core.Caller, withdrawalID, comment string) (*core.Withdrawal, error) {
+	return s.decideWithdrawal(ctx, caller, withdrawalID, core.DecisionApprove, comment)
+}
+
+// RejectWithdrawal отклоняет вывод и освобождает зарезервированные деньги
+func (s *Service) RejectWithdrawal(ctx context.Context, caller core.Caller, withdrawalID, comment string) (*core.Withdrawal, error) {
+	return s.decideWithdrawal(ctx, caller, withdrawalID, core.DecisionReject, comment)
+}
+
+func (s *Service) decideWithdrawal(ctx context.Context, caller core.Caller, withdrawalID string, decision core.WithdrawalDecisionType, comment string) (_ *core.Withdrawal, err error) {
+	var entityType string
+	var entityID int
+	defer func() {
+		action := auditApproveWithdrawal
+		if decision == core.DecisionReject {
+			action = auditRejectWithdrawal
+		}
+		s.audit(ctx, caller, action, entityType, entityID, withdrawalID, nil, nil, err)
+	}()
+	if !caller.Admin || caller.Banned {
+		return nil, core.ErrForbidden
+	}
+
+	withdrawal, err := s.repo.DecideWithdrawal(ctx, &core.WithdrawalDecision{
+		WithdrawalID: withdrawalID,
+		AdminID:      caller.UserID,
+		Decision:     decision,
+		Comment:      comment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	entityType, entityID = withdrawal.EntityType, withdrawal.EntityID
+	s.log.Info("withdrawal decision saved", "withdrawal_id", withdrawal.ID, "admin_id", caller.UserID, "decision", decision,
+		"approvals", withdrawal.Approvals, "required", withdrawal.RequiredApprovals, "status", withdrawal.Status)
+
+	switch withdrawal.Status {
+	case core.WithdrawalPending:
+		err = s.runWithdrawalSaga(ctx, sagaWithdrawalPayout, withdrawal)
+	case core.WithdrawalRejected:
+		err = s.runWithdrawalSaga(ctx, sagaWithdrawalReject, withdrawal)
+	}
+	if err != nil {
+		// решение уже сохранено; если провайдер отказал, сага освободила холд и вывод стал
+		// failed, а если не ответил — вывод вернулся на проверку; это видно по статусу в ответе
+		s.log.Error("withdrawal saga failed after decision", "error", err, "withdrawal_id", withdrawal.ID)
+	}
+	return s.repo.GetWithdrawalByID(ctx, withdrawal.ID)
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	ErrSourceNotAllowed = errors.New("webhook source ip is not allowed")
+	ErrBadSignature     = errors.New("webhook signature is invalid")
+)
+
+// SignatureHeader — заголовок с HMAC-SHA256 тела запроса в hex. Если его нет,
+// секрет проверяется по параметру token в URL уведомления, заданном в кабинете ЮKassa.
+const SignatureHeader = "X-Webhook-Signature"
+
+// DefaultAllowedIPs — адреса, с которых ЮKassa отправляет уведомления
+// (https://yookassa.ru/developers/using-api/webhooks#ip)
+var DefaultAllowedIPs = []string{
+	"185.71.76.0/27",
+	"185.71.77.0/27",
+	"77.75.153.0/25",
+	"77.75.156.11/32",
+	"77.75.156.35/32",
+	"77.75.154.128/25",
+	"2a02:5180::/32",
+}
+
+// Verifier проверяет, что вебхук пришел от ЮKassa: адрес отправителя из списка
+// разрешенных и секрет (подпись) совпадает. Пустой секрет отключает проверку подписи.
+type Verifier struct {
+	allowed []*net.IPNet
+	proxies []*net.IPNet
+	secret  []byte
+}
+
+// NewVerifier разбирает списки подсетей. Адрес из X-Real-IP учитывается, только
+// если запрос пришел с одного из proxies (например, из nginx), иначе его можно подделать.
+func NewVerifier(allowed, proxies []string, secret string) (*Verifier, error) {
+	v := &Verifier{secret: []byte(secret)}
+	var err error
+	if v.allowed, err = parseNets(allowed); err != nil {
+		return nil, err
+	}
+	if v.proxies, err = parseNets(proxies); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// NewVerifierFromEnv читает YOOKASSA_WEBHOOK_IPS, WEBHOOK_TRUSTED_PROXIES
+// (подсети через запятую) и YOOKASSA_WEBHOOK_SECRET
+func NewVerifierFromEnv() (*Verifier, error) {
+	allowed := DefaultAllowedIPs
+	if env := os.Getenv("YOOKASSA_WEBHOOK_IPS"); env != "" {
+		allowed = splitList(env)
+	}
+	return NewVerifier(allowed, splitList(os.Getenv("WEBHOOK_TRUSTED_PROXIES")), os.Getenv("YOOKASSA_WEBHOOK_SECRET"))
+}
+
+// Allow добавляет подсети к списку разрешенных отправителей
+func (v *Verifier) Allow(subnets ...string) error {
+	nets, err := parseNets(subnets)
+	if err != nil {
+		return err
+	}
+	v.allowed = append(v.allowed, nets...)
+	return nil
+}
+
+// HasSecret — задан ли секрет для проверки подписи
+func (v *Verifier) HasSecret() bool {
+	return len(v.secret) > 0
+}
+
+// SourceIP — адрес отправителя с учетом доверенных прокси
+func (v *Verifier) SourceIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && contains(v.proxies, ip) {
+		if real := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); real != nil {
+			return real
+		}
+	}
+	return ip
+}
+
+// Verify проверяет адрес отправителя и подпись тела body
+func (v *Verifier) Verify(r *http.Request, body []byte) error {
+	ip := v.SourceIP(r)
+	if ip == nil || !contains(v.allowed, ip) {
+		return ErrSourceNotAllowed
+	}
+	if !v.HasSecret() {
+		return nil
+	}
+
+	if sig := r.Header.Get(SignatureHeader); sig != "" {
+		got, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+		if err != nil {
+			return ErrBadSignature
+		}
+		if !hmac.Equal(got, Sign(v.secret, body)) {
+			return ErrBadSignature
+		}
+		return nil
+	}
+
+	token := r.URL.Query().Get("token")
+	if subtle.ConstantTimeCompare([]byte(token), v.secret) != 1 {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Sign считает HMAC-SHA256 тела вебхука
+func Sign(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func parseNets(list []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, s := range list {
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q: %w", s, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
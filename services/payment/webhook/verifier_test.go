@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":"payment.succeeded","object":{"id":"2c5f"}}`)
+	v, err := NewVerifier(DefaultAllowedIPs, []string{"10.0.0.0/8"}, "s3cret")
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	signature := hex.EncodeToString(Sign([]byte("s3cret"), body))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		realIP     string
+		url        string
+		signature  string
+		want       error
+	}{
+		{"signed from yookassa", "185.71.76.5:443", "", "/pay/webhook", signature, nil},
+		{"token from yookassa", "77.75.156.11:443", "", "/pay/webhook?token=s3cret", "", nil},
+		{"through trusted proxy", "10.1.2.3:5000", "185.71.77.10", "/pay/webhook", signature, nil},
+		{"unknown source", "8.8.8.8:443", "", "/pay/webhook", signature, ErrSourceNotAllowed},
+		{"spoofed real ip", "8.8.8.8:443", "185.71.76.5", "/pay/webhook", signature, ErrSourceNotAllowed},
+		{"wrong signature", "185.71.76.5:443", "", "/pay/webhook", "deadbeef", ErrBadSignature},
+		{"wrong token", "185.71.76.5:443", "", "/pay/webhook?token=guess", "", ErrBadSignature},
+		{"no secret", "185.71.76.5:443", "", "/pay/webhook", "", ErrBadSignature},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", tt.url, nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if tt.signature != "" {
+			r.Header.Set(SignatureHeader, tt.signature)
+		}
+		if got := v.Verify(r, body); got != tt.want {
+			t.Errorf("Verify() %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyWithoutSecret(t *testing.T) {
+	v, err := NewVerifier([]string{"185.71.76.0/27"}, nil, "")
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	r := httptest.NewRequest("POST", "/pay/webhook", nil)
+	r.RemoteAddr = "185.71.76.1:443"
+	if err := v.Verify(r, []byte("{}")); err != nil {
+		t.Errorf("Verify() without secret = %v, want nil", err)
+	}
+}
+
+func TestVerifierFromEnvBehindNginx(t *testing.T) {
+	t.Setenv("YOOKASSA_WEBHOOK_IPS", "")
+	t.Setenv("WEBHOOK_TRUSTED_PROXIES", "172.28.0.0/16")
+	t.Setenv("YOOKASSA_WEBHOOK_SECRET", "s3cret")
+	v, err := NewVerifierFromEnv()
+	if err != nil {
+		t.Fatalf("NewVerifierFromEnv() error = %v", err)
+	}
+
+	// nginx в сети docker-compose передает адрес ЮKassa в X-Real-IP
+	r := httptest.NewRequest("POST", "/pay/webhook?token=s3cret", nil)
+	r.RemoteAddr = "172.28.0.4:41000"
+	r.Header.Set("X-Real-IP", "185.71.76.5")
+	if err := v.Verify(r, []byte("{}")); err != nil {
+		t.Errorf("Verify() through nginx = %v, want nil", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/Starostina-elena/investment_platform/services/payment/provider"
 	"github.com/google/uuid"
 )
 
@@ -19,6 +20,7 @@ type Client struct {
 	PayoutAPIKey string
 	APIURL       string
 	PayoutAPIURL string
+	RefundAPIURL string
 	HTTP         *http.Client
 }
 
@@ -39,10 +41,15 @@ func NewClient() *Client {
 		PayoutAPIKey: payoutAPIKey,
 		APIURL:       "https://api.yookassa.ru/v3/payments",
 		PayoutAPIURL: "https://api.yookassa.ru/v3/payouts",
+		RefundAPIURL: "https://api.yookassa.ru/v3/refunds",
 		HTTP:         &http.Client{},
 	}
 }
 
+func (c *Client) Name() string {
+	return "yookassa"
+}
+
 type Amount struct {
 	Value    string `json:"value"`
 	Currency string `json:"currency"`
@@ -54,10 +61,12 @@ type Confirmation struct {
 }
 
 type CreatePaymentRequest struct {
-	Amount       Amount       `json:"amount"`
-	Capture      bool         `json:"capture"`
-	Confirmation Confirmation `json:"confirmation"`
-	Description  string       `json:"description"`
+	Amount            Amount        `json:"amount"`
+	Capture           bool          `json:"capture"`
+	Confirmation      *Confirmation `json:"confirmation,omitempty"`
+	Description       string        `json:"description"`
+	SavePaymentMethod bool          `json:"save_payment_method,omitempty"`
+	PaymentMethodID   string        `json:"payment_method_id,omitempty"`
 }
 
 type CreatePaymentResponse struct {
@@ -68,20 +77,43 @@ type CreatePaymentResponse struct {
 		Type            string `json:"type"`
 		ConfirmationURL string `json:"confirmation_url"`
 	} `json:"confirmation"`
+	CancellationDetails struct {
+		Party  string `json:"party"`
+		Reason string `json:"reason"`
+	} `json:"cancellation_details"`
+	PaymentMethod *provider.PaymentMethod `json:"payment_method"`
+}
+
+func (r *CreatePaymentResponse) payment() *provider.Payment {
+	return &provider.Payment{
+		ID:                 r.ID,
+		Status:             r.Status,
+		Paid:               r.Paid,
+		ConfirmationURL:    r.Confirmation.ConfirmationURL,
+		CancellationReason: r.CancellationDetails.Reason,
+		PaymentMethod:      r.PaymentMethod,
+	}
 }
 
-func (c *Client) CreatePayment(amount string, description string, returnURL string) (*CreatePaymentResponse, error) {
+// CreatePayment создает платеж. При Capture = false деньги после оплаты только
+// блокируются на карте, и платеж ждет CapturePayment или CancelPayment.
+// Платеж сохраненным способом (PaymentMethodID) проходит без подтверждения пользователем.
+func (c *Client) CreatePayment(amount string, currency string, description string, returnURL string, opts provider.PaymentOptions) (*provider.Payment, error) {
 	reqBody := CreatePaymentRequest{
 		Amount: Amount{
 			Value:    amount,
-			Currency: "RUB",
+			Currency: currency,
 		},
-		Capture: true,
-		Confirmation: Confirmation{
+		Capture:           opts.Capture,
+		Description:       description,
+		SavePaymentMethod: opts.SavePaymentMethod,
+		PaymentMethodID:   opts.PaymentMethodID,
+	}
+	if opts.PaymentMethodID == "" {
+		reqBody.Confirmation = &Confirmation{
 			Type:      "redirect",
 			ReturnURL: returnURL,
-		},
-		Description: description,
+		}
 	}
 
 	bodyBytes, _ := json.Marshal(reqBody)
@@ -91,8 +123,12 @@ func (c *Client) CreatePayment(amount string, description string, returnURL stri
 	}
 
 	auth := base64.StdEncoding.EncodeToString([]byte(c.ShopID + ":" + c.SecretKey))
+	idempotenceKey := opts.IdempotenceKey
+	if idempotenceKey == "" {
+		idempotenceKey = uuid.New().String()
+	}
 	req.Header.Set("Authorization", "Basic "+auth)
-	req.Header.Set("Idempotence-Key", uuid.New().String())
+	req.Header.Set("Idempotence-Key", idempotenceKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	fmt.Printf("YooKassa CreatePayment:\n")
@@ -115,10 +151,10 @@ func (c *Client) CreatePayment(amount string, description string, returnURL stri
 		return nil, err
 	}
 
-	return &result, nil
+	return result.payment(), nil
 }
 
-func (c *Client) GetPayment(paymentID string) (*CreatePaymentResponse, error) {
+func (c *Client) GetPayment(paymentID string) (*provider.Payment, error) {
 	req, err := http.NewRequest("GET", c.APIURL+"/"+paymentID, nil)
 	if err != nil {
 		return nil, err
@@ -144,14 +180,66 @@ func (c *Client) GetPayment(paymentID string) (*CreatePaymentResponse, error) {
 		return nil, err
 	}
 
-	return &result, nil
+	return result.payment(), nil
+}
+
+type CapturePaymentRequest struct {
+	Amount Amount `json:"amount"`
+}
+
+// CapturePayment подтверждает платеж в статусе waiting_for_capture. Сумма может
+// быть меньше оплаченной, тогда остаток вернется покупателю.
+func (c *Client) CapturePayment(paymentID string, amount string, currency string, idempotenceKey string) (*provider.Payment, error) {
+	bodyBytes, _ := json.Marshal(CapturePaymentRequest{Amount: Amount{Value: amount, Currency: currency}})
+	return c.paymentAction(paymentID, "capture", bodyBytes, idempotenceKey)
+}
+
+// CancelPayment отменяет платеж в статусе waiting_for_capture
+func (c *Client) CancelPayment(paymentID string, idempotenceKey string) (*provider.Payment, error) {
+	return c.paymentAction(paymentID, "cancel", []byte("{}"), idempotenceKey)
+}
+
+func (c *Client) paymentAction(paymentID, action string, body []byte, idempotenceKey string) (*provider.Payment, error) {
+	req, err := http.NewRequest("POST", c.APIURL+"/"+paymentID+"/"+action, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte(c.ShopID + ":" + c.SecretKey))
+	req.Header.Set("Authorization", "Basic "+auth)
+	req.Header.Set("Idempotence-Key", idempotenceKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("yookassa %s error: %s", action, string(respBody))
+	}
+
+	var result CreatePaymentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result.payment(), nil
 }
 
 type CreatePayoutRequest struct {
-	Amount      Amount            `json:"amount"`
-	Description string            `json:"description"`
-	PayoutToken string            `json:"payout_token"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
+	Amount                Amount                 `json:"amount"`
+	Description           string                 `json:"description"`
+	PayoutToken           string                 `json:"payout_token,omitempty"`
+	PayoutDestinationData *PayoutDestinationData `json:"payout_destination_data,omitempty"`
+	Metadata              map[string]string      `json:"metadata,omitempty"`
+}
+
+type PayoutDestinationData struct {
+	Type          string `json:"type"`
+	AccountNumber string `json:"account_number,omitempty"`
 }
 
 type PayoutResponse struct {
@@ -159,14 +247,24 @@ type PayoutResponse struct {
 	Status string `json:"status"`
 }
 
-func (c *Client) CreatePayout(amount string, description string, payoutToken string) (*PayoutResponse, error) {
+// CreatePayout создает выплату. idempotenceKey должен быть одинаковым при повторах
+// одной и той же выплаты, иначе ЮKassa создаст ее второй раз. Выплаты ЮKassa
+// принимают карту (по токену из виджета) и кошелек ЮMoney, но не расчетный счет.
+func (c *Client) CreatePayout(amount string, currency string, description string, dest provider.PayoutDestination, idempotenceKey string) (*provider.Payout, error) {
 	reqBody := CreatePayoutRequest{
 		Amount: Amount{
 			Value:    amount,
-			Currency: "RUB",
+			Currency: currency,
 		},
 		Description: description,
-		PayoutToken: payoutToken,
+	}
+	switch dest.Type {
+	case provider.DestinationBankCard:
+		reqBody.PayoutToken = dest.PayoutToken
+	case provider.DestinationYooMoney:
+		reqBody.PayoutDestinationData = &PayoutDestinationData{Type: provider.DestinationYooMoney, AccountNumber: dest.AccountNumber}
+	default:
+		return nil, provider.ErrDestinationNotSupported
 	}
 
 	bodyBytes, _ := json.Marshal(reqBody)
@@ -178,7 +276,7 @@ func (c *Client) CreatePayout(amount string, description string, payoutToken str
 
 	auth := base64.StdEncoding.EncodeToString([]byte(c.AgentID + ":" + c.PayoutAPIKey))
 	req.Header.Set("Authorization", "Basic "+auth)
-	req.Header.Set("Idempotence-Key", uuid.New().String())
+	req.Header.Set("Idempotence-Key", idempotenceKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.HTTP.Do(req)
@@ -189,6 +287,10 @@ func (c *Client) CreatePayout(amount string, description string, payoutToken str
 
 	if resp.StatusCode >= 400 {
 		respBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode < 500 {
+			// запрос отклонен до обработки, выплата не создана
+			return nil, fmt.Errorf("%w: yookassa payout error: %s", provider.ErrPayoutRejected, string(respBody))
+		}
 		return nil, fmt.Errorf("yookassa payout error: %s", string(respBody))
 	}
 
@@ -197,11 +299,11 @@ func (c *Client) CreatePayout(amount string, description string, payoutToken str
 		return nil, err
 	}
 
-	return &result, nil
+	return &provider.Payout{ID: result.ID, Status: result.Status}, nil
 }
 
-func (c *Client) GetPayout(payoutID string) (*PayoutResponse, error) {
-	payoutURL := "https://api.yookassa.ru/v3/payouts/" + payoutID
+func (c *Client) GetPayout(payoutID string) (*provider.Payout, error) {
+	payoutURL := c.PayoutAPIURL + "/" + payoutID
 
 	req, err := http.NewRequest("GET", payoutURL, nil)
 	if err != nil {
@@ -228,5 +330,75 @@ func (c *Client) GetPayout(payoutID string) (*PayoutResponse, error) {
 		return nil, err
 	}
 
-	return &result, nil
+	return &provider.Payout{ID: result.ID, Status: result.Status}, nil
+}
+
+type CreateRefundRequest struct {
+	PaymentID   string `json:"payment_id"`
+	Amount      Amount `json:"amount"`
+	Description string `json:"description,omitempty"`
+}
+
+type RefundResponse struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"payment_id"`
+	Status    string `json:"status"`
+}
+
+// CreateRefund возвращает деньги по платежу paymentID (полностью или частично)
+func (c *Client) CreateRefund(paymentID string, amount string, currency string, description string, idempotenceKey string) (*provider.Refund, error) {
+	reqBody := CreateRefundRequest{
+		PaymentID: paymentID,
+		Amount: Amount{
+			Value:    amount,
+			Currency: currency,
+		},
+		Description: description,
+	}
+
+	bodyBytes, _ := json.Marshal(reqBody)
+	req, err := http.NewRequest("POST", c.RefundAPIURL, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte(c.ShopID + ":" + c.SecretKey))
+	req.Header.Set("Authorization", "Basic "+auth)
+	req.Header.Set("Idempotence-Key", idempotenceKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.doRefund(req)
+}
+
+func (c *Client) GetRefund(refundID string) (*provider.Refund, error) {
+	req, err := http.NewRequest("GET", c.RefundAPIURL+"/"+refundID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte(c.ShopID + ":" + c.SecretKey))
+	req.Header.Set("Authorization", "Basic "+auth)
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.doRefund(req)
+}
+
+func (c *Client) doRefund(req *http.Request) (*provider.Refund, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("yookassa refund error: %s", string(respBody))
+	}
+
+	var result RefundResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &provider.Refund{ID: result.ID, PaymentID: result.PaymentID, Status: result.Status}, nil
 }
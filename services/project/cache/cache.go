@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+// feedVersionKey — номер версии ленты. Любое изменение проекта увеличивает его, и все
+// закешированные страницы старой версии перестают читаться, а потом истекают по TTL.
+const feedVersionKey = "project_feed:version"
+
+type Cache struct {
+	client  *redis.Client
+	log     slog.Logger
+	ttl     time.Duration
+	feedTTL time.Duration
+}
+
+func NewCache(client *redis.Client, log slog.Logger) *Cache {
+	return &Cache{
+		client:  client,
+		log:     log,
+		ttl:     time.Minute,
+		feedTTL: 30 * time.Second,
+	}
+}
+
+// cachedProject сохраняет поля, скрытые из JSON ответа
+type cachedProject struct {
+	core.Project
+	QuickPeekPicturePath *string `json:"quick_peek_picture_path,omitempty"`
+}
+
+// FeedPage — закешированная страница ленты
+type FeedPage struct {
+	Projects []core.Project `json:"projects"`
+	Next     *core.Cursor   `json:"next,omitempty"`
+}
+
+func projectKey(id int) string {
+	return "project:" + strconv.Itoa(id)
+}
+
+func encodeProject(p *core.Project) ([]byte, error) {
+	return json.Marshal(cachedProject{Project: *p, QuickPeekPicturePath: p.QuickPeekPicturePath})
+}
+
+func decodeProject(data []byte) (*core.Project, error) {
+	var cached cachedProject
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+	p := cached.Project
+	p.QuickPeekPicturePath = cached.QuickPeekPicturePath
+	return &p, nil
+}
+
+func (c *Cache) SetProject(ctx context.Context, p *core.Project) error {
+	data, err := encodeProject(p)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, projectKey(p.ID), data, c.ttl).Err()
+}
+
+func (c *Cache) GetProject(ctx context.Context, id int) (*core.Project, error) {
+	val, err := c.client.Get(ctx, projectKey(id)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return decodeProject([]byte(val))
+}
+
+func (c *Cache) DeleteProjects(ctx context.Context, ids ...int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = projectKey(id)
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+// FeedVersion возвращает текущую версию ленты, 0 — ее еще ни разу не меняли
+func (c *Cache) FeedVersion(ctx context.Context) (int64, error) {
+	v, err := c.client.Get(ctx, feedVersionKey).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return v, err
+}
+
+func (c *Cache) BumpFeedVersion(ctx context.Context) error {
+	return c.client.Incr(ctx, feedVersionKey).Err()
+}
+
+// FeedKey строит ключ страницы из всех параметров запроса
+func FeedKey(version int64, page core.Page, f core.ProjectFilter) string {
+	data, _ := json.Marshal(struct {
+		Page   core.Page
+		Filter core.ProjectFilter
+	}{page, f})
+	sum := sha1.Sum(data)
+	return "project_feed:" + strconv.FormatInt(version, 10) + ":" + hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) GetFeedPage(ctx context.Context, key string) (*FeedPage, error) {
+	val, err := c.client.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var page FeedPage
+	if err := json.Unmarshal([]byte(val), &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *Cache) SetFeedPage(ctx context.Context, key string, page FeedPage) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, key, data, c.feedTTL).Err()
+}
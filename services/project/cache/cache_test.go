@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+	"github.com/Starostina-elena/investment_platform/services/project/money"
+)
+
+func TestProjectRoundTrip(t *testing.T) {
+	picture := "projects/7/peek.png"
+	activated := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	p := &core.Project{
+		ID:                     7,
+		Name:                   "Пекарня",
+		CreatorID:              3,
+		QuickPeekPicturePath:   &picture,
+		CurrentMoney:           money.FromRubles(1500),
+		WantedMoney:            money.FromRubles(10000),
+		Currency:               money.RUB,
+		MoneyRequiredToPayback: money.FromRubles(1650),
+		Status:                 core.StatusActive,
+		ActivatedAt:            &activated,
+		Tags:                   []core.Tag{{ID: 1, Name: "Еда"}},
+	}
+
+	data, err := encodeProject(p)
+	if err != nil {
+		t.Fatalf("encodeProject() error = %v", err)
+	}
+	got, err := decodeProject(data)
+	if err != nil {
+		t.Fatalf("decodeProject() error = %v", err)
+	}
+
+	// путь к картинке скрыт из JSON ответа, но в кеше должен сохраниться
+	if got.QuickPeekPicturePath == nil || *got.QuickPeekPicturePath != picture {
+		t.Errorf("QuickPeekPicturePath = %v, want %q", got.QuickPeekPicturePath, picture)
+	}
+	if got.ID != p.ID || got.Name != p.Name || got.Status != p.Status {
+		t.Errorf("decoded %d %q %s, want %d %q %s", got.ID, got.Name, got.Status, p.ID, p.Name, p.Status)
+	}
+	if got.CurrentMoney != p.CurrentMoney || got.MoneyRequiredToPayback != p.MoneyRequiredToPayback {
+		t.Errorf("decoded money %v/%v, want %v/%v", got.CurrentMoney, got.MoneyRequiredToPayback, p.CurrentMoney, p.MoneyRequiredToPayback)
+	}
+	if got.ActivatedAt == nil || !got.ActivatedAt.Equal(activated) {
+		t.Errorf("ActivatedAt = %v, want %v", got.ActivatedAt, activated)
+	}
+	if len(got.Tags) != 1 || got.Tags[0].Name != "Еда" {
+		t.Errorf("Tags = %+v, want one tag Еда", got.Tags)
+	}
+}
+
+func TestDecodeProjectInvalid(t *testing.T) {
+	if _, err := decodeProject([]byte("{")); err == nil {
+		t.Error("decodeProject() of broken JSON: want error")
+	}
+}
+
+func TestProjectKey(t *testing.T) {
+	if got := projectKey(42); got != "project:42" {
+		t.Errorf("projectKey(42) = %q, want %q", got, "project:42")
+	}
+}
+
+func TestFeedKey(t *testing.T) {
+	progress := 50.0
+	page := core.Page{Limit: 20, Sort: core.SortNewest}
+	filter := core.ProjectFilter{TagIDs: []int{1, 2}, MinProgress: &progress}
+
+	key := FeedKey(3, page, filter)
+	if !strings.HasPrefix(key, "project_feed:3:") {
+		t.Errorf("FeedKey() = %q, want prefix project_feed:3:", key)
+	}
+	if again := FeedKey(3, page, filter); again != key {
+		t.Errorf("FeedKey() is not stable: %q != %q", again, key)
+	}
+
+	otherProgress := 60.0
+	tests := []struct {
+		name    string
+		version int64
+		page    core.Page
+		filter  core.ProjectFilter
+	}{
+		{name: "new feed version", version: 4, page: page, filter: filter},
+		{name: "limit", version: 3, page: core.Page{Limit: 10, Sort: core.SortNewest}, filter: filter},
+		{name: "sort", version: 3, page: core.Page{Limit: 20, Sort: core.SortMostFunded}, filter: filter},
+		{name: "cursor", version: 3, page: core.Page{Limit: 20, Sort: core.SortNewest, Cursor: &core.Cursor{Sort: core.SortNewest, Key: "2026-01-01T00:00:00Z", ID: 5}}, filter: filter},
+		{name: "tags", version: 3, page: page, filter: core.ProjectFilter{TagIDs: []int{1}, MinProgress: &progress}},
+		{name: "progress value", version: 3, page: page, filter: core.ProjectFilter{TagIDs: []int{1, 2}, MinProgress: &otherProgress}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FeedKey(tt.version, tt.page, tt.filter); got == key {
+				t.Errorf("FeedKey() = %q, want a different key", got)
+			}
+		})
+	}
+}
@@ -4,11 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+
+	"github.com/Starostina-elena/investment_platform/services/project/money"
 )
 
+// ErrTransferRejected — сервис транзакций отклонил перевод (недостаточно средств,
+// неверные параметры); повтор того же запроса не поможет
+var ErrTransferRejected = errors.New("transfer rejected by transaction service")
+
 type TransactionClient struct {
 	url    string
 	client *http.Client
@@ -25,13 +32,17 @@ func NewTransactionClient() *TransactionClient {
 	}
 }
 
-func (tc *TransactionClient) Transfer(ctx context.Context, fromType string, fromID int, toType string, toID int, amount float64) error {
+// Transfer передает idempotencyKey в заголовке Idempotency-Key, поэтому
+// повторный вызов с тем же ключом не проводит перевод второй раз.
+// amount указывается в currency — валюте счета отправителя.
+func (tc *TransactionClient) Transfer(ctx context.Context, idempotencyKey string, fromType string, fromID int, toType string, toID int, amount money.Amount, currency money.Currency) error {
 	reqBody, _ := json.Marshal(map[string]interface{}{
 		"from_type": fromType,
 		"from_id":   fromID,
 		"to_type":   toType,
 		"to_id":     toID,
 		"amount":    amount,
+		"currency":  currency,
 	})
 
 	req, err := http.NewRequestWithContext(ctx, "POST", tc.url+"/transfer", bytes.NewBuffer(reqBody))
@@ -39,6 +50,7 @@ func (tc *TransactionClient) Transfer(ctx context.Context, fromType string, from
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
 
 	resp, err := tc.client.Do(req)
 	if err != nil {
@@ -46,6 +58,9 @@ func (tc *TransactionClient) Transfer(ctx context.Context, fromType string, from
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return fmt.Errorf("%w: %d", ErrTransferRejected, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("transaction service error: %d", resp.StatusCode)
 	}
@@ -12,10 +12,15 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 
+	"github.com/Starostina-elena/investment_platform/services/project/cache"
 	"github.com/Starostina-elena/investment_platform/services/project/clients"
+	"github.com/Starostina-elena/investment_platform/services/project/events"
 	"github.com/Starostina-elena/investment_platform/services/project/handler"
+	"github.com/Starostina-elena/investment_platform/services/project/outbox"
 	"github.com/Starostina-elena/investment_platform/services/project/repo"
+	"github.com/Starostina-elena/investment_platform/services/project/saga"
 	"github.com/Starostina-elena/investment_platform/services/project/service"
 	"github.com/Starostina-elena/investment_platform/services/project/storage"
 )
@@ -34,6 +39,21 @@ func openDB() *sqlx.DB {
 	return db
 }
 
+func openRedis() *redis.Client {
+	host := os.Getenv("REDIS_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := os.Getenv("REDIS_PORT")
+	if port == "" {
+		port = "6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr: host + ":" + port,
+	})
+	return client
+}
+
 func openOrgClient(log slog.Logger) *clients.OrgClient {
 	return clients.NewOrgClient(os.Getenv("ORG_SERVICE_URL"), log)
 }
@@ -67,12 +87,17 @@ func main() {
 	db := openDB()
 	defer db.Close()
 
-	repo := repo.NewRepo(db, *logger)
+	redisClient := openRedis()
+	defer redisClient.Close()
+
+	cacheLayer := cache.NewCache(redisClient, *logger)
+	repo := repo.NewRepo(db, cacheLayer, *logger)
 
 	orgClient := openOrgClient(*logger)
 	transactionClient := openTransactionClient()
 	minioStorage := openMinioStorage(*logger)
-	service := service.NewService(repo, orgClient, transactionClient, minioStorage, *logger)
+	sagas := saga.NewOrchestrator(db, "project", *logger)
+	service := service.NewService(repo, orgClient, transactionClient, minioStorage, sagas, *logger)
 
 	handler := handler.NewHandler(service, *logger)
 
@@ -86,6 +111,19 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	// продолжает выплаты инвесторам, прерванные сбоем или отложенные на повтор
+	go sagas.RunWorker(ctx, 10*time.Second)
+
+	relay := outbox.NewRelay(db, redisClient, "project", *logger)
+	go relay.Run(ctx, time.Second)
+
+	// деньги проектов и блокировки организаций меняют другие сервисы, кеш сбрасывается по их событиям
+	consumer := events.NewConsumer(redisClient, "project", *logger)
+	consumer.Handle("transfer.completed", events.TransferCompletedHandler(repo, *logger))
+	consumer.Handle("org.banned", events.OrgBannedHandler(repo, *logger))
+	consumer.Handle("project.updated", events.ProjectUpdatedHandler(repo, *logger))
+	go consumer.Run(ctx)
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Error("listen", "error", err)
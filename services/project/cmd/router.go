@@ -11,18 +11,31 @@ func getRouter(h *handler.Handler) *http.ServeMux {
 	router := http.NewServeMux()
 
 	router.Handle("POST /create", middleware.AuthMiddleware(handler.CreateProjectHandler(h)))
-	router.Handle("GET /{id}", handler.GetProjectHandler(h))
+	router.Handle("GET /{id}", middleware.OptionalAuthMiddleware(handler.GetProjectHandler(h)))
 	router.Handle("POST /{id}/update", middleware.AuthMiddleware(handler.UpdateProjectHandler(h)))
 
 	router.Handle("GET /projects", handler.GetProjectListHandler(h))
+	router.Handle("GET /projects/search", handler.SearchProjectsHandler(h))
 	router.Handle("GET /projects/org/{creator_id}", handler.GetPublicProjectsByCreatorHandler(h))
 	router.Handle("GET /projects/all/org/{creator_id}", middleware.AuthMiddleware(handler.GetAllProjectsByCreatorHandler(h)))
 
+	router.Handle("GET /tags", handler.GetTagsHandler(h))
+	router.Handle("POST /tags/create", middleware.AuthMiddleware(handler.CreateTagHandler(h)))
+	router.Handle("POST /tags/{id}/update", middleware.AuthMiddleware(handler.UpdateTagHandler(h)))
+	router.Handle("POST /tags/{id}/delete", middleware.AuthMiddleware(handler.DeleteTagHandler(h)))
+
 	router.Handle("POST /{id}/ban", middleware.AuthMiddleware(handler.BanProjectHandler(h)))
 	router.Handle("POST /{id}/public", middleware.AuthMiddleware(handler.ChangeProjectPublicityHandler(h)))
 	router.Handle("POST /{id}/completed", middleware.AuthMiddleware(handler.MarkProjectCompletedHandler(h)))
 	router.Handle("POST /{id}/payback", middleware.AuthMiddleware(handler.StartPaybackHandler(h)))
 
+	router.Handle("POST /{id}/submit", middleware.AuthMiddleware(handler.SubmitProjectHandler(h)))
+	router.Handle("POST /{id}/approve", middleware.AuthMiddleware(handler.ReviewProjectHandler(h, true)))
+	router.Handle("POST /{id}/reject", middleware.AuthMiddleware(handler.ReviewProjectHandler(h, false)))
+	router.Handle("POST /{id}/close", middleware.AuthMiddleware(handler.CloseProjectHandler(h)))
+	router.Handle("GET /{id}/history", middleware.AuthMiddleware(handler.GetStatusHistoryHandler(h)))
+	router.Handle("GET /projects/review", middleware.AuthMiddleware(handler.GetReviewQueueHandler(h)))
+
 	router.Handle("POST /{id}/picture/upload", middleware.AuthMiddleware(handler.UploadPictureHandler(h)))
 	router.Handle("DELETE /{id}/picture", middleware.AuthMiddleware(handler.DeletePictureHandler(h)))
 
@@ -31,9 +44,8 @@ func getRouter(h *handler.Handler) *http.ServeMux {
 		w.Write([]byte("pong"))
 	}))
 
-	router.Handle("POST /{id}/funds", handler.AddFundsHandler(h))
-	router.Handle("POST /{id}/funds/decrease", handler.DecreaseFundsHandler(h))
 	router.Handle("POST /{id}/money-required-payback", handler.UpdateMoneyRequiredToPaybackHandler(h))
+	router.Handle("GET /internal/projects/{id}", handler.GetProjectInternalHandler(h))
 
 	return router
 }
@@ -6,7 +6,14 @@ var (
 	ErrProjectNotFound     = errors.New("project not found")
 	ErrNotAuthorized       = errors.New("not authorized")
 	ErrInvalidInput        = errors.New("invalid input")
-	ErrPaybackStarted      = errors.New("cannot change is_completed when payback has started")
 	ErrPaybackNotSupported = errors.New("payback is not supported for charity and custom monetization types")
 	ErrNotEnoughFunds      = errors.New("not enough funds to complete payback")
+	ErrPaybackInProgress   = errors.New("payback is already in progress")
+	ErrTagNotFound         = errors.New("tag not found")
+	ErrTagExists           = errors.New("tag with this name already exists")
+	ErrInvalidCursor       = errors.New("invalid page cursor")
+	ErrInvalidTransition   = errors.New("project status transition is not allowed")
+	ErrNotApproved         = errors.New("project is not approved by a moderator")
+	ErrPaybackRequired     = errors.New("investors must be paid back before the project is closed")
+	ErrNotEditable         = errors.New("only draft projects can be edited")
 )
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ProjectSort — порядок проектов в ленте
+type ProjectSort string
+
+const (
+	SortNewest         ProjectSort = "new"       // сначала новые
+	SortClosestToGoal  ProjectSort = "closest"   // больше всего собрано от цели
+	SortEndingSoon     ProjectSort = "ending"    // ближайший срок окончания сбора
+	SortHighestPercent ProjectSort = "percent"   // самая высокая доходность
+	SortMostFunded     ProjectSort = "funded"    // самая большая собранная сумма
+	SortRelevance      ProjectSort = "relevance" // только с поисковой строкой
+)
+
+func (s ProjectSort) Valid() bool {
+	switch s {
+	case SortNewest, SortClosestToGoal, SortEndingSoon, SortHighestPercent, SortMostFunded, SortRelevance:
+		return true
+	}
+	return false
+}
+
+// cursorTimeLayout — вид TIMESTAMP в тексте Postgres, так ключ попадает в курсор
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+var (
+	cursorNumeric = regexp.MustCompile(`^-?[0-9]{1,40}(\.[0-9]{1,40})?$`)
+	cursorFloat   = regexp.MustCompile(`^-?[0-9]{1,40}(\.[0-9]{1,40})?(e[-+]?[0-9]{1,3})?$`)
+)
+
+// validKey проверяет, что значение ключа из курсора приводится к типу ключа сортировки
+// (repo сравнивает с ним через ::timestamp, ::numeric или ::float8). Иначе подмененный
+// курсор дошел бы до БД и вернул ошибку сервера вместо 400.
+func (s ProjectSort) validKey(key string) bool {
+	switch s {
+	case SortNewest, SortEndingSoon:
+		_, err := time.Parse(cursorTimeLayout, key)
+		return err == nil
+	case SortClosestToGoal, SortHighestPercent, SortMostFunded:
+		return cursorNumeric.MatchString(key)
+	case SortRelevance:
+		if !cursorFloat.MatchString(key) {
+			return false
+		}
+		f, err := strconv.ParseFloat(key, 64)
+		return err == nil && !math.IsInf(f, 0)
+	}
+	return false
+}
+
+// Page — запрошенная страница ленты. С курсором смещение не используется.
+type Page struct {
+	Limit  int
+	Offset int
+	Sort   ProjectSort
+	Cursor *Cursor
+}
+
+// Cursor — позиция последнего проекта страницы: значение ключа сортировки и id.
+// Клиенту отдается непрозрачной строкой.
+type Cursor struct {
+	Sort ProjectSort `json:"s"`
+	Key  string      `json:"k"`
+	ID   int         `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || !c.Sort.Valid() || !c.Sort.validKey(c.Key) || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestDecodeCursorRoundTrip(t *testing.T) {
+	cursors := []Cursor{
+		{Sort: SortNewest, Key: "2026-03-01 12:30:45.123456", ID: 7},
+		{Sort: SortEndingSoon, Key: "2026-03-31 12:30:45", ID: 7},
+		{Sort: SortClosestToGoal, Key: "0.15000000000000000000", ID: 3},
+		{Sort: SortHighestPercent, Key: "12.5", ID: 3},
+		{Sort: SortMostFunded, Key: "1500000.00", ID: 3},
+		{Sort: SortRelevance, Key: "0.0607927", ID: 1},
+		{Sort: SortRelevance, Key: "1e-05", ID: 1},
+	}
+	for _, c := range cursors {
+		got, err := DecodeCursor(c.Encode())
+		if err != nil {
+			t.Errorf("DecodeCursor(%+v): %v", c, err)
+			continue
+		}
+		if *got != c {
+			t.Errorf("DecodeCursor = %+v, want %+v", *got, c)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsTamperedKey(t *testing.T) {
+	cursors := []Cursor{
+		{Sort: SortNewest, Key: "", ID: 7},
+		{Sort: SortNewest, Key: "yesterday", ID: 7},
+		{Sort: SortNewest, Key: "infinity", ID: 7},
+		{Sort: SortNewest, Key: "0.5", ID: 7},
+		{Sort: SortEndingSoon, Key: "2026-03-01' OR 1=1", ID: 7},
+		{Sort: SortClosestToGoal, Key: "NaN", ID: 3},
+		{Sort: SortHighestPercent, Key: "1e5", ID: 3},
+		{Sort: SortMostFunded, Key: "2026-03-01 12:30:45", ID: 3},
+		{Sort: SortRelevance, Key: "Infinity", ID: 1},
+		{Sort: SortRelevance, Key: "NaN", ID: 1},
+		{Sort: SortRelevance, Key: "1e999", ID: 1},
+		{Sort: SortRelevance, Key: "0x1p-2", ID: 1},
+		{Sort: SortNewest, Key: "2026-03-01 12:30:45", ID: 0},
+		{Sort: "name", Key: "a", ID: 1},
+	}
+	for _, c := range cursors {
+		if _, err := DecodeCursor(c.Encode()); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%+v) error = %v, want ErrInvalidCursor", c, err)
+		}
+	}
+
+	for _, s := range []string{"%%%", base64.RawURLEncoding.EncodeToString([]byte("{"))} {
+		if _, err := DecodeCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package core
+
+import "time"
+
+// ProjectStatus — этап жизненного цикла проекта. Блокировка администратором
+// хранится отдельно (IsBanned) и действует поверх любого статуса.
+type ProjectStatus string
+
+const (
+	StatusDraft             ProjectStatus = "draft"               // черновик, виден только организации
+	StatusPendingReview     ProjectStatus = "pending_review"      // ждет решения модератора
+	StatusActive            ProjectStatus = "active"              // идет сбор
+	StatusFunded            ProjectStatus = "funded"              // сбор завершен
+	StatusFailed            ProjectStatus = "failed"              // цель не собрана к сроку, вклады возвращаются
+	StatusPaybackInProgress ProjectStatus = "payback_in_progress" // идут выплаты инвесторам
+	StatusClosed            ProjectStatus = "closed"
+)
+
+var statusTransitions = map[ProjectStatus][]ProjectStatus{
+	StatusDraft:             {StatusPendingReview},
+	StatusPendingReview:     {StatusActive, StatusDraft},
+	StatusActive:            {StatusFunded, StatusFailed},
+	StatusFunded:            {StatusPaybackInProgress, StatusClosed},
+	StatusPaybackInProgress: {StatusClosed},
+	StatusFailed:            {StatusClosed},
+}
+
+func (s ProjectStatus) CanTransition(to ProjectStatus) bool {
+	for _, next := range statusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Approved — проект прошел модерацию и может быть опубликован
+func (s ProjectStatus) Approved() bool {
+	return s != StatusDraft && s != StatusPendingReview
+}
+
+// Completed — сбор закончен; соответствует флагу is_completed
+func (s ProjectStatus) Completed() bool {
+	return s.Approved() && s != StatusActive
+}
+
+// Действия в истории статусов проекта
+const (
+	ActionTransition = "transition"
+	ActionBan        = "ban"
+	ActionUnban      = "unban"
+)
+
+// StatusChange — запись истории: смена статуса или блокировка. ActorID nil — система.
+type StatusChange struct {
+	ID         int64          `json:"id" db:"id"`
+	ProjectID  int            `json:"project_id" db:"project_id"`
+	Action     string         `json:"action" db:"action"`
+	FromStatus *ProjectStatus `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   ProjectStatus  `json:"to_status" db:"to_status"`
+	Reason     string         `json:"reason" db:"reason"`
+	ActorID    *int           `json:"actor_id,omitempty" db:"actor_id"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,56 @@
+package core
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to ProjectStatus
+		want     bool
+	}{
+		{StatusDraft, StatusPendingReview, true},
+		{StatusDraft, StatusActive, false},
+		{StatusPendingReview, StatusActive, true},
+		{StatusPendingReview, StatusDraft, true},
+		{StatusPendingReview, StatusFunded, false},
+		{StatusActive, StatusFunded, true},
+		{StatusActive, StatusFailed, true},
+		{StatusActive, StatusDraft, false},
+		{StatusActive, StatusClosed, false},
+		{StatusFunded, StatusPaybackInProgress, true},
+		{StatusFunded, StatusClosed, true},
+		{StatusPaybackInProgress, StatusClosed, true},
+		{StatusPaybackInProgress, StatusFunded, false},
+		{StatusFailed, StatusClosed, true},
+		{StatusFailed, StatusActive, false},
+		{StatusClosed, StatusActive, false},
+		{StatusClosed, StatusClosed, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%s -> %s: CanTransition = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestStatusFlags(t *testing.T) {
+	tests := []struct {
+		status              ProjectStatus
+		approved, completed bool
+	}{
+		{StatusDraft, false, false},
+		{StatusPendingReview, false, false},
+		{StatusActive, true, false},
+		{StatusFunded, true, true},
+		{StatusFailed, true, true},
+		{StatusPaybackInProgress, true, true},
+		{StatusClosed, true, true},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Approved(); got != tt.approved {
+			t.Errorf("%s: Approved = %v, want %v", tt.status, got, tt.approved)
+		}
+		if got := tt.status.Completed(); got != tt.completed {
+			t.Errorf("%s: Completed = %v, want %v", tt.status, got, tt.completed)
+		}
+	}
+}
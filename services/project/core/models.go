@@ -1,46 +1,102 @@
 package core
 
-import "time"
+import (
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/project/money"
+)
 
 type Project struct {
-	ID                     int        `json:"id" db:"id"`
-	Name                   string     `json:"name" db:"name"`
-	CreatorID              int        `json:"creator_id" db:"creator_id"`
-	QuickPeek              string     `json:"quick_peek" db:"quick_peek"`
-	QuickPeekPicturePath   *string    `json:"-" db:"quick_peek_picture_path"`
-	Content                string     `json:"content" db:"content"`
-	IsPublic               bool       `json:"is_public" db:"is_public"`
-	IsCompleted            bool       `json:"is_completed" db:"is_completed"`
-	CurrentMoney           float64    `json:"current_money" db:"current_money"`
-	WantedMoney            float64    `json:"wanted_money" db:"wanted_money"`
-	DurationDays           int        `json:"duration_days" db:"duration_days"`
-	CreatedAt              time.Time  `json:"created_at" db:"created_at"`
-	IsBanned               bool       `json:"is_banned" db:"is_banned"`
-	MonetizationType       string     `json:"monetization_type" db:"monetization_type"`
-	Percent                float64    `json:"percent,omitempty" db:"percent"`
-	PaybackStarted         bool       `json:"payback_started" db:"payback_started"`
-	PaybackStartedDate     *time.Time `json:"payback_started_date,omitempty" db:"payback_started_date"`
-	MoneyRequiredToPayback float64    `json:"money_required_to_payback" db:"money_required_to_payback"`
+	ID                     int            `json:"id" db:"id"`
+	Name                   string         `json:"name" db:"name"`
+	CreatorID              int            `json:"creator_id" db:"creator_id"`
+	QuickPeek              string         `json:"quick_peek" db:"quick_peek"`
+	QuickPeekPicturePath   *string        `json:"-" db:"quick_peek_picture_path"`
+	Content                string         `json:"content" db:"content"`
+	IsPublic               bool           `json:"is_public" db:"is_public"`
+	IsCompleted            bool           `json:"is_completed" db:"is_completed"`
+	CurrentMoney           money.Amount   `json:"current_money" db:"current_money"`
+	WantedMoney            money.Amount   `json:"wanted_money" db:"wanted_money"`
+	Currency               money.Currency `json:"currency" db:"currency"` // целевая валюта: в ней проект принимает вложения и делает выплаты
+	DurationDays           int            `json:"duration_days" db:"duration_days"`
+	CreatedAt              time.Time      `json:"created_at" db:"created_at"`
+	IsBanned               bool           `json:"is_banned" db:"is_banned"`
+	MonetizationType       string         `json:"monetization_type" db:"monetization_type"`
+	Percent                float64        `json:"percent,omitempty" db:"percent"`
+	PaybackStarted         bool           `json:"payback_started" db:"payback_started"`
+	PaybackStartedDate     *time.Time     `json:"payback_started_date,omitempty" db:"payback_started_date"`
+	MoneyRequiredToPayback money.Amount   `json:"money_required_to_payback" db:"money_required_to_payback"`
+	Status                 ProjectStatus  `json:"status" db:"status"`
+	ActivatedAt            *time.Time     `json:"activated_at,omitempty" db:"activated_at"` // одобрение модератором, от него считается срок сбора
+	Tags                   []Tag          `json:"tags" db:"-"`
+}
+
+type Tag struct {
+	ID          int     `json:"id" db:"id"`
+	Name        string  `json:"name" db:"name"`
+	Description *string `json:"description,omitempty" db:"description"`
+}
+
+// TagCount — тег и число проектов с ним в ленте, для навигатора по категориям
+type TagCount struct {
+	Tag
+	ProjectCount int `json:"project_count" db:"project_count"`
+}
+
+// ProjectFilter — условия ленты и поиска; пустые поля не ограничивают выборку
+type ProjectFilter struct {
+	Query            string
+	MonetizationType string
+	TagIDs           []int    // хотя бы один из тегов
+	OrgType          string   // тип организации-владельца: jur, phys или ip
+	MinProgress      *float64 // доля собранного от цели, %
+	MaxProgress      *float64
+	MinPercent       *float64 // обещанная доходность проекта, %
+	MaxPercent       *float64
 }
 
+// SearchResult — найденный проект с релевантностью и фрагментами текста, где совпадения
+// обернуты в <mark>; остальной текст экранирован и его можно вставлять как HTML
+type SearchResult struct {
+	Project
+	Rank          float64 `json:"rank" db:"rank"`
+	NameHighlight string  `json:"name_highlight" db:"name_highlight"`
+	Snippet       string  `json:"snippet" db:"snippet"`
+}
+
+// Transaction — вложение или выплата проекта. Amount списано с отправителя,
+// ToAmount зачислено получателю, Fee — удержанная с зачисления комиссия платформы.
 type Transaction struct {
-	ID             int       `db:"id"`
-	FromID         *int      `db:"from_id"`
-	ReceiverID     *int      `db:"reciever_id"`
-	Type           string    `db:"type"`
-	Amount         float64   `db:"amount"`
-	TimeAt         time.Time `db:"time_at"`
+	ID         int          `db:"id"`
+	FromID     *int         `db:"from_id"`
+	ReceiverID *int         `db:"reciever_id"`
+	Type       string       `db:"type"`
+	Amount     money.Amount `db:"amount"`
+	ToAmount   money.Amount `db:"to_amount"`
+	Fee        money.Amount `db:"fee"`
+	TimeAt     time.Time    `db:"time_at"`
+}
+
+// Invested — вложение в валюте проекта вместе с комиссией: ее платит проект,
+// поэтому доход инвестора считается от всей суммы
+func (t Transaction) Invested() money.Amount {
+	return t.ToAmount + t.Fee
+}
+
+// Paid — сколько проект отдал по выплате, включая комиссию с получателя
+func (t Transaction) Paid() money.Amount {
+	return t.Amount
 }
 
 type InvestorPayback struct {
-	UserID         int
-	TotalInvested  float64
-	TotalReceived  float64
-	PaybackAmount  float64
-	Investments    []Investment
+	UserID        int
+	TotalInvested money.Amount
+	TotalReceived money.Amount
+	PaybackAmount money.Amount
+	Investments   []Investment
 }
 
 type Investment struct {
-	Amount    float64
+	Amount     money.Amount
 	InvestedAt time.Time
 }
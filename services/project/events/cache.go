@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/Starostina-elena/investment_platform/services/project/repo"
+)
+
+type transferCompletedEvent struct {
+	FromType string `json:"from_type"`
+	FromID   int    `json:"from_id"`
+	ToType   string `json:"to_type"`
+	ToID     int    `json:"to_id"`
+}
+
+type orgBannedEvent struct {
+	OrgID int `json:"org_id"`
+}
+
+type projectUpdatedEvent struct {
+	ProjectID int `json:"project_id"`
+}
+
+// TransferCompletedHandler сбрасывает из кеша проект, на который пришли или с которого
+// ушли деньги: вложения, выплаты инвесторам и возвраты меняют собранную сумму
+func TransferCompletedHandler(r repo.RepoInterface, log slog.Logger) HandlerFunc {
+	return func(ctx context.Context, e Event) error {
+		var event transferCompletedEvent
+		if err := json.Unmarshal(e.Payload, &event); err != nil {
+			log.Error("failed to decode transfer completed event", "event_id", e.EventID, "error", err)
+			return nil
+		}
+
+		var ids []int
+		if event.ToType == "project" {
+			ids = append(ids, event.ToID)
+		}
+		if event.FromType == "project" {
+			ids = append(ids, event.FromID)
+		}
+		if len(ids) > 0 {
+			r.InvalidateCache(ctx, ids...)
+		}
+		return nil
+	}
+}
+
+// OrgBannedHandler сбрасывает из кеша проекты заблокированной организации. Снимает их
+// с публикации daemon, и если он не успел к этому моменту, устаревшие данные
+// продержатся в кеше не дольше TTL.
+func OrgBannedHandler(r repo.RepoInterface, log slog.Logger) HandlerFunc {
+	return func(ctx context.Context, e Event) error {
+		var event orgBannedEvent
+		if err := json.Unmarshal(e.Payload, &event); err != nil {
+			log.Error("failed to decode org banned event", "event_id", e.EventID, "error", err)
+			return nil
+		}
+		return r.InvalidateCreatorCache(ctx, event.OrgID)
+	}
+}
+
+// ProjectUpdatedHandler сбрасывает из кеша проект, который daemon изменил напрямую в БД:
+// завершил сбор, пересчитал сумму к выплате или снял с публикации
+func ProjectUpdatedHandler(r repo.RepoInterface, log slog.Logger) HandlerFunc {
+	return func(ctx context.Context, e Event) error {
+		var event projectUpdatedEvent
+		if err := json.Unmarshal(e.Payload, &event); err != nil {
+			log.Error("failed to decode project updated event", "event_id", e.EventID, "error", err)
+			return nil
+		}
+		r.InvalidateCache(ctx, event.ProjectID)
+		return nil
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Starostina-elena/investment_platform/services/project/core"
 	"github.com/Starostina-elena/investment_platform/services/project/middleware"
+	"github.com/Starostina-elena/investment_platform/services/project/money"
 	"github.com/Starostina-elena/investment_platform/services/project/service"
 )
 
@@ -17,8 +18,9 @@ const (
 	MaxNameLength      = 128
 	MaxQuickPeekLength = 128
 	MaxContentLength   = 1024
-	MaxWantedMoney     = 1e9
+	MaxWantedMoney     = money.Amount(1e9 * 100)
 	MaxDurationDays    = 36500
+	MaxProjectTags     = 10
 )
 
 type Handler struct {
@@ -46,11 +48,14 @@ func ValidateProjectInput(req interface{}) error {
 			return fmt.Errorf("полное описание слишком длинное (макс %d символов)", MaxContentLength)
 		}
 		if r.WantedMoney > MaxWantedMoney {
-			return fmt.Errorf("желаемая сумма слишком велика (макс %.0f)", MaxWantedMoney)
+			return fmt.Errorf("желаемая сумма слишком велика (макс %.0f)", MaxWantedMoney.Float64())
 		}
 		if r.DurationDays > MaxDurationDays {
 			return fmt.Errorf("срок слишком велик (макс %d дней)", MaxDurationDays)
 		}
+		if len(r.TagIDs) > MaxProjectTags {
+			return fmt.Errorf("слишком много тегов (макс %d)", MaxProjectTags)
+		}
 	case UpdateProjectRequest:
 		if r.Name == "" || r.QuickPeek == "" || r.Content == "" || r.WantedMoney <= 0 || r.DurationDays <= 0 {
 			return fmt.Errorf("название, краткое и полное описание, срок и желаемая сумма обязательны")
@@ -65,24 +70,42 @@ func ValidateProjectInput(req interface{}) error {
 			return fmt.Errorf("полное описание слишком длинное (макс %d символов)", MaxContentLength)
 		}
 		if r.WantedMoney > MaxWantedMoney {
-			return fmt.Errorf("желаемая сумма слишком велика (макс %.0f)", MaxWantedMoney)
+			return fmt.Errorf("желаемая сумма слишком велика (макс %.0f)", MaxWantedMoney.Float64())
 		}
 		if r.DurationDays > MaxDurationDays {
 			return fmt.Errorf("срок слишком велик (макс %d дней)", MaxDurationDays)
 		}
+		if r.TagIDs != nil && len(*r.TagIDs) > MaxProjectTags {
+			return fmt.Errorf("слишком много тегов (макс %d)", MaxProjectTags)
+		}
 	}
 	return nil
 }
 
+// tagsFromIDs превращает id тегов из запроса в теги проекта; nil остается nil
+func tagsFromIDs(ids []int) []core.Tag {
+	if ids == nil {
+		return nil
+	}
+	tags := make([]core.Tag, len(ids))
+	for i, id := range ids {
+		tags[i] = core.Tag{ID: id}
+	}
+	return tags
+}
+
 type CreateProjectRequest struct {
-	Name             string  `json:"name"`
-	CreatorID        int     `json:"creator_id"`
-	QuickPeek        string  `json:"quick_peek"`
-	Content          string  `json:"content"`
-	WantedMoney      float64 `json:"wanted_money"`
-	DurationDays     int     `json:"duration_days"`
-	MonetizationType string  `json:"monetization_type"`
-	Percent          float64 `json:"percent"`
+	Name             string       `json:"name"`
+	CreatorID        int          `json:"creator_id"`
+	QuickPeek        string       `json:"quick_peek"`
+	Content          string       `json:"content"`
+	WantedMoney      money.Amount `json:"wanted_money"`
+	DurationDays     int          `json:"duration_days"`
+	MonetizationType string       `json:"monetization_type"`
+	Percent          float64      `json:"percent"`
+	// Currency — целевая валюта проекта, по умолчанию RUB
+	Currency string `json:"currency"`
+	TagIDs   []int  `json:"tag_ids"`
 }
 
 func CreateProjectHandler(h *Handler) http.HandlerFunc {
@@ -110,6 +133,12 @@ func CreateProjectHandler(h *Handler) http.HandlerFunc {
 			return
 		}
 
+		currency, err := money.ParseCurrency(req.Currency)
+		if err != nil {
+			http.Error(w, "Неизвестная валюта проекта", http.StatusBadRequest)
+			return
+		}
+
 		if req.MonetizationType == "fixed_percent" || req.MonetizationType == "time_percent" {
 			if req.Percent <= 0 {
 				req.Percent = 5.0
@@ -124,9 +153,11 @@ func CreateProjectHandler(h *Handler) http.HandlerFunc {
 			QuickPeek:        req.QuickPeek,
 			Content:          req.Content,
 			WantedMoney:      req.WantedMoney,
+			Currency:         currency,
 			DurationDays:     req.DurationDays,
 			MonetizationType: req.MonetizationType,
 			Percent:          req.Percent,
+			Tags:             tagsFromIDs(req.TagIDs),
 		}
 
 		proj, err := h.service.Create(r.Context(), p, req.CreatorID, claims.UserID)
@@ -139,6 +170,9 @@ func CreateProjectHandler(h *Handler) http.HandlerFunc {
 			case core.ErrNotAuthorized:
 				http.Error(w, "Нет прав для создания проекта в этой организации", http.StatusForbidden)
 				return
+			case core.ErrTagNotFound:
+				http.Error(w, "Тег не найден", http.StatusBadRequest)
+				return
 			}
 			http.Error(w, "Ошибка сервера при создании проекта", http.StatusInternalServerError)
 			return
@@ -160,7 +194,39 @@ func GetProjectHandler(h *Handler) http.HandlerFunc {
 			return
 		}
 
-		p, err := h.service.Get(r.Context(), id)
+		userID, isAdmin := 0, false
+		if claims := middleware.FromContext(r.Context()); claims != nil {
+			userID, isAdmin = claims.UserID, claims.Admin
+		}
+		p, err := h.service.Get(r.Context(), id, userID, isAdmin)
+		if err != nil {
+			h.log.Error("err while getting project", "id", id, "error", err)
+			if err == core.ErrProjectNotFound {
+				http.Error(w, "Проект не найден", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(p)
+	}
+}
+
+// GetProjectInternalHandler отдает проект из БД, а не из кеша, для проверок
+// сервиса транзакций перед переводом. Снаружи ручка закрыта.
+func GetProjectInternalHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := r.PathValue("id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			h.log.Error("invalid project id", "id", idStr, "error", err)
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		p, err := h.service.GetFresh(r.Context(), id)
 		if err != nil {
 			h.log.Error("err while getting project", "id", id, "error", err)
 			if err == core.ErrProjectNotFound {
@@ -177,12 +243,14 @@ func GetProjectHandler(h *Handler) http.HandlerFunc {
 }
 
 type UpdateProjectRequest struct {
-	Name         string  `json:"name"`
-	QuickPeek    string  `json:"quick_peek"`
-	Content      string  `json:"content"`
-	IsPublic     bool    `json:"is_public"`
-	WantedMoney  float64 `json:"wanted_money"`
-	DurationDays int     `json:"duration_days"`
+	Name         string       `json:"name"`
+	QuickPeek    string       `json:"quick_peek"`
+	Content      string       `json:"content"`
+	IsPublic     bool         `json:"is_public"`
+	WantedMoney  money.Amount `json:"wanted_money"`
+	DurationDays int          `json:"duration_days"`
+	// TagIDs заменяет теги проекта; без поля теги не меняются
+	TagIDs *[]int `json:"tag_ids"`
 }
 
 func UpdateProjectHandler(h *Handler) http.HandlerFunc {
@@ -226,6 +294,9 @@ func UpdateProjectHandler(h *Handler) http.HandlerFunc {
 			WantedMoney:  req.WantedMoney,
 			DurationDays: req.DurationDays,
 		}
+		if req.TagIDs != nil {
+			p.Tags = tagsFromIDs(*req.TagIDs)
+		}
 
 		proj, err := h.service.Update(r.Context(), id, p, claims.UserID)
 		if err != nil {
@@ -237,9 +308,15 @@ func UpdateProjectHandler(h *Handler) http.HandlerFunc {
 			case core.ErrNotAuthorized:
 				http.Error(w, "Нет прав для изменения проекта", http.StatusForbidden)
 				return
+			case core.ErrNotEditable:
+				http.Error(w, "Изменить можно только черновик: проект уже отправлен на модерацию", http.StatusConflict)
+				return
 			case core.ErrInvalidInput:
 				http.Error(w, "Некорректные входные данные", http.StatusBadRequest)
 				return
+			case core.ErrTagNotFound:
+				http.Error(w, "Тег не найден", http.StatusBadRequest)
+				return
 			}
 			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
 			return
@@ -250,47 +327,63 @@ func UpdateProjectHandler(h *Handler) http.HandlerFunc {
 	}
 }
 
+// GetProjectListHandler отдает страницу ленты массивом проектов. Порядок задается параметром sort,
+// страница — offset или cursor; курсор следующей страницы приходит в заголовке X-Next-Cursor.
 func GetProjectListHandler(h *Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		limitStr := r.URL.Query().Get("limit")
 		offsetStr := r.URL.Query().Get("offset")
-		monetizationType := r.URL.Query().Get("type")
-		searchQuery := r.URL.Query().Get("search")
+		cursorStr := r.URL.Query().Get("cursor")
 
-		limit := 10
-		offset := 0
+		f, msg := parseProjectFilter(r.URL.Query())
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		page := core.Page{Limit: 10, Sort: core.ProjectSort(r.URL.Query().Get("sort"))}
+		if page.Sort != "" && !page.Sort.Valid() {
+			http.Error(w, "Некорректный порядок сортировки", http.StatusBadRequest)
+			return
+		}
 
 		if limitStr != "" {
 			if l, err := strconv.Atoi(limitStr); err == nil {
-				limit = l
+				page.Limit = l
 			}
 		}
 		if offsetStr != "" {
 			if o, err := strconv.Atoi(offsetStr); err == nil {
-				offset = o
+				page.Offset = o
 			}
 		}
-
-		if monetizationType != "" {
-			validTypes := map[string]bool{
-				"charity":       true,
-				"custom":        true,
-				"fixed_percent": true,
-				"time_percent":  true,
-			}
-			if !validTypes[monetizationType] {
-				http.Error(w, "Некорректный тип монетизации", http.StatusBadRequest)
+		if cursorStr != "" {
+			cursor, err := core.DecodeCursor(cursorStr)
+			if err != nil {
+				http.Error(w, "Некорректный курсор", http.StatusBadRequest)
 				return
 			}
+			page.Cursor = cursor
 		}
 
-		projects, err := h.service.GetList(r.Context(), limit, offset, monetizationType, searchQuery)
+		projects, next, err := h.service.GetList(r.Context(), page, f)
 		if err != nil {
+			if err == core.ErrInvalidCursor {
+				http.Error(w, "Курсор относится к другому порядку сортировки", http.StatusBadRequest)
+				return
+			}
+			if err == core.ErrInvalidInput {
+				http.Error(w, "Сортировка по релевантности доступна только с поисковым запросом", http.StatusBadRequest)
+				return
+			}
 			h.log.Error("failed to get projects list", "error", err)
 			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
 			return
 		}
 
+		if next != "" {
+			w.Header().Set("X-Next-Cursor", next)
+		}
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		_ = json.NewEncoder(w).Encode(projects)
 	}
@@ -382,8 +475,18 @@ func BanProjectHandler(h *Handler) http.HandlerFunc {
 			return
 		}
 
-		err = h.service.BanProject(r.Context(), projectID, ban)
+		reason := strings.TrimSpace(r.URL.Query().Get("reason"))
+		if len(reason) > MaxReasonLength {
+			http.Error(w, "Причина слишком длинная", http.StatusBadRequest)
+			return
+		}
+
+		err = h.service.BanProject(r.Context(), projectID, ban, reason, claims.UserID)
 		if err != nil {
+			if err == core.ErrProjectNotFound {
+				http.Error(w, "Проект не найден", http.StatusNotFound)
+				return
+			}
 			h.log.Error("failed to ban/unban project", "project_id", projectID, "banned", ban, "error", err)
 			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
 			return
@@ -428,6 +531,10 @@ func ChangeProjectPublicityHandler(h *Handler) http.HandlerFunc {
 				http.Error(w, "Нет прав для изменения публичности проекта", http.StatusForbidden)
 				return
 			}
+			if err == core.ErrNotApproved {
+				http.Error(w, "Проект еще не одобрен модератором", http.StatusConflict)
+				return
+			}
 			h.log.Error("failed to change project publicity", "project_id", projectID, "error", err)
 			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
 			return
@@ -465,16 +572,22 @@ func MarkProjectCompletedHandler(h *Handler) http.HandlerFunc {
 			return
 		}
 
-		err = h.service.MarkProjectCompleted(r.Context(), projectID, claims.UserID, completed)
+		reason := strings.TrimSpace(r.URL.Query().Get("reason"))
+		if len(reason) > MaxReasonLength {
+			http.Error(w, "Причина слишком длинная", http.StatusBadRequest)
+			return
+		}
+
+		err = h.service.MarkProjectCompleted(r.Context(), projectID, claims.UserID, completed, reason)
 		if err != nil {
 			if err == core.ErrNotAuthorized {
 				h.log.Warn("unauthorized attempt to mark project completed", "project_id", projectID, "user_id", claims.UserID)
 				http.Error(w, "Нет прав для изменения статуса проекта", http.StatusForbidden)
 				return
 			}
-			if err == core.ErrPaybackStarted {
-				h.log.Warn("attempt to change is_completed when payback has started", "project_id", projectID)
-				http.Error(w, "Невозможно изменить статус завершенности проекта после начала возврата средств инвесторам", http.StatusBadRequest)
+			if err == core.ErrInvalidTransition {
+				h.log.Warn("attempt to complete project that is not collecting funds", "project_id", projectID, "completed", completed)
+				http.Error(w, "Завершить можно только проект, по которому идет сбор; вернуть проект в сбор нельзя", http.StatusConflict)
 				return
 			}
 			h.log.Error("failed to mark project as completed", "project_id", projectID, "error", err)
@@ -509,9 +622,9 @@ func StartPaybackHandler(h *Handler) http.HandlerFunc {
 				http.Error(w, "Нет прав для запуска возврата средств (требуется money_management)", http.StatusForbidden)
 				return
 			}
-			if err == core.ErrPaybackStarted {
-				h.log.Warn("payback already started", "project_id", projectID)
-				http.Error(w, "Возврат средств уже запущен для этого проекта", http.StatusBadRequest)
+			if err == core.ErrInvalidTransition {
+				h.log.Warn("payback requires a funded project", "project_id", projectID)
+				http.Error(w, "Возврат средств можно начать только после завершения сбора", http.StatusConflict)
 				return
 			}
 			if err == core.ErrPaybackNotSupported {
@@ -519,6 +632,11 @@ func StartPaybackHandler(h *Handler) http.HandlerFunc {
 				http.Error(w, "Возврат средств не поддерживается для проектов с типом монетизации charity или custom", http.StatusBadRequest)
 				return
 			}
+			if err == core.ErrPaybackInProgress {
+				h.log.Warn("payback already in progress", "project_id", projectID)
+				http.Error(w, "Возврат средств по этому проекту уже выполняется", http.StatusConflict)
+				return
+			}
 			if err == core.ErrNotEnoughFunds {
 				h.log.Warn("not enough funds to complete payback", "project_id", projectID)
 				http.Error(w, "Недостаточно средств на проекте для полного возврата инвесторам", http.StatusBadRequest)
@@ -533,76 +651,8 @@ func StartPaybackHandler(h *Handler) http.HandlerFunc {
 	}
 }
 
-type AddFundsRequest struct {
-	Amount float64 `json:"amount"`
-}
-
-func AddFundsHandler(h *Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := r.PathValue("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			http.Error(w, "invalid id", http.StatusBadRequest)
-			return
-		}
-
-		var req AddFundsRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "bad request", http.StatusBadRequest)
-			return
-		}
-
-		if req.Amount <= 0 {
-			http.Error(w, "amount must be positive", http.StatusBadRequest)
-			return
-		}
-
-		err = h.service.AddFunds(r.Context(), id, req.Amount)
-		if err != nil {
-			http.Error(w, "internal error", http.StatusInternalServerError)
-			return
-		}
-
-		w.WriteHeader(http.StatusOK)
-	}
-}
-
-type DecreaseFundsRequest struct {
-	Amount float64 `json:"amount"`
-}
-
-func DecreaseFundsHandler(h *Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		idStr := r.PathValue("id")
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			http.Error(w, "invalid id", http.StatusBadRequest)
-			return
-		}
-
-		var req DecreaseFundsRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "bad request", http.StatusBadRequest)
-			return
-		}
-
-		if req.Amount <= 0 {
-			http.Error(w, "amount must be positive", http.StatusBadRequest)
-			return
-		}
-
-		err = h.service.AddFunds(r.Context(), id, -req.Amount)
-		if err != nil {
-			http.Error(w, "internal error", http.StatusInternalServerError)
-			return
-		}
-
-		w.WriteHeader(http.StatusOK)
-	}
-}
-
 type UpdateMoneyRequiredToPaybackRequest struct {
-	Amount float64 `json:"amount"`
+	Amount money.Amount `json:"amount"`
 }
 
 func UpdateMoneyRequiredToPaybackHandler(h *Handler) http.HandlerFunc {
@@ -622,7 +672,7 @@ func UpdateMoneyRequiredToPaybackHandler(h *Handler) http.HandlerFunc {
 			return
 		}
 
-		if req.Amount < 0 {
+		if req.Amount.IsNegative() {
 			http.Error(w, "amount must not be negative", http.StatusBadRequest)
 			return
 		}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+	"github.com/Starostina-elena/investment_platform/services/project/middleware"
+)
+
+const MaxReasonLength = 1024
+
+type ReasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// readReason читает необязательную причину из тела запроса
+func readReason(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req ReasonRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return "", false
+		}
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > MaxReasonLength {
+		http.Error(w, "Причина слишком длинная", http.StatusBadRequest)
+		return "", false
+	}
+	return req.Reason, true
+}
+
+// SubmitProjectHandler отправляет черновик на модерацию
+func SubmitProjectHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		projectID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.SubmitForReview(r.Context(), projectID, claims.UserID); err != nil {
+			writeLifecycleError(h, w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ReviewProjectHandler — решение модератора по проекту из очереди; при отказе причина обязательна
+func ReviewProjectHandler(h *Handler, approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r) {
+			return
+		}
+		claims := middleware.FromContext(r.Context())
+
+		projectID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+		reason, ok := readReason(w, r)
+		if !ok {
+			return
+		}
+		if !approve && reason == "" {
+			http.Error(w, "Укажите причину отказа", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.ReviewProject(r.Context(), projectID, claims.UserID, approve, reason); err != nil {
+			writeLifecycleError(h, w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CloseProjectHandler закрывает проект после сбора без выплат инвесторам
+func CloseProjectHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		projectID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+		reason, ok := readReason(w, r)
+		if !ok {
+			return
+		}
+
+		if err := h.service.CloseProject(r.Context(), projectID, claims.UserID, reason); err != nil {
+			writeLifecycleError(h, w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetStatusHistoryHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		projectID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		history, err := h.service.GetStatusHistory(r.Context(), projectID, claims.UserID, claims.Admin)
+		if err != nil {
+			writeLifecycleError(h, w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(history)
+	}
+}
+
+// GetReviewQueueHandler — проекты, ждущие модерации, в порядке отправки
+func GetReviewQueueHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r) {
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		projects, err := h.service.GetPendingReview(r.Context(), limit, offset)
+		if err != nil {
+			h.log.Error("failed to get review queue", "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(projects)
+	}
+}
+
+func writeLifecycleError(h *Handler, w http.ResponseWriter, err error) {
+	switch err {
+	case core.ErrProjectNotFound:
+		http.Error(w, "Проект не найден", http.StatusNotFound)
+	case core.ErrNotAuthorized:
+		http.Error(w, "Нет прав для изменения статуса проекта", http.StatusForbidden)
+	case core.ErrInvalidTransition:
+		http.Error(w, "Недопустимый переход статуса проекта", http.StatusConflict)
+	case core.ErrPaybackRequired:
+		http.Error(w, "Проект с доходностью закрывается после выплат инвесторам", http.StatusConflict)
+	case core.ErrInvalidInput:
+		http.Error(w, "Укажите причину отказа", http.StatusBadRequest)
+	default:
+		h.log.Error("project lifecycle operation failed", "error", err)
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+const MaxSearchQueryLength = 256
+
+var (
+	validMonetizationTypes = map[string]bool{
+		"charity":       true,
+		"custom":        true,
+		"fixed_percent": true,
+		"time_percent":  true,
+	}
+	validOrgTypes = map[string]bool{"jur": true, "phys": true, "ip": true}
+)
+
+// parseProjectFilter читает фильтры ленты и поиска из query-параметров:
+// search, type, tags, org_type, progress_min/progress_max и percent_min/percent_max.
+// Вторым значением возвращается текст ошибки для ответа 400.
+func parseProjectFilter(query url.Values) (core.ProjectFilter, string) {
+	f := core.ProjectFilter{
+		Query:            strings.TrimSpace(query.Get("search")),
+		MonetizationType: query.Get("type"),
+		OrgType:          query.Get("org_type"),
+	}
+	if len(f.Query) > MaxSearchQueryLength {
+		return f, "Слишком длинный поисковый запрос"
+	}
+	if f.MonetizationType != "" && !validMonetizationTypes[f.MonetizationType] {
+		return f, "Некорректный тип монетизации"
+	}
+	if f.OrgType != "" && !validOrgTypes[f.OrgType] {
+		return f, "Некорректный тип организации"
+	}
+
+	var err error
+	if f.TagIDs, err = parseTagIDs(query.Get("tags")); err != nil {
+		return f, "Некорректный список тегов"
+	}
+	if f.MinProgress, f.MaxProgress, err = parseRange(query, "progress_min", "progress_max"); err != nil {
+		return f, "Некорректный диапазон прогресса сбора"
+	}
+	if f.MinPercent, f.MaxPercent, err = parseRange(query, "percent_min", "percent_max"); err != nil {
+		return f, "Некорректный диапазон доходности"
+	}
+	return f, ""
+}
+
+// parseRange разбирает необязательный диапазон неотрицательных конечных чисел.
+// ParseFloat понимает NaN и Inf, а такие границы дошли бы до SQL.
+func parseRange(query url.Values, minKey, maxKey string) (*float64, *float64, error) {
+	parse := func(key string) (*float64, error) {
+		s := query.Get(key)
+		if s == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, core.ErrInvalidInput
+		}
+		return &v, nil
+	}
+	lo, err := parse(minKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	hi, err := parse(maxKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if lo != nil && hi != nil && *lo > *hi {
+		return nil, nil, core.ErrInvalidInput
+	}
+	return lo, hi, nil
+}
+
+// SearchProjectsHandler ищет проекты по словам из search с теми же фильтрами, что и лента.
+// Результаты отсортированы по релевантности и содержат фрагменты с подсветкой.
+func SearchProjectsHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, msg := parseProjectFilter(r.URL.Query())
+		if msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if f.Query == "" {
+			http.Error(w, "Поисковый запрос обязателен", http.StatusBadRequest)
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		results, err := h.service.Search(r.Context(), limit, offset, f)
+		if err != nil {
+			h.log.Error("failed to search projects", "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(results)
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		query   string
+		lo, hi  *float64
+		wantErr bool
+	}{
+		{query: "", lo: nil, hi: nil},
+		{query: "min=10", lo: ptr(10)},
+		{query: "max=50.5", hi: ptr(50.5)},
+		{query: "min=0&max=0", lo: ptr(0), hi: ptr(0)},
+		{query: "min=10&max=100", lo: ptr(10), hi: ptr(100)},
+		{query: "min=100&max=10", wantErr: true},
+		{query: "min=-1", wantErr: true},
+		{query: "max=abc", wantErr: true},
+		{query: "min=NaN", wantErr: true},
+		{query: "max=nan", wantErr: true},
+		{query: "max=Inf", wantErr: true},
+		{query: "max=%2BInf", wantErr: true},
+		{query: "min=-Inf", wantErr: true},
+		{query: "max=infinity", wantErr: true},
+		{query: "max=1e400", wantErr: true},
+	}
+	for _, tt := range tests {
+		query, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lo, hi, err := parseRange(query, "min", "max")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRange(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(lo, tt.lo) || !reflect.DeepEqual(hi, tt.hi) {
+			t.Errorf("parseRange(%q) = %v, %v; want %v, %v", tt.query, deref(lo), deref(hi), deref(tt.lo), deref(tt.hi))
+		}
+	}
+}
+
+func TestParseProjectFilter(t *testing.T) {
+	query, _ := url.ParseQuery("search=+пекарня+&type=fixed_percent&org_type=ip&tags=1,2&progress_min=50&percent_max=20")
+	f, msg := parseProjectFilter(query)
+	if msg != "" {
+		t.Fatalf("parseProjectFilter() msg = %q, want none", msg)
+	}
+	if f.Query != "пекарня" || f.MonetizationType != "fixed_percent" || f.OrgType != "ip" ||
+		!reflect.DeepEqual(f.TagIDs, []int{1, 2}) {
+		t.Errorf("parseProjectFilter() = %+v", f)
+	}
+	if f.MinProgress == nil || *f.MinProgress != 50 || f.MaxProgress != nil ||
+		f.MinPercent != nil || f.MaxPercent == nil || *f.MaxPercent != 20 {
+		t.Errorf("parseProjectFilter() ranges = %v-%v, %v-%v",
+			deref(f.MinProgress), deref(f.MaxProgress), deref(f.MinPercent), deref(f.MaxPercent))
+	}
+
+	if f, msg := parseProjectFilter(url.Values{}); msg != "" || !reflect.DeepEqual(f, core.ProjectFilter{}) {
+		t.Errorf("parseProjectFilter(empty) = %+v, %q; want no filters", f, msg)
+	}
+}
+
+func TestParseProjectFilterRejects(t *testing.T) {
+	tests := map[string]string{
+		"search=" + strings.Repeat("a", MaxSearchQueryLength+1): "Слишком длинный поисковый запрос",
+		"type=loan":                       "Некорректный тип монетизации",
+		"org_type=llc":                    "Некорректный тип организации",
+		"tags=1,x":                        "Некорректный список тегов",
+		"progress_min=NaN":                "Некорректный диапазон прогресса сбора",
+		"progress_min=90&progress_max=10": "Некорректный диапазон прогресса сбора",
+		"percent_max=Inf":                 "Некорректный диапазон доходности",
+	}
+	for raw, want := range tests {
+		query, err := url.ParseQuery(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, msg := parseProjectFilter(query); msg != want {
+			t.Errorf("parseProjectFilter(%.40q) msg = %q, want %q", raw, msg, want)
+		}
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+	"github.com/Starostina-elena/investment_platform/services/project/middleware"
+)
+
+const (
+	MaxTagNameLength        = 128
+	MaxTagDescriptionLength = 256
+	MaxTagsInFilter         = 20
+)
+
+// parseTagIDs разбирает фильтр ленты вида "1,2,3"
+func parseTagIDs(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) > MaxTagsInFilter {
+		return nil, core.ErrInvalidInput
+	}
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			return nil, core.ErrInvalidInput
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetTagsHandler отдает все теги с числом проектов в ленте для навигатора по категориям
+func GetTagsHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tags, err := h.service.GetTags(r.Context())
+		if err != nil {
+			h.log.Error("failed to get tags", "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(tags)
+	}
+}
+
+type TagRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (req TagRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "Название тега обязательно"
+	}
+	if len(req.Name) > MaxTagNameLength {
+		return "Название тега слишком длинное"
+	}
+	if req.Description != nil && len(*req.Description) > MaxTagDescriptionLength {
+		return "Описание тега слишком длинное"
+	}
+	return ""
+}
+
+func CreateTagHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r) {
+			return
+		}
+
+		var req TagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if msg := req.validate(); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		tag, err := h.service.CreateTag(r.Context(), core.Tag{Name: strings.TrimSpace(req.Name), Description: req.Description})
+		if err != nil {
+			writeTagError(h, w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(tag)
+	}
+}
+
+func UpdateTagHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r) {
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		var req TagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if msg := req.validate(); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		tag, err := h.service.UpdateTag(r.Context(), core.Tag{ID: id, Name: strings.TrimSpace(req.Name), Description: req.Description})
+		if err != nil {
+			writeTagError(h, w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(tag)
+	}
+}
+
+// DeleteTagHandler удаляет тег и снимает его со всех проектов
+func DeleteTagHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(w, r) {
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.DeleteTag(r.Context(), id); err != nil {
+			writeTagError(h, w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	claims := middleware.FromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if !claims.Admin || claims.Banned {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func writeTagError(h *Handler, w http.ResponseWriter, err error) {
+	switch err {
+	case core.ErrTagNotFound:
+		http.Error(w, "Тег не найден", http.StatusNotFound)
+	case core.ErrTagExists:
+		http.Error(w, "Тег с таким названием уже есть", http.StatusConflict)
+	default:
+		h.log.Error("tag operation failed", "error", err)
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTagIDs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "3", want: []int{3}},
+		{in: "1,2, 5", want: []int{1, 2, 5}},
+		{in: "1,,2", wantErr: true},
+		{in: "1,a", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "-4", wantErr: true},
+		{in: "1.5", wantErr: true},
+		{in: ",", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTagIDs(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTagIDs(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTagIDs(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTagIDsLimit(t *testing.T) {
+	ids := make([]string, MaxTagsInFilter+1)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i + 1)
+	}
+	if got, err := parseTagIDs(strings.Join(ids[:MaxTagsInFilter], ",")); err != nil || len(got) != MaxTagsInFilter {
+		t.Errorf("parseTagIDs(%d ids) = %v, %v; want all ids", MaxTagsInFilter, got, err)
+	}
+	if _, err := parseTagIDs(strings.Join(ids, ",")); err == nil {
+		t.Errorf("parseTagIDs(%d ids) error = nil, want ErrInvalidInput", len(ids))
+	}
+}
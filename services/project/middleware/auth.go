@@ -31,6 +31,21 @@ func SetClaimsInContext(ctx context.Context, claims *UserClaims) context.Context
 	return context.WithValue(ctx, ctxUserKey, claims)
 }
 
+// OptionalAuthMiddleware кладет в контекст пользователя, если запрос пришел с токеном.
+// Без токена или с недействительным токеном запрос обрабатывается как анонимный.
+func OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := auth.ParseAndVerify(parts[1]); err == nil {
+				uc := &UserClaims{UserID: claims.UserID, Admin: claims.Admin, Banned: claims.Banned}
+				r = r.WithContext(context.WithValue(r.Context(), ctxUserKey, uc))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authz := r.Header.Get("Authorization")
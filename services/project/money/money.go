@@ -0,0 +1,249 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Amount — денежная сумма в копейках. Суммы хранятся целым числом, поэтому
+// сложение, вычитание и сравнение точные; операции с дробными множителями
+// (проценты, доли) округляются явно выбранным способом.
+type Amount int64
+
+type RoundingMode int
+
+const (
+	// RoundHalfUp — до ближайшей копейки, половина от нуля (0.005 → 0.01)
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven — до ближайшей копейки, половина к четной (банковское округление)
+	RoundHalfEven
+	// RoundDown — отбрасывание дробной части копейки (к нулю)
+	RoundDown
+	// RoundUp — до целой копейки от нуля
+	RoundUp
+)
+
+const Zero Amount = 0
+
+var (
+	ErrInvalid   = errors.New("invalid money amount")
+	ErrPrecision = errors.New("money amount has more than two decimal places")
+	ErrOverflow  = errors.New("money amount is out of range")
+)
+
+var hundred = big.NewInt(100)
+
+func FromKopecks(k int64) Amount {
+	return Amount(k)
+}
+
+func FromRubles(r int64) Amount {
+	return Amount(r * 100)
+}
+
+// Parse разбирает десятичную запись («1500», «99.9», «-0.01», «1e3»).
+// Запись точнее копейки — ошибка, молча округлять входные суммы нельзя.
+func Parse(s string) (Amount, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(hundred))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("%w: %q", ErrPrecision, s)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+	return Amount(r.Num().Int64()), nil
+}
+
+// MustParse — Parse для констант в коде и тестах
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// FromRat переводит точное значение в рублях в копейки с округлением mode.
+// Значение, которое не помещается в Amount, — ErrOverflow.
+func FromRat(r *big.Rat, mode RoundingMode) (Amount, error) {
+	num := new(big.Int).Mul(r.Num(), hundred)
+	k := roundQuo(num, r.Denom(), mode)
+	if !k.IsInt64() {
+		return 0, fmt.Errorf("%w: %s", ErrOverflow, r.FloatString(2))
+	}
+	return Amount(k.Int64()), nil
+}
+
+// Rat — точное значение суммы в рублях
+func (a Amount) Rat() *big.Rat {
+	return big.NewRat(int64(a), 100)
+}
+
+func (a Amount) Kopecks() int64 {
+	return int64(a)
+}
+
+// Float64 — приближенное значение в рублях, только для отображения
+func (a Amount) Float64() float64 {
+	return float64(a) / 100
+}
+
+func (a Amount) String() string {
+	sign := ""
+	k := int64(a)
+	if k < 0 {
+		sign = "-"
+		k = -k
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, k/100, k%100)
+}
+
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+func (a Amount) IsNegative() bool {
+	return a < 0
+}
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// MulRat умножает сумму на точный множитель и округляет результат до копейки
+func (a Amount) MulRat(r *big.Rat, mode RoundingMode) (Amount, error) {
+	return FromRat(new(big.Rat).Mul(a.Rat(), r), mode)
+}
+
+// Percent — p процентов от суммы, округленные до копейки
+func (a Amount) Percent(p float64, mode RoundingMode) (Amount, error) {
+	rate, err := PercentRate(p)
+	if err != nil {
+		return 0, err
+	}
+	return a.MulRat(rate, mode)
+}
+
+// PercentRate переводит процент в точную долю (12.5 → 1/8). Берется кратчайшая
+// десятичная запись float64, то есть ровно то число, которое ввел пользователь.
+// NaN и бесконечность — ErrInvalid.
+func PercentRate(p float64) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(p, 'f', -1, 64))
+	if !ok {
+		return nil, fmt.Errorf("%w: percent %v", ErrInvalid, p)
+	}
+	return r.Quo(r, big.NewRat(100, 1)), nil
+}
+
+// roundQuo делит num на den (den > 0) с округлением mode
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+
+	sign := int64(num.Sign())
+	twiceRem := new(big.Int).Abs(rem)
+	twiceRem.Lsh(twiceRem, 1)
+	cmpHalf := twiceRem.Cmp(den)
+
+	away := false
+	switch mode {
+	case RoundHalfUp:
+		away = cmpHalf >= 0
+	case RoundHalfEven:
+		away = cmpHalf > 0 || (cmpHalf == 0 && q.Bit(0) == 1)
+	case RoundDown:
+		away = false
+	case RoundUp:
+		away = true
+	}
+	if away {
+		q.Add(q, big.NewInt(sign))
+	}
+	return q
+}
+
+// MarshalJSON пишет сумму JSON-числом с двумя знаками после точки
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON принимает число или строку и разбирает запись без перевода во float
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Value передает сумму в БД строкой, Postgres приводит ее к DECIMAL без потерь
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		*a = FromRubles(v)
+		return nil
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(v) == nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, v)
+		}
+		amount, err := FromRat(r, RoundHalfUp)
+		if err != nil {
+			return err
+		}
+		*a = amount
+		return nil
+	}
+	return fmt.Errorf("money: cannot scan %T", src)
+}
+
+// scanString разбирает DECIMAL из БД. Колонки с большей точностью (например,
+// результат деления в запросе) округляются до копейки, а не отклоняются.
+func (a *Amount) scanString(s string) error {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	v, err := FromRat(r, RoundHalfUp)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
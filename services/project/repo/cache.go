@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Starostina-elena/investment_platform/services/project/cache"
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+// GetCached читает проект через кеш. Для проверок перед изменением проекта нужен Get:
+// кеш может отставать от БД на время инвалидации.
+func (r *Repo) GetCached(ctx context.Context, id int) (*core.Project, error) {
+	if cached, err := r.cache.GetProject(ctx, id); err == nil && cached != nil {
+		return cached, nil
+	} else if err != nil {
+		r.log.Error("cache get project failed", "id", id, "error", err)
+	}
+
+	p, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.cache.SetProject(ctx, p); err != nil {
+		r.log.Error("cache set project failed", "id", id, "error", err)
+	}
+	return p, nil
+}
+
+// GetList возвращает страницу ленты в порядке page.Sort и курсор следующей страницы
+// (nil, если страница последняя). Страницы без поисковой строки кешируются под текущей
+// версией ленты.
+func (r *Repo) GetList(ctx context.Context, page core.Page, f core.ProjectFilter) ([]core.Project, *core.Cursor, error) {
+	if f.Query != "" {
+		return r.getList(ctx, page, f)
+	}
+
+	version, err := r.cache.FeedVersion(ctx)
+	if err != nil {
+		r.log.Error("cache get feed version failed", "error", err)
+		return r.getList(ctx, page, f)
+	}
+	key := cache.FeedKey(version, page, f)
+	if cached, err := r.cache.GetFeedPage(ctx, key); err == nil && cached != nil {
+		return cached.Projects, cached.Next, nil
+	} else if err != nil {
+		r.log.Error("cache get feed page failed", "error", err)
+	}
+
+	projects, next, err := r.getList(ctx, page, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.cache.SetFeedPage(ctx, key, cache.FeedPage{Projects: projects, Next: next}); err != nil {
+		r.log.Error("cache set feed page failed", "error", err)
+	}
+	return projects, next, nil
+}
+
+// InvalidateCache сбрасывает проекты из кеша и переводит ленту на новую версию.
+// Ошибки Redis только логируются: запись в БД уже прошла, а устаревшие ключи истекут по TTL.
+func (r *Repo) InvalidateCache(ctx context.Context, projectIDs ...int) {
+	if err := r.cache.DeleteProjects(ctx, projectIDs...); err != nil {
+		r.log.Error("cache delete projects failed", "project_ids", projectIDs, "error", err)
+	}
+	if err := r.cache.BumpFeedVersion(ctx); err != nil {
+		r.log.Error("cache bump feed version failed", "error", err)
+	}
+}
+
+// InvalidateCreatorCache сбрасывает из кеша все проекты организации
+func (r *Repo) InvalidateCreatorCache(ctx context.Context, creatorID int) error {
+	var ids []int
+	if err := r.db.SelectContext(ctx, &ids, `SELECT id FROM projects WHERE creator_id = $1`, creatorID); err != nil {
+		r.log.Error("failed to get creator projects", "creator_id", creatorID, "error", err)
+		return err
+	}
+	r.InvalidateCache(ctx, ids...)
+	return nil
+}
@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+// sortKey — выражение, по которому упорядочена лента, и тип для сравнения со значением
+// из курсора. Выражения совпадают с индексами из 0037_project_feed_sort и
+// 0038_project_lifecycle, иначе планировщик их не использует.
+type sortKey struct {
+	expr string
+	typ  string
+	desc bool
+}
+
+var sortKeys = map[core.ProjectSort]sortKey{
+	core.SortNewest:         {expr: "p.created_at", typ: "timestamp", desc: true},
+	core.SortClosestToGoal:  {expr: "(COALESCE(p.current_money, 0) / GREATEST(p.wanted_money, 0.01))", typ: "numeric", desc: true},
+	core.SortEndingSoon:     {expr: "(p.activated_at + COALESCE(p.duration_days, 30) * interval '1 day')", typ: "timestamp"},
+	core.SortHighestPercent: {expr: "COALESCE(p.percent, 0)", typ: "numeric", desc: true},
+	core.SortMostFunded:     {expr: "COALESCE(p.current_money, 0)", typ: "numeric", desc: true},
+}
+
+// feedOrder возвращает ключ сортировки страницы и добавляет в запрос условие курсора.
+// При равных ключах проекты идут по возрастанию id, поэтому курсор однозначен.
+func (q *projectQuery) feedOrder(page core.Page) sortKey {
+	key, ok := sortKeys[page.Sort]
+	if page.Sort == core.SortRelevance && q.rank != "" {
+		key, ok = sortKey{expr: q.rank, typ: "float8", desc: true}, true
+	}
+	if !ok {
+		key = sortKeys[core.SortNewest]
+	}
+	if page.Sort == core.SortEndingSoon {
+		// у проектов с истекшим сроком сбор уже закончился, daemon завершит их
+		q.where = append(q.where, key.expr+" > NOW()")
+	}
+
+	if c := page.Cursor; c != nil {
+		op := ">"
+		if key.desc {
+			op = "<"
+		}
+		k := q.arg(c.Key) + "::" + key.typ
+		q.where = append(q.where, fmt.Sprintf("%s %s= %s AND (%s %s %s OR p.id > %s)",
+			key.expr, op, k, key.expr, op, k, q.arg(c.ID)))
+	}
+	return key
+}
+
+func (k sortKey) orderBy() string {
+	if k.desc {
+		return k.expr + " DESC, p.id ASC"
+	}
+	return k.expr + " ASC, p.id ASC"
+}
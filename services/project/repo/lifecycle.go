@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+// Transition переводит проект в статус to и пишет переход в историю. Флаги is_public
+// и is_completed, которые читают лента и другие сервисы, выставляются по новому статусу:
+// до одобрения проект скрыт, одобрение публикует его и запускает срок сбора.
+func (r *Repo) Transition(ctx context.Context, projectID int, to core.ProjectStatus, reason string, actorID *int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var from core.ProjectStatus
+	err = tx.GetContext(ctx, &from, `SELECT status FROM projects WHERE id = $1 FOR UPDATE`, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return core.ErrProjectNotFound
+	}
+	if err != nil {
+		r.log.Error("failed to lock project", "project_id", projectID, "error", err)
+		return err
+	}
+	if !from.CanTransition(to) {
+		r.log.Warn("invalid project status transition", "project_id", projectID, "from", from, "to", to)
+		return core.ErrInvalidTransition
+	}
+
+	var isPublic *bool
+	if !to.Approved() || to == core.StatusActive {
+		public := to == core.StatusActive
+		isPublic = &public
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE projects SET status = $1, is_completed = $2, is_public = COALESCE($3, is_public),
+			activated_at = CASE WHEN $4 THEN NOW() ELSE activated_at END,
+			payback_started = payback_started OR $5,
+			payback_started_date = CASE WHEN $5 AND NOT payback_started THEN CURRENT_TIMESTAMP ELSE payback_started_date END
+		WHERE id = $6`,
+		to, to.Completed(), isPublic, to == core.StatusActive, to == core.StatusPaybackInProgress, projectID)
+	if err != nil {
+		r.log.Error("failed to change project status", "project_id", projectID, "to", to, "error", err)
+		return err
+	}
+
+	if err := addStatusChange(ctx, tx, core.StatusChange{
+		ProjectID: projectID, Action: core.ActionTransition, FromStatus: &from, ToStatus: to, Reason: reason, ActorID: actorID,
+	}); err != nil {
+		r.log.Error("failed to add project status history", "project_id", projectID, "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.log.Info("project status changed", "project_id", projectID, "from", from, "to", to)
+	r.InvalidateCache(ctx, projectID)
+	return nil
+}
+
+// SetBanned блокирует или разблокирует проект, статус при этом не меняется
+func (r *Repo) SetBanned(ctx context.Context, projectID int, banned bool, reason string, adminID int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var status core.ProjectStatus
+	err = tx.GetContext(ctx, &status,
+		`UPDATE projects SET is_banned = $1 WHERE id = $2 RETURNING status`, banned, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return core.ErrProjectNotFound
+	}
+	if err != nil {
+		r.log.Error("failed to ban/unban project", "project_id", projectID, "banned", banned, "error", err)
+		return err
+	}
+
+	action := core.ActionUnban
+	if banned {
+		action = core.ActionBan
+	}
+	if err := addStatusChange(ctx, tx, core.StatusChange{
+		ProjectID: projectID, Action: action, FromStatus: &status, ToStatus: status, Reason: reason, ActorID: &adminID,
+	}); err != nil {
+		r.log.Error("failed to add project status history", "project_id", projectID, "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.InvalidateCache(ctx, projectID)
+	return nil
+}
+
+func (r *Repo) GetStatusHistory(ctx context.Context, projectID int) ([]core.StatusChange, error) {
+	history := []core.StatusChange{}
+	if err := r.db.SelectContext(ctx, &history, `
+		SELECT id, project_id, action, from_status, to_status, reason, actor_id, created_at
+		FROM project_status_history WHERE project_id = $1
+		ORDER BY created_at, id`, projectID); err != nil {
+		r.log.Error("failed to get project status history", "project_id", projectID, "error", err)
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetPendingReview — очередь модерации, первыми идут проекты, отправленные раньше
+func (r *Repo) GetPendingReview(ctx context.Context, limit, offset int) ([]core.Project, error) {
+	projects := []core.Project{}
+	if err := r.db.SelectContext(ctx, &projects, `
+		SELECT `+listColumns+`
+		FROM projects p
+		WHERE p.status = $1
+		ORDER BY (SELECT MAX(h.created_at) FROM project_status_history h
+		          WHERE h.project_id = p.id AND h.to_status = $1), p.id
+		LIMIT $2 OFFSET $3`, core.StatusPendingReview, limit, offset); err != nil {
+		r.log.Error("failed to get projects pending review", "error", err)
+		return nil, err
+	}
+	if err := r.attachTags(ctx, projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func addStatusChange(ctx context.Context, tx *sqlx.Tx, c core.StatusChange) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO project_status_history (project_id, action, from_status, to_status, reason, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		c.ProjectID, c.Action, c.FromStatus, c.ToStatus, c.Reason, c.ActorID)
+	return err
+}
@@ -10,46 +10,111 @@ import (
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 
+	"github.com/Starostina-elena/investment_platform/services/project/cache"
 	"github.com/Starostina-elena/investment_platform/services/project/core"
+	"github.com/Starostina-elena/investment_platform/services/project/money"
+	"github.com/Starostina-elena/investment_platform/services/project/outbox"
 )
 
+const outboxService = "project"
+
+type ProjectCreatedEvent struct {
+	ProjectID        int            `json:"project_id"`
+	Name             string         `json:"name"`
+	CreatorID        int            `json:"creator_id"`
+	WantedMoney      money.Amount   `json:"wanted_money"`
+	Currency         money.Currency `json:"currency"`
+	DurationDays     int            `json:"duration_days"`
+	MonetizationType string         `json:"monetization_type"`
+}
+
 type Repo struct {
-	db  *sqlx.DB
-	log slog.Logger
+	db    *sqlx.DB
+	cache *cache.Cache
+	log   slog.Logger
 }
 
 type RepoInterface interface {
-	Create(ctx context.Context, p *core.Project) (int, error)
+	Create(ctx context.Context, p *core.Project, userID int) (int, error)
 	Get(ctx context.Context, id int) (*core.Project, error)
+	GetCached(ctx context.Context, id int) (*core.Project, error)
 	Update(ctx context.Context, p *core.Project) (*core.Project, error)
-	GetList(ctx context.Context, limit, offset int, monetizationType string, searchQuery string) ([]core.Project, error)
+	GetList(ctx context.Context, page core.Page, f core.ProjectFilter) ([]core.Project, *core.Cursor, error)
+	Search(ctx context.Context, limit, offset int, f core.ProjectFilter) ([]core.SearchResult, error)
 	GetByCreator(ctx context.Context, creatorID int) ([]core.Project, error)
 	GetAllByCreator(ctx context.Context, creatorID int) ([]core.Project, error)
 	UpdatePicturePath(ctx context.Context, projectID int, picturePath *string) error
-	BanProject(ctx context.Context, projectID int, banned bool) error
+	SetBanned(ctx context.Context, projectID int, banned bool, reason string, adminID int) error
 	ChangeProjectPublicity(ctx context.Context, projectID int, isPublic bool) error
-	MarkProjectCompleted(ctx context.Context, projectID int, completed bool) error
-	StartPayback(ctx context.Context, projectID int) error
+	Transition(ctx context.Context, projectID int, to core.ProjectStatus, reason string, actorID *int) error
+	GetStatusHistory(ctx context.Context, projectID int) ([]core.StatusChange, error)
+	GetPendingReview(ctx context.Context, limit, offset int) ([]core.Project, error)
 	GetProjectTransactions(ctx context.Context, projectID int) ([]core.Transaction, error)
-	UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, newAmount float64) error
-	AddFunds(ctx context.Context, projectID int, amount float64) error
+	UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, newAmount money.Amount) error
+	GetTags(ctx context.Context) ([]core.TagCount, error)
+	CreateTag(ctx context.Context, t *core.Tag) error
+	UpdateTag(ctx context.Context, t *core.Tag) error
+	DeleteTag(ctx context.Context, id int) error
+	InvalidateCache(ctx context.Context, projectIDs ...int)
+	InvalidateCreatorCache(ctx context.Context, creatorID int) error
 }
 
-func NewRepo(db *sqlx.DB, log slog.Logger) RepoInterface {
-	return &Repo{db: db, log: log}
+func NewRepo(db *sqlx.DB, c *cache.Cache, log slog.Logger) RepoInterface {
+	return &Repo{db: db, cache: c, log: log}
 }
 
-func (r *Repo) Create(ctx context.Context, p *core.Project) (int, error) {
+// Create сохраняет проект в статусе p.Status; userID попадает в историю статусов как автор
+func (r *Repo) Create(ctx context.Context, p *core.Project, userID int) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
 	var id int
-	row := r.db.QueryRowxContext(ctx,
-		`INSERT INTO projects (name, creator_id, quick_peek, content, wanted_money, duration_days, is_public, monetization_type, percent) 
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING id`,
-		p.Name, p.CreatorID, p.QuickPeek, p.Content, p.WantedMoney, p.DurationDays, p.IsPublic, p.MonetizationType, p.Percent,
+	row := tx.QueryRowxContext(ctx,
+		`INSERT INTO projects (name, creator_id, quick_peek, content, wanted_money, duration_days, is_public, monetization_type, percent, currency, status) 
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING id`,
+		p.Name, p.CreatorID, p.QuickPeek, p.Content, p.WantedMoney, p.DurationDays, p.IsPublic, p.MonetizationType, p.Percent, p.Currency, p.Status,
 	)
 	if err := row.Scan(&id); err != nil {
 		r.log.Error("failed to insert project", "error", err)
 		return 0, err
 	}
+	if err := addStatusChange(ctx, tx, core.StatusChange{
+		ProjectID: id, Action: core.ActionTransition, ToStatus: p.Status, ActorID: &userID,
+	}); err != nil {
+		r.log.Error("failed to add project status history", "project_id", id, "error", err)
+		return 0, err
+	}
+
+	if len(p.Tags) > 0 {
+		if err := r.setProjectTags(ctx, tx, id, p.Tags); err != nil {
+			r.log.Error("failed to set project tags", "project_id", id, "error", err)
+			return 0, err
+		}
+	}
+
+	err = outbox.Add(ctx, tx, outboxService, outbox.TypeProjectCreated, ProjectCreatedEvent{
+		ProjectID:        id,
+		Name:             p.Name,
+		CreatorID:        p.CreatorID,
+		WantedMoney:      p.WantedMoney,
+		Currency:         p.Currency,
+		DurationDays:     p.DurationDays,
+		MonetizationType: p.MonetizationType,
+	})
+	if err != nil {
+		r.log.Error("failed to add project created event", "project_id", id, "error", err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	r.InvalidateCache(ctx)
 	return id, nil
 }
 
@@ -59,7 +124,7 @@ func (r *Repo) Get(ctx context.Context, id int) (*core.Project, error) {
 		SELECT id, name, creator_id, quick_peek, quick_peek_picture_path, content, 
 		       is_public, is_completed, current_money, wanted_money, duration_days, 
 		       created_at, is_banned, monetization_type, percent, payback_started,
-		       payback_started_date, money_required_to_payback
+		       payback_started_date, money_required_to_payback, currency, status, activated_at
 		FROM projects WHERE id = $1`, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, core.ErrProjectNotFound
@@ -67,11 +132,24 @@ func (r *Repo) Get(ctx context.Context, id int) (*core.Project, error) {
 		r.log.Error("failed to get project", "id", id, "error", err)
 		return nil, err
 	}
-	return p, nil
+	projects := []core.Project{*p}
+	if err := r.attachTags(ctx, projects); err != nil {
+		return nil, err
+	}
+	return &projects[0], nil
 }
 
+// Update сохраняет поля проекта; теги заменяются, только если p.Tags не nil
 func (r *Repo) Update(ctx context.Context, p *core.Project) (*core.Project, error) {
-	_, err := r.db.ExecContext(ctx,
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx,
 		`UPDATE projects SET name=$1, quick_peek=$2, content=$3, is_public=$4, 
 		wanted_money=$5, duration_days=$6 WHERE id=$7`,
 		p.Name, p.QuickPeek, p.Content, p.IsPublic, p.WantedMoney, p.DurationDays, p.ID,
@@ -80,43 +158,52 @@ func (r *Repo) Update(ctx context.Context, p *core.Project) (*core.Project, erro
 		r.log.Error("failed to update project", "id", p.ID, "error", err)
 		return nil, err
 	}
+	if p.Tags != nil {
+		if err := r.setProjectTags(ctx, tx, p.ID, p.Tags); err != nil {
+			r.log.Error("failed to set project tags", "project_id", p.ID, "error", err)
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.InvalidateCache(ctx, p.ID)
 	return r.Get(ctx, p.ID)
 }
 
-func (r *Repo) GetList(ctx context.Context, limit, offset int, monetizationType string, searchQuery string) ([]core.Project, error) {
-	projects := []core.Project{}
-
-	query := `SELECT id, name, creator_id, quick_peek, quick_peek_picture_path, content, 
-	       is_public, is_completed, current_money, wanted_money, duration_days,
-	       payback_started_date, money_required_to_payback, 
-	       created_at, is_banned, monetization_type, percent, payback_started
-	FROM projects
-	WHERE is_public = true AND is_banned = false AND is_completed = false`
-
-	var args []interface{}
-	argIndex := 1
-
-	if monetizationType != "" {
-		query += " AND monetization_type = $" + fmt.Sprintf("%d", argIndex)
-		args = append(args, monetizationType)
-		argIndex++
+func (r *Repo) getList(ctx context.Context, page core.Page, f core.ProjectFilter) ([]core.Project, *core.Cursor, error) {
+	q := newProjectQuery(f)
+	key := q.feedOrder(page)
+	offset := page.Offset
+	if page.Cursor != nil {
+		offset = 0
 	}
+	query := fmt.Sprintf(`SELECT %s, (%s)::text AS sort_key FROM projects p WHERE %s ORDER BY %s LIMIT %s OFFSET %s`,
+		listColumns, key.expr, q.whereClause(), key.orderBy(), q.arg(page.Limit), q.arg(offset))
 
-	if searchQuery != "" {
-		query += " AND name ILIKE $" + fmt.Sprintf("%d", argIndex)
-		args = append(args, "%"+searchQuery+"%")
-		argIndex++
+	var rows []struct {
+		core.Project
+		SortKey string `db:"sort_key"`
 	}
-
-	query += " ORDER BY created_at DESC, id ASC LIMIT $" + fmt.Sprintf("%d", argIndex) + " OFFSET $" + fmt.Sprintf("%d", argIndex+1)
-	args = append(args, limit, offset)
-
-	if err := r.db.SelectContext(ctx, &projects, query, args...); err != nil {
+	if err := r.db.SelectContext(ctx, &rows, query, q.args...); err != nil {
 		r.log.Error("failed to get projects list", "error", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	return projects, nil
+	projects := make([]core.Project, len(rows))
+	for i := range rows {
+		projects[i] = rows[i].Project
+	}
+	if err := r.attachTags(ctx, projects); err != nil {
+		return nil, nil, err
+	}
+
+	var next *core.Cursor
+	if len(rows) == page.Limit && len(rows) > 0 {
+		last := rows[len(rows)-1]
+		next = &core.Cursor{Sort: page.Sort, Key: last.SortKey, ID: last.ID}
+	}
+	return projects, next, nil
 }
 
 func (r *Repo) GetByCreator(ctx context.Context, creatorID int) ([]core.Project, error) {
@@ -124,12 +211,15 @@ func (r *Repo) GetByCreator(ctx context.Context, creatorID int) ([]core.Project,
 	if err := r.db.SelectContext(ctx, &projects, `
 		SELECT id, name, creator_id, quick_peek, quick_peek_picture_path, content, 
 		       is_public, is_completed, current_money, wanted_money, duration_days,
-		       payback_started_date, money_required_to_payback, 
-		       created_at, is_banned, monetization_type, percent, payback_started
+		       payback_started_date, money_required_to_payback, currency, 
+		       created_at, is_banned, monetization_type, percent, payback_started, status, activated_at
 		FROM projects WHERE creator_id = $1 AND is_banned = false AND is_public = true ORDER BY created_at DESC, id ASC`, creatorID); err != nil {
 		r.log.Error("failed to get projects by creator", "creator_id", creatorID, "error", err)
 		return nil, err
 	}
+	if err := r.attachTags(ctx, projects); err != nil {
+		return nil, err
+	}
 	return projects, nil
 }
 
@@ -137,13 +227,16 @@ func (r *Repo) GetAllByCreator(ctx context.Context, creatorID int) ([]core.Proje
 	projects := []core.Project{}
 	if err := r.db.SelectContext(ctx, &projects, `
 		SELECT id, name, creator_id, quick_peek, quick_peek_picture_path, content,
-		       payback_started_date, money_required_to_payback, 
+		       payback_started_date, money_required_to_payback, currency, 
 		       is_public, is_completed, current_money, wanted_money, duration_days, 
-		       created_at, is_banned, monetization_type, percent, payback_started
+		       created_at, is_banned, monetization_type, percent, payback_started, status, activated_at
 		FROM projects WHERE creator_id = $1 ORDER BY created_at DESC, id ASC`, creatorID); err != nil {
 		r.log.Error("failed to get all projects by creator", "creator_id", creatorID, "error", err)
 		return nil, err
 	}
+	if err := r.attachTags(ctx, projects); err != nil {
+		return nil, err
+	}
 	return projects, nil
 }
 
@@ -156,18 +249,7 @@ func (r *Repo) UpdatePicturePath(ctx context.Context, projectID int, picturePath
 		r.log.Error("failed to update picture path", "project_id", projectID, "error", err)
 		return err
 	}
-	return nil
-}
-
-func (r *Repo) BanProject(ctx context.Context, projectID int, banned bool) error {
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE projects SET is_banned = $1 WHERE id = $2`,
-		banned, projectID,
-	)
-	if err != nil {
-		r.log.Error("failed to ban/unban project", "project_id", projectID, "banned", banned, "error", err)
-		return err
-	}
+	r.InvalidateCache(ctx, projectID)
 	return nil
 }
 
@@ -180,51 +262,16 @@ func (r *Repo) ChangeProjectPublicity(ctx context.Context, projectID int, isPubl
 		r.log.Error("failed to change project publicity", "project_id", projectID, "is_public", isPublic, "error", err)
 		return err
 	}
+	r.InvalidateCache(ctx, projectID)
 	return nil
 }
 
-func (r *Repo) MarkProjectCompleted(ctx context.Context, projectID int, completed bool) error {
-	var paybackStarted bool
-	err := r.db.GetContext(ctx, &paybackStarted, `SELECT payback_started FROM projects WHERE id = $1`, projectID)
-	if err != nil {
-		r.log.Error("failed to check payback_started status", "project_id", projectID, "error", err)
-		return err
-	}
-
-	if paybackStarted {
-		r.log.Warn("cannot change is_completed when payback_started is true", "project_id", projectID)
-		return core.ErrPaybackStarted
-	}
-
-	_, err = r.db.ExecContext(ctx,
-		`UPDATE projects SET is_completed = $1 WHERE id = $2`,
-		completed, projectID,
-	)
-	if err != nil {
-		r.log.Error("failed to mark project as completed/incomplete", "project_id", projectID, "completed", completed, "error", err)
-		return err
-	}
-	return nil
-}
-
-func (r *Repo) StartPayback(ctx context.Context, projectID int) error {
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE projects SET payback_started = true,
-		payback_started_date = CURRENT_TIMESTAMP, is_completed = true WHERE id = $1`,
-		projectID,
-	)
-	if err != nil {
-		r.log.Error("failed to start payback", "project_id", projectID, "error", err)
-		return err
-	}
-	r.log.Info("payback started", "project_id", projectID)
-	return nil
-}
-
+// GetProjectTransactions возвращает вложения и выплаты проекта. У вложений валюта
+// проекта — у зачисленной суммы to_amount, у выплат — у списанной amount.
 func (r *Repo) GetProjectTransactions(ctx context.Context, projectID int) ([]core.Transaction, error) {
 	var transactions []core.Transaction
 	err := r.db.SelectContext(ctx, &transactions,
-		`SELECT id, from_id, reciever_id, type, amount, time_at 
+		`SELECT id, from_id, reciever_id, type, amount, COALESCE(to_amount, amount) AS to_amount, fee, time_at
 		FROM transactions 
 		WHERE (from_id = $1 AND type = 'project_to_user') 
 		   OR (reciever_id = $1 AND type = 'user_to_project')
@@ -238,7 +285,7 @@ func (r *Repo) GetProjectTransactions(ctx context.Context, projectID int) ([]cor
 	return transactions, nil
 }
 
-func (r *Repo) UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, newAmount float64) error {
+func (r *Repo) UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, newAmount money.Amount) error {
 	_, err := r.db.ExecContext(ctx,
 		`UPDATE projects SET money_required_to_payback = $1 WHERE id = $2`,
 		newAmount, projectID,
@@ -247,23 +294,6 @@ func (r *Repo) UpdateMoneyRequiredToPayback(ctx context.Context, projectID int,
 		r.log.Error("failed to update money_required_to_payback", "project_id", projectID, "error", err)
 		return err
 	}
+	r.InvalidateCache(ctx, projectID)
 	return nil
 }
-
-func (r *Repo) AddFunds(ctx context.Context, projectID int, amount float64) error {
-	if amount < 0 {
-		var current float64
-		err := r.db.GetContext(ctx, &current, "SELECT current_money FROM projects WHERE id=$1", projectID)
-		if err != nil {
-			return err
-		}
-		if current+amount < 0 {
-			return fmt.Errorf("insufficient funds")
-		}
-	}
-
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE projects SET current_money = current_money + $1 WHERE id = $2`,
-		amount, projectID)
-	return err
-}
@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+// listColumns — поля проекта для ленты и поиска, таблица projects под псевдонимом p
+const listColumns = `p.id, p.name, p.creator_id, p.quick_peek, p.quick_peek_picture_path, p.content,
+	p.is_public, p.is_completed, p.current_money, p.wanted_money, p.duration_days,
+	p.payback_started_date, p.money_required_to_payback, p.currency,
+	p.created_at, p.is_banned, p.monetization_type, p.percent, p.payback_started, p.status, p.activated_at`
+
+// Фрагменты для подсветки: до двух отрывков по 5-20 слов. Текст перед разбором
+// экранируется, поэтому в ответе HTML-разметка только у <mark>.
+const (
+	headlineOptions      = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5"
+	nameHighlightOptions = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+)
+
+// projectQuery собирает условия ленты: видимость проекта, фильтры и поисковую строку
+type projectQuery struct {
+	where []string
+	args  []interface{}
+	// tsquery и rank — выражения поиска, пустые без поисковой строки
+	tsquery string
+	rank    string
+}
+
+func (q *projectQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func newProjectQuery(f core.ProjectFilter) *projectQuery {
+	q := &projectQuery{where: []string{"p.is_public = true", "p.is_banned = false", "p.is_completed = false"}}
+
+	if f.Query != "" {
+		text := q.arg(f.Query)
+		q.tsquery = fmt.Sprintf("(websearch_to_tsquery('russian', %s) || websearch_to_tsquery('english', %s))", text, text)
+		// совпадение слов через tsvector или похожее название (опечатки) через триграммы
+		q.where = append(q.where, fmt.Sprintf("(p.search_vector @@ %s OR %s <%% p.name)", q.tsquery, text))
+		q.rank = fmt.Sprintf("(ts_rank_cd(p.search_vector, %s, 32) + word_similarity(%s, p.name))", q.tsquery, text)
+	}
+	if f.MonetizationType != "" {
+		q.where = append(q.where, "p.monetization_type = "+q.arg(f.MonetizationType))
+	}
+	if len(f.TagIDs) > 0 {
+		ids := make([]int64, len(f.TagIDs))
+		for i, id := range f.TagIDs {
+			ids[i] = int64(id)
+		}
+		q.where = append(q.where, "EXISTS (SELECT 1 FROM project_tags pt WHERE pt.project_id = p.id AND pt.tag_id = ANY("+q.arg(pq.Array(ids))+"))")
+	}
+	if f.OrgType != "" {
+		q.where = append(q.where, "EXISTS (SELECT 1 FROM organizations o WHERE o.id = p.creator_id AND o.type::text = "+q.arg(f.OrgType)+")")
+	}
+	if f.MinProgress != nil {
+		q.where = append(q.where, "p.current_money * 100 >= p.wanted_money * "+q.arg(*f.MinProgress))
+	}
+	if f.MaxProgress != nil {
+		q.where = append(q.where, "p.current_money * 100 <= p.wanted_money * "+q.arg(*f.MaxProgress))
+	}
+	if f.MinPercent != nil {
+		q.where = append(q.where, "p.percent >= "+q.arg(*f.MinPercent))
+	}
+	if f.MaxPercent != nil {
+		q.where = append(q.where, "p.percent <= "+q.arg(*f.MaxPercent))
+	}
+	return q
+}
+
+func (q *projectQuery) whereClause() string {
+	return strings.Join(q.where, " AND ")
+}
+
+// escapeHTML — SQL-выражение, экранирующее текст для вставки в HTML
+func escapeHTML(expr string) string {
+	return fmt.Sprintf("replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')", expr)
+}
+
+// Search ищет проекты по словам в названии и описаниях с учетом словоформ и опечаток
+// в названии. Результаты упорядочены по релевантности, фрагменты с подсветкой строятся
+// только для страницы.
+func (r *Repo) Search(ctx context.Context, limit, offset int, f core.ProjectFilter) ([]core.SearchResult, error) {
+	q := newProjectQuery(f)
+	query := fmt.Sprintf(`
+		SELECT %s, s.rank,
+		       ts_headline('russian', %s, %s, '%s') AS name_highlight,
+		       ts_headline('russian', %s, %s, '%s') AS snippet
+		FROM (
+			SELECT p.id, %s AS rank
+			FROM projects p
+			WHERE %s
+			ORDER BY rank DESC, p.id ASC
+			LIMIT %s OFFSET %s
+		) s
+		JOIN projects p ON p.id = s.id
+		ORDER BY s.rank DESC, p.id ASC`,
+		listColumns,
+		escapeHTML("p.name"), q.tsquery, nameHighlightOptions,
+		escapeHTML("p.quick_peek || ' ' || p.content"), q.tsquery, headlineOptions,
+		q.rank, q.whereClause(), q.arg(limit), q.arg(offset))
+
+	results := []core.SearchResult{}
+	if err := r.db.SelectContext(ctx, &results, query, q.args...); err != nil {
+		r.log.Error("failed to search projects", "error", err)
+		return nil, err
+	}
+
+	projects := make([]core.Project, len(results))
+	for i := range results {
+		projects[i] = results[i].Project
+	}
+	if err := r.attachTags(ctx, projects); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Tags = projects[i].Tags
+	}
+	return results, nil
+}
@@ -0,0 +1,175 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+// GetTags возвращает все теги с числом проектов в ленте (публичных, незаблокированных, незавершенных)
+func (r *Repo) GetTags(ctx context.Context) ([]core.TagCount, error) {
+	tags := []core.TagCount{}
+	if err := r.db.SelectContext(ctx, &tags, `
+		SELECT t.id, t.name, t.description, COUNT(p.id) AS project_count
+		FROM tags t
+		LEFT JOIN project_tags pt ON pt.tag_id = t.id
+		LEFT JOIN projects p ON p.id = pt.project_id
+			AND p.is_public = true AND p.is_banned = false AND p.is_completed = false
+		GROUP BY t.id
+		ORDER BY project_count DESC, t.name`); err != nil {
+		r.log.Error("failed to get tags", "error", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *Repo) CreateTag(ctx context.Context, t *core.Tag) error {
+	err := r.db.GetContext(ctx, &t.ID,
+		`INSERT INTO tags (name, description) VALUES ($1, $2) RETURNING id`, t.Name, t.Description)
+	if isUniqueViolation(err) {
+		return core.ErrTagExists
+	}
+	if err != nil {
+		r.log.Error("failed to create tag", "name", t.Name, "error", err)
+	}
+	return err
+}
+
+func (r *Repo) UpdateTag(ctx context.Context, t *core.Tag) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE tags SET name = $1, description = $2 WHERE id = $3`, t.Name, t.Description, t.ID)
+	if isUniqueViolation(err) {
+		return core.ErrTagExists
+	}
+	if err != nil {
+		r.log.Error("failed to update tag", "id", t.ID, "error", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return core.ErrTagNotFound
+	}
+	r.invalidateTagged(ctx, t.ID)
+	return nil
+}
+
+// DeleteTag удаляет тег вместе с его привязками к проектам
+func (r *Repo) DeleteTag(ctx context.Context, id int) error {
+	// проекты ищутся до удаления привязок, а сбрасываются после коммита
+	var projectIDs []int
+	if err := r.db.SelectContext(ctx, &projectIDs, `SELECT project_id FROM project_tags WHERE tag_id = $1`, id); err != nil {
+		r.log.Error("failed to get tagged projects", "tag_id", id, "error", err)
+		return err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM project_tags WHERE tag_id = $1`, id); err != nil {
+		r.log.Error("failed to detach tag from projects", "id", id, "error", err)
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, id)
+	if err != nil {
+		r.log.Error("failed to delete tag", "id", id, "error", err)
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return core.ErrTagNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.InvalidateCache(ctx, projectIDs...)
+	return nil
+}
+
+// invalidateTagged сбрасывает из кеша проекты с тегом, чтобы они отдавались с новым названием
+func (r *Repo) invalidateTagged(ctx context.Context, tagID int) {
+	var projectIDs []int
+	if err := r.db.SelectContext(ctx, &projectIDs, `SELECT project_id FROM project_tags WHERE tag_id = $1`, tagID); err != nil {
+		r.log.Error("failed to get tagged projects", "tag_id", tagID, "error", err)
+		return
+	}
+	r.InvalidateCache(ctx, projectIDs...)
+}
+
+// setProjectTags заменяет теги проекта на переданные
+func (r *Repo) setProjectTags(ctx context.Context, tx *sqlx.Tx, projectID int, tags []core.Tag) error {
+	ids := make([]int64, 0, len(tags))
+	for _, t := range tags {
+		ids = append(ids, int64(t.ID))
+	}
+
+	var found int
+	if err := tx.GetContext(ctx, &found, `SELECT COUNT(*) FROM tags WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return err
+	}
+	if found != len(uniqueIDs(ids)) {
+		return core.ErrTagNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM project_tags WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO project_tags (project_id, tag_id)
+		SELECT $1, unnest($2::int[])
+		ON CONFLICT (project_id, tag_id) DO NOTHING`, projectID, pq.Array(ids))
+	return err
+}
+
+// attachTags заполняет Tags у проектов одним запросом
+func (r *Repo) attachTags(ctx context.Context, projects []core.Project) error {
+	if len(projects) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(projects))
+	byID := make(map[int]*core.Project, len(projects))
+	for i := range projects {
+		ids[i] = int64(projects[i].ID)
+		projects[i].Tags = []core.Tag{}
+		byID[projects[i].ID] = &projects[i]
+	}
+
+	var rows []struct {
+		ProjectID int `db:"project_id"`
+		core.Tag
+	}
+	err := r.db.SelectContext(ctx, &rows, `
+		SELECT pt.project_id, t.id, t.name, t.description
+		FROM project_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.project_id = ANY($1)
+		ORDER BY t.name`, pq.Array(ids))
+	if err != nil {
+		r.log.Error("failed to get project tags", "error", err)
+		return err
+	}
+	for _, row := range rows {
+		p := byID[row.ProjectID]
+		p.Tags = append(p.Tags, row.Tag)
+	}
+	return nil
+}
+
+func uniqueIDs(ids []int64) map[int64]bool {
+	set := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,216 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	defaultMaxAttempts = 5
+	// lease — на сколько сага закрепляется за процессом; должен быть больше самого долгого шага
+	lease       = 2 * time.Minute
+	resumeBatch = 50
+)
+
+// Orchestrator запускает и продолжает саги одного сервиса. Саги разных
+// сервисов живут в одной таблице и различаются колонкой service.
+type Orchestrator struct {
+	store   *store
+	service string
+	defs    map[string]Definition
+	log     slog.Logger
+}
+
+func NewOrchestrator(db *sqlx.DB, service string, log slog.Logger) *Orchestrator {
+	return &Orchestrator{
+		store:   &store{db: db},
+		service: service,
+		defs:    make(map[string]Definition),
+		log:     log,
+	}
+}
+
+func (o *Orchestrator) Register(def Definition) {
+	if def.MaxAttempts == 0 {
+		def.MaxAttempts = defaultMaxAttempts
+	}
+	o.defs[def.Kind] = def
+}
+
+// Start сохраняет новую сагу. ref — объект, над которым идет операция; пока сага
+// с тем же kind и ref не завершена, повторный Start вернет ErrAlreadyRunning.
+func (o *Orchestrator) Start(ctx context.Context, kind, ref string, payload interface{}) (*Saga, error) {
+	if _, ok := o.defs[kind]; !ok {
+		return nil, ErrUnknownKind
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Saga{
+		ID:        uuid.New().String(),
+		Service:   o.service,
+		Kind:      kind,
+		Ref:       ref,
+		Payload:   data,
+		Status:    StatusRunning,
+		NextRunAt: time.Now(),
+		store:     o.store,
+	}
+	if err := o.store.create(ctx, s); err != nil {
+		return nil, err
+	}
+	o.log.Info("saga started", "saga_id", s.ID, "kind", kind, "ref", ref)
+	return s, nil
+}
+
+// StartAndRun запускает сагу и сразу выполняет ее в текущем запросе.
+// Ошибка шага, после которой сага отложена на повтор, возвращается вместе с сагой.
+func (o *Orchestrator) StartAndRun(ctx context.Context, kind, ref string, payload interface{}) (*Saga, error) {
+	s, err := o.Start(ctx, kind, ref, payload)
+	if err != nil {
+		return nil, err
+	}
+	return o.Run(ctx, s.ID)
+}
+
+// Run выполняет оставшиеся шаги саги (или компенсации). Если сагу сейчас
+// выполняет другой процесс, возвращает ее текущее состояние.
+func (o *Orchestrator) Run(ctx context.Context, id string) (*Saga, error) {
+	s, err := o.store.claim(ctx, id, lease)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return o.store.get(ctx, id)
+	}
+
+	def, ok := o.defs[s.Kind]
+	if !ok {
+		o.log.Error("saga kind is not registered", "saga_id", s.ID, "kind", s.Kind)
+		return s, ErrUnknownKind
+	}
+
+	if s.Status == StatusRunning {
+		for s.CurrentStep < len(def.Steps) {
+			i := s.CurrentStep
+			step := def.Steps[i]
+			if err := step.Action(ctx, s); err != nil {
+				return s, o.stepFailed(ctx, s, def, step.Name, err)
+			}
+			s.CurrentStep++
+			s.Attempts = 0
+			s.LastError = nil
+			if err := o.store.update(ctx, s, i, step.Name, stepDone, nil); err != nil {
+				return s, err
+			}
+		}
+		return s, o.finish(ctx, s, StatusCompleted)
+	}
+
+	return s, o.compensate(ctx, s, def)
+}
+
+func (o *Orchestrator) stepFailed(ctx context.Context, s *Saga, def Definition, stepName string, stepErr error) error {
+	s.Attempts++
+	msg := stepErr.Error()
+	s.LastError = &msg
+
+	if IsPermanent(stepErr) || s.Attempts >= def.MaxAttempts {
+		o.log.Warn("saga step failed, compensating", "saga_id", s.ID, "kind", s.Kind, "step", stepName, "attempts", s.Attempts, "error", stepErr)
+		s.Status = StatusCompensating
+		s.Attempts = 0
+		if err := o.store.update(ctx, s, s.CurrentStep, stepName, stepFailed, stepErr); err != nil {
+			return err
+		}
+		if err := o.compensate(ctx, s, def); err != nil {
+			return err
+		}
+		return stepErr
+	}
+
+	o.log.Warn("saga step failed, will retry", "saga_id", s.ID, "kind", s.Kind, "step", stepName, "attempts", s.Attempts, "error", stepErr)
+	s.NextRunAt = time.Now().Add(backoff(s.Attempts))
+	s.LockedUntil = nil
+	if err := o.store.update(ctx, s, s.CurrentStep, stepName, stepFailed, stepErr); err != nil {
+		return err
+	}
+	return stepErr
+}
+
+// compensate откатывает выполненные шаги в обратном порядке. Компенсации
+// повторяются без ограничения числа попыток: деньги нельзя оставить в промежуточном состоянии.
+func (o *Orchestrator) compensate(ctx context.Context, s *Saga, def Definition) error {
+	for s.CurrentStep > 0 {
+		i := s.CurrentStep - 1
+		step := def.Steps[i]
+		if step.Compensate != nil {
+			if err := step.Compensate(ctx, s); err != nil {
+				s.Attempts++
+				msg := err.Error()
+				s.LastError = &msg
+				s.NextRunAt = time.Now().Add(backoff(s.Attempts))
+				s.LockedUntil = nil
+				o.log.Error("saga compensation failed, will retry", "saga_id", s.ID, "kind", s.Kind, "step", step.Name, "attempts", s.Attempts, "error", err)
+				if uerr := o.store.update(ctx, s, i, step.Name, stepFailed, err); uerr != nil {
+					return uerr
+				}
+				return fmt.Errorf("compensate %s: %w", step.Name, err)
+			}
+		}
+		s.CurrentStep--
+		s.Attempts = 0
+		if err := o.store.update(ctx, s, i, step.Name, stepCompensated, nil); err != nil {
+			return err
+		}
+	}
+	return o.finish(ctx, s, StatusFailed)
+}
+
+func (o *Orchestrator) finish(ctx context.Context, s *Saga, status string) error {
+	s.Status = status
+	s.LockedUntil = nil
+	if err := o.store.update(ctx, s, 0, "", "", nil); err != nil {
+		return err
+	}
+	o.log.Info("saga finished", "saga_id", s.ID, "kind", s.Kind, "ref", s.Ref, "status", status)
+	return nil
+}
+
+// ResumePending продолжает саги, у которых подошло время следующей попытки,
+// в том числе брошенные упавшим процессом после истечения lease.
+func (o *Orchestrator) ResumePending(ctx context.Context) {
+	ids, err := o.store.pendingIDs(ctx, o.service, resumeBatch)
+	if err != nil {
+		o.log.Error("failed to get pending sagas", "error", err)
+		return
+	}
+	for _, id := range ids {
+		if _, err := o.Run(ctx, id); err != nil {
+			o.log.Error("saga run failed", "saga_id", id, "error", err)
+		}
+	}
+}
+
+// RunWorker раз в interval продолжает отложенные саги, пока не отменен ctx
+func (o *Orchestrator) RunWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	o.ResumePending(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.ResumePending(ctx)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+// SubmitForReview отправляет черновик модератору
+func (s *service) SubmitForReview(ctx context.Context, projectID int, userID int) error {
+	existingProject, err := s.repo.Get(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	allowed, err := s.orgClient.CheckUserOrgPermission(ctx, existingProject.CreatorID, userID, "project_management")
+	if err != nil || !allowed {
+		return core.ErrNotAuthorized
+	}
+	return s.repo.Transition(ctx, projectID, core.StatusPendingReview, "", &userID)
+}
+
+// ReviewProject — решение модератора: одобренный проект публикуется и начинает сбор,
+// отклоненный возвращается в черновики с причиной
+func (s *service) ReviewProject(ctx context.Context, projectID int, adminID int, approve bool, reason string) error {
+	to := core.StatusActive
+	if !approve {
+		if reason == "" {
+			return core.ErrInvalidInput
+		}
+		to = core.StatusDraft
+	}
+	return s.repo.Transition(ctx, projectID, to, reason, &adminID)
+}
+
+// CloseProject закрывает завершенный проект без выплат. Проекты с доходностью
+// закрываются сами, когда выплаты инвесторам сделаны полностью.
+func (s *service) CloseProject(ctx context.Context, projectID int, userID int, reason string) error {
+	existingProject, err := s.repo.Get(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	allowed, err := s.orgClient.CheckUserOrgPermission(ctx, existingProject.CreatorID, userID, "project_management")
+	if err != nil || !allowed {
+		return core.ErrNotAuthorized
+	}
+	if existingProject.Status == core.StatusFunded && existingProject.MonetizationType != "charity" && existingProject.MonetizationType != "custom" {
+		return core.ErrPaybackRequired
+	}
+	return s.repo.Transition(ctx, projectID, core.StatusClosed, reason, &userID)
+}
+
+// GetStatusHistory отдает историю статусов организации проекта и администраторам:
+// в ней причины отказов модератора
+func (s *service) GetStatusHistory(ctx context.Context, projectID int, userID int, isAdmin bool) ([]core.StatusChange, error) {
+	if !isAdmin {
+		existingProject, err := s.repo.Get(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		allowed, err := s.orgClient.CheckUserOrgPermission(ctx, existingProject.CreatorID, userID, "project_management")
+		if err != nil || !allowed {
+			return nil, core.ErrNotAuthorized
+		}
+	}
+	return s.repo.GetStatusHistory(ctx, projectID)
+}
+
+func (s *service) GetPendingReview(ctx context.Context, limit, offset int) ([]core.Project, error) {
+	limit, offset = pageBounds(limit, offset)
+	return s.repo.GetPendingReview(ctx, limit, offset)
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/project/clients"
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+	"github.com/Starostina-elena/investment_platform/services/project/repo"
+)
+
+// lifecycleRepo хранит один проект в памяти и проверяет переходы так же, как repo.Transition
+type lifecycleRepo struct {
+	repo.RepoInterface
+	project     core.Project
+	transitions []core.ProjectStatus
+	updated     bool
+}
+
+func (r *lifecycleRepo) Get(ctx context.Context, id int) (*core.Project, error) {
+	if id != r.project.ID {
+		return nil, core.ErrProjectNotFound
+	}
+	p := r.project
+	return &p, nil
+}
+
+func (r *lifecycleRepo) GetCached(ctx context.Context, id int) (*core.Project, error) {
+	return r.Get(ctx, id)
+}
+
+func (r *lifecycleRepo) Update(ctx context.Context, p *core.Project) (*core.Project, error) {
+	r.updated = true
+	r.project = *p
+	return p, nil
+}
+
+func (r *lifecycleRepo) Transition(ctx context.Context, projectID int, to core.ProjectStatus, reason string, actorID *int) error {
+	if projectID != r.project.ID {
+		return core.ErrProjectNotFound
+	}
+	if !r.project.Status.CanTransition(to) {
+		return core.ErrInvalidTransition
+	}
+	r.project.Status = to
+	r.transitions = append(r.transitions, to)
+	return nil
+}
+
+// newLifecycleService поднимает сервис организаций, в котором право project_management
+// есть только у пользователя member
+func newLifecycleService(t *testing.T, status core.ProjectStatus, member int) (*service, *lifecycleRepo) {
+	t.Helper()
+	org := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := r.URL.Path == fmt.Sprintf("/5/rights/%d/project_management", member)
+		fmt.Fprintf(w, `{"allowed": %t}`, allowed)
+	}))
+	t.Cleanup(org.Close)
+
+	r := &lifecycleRepo{project: core.Project{ID: 1, CreatorID: 5, Status: status}}
+	return &service{repo: r, orgClient: clients.NewOrgClient(org.URL, *slog.Default()), log: *slog.Default()}, r
+}
+
+func TestSubmitForReview(t *testing.T) {
+	s, r := newLifecycleService(t, core.StatusDraft, 10)
+
+	if err := s.SubmitForReview(context.Background(), 1, 11); !errors.Is(err, core.ErrNotAuthorized) {
+		t.Fatalf("submit by outsider: err = %v, want ErrNotAuthorized", err)
+	}
+	if err := s.SubmitForReview(context.Background(), 1, 10); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if r.project.Status != core.StatusPendingReview {
+		t.Fatalf("status = %s, want pending_review", r.project.Status)
+	}
+	if err := s.SubmitForReview(context.Background(), 1, 10); !errors.Is(err, core.ErrInvalidTransition) {
+		t.Fatalf("second submit: err = %v, want ErrInvalidTransition", err)
+	}
+}
+
+func TestReviewProject(t *testing.T) {
+	s, r := newLifecycleService(t, core.StatusPendingReview, 10)
+
+	if err := s.ReviewProject(context.Background(), 1, 99, false, ""); !errors.Is(err, core.ErrInvalidInput) {
+		t.Fatalf("reject without reason: err = %v, want ErrInvalidInput", err)
+	}
+	if len(r.transitions) != 0 {
+		t.Fatalf("transitions = %v, want none", r.transitions)
+	}
+	if err := s.ReviewProject(context.Background(), 1, 99, false, "нет бизнес-плана"); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if r.project.Status != core.StatusDraft {
+		t.Fatalf("status after reject = %s, want draft", r.project.Status)
+	}
+	if err := s.ReviewProject(context.Background(), 1, 99, true, ""); !errors.Is(err, core.ErrInvalidTransition) {
+		t.Fatalf("approve draft: err = %v, want ErrInvalidTransition", err)
+	}
+
+	r.project.Status = core.StatusPendingReview
+	if err := s.ReviewProject(context.Background(), 1, 99, true, ""); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if r.project.Status != core.StatusActive {
+		t.Fatalf("status after approve = %s, want active", r.project.Status)
+	}
+}
+
+func TestGetHidesUnapprovedProjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  core.ProjectStatus
+		userID  int
+		isAdmin bool
+		visible bool
+	}{
+		{"active anonymous", core.StatusActive, 0, false, true},
+		{"draft anonymous", core.StatusDraft, 0, false, false},
+		{"draft outsider", core.StatusDraft, 11, false, false},
+		{"draft member", core.StatusDraft, 10, false, true},
+		{"pending outsider", core.StatusPendingReview, 11, false, false},
+		{"pending admin", core.StatusPendingReview, 11, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newLifecycleService(t, tt.status, 10)
+			p, err := s.Get(context.Background(), 1, tt.userID, tt.isAdmin)
+			if tt.visible {
+				if err != nil || p == nil {
+					t.Fatalf("Get: %v, want project", err)
+				}
+				return
+			}
+			if !errors.Is(err, core.ErrProjectNotFound) {
+				t.Fatalf("Get: err = %v, want ErrProjectNotFound", err)
+			}
+		})
+	}
+}
+
+func TestUpdateOnlyDrafts(t *testing.T) {
+	for _, status := range []core.ProjectStatus{core.StatusPendingReview, core.StatusActive, core.StatusFunded} {
+		s, r := newLifecycleService(t, status, 10)
+		_, err := s.Update(context.Background(), 1, core.Project{Name: "Новое название"}, 10)
+		if !errors.Is(err, core.ErrNotEditable) {
+			t.Errorf("%s: err = %v, want ErrNotEditable", status, err)
+		}
+		if r.updated {
+			t.Errorf("%s: project updated", status)
+		}
+	}
+
+	s, r := newLifecycleService(t, core.StatusDraft, 10)
+	if _, err := s.Update(context.Background(), 1, core.Project{Name: "Новое название"}, 10); err != nil {
+		t.Fatalf("update draft: %v", err)
+	}
+	if !r.updated || r.project.Name != "Новое название" {
+		t.Fatalf("draft not updated: %+v", r.project)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+	"github.com/Starostina-elena/investment_platform/services/project/money"
+)
+
+func TestCalculatePaybacksCountsFees(t *testing.T) {
+	project := &core.Project{ID: 1, CreatorID: 5, MonetizationType: "fixed_percent", Percent: 10}
+	projectID, investor := 1, 7
+	invested := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	transactions := []core.Transaction{
+		// вложение 1000, проекту зачислено 950 после комиссии 5%
+		{ID: 1, FromID: &investor, ReceiverID: &projectID, Type: "user_to_project",
+			Amount: money.FromRubles(1000), ToAmount: money.FromRubles(950), Fee: money.FromRubles(50), TimeAt: invested},
+		// выплата 1100, инвестор получил 1045 после комиссии на выплату
+		{ID: 2, FromID: &projectID, ReceiverID: &investor, Type: "project_to_user",
+			Amount: money.FromRubles(1100), ToAmount: money.FromRubles(1045), Fee: money.FromRubles(55), TimeAt: invested.AddDate(0, 1, 0)},
+	}
+
+	s := &service{}
+	paybacks, err := s.calculatePaybacks(project, transactions)
+	if err != nil {
+		t.Fatalf("calculatePaybacks() error = %v", err)
+	}
+	if len(paybacks) != 1 {
+		t.Fatalf("calculatePaybacks() = %+v, want one investor", paybacks)
+	}
+	p := paybacks[0]
+	if p.TotalInvested != money.FromRubles(1000) {
+		t.Errorf("TotalInvested = %s, want 1000.00 with the investment fee", p.TotalInvested)
+	}
+	if p.PaybackAmount != money.FromRubles(1100) {
+		t.Errorf("PaybackAmount = %s, want 1100.00", p.PaybackAmount)
+	}
+	if p.TotalReceived != money.FromRubles(1100) {
+		t.Errorf("TotalReceived = %s, want 1100.00 with the payback fee", p.TotalReceived)
+	}
+	if remaining := p.PaybackAmount - p.TotalReceived; remaining != 0 {
+		t.Errorf("remaining = %s, want the investor fully paid", remaining)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Starostina-elena/investment_platform/services/project/clients"
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+	"github.com/Starostina-elena/investment_platform/services/project/money"
+	"github.com/Starostina-elena/investment_platform/services/project/saga"
+)
+
+const sagaPayback = "payback"
+
+type paybackPayout struct {
+	UserID int          `json:"user_id"`
+	Amount money.Amount `json:"amount"`
+	Paid   bool         `json:"paid"`
+}
+
+// paybackPayload — план выплат инвесторам и отметки о сделанных выплатах.
+// По нему после сбоя видно, кому уже заплатили, а кому еще нет.
+type paybackPayload struct {
+	ProjectID           int             `json:"project_id"`
+	Currency            money.Currency  `json:"currency"`
+	Planned             bool            `json:"planned"`
+	MoneyRequiredBefore money.Amount    `json:"money_required_before"`
+	Payouts             []paybackPayout `json:"payouts"`
+	// Complete — денег хватило всем инвесторам, после выплат проект закрывается
+	Complete bool `json:"complete"`
+}
+
+func (s *service) registerSagas() {
+	// выплаты не компенсируются: деньги, дошедшие до инвестора, остаются у него,
+	// а прерванная сага продолжается с первой неоплаченной выплаты
+	s.sagas.Register(saga.Definition{
+		Kind: sagaPayback,
+		Steps: []saga.Step{
+			{Name: "plan_payouts", Action: s.planPayouts},
+			{Name: "pay_investors", Action: s.payInvestors},
+		},
+	})
+}
+
+func (s *service) planPayouts(ctx context.Context, sg *saga.Saga) error {
+	var p paybackPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+	if p.Planned {
+		return nil
+	}
+
+	project, err := s.repo.Get(ctx, p.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	transactions, err := s.repo.GetProjectTransactions(ctx, p.ProjectID)
+	if err != nil {
+		s.log.Error("failed to get project transactions", "error", err)
+		return err
+	}
+
+	paybacks, err := s.calculatePaybacks(project, transactions)
+	if err != nil {
+		// процент проекта не дает посчитать выплаты, повтор ничего не изменит
+		s.log.Error("failed to calculate paybacks", "project_id", p.ProjectID, "error", err)
+		return saga.Permanent(err)
+	}
+
+	available := project.CurrentMoney
+	p.Complete = true
+	for _, payback := range paybacks {
+		amountRemaining := payback.PaybackAmount - payback.TotalReceived
+		if amountRemaining <= 0 {
+			s.log.Info("investor already fully paid", "project_id", p.ProjectID, "user_id", payback.UserID)
+			continue
+		}
+		if available <= 0 {
+			s.log.Info("no more funds for payback", "project_id", p.ProjectID)
+			p.Complete = false
+			break
+		}
+
+		amountToPay := amountRemaining
+		if available < amountToPay {
+			amountToPay = available
+			p.Complete = false
+			s.log.Info("partial payback - insufficient funds", "project_id", p.ProjectID, "user_id", payback.UserID, "amount_to_pay", amountToPay, "amount_remaining", amountRemaining)
+		}
+		p.Payouts = append(p.Payouts, paybackPayout{UserID: payback.UserID, Amount: amountToPay})
+		available -= amountToPay
+	}
+
+	p.Planned = true
+	p.Currency = project.Currency
+	p.MoneyRequiredBefore = project.MoneyRequiredToPayback
+	return sg.Save(ctx, p)
+}
+
+func (s *service) payInvestors(ctx context.Context, sg *saga.Saga) error {
+	var p paybackPayload
+	if err := sg.Decode(&p); err != nil {
+		return saga.Permanent(err)
+	}
+
+	paid := money.Zero
+	for i, payout := range p.Payouts {
+		if payout.Paid {
+			paid += payout.Amount
+			continue
+		}
+
+		// ключ привязан к саге и инвестору: повтор после сбоя не переведет деньги второй раз.
+		// Выплата идет в валюте проекта и зачисляется на кошелек инвестора в той же валюте.
+		key := fmt.Sprintf("payback:%s:%d", sg.ID, payout.UserID)
+		if err := s.transactionClient.Transfer(ctx, key, "project", p.ProjectID, "user", payout.UserID, payout.Amount, p.Currency); err != nil {
+			s.log.Error("failed to create payback transaction", "error", err, "project_id", p.ProjectID, "user_id", payout.UserID, "amount", payout.Amount)
+			if errors.Is(err, clients.ErrTransferRejected) {
+				return saga.Permanent(err)
+			}
+			return err
+		}
+		paid += payout.Amount
+
+		newMoneyRequired := max(p.MoneyRequiredBefore-paid, 0)
+		if err := s.repo.UpdateMoneyRequiredToPayback(ctx, p.ProjectID, newMoneyRequired); err != nil {
+			return err
+		}
+
+		p.Payouts[i].Paid = true
+		if err := sg.Save(ctx, p); err != nil {
+			return err
+		}
+	}
+
+	if p.Complete {
+		err := s.repo.Transition(ctx, p.ProjectID, core.StatusClosed, "выплаты инвесторам завершены", nil)
+		// повтор шага после сбоя: проект уже закрыт
+		if err != nil && !errors.Is(err, core.ErrInvalidTransition) {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,33 +2,48 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"math/big"
 	"mime/multipart"
+	"strconv"
 	"time"
 
 	"github.com/Starostina-elena/investment_platform/services/project/clients"
 	"github.com/Starostina-elena/investment_platform/services/project/core"
+	"github.com/Starostina-elena/investment_platform/services/project/money"
 	"github.com/Starostina-elena/investment_platform/services/project/repo"
+	"github.com/Starostina-elena/investment_platform/services/project/saga"
 	"github.com/Starostina-elena/investment_platform/services/project/storage"
 )
 
 type Service interface {
 	Create(ctx context.Context, req core.Project, creatorID int, userID int) (*core.Project, error)
-	Get(ctx context.Context, id int) (*core.Project, error)
+	Get(ctx context.Context, id int, userID int, isAdmin bool) (*core.Project, error)
+	GetFresh(ctx context.Context, id int) (*core.Project, error)
 	Update(ctx context.Context, projectID int, p core.Project, userID int) (*core.Project, error)
-	GetList(ctx context.Context, limit, offset int, monetizationType string, searchQuery string) ([]core.Project, error)
+	GetList(ctx context.Context, page core.Page, f core.ProjectFilter) ([]core.Project, string, error)
+	Search(ctx context.Context, limit, offset int, f core.ProjectFilter) ([]core.SearchResult, error)
 	GetByCreator(ctx context.Context, creatorID int) ([]core.Project, error)
 	GetAllByCreator(ctx context.Context, projectID int, userID int, isAdmin bool) ([]core.Project, error)
 	UpdatePicturePath(ctx context.Context, projectID int, picturePath string) error
-	BanProject(ctx context.Context, projectID int, banned bool) error
+	BanProject(ctx context.Context, projectID int, banned bool, reason string, adminID int) error
 	ChangeProjectPublicity(ctx context.Context, projectID int, userID int, isPublic bool) error
-	MarkProjectCompleted(ctx context.Context, projectID int, userID int, completed bool) error
+	MarkProjectCompleted(ctx context.Context, projectID int, userID int, completed bool, reason string) error
 	StartPayback(ctx context.Context, projectID int, userID int) error
+	SubmitForReview(ctx context.Context, projectID int, userID int) error
+	ReviewProject(ctx context.Context, projectID int, adminID int, approve bool, reason string) error
+	CloseProject(ctx context.Context, projectID int, userID int, reason string) error
+	GetStatusHistory(ctx context.Context, projectID int, userID int, isAdmin bool) ([]core.StatusChange, error)
+	GetPendingReview(ctx context.Context, limit, offset int) ([]core.Project, error)
 	UploadPicture(ctx context.Context, projectID int, userID int, file multipart.File, fileHeader *multipart.FileHeader) (string, error)
 	deletePicture(ctx context.Context, projectID int, picturePath string) error
 	DeletePictureFromProject(ctx context.Context, projectID int, userID int) error
-	AddFunds(ctx context.Context, projectID int, amount float64) error
-	UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, amount float64) error
+	UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, amount money.Amount) error
+	GetTags(ctx context.Context) ([]core.TagCount, error)
+	CreateTag(ctx context.Context, t core.Tag) (*core.Tag, error)
+	UpdateTag(ctx context.Context, t core.Tag) (*core.Tag, error)
+	DeleteTag(ctx context.Context, id int) error
 }
 
 type service struct {
@@ -36,11 +51,14 @@ type service struct {
 	orgClient         *clients.OrgClient
 	transactionClient *clients.TransactionClient
 	minio             *storage.MinioStorage
+	sagas             *saga.Orchestrator
 	log               slog.Logger
 }
 
-func NewService(r repo.RepoInterface, orgClient *clients.OrgClient, transactionClient *clients.TransactionClient, minioStorage *storage.MinioStorage, log slog.Logger) Service {
-	return &service{repo: r, orgClient: orgClient, transactionClient: transactionClient, minio: minioStorage, log: log}
+func NewService(r repo.RepoInterface, orgClient *clients.OrgClient, transactionClient *clients.TransactionClient, minioStorage *storage.MinioStorage, sagas *saga.Orchestrator, log slog.Logger) Service {
+	s := &service{repo: r, orgClient: orgClient, transactionClient: transactionClient, minio: minioStorage, sagas: sagas, log: log}
+	s.registerSagas()
+	return s
 }
 
 func (s *service) Create(ctx context.Context, p core.Project, creatorID int, userID int) (*core.Project, error) {
@@ -53,22 +71,57 @@ func (s *service) Create(ctx context.Context, p core.Project, creatorID int, use
 		return nil, core.ErrNotAuthorized
 	}
 
-	p.IsPublic = true
+	// новый проект — черновик: в ленту он попадет после одобрения модератором
+	p.Status = core.StatusDraft
+	p.IsPublic = false
 	p.IsCompleted = false
-	p.CurrentMoney = 0.0
+	p.CurrentMoney = money.Zero
 	p.CreatedAt = time.Now()
 	p.IsBanned = false
 
-	id, err := s.repo.Create(ctx, &p)
+	id, err := s.repo.Create(ctx, &p, userID)
 	if err != nil {
 		s.log.Error("failed to create project", "error", err)
 		return nil, err
 	}
 	p.ID = id
+	if len(p.Tags) > 0 {
+		// в запросе были только id тегов, названия берем из БД
+		if created, err := s.repo.Get(ctx, id); err == nil {
+			return created, nil
+		}
+	}
 	return &p, nil
 }
 
-func (s *service) Get(ctx context.Context, id int) (*core.Project, error) {
+// Get отдает проект из кеша. Черновик и проект на модерации видят только сотрудники
+// организации с правом project_management и администраторы, остальным он не найден.
+// userID = 0 — анонимный запрос.
+func (s *service) Get(ctx context.Context, id int, userID int, isAdmin bool) (*core.Project, error) {
+	p, err := s.repo.GetCached(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status.Approved() || isAdmin {
+		return p, nil
+	}
+	if userID == 0 {
+		return nil, core.ErrProjectNotFound
+	}
+	allowed, err := s.orgClient.CheckUserOrgPermission(ctx, p.CreatorID, userID, "project_management")
+	if err != nil {
+		s.log.Error("failed to check organisation permission", "error", err)
+		return nil, err
+	}
+	if !allowed {
+		return nil, core.ErrProjectNotFound
+	}
+	return p, nil
+}
+
+// GetFresh читает проект мимо кеша. Нужен другим сервисам, которые по нему
+// решают, принять ли перевод, и пересчитывают сумму к выплате.
+func (s *service) GetFresh(ctx context.Context, id int) (*core.Project, error) {
 	return s.repo.Get(ctx, id)
 }
 
@@ -82,13 +135,21 @@ func (s *service) Update(ctx context.Context, projectID int, p core.Project, use
 	if err != nil || !allowed {
 		return nil, core.ErrNotAuthorized
 	}
+	// модератор одобряет проект целиком: после отправки на проверку ни описание,
+	// ни цель сбора не меняются, иначе в ленту попадет непроверенный текст
+	if existingProject.Status != core.StatusDraft {
+		return nil, core.ErrNotEditable
+	}
 
 	existingProject.Name = p.Name
 	existingProject.QuickPeek = p.QuickPeek
 	existingProject.Content = p.Content
-	existingProject.IsPublic = p.IsPublic
+	// до одобрения модератором проект нельзя опубликовать
+	existingProject.IsPublic = false
 	existingProject.WantedMoney = p.WantedMoney
 	existingProject.DurationDays = p.DurationDays
+	// nil — теги не переданы и остаются прежними
+	existingProject.Tags = p.Tags
 
 	updatedProject, err := s.repo.Update(ctx, existingProject)
 	if err != nil {
@@ -98,7 +159,43 @@ func (s *service) Update(ctx context.Context, projectID int, p core.Project, use
 	return updatedProject, nil
 }
 
-func (s *service) GetList(ctx context.Context, limit, offset int, monetizationType string, searchQuery string) ([]core.Project, error) {
+// GetList возвращает страницу ленты и непрозрачный курсор следующей страницы (пустой на последней).
+// Без явного порядка лента идет от новых проектов, а с поисковой строкой — по релевантности.
+func (s *service) GetList(ctx context.Context, page core.Page, f core.ProjectFilter) ([]core.Project, string, error) {
+	page.Limit, page.Offset = pageBounds(page.Limit, page.Offset)
+	if page.Cursor != nil {
+		if page.Sort != "" && page.Sort != page.Cursor.Sort {
+			return nil, "", core.ErrInvalidCursor
+		}
+		page.Sort = page.Cursor.Sort
+	}
+	if page.Sort == "" {
+		page.Sort = core.SortNewest
+		if f.Query != "" {
+			page.Sort = core.SortRelevance
+		}
+	}
+	if !page.Sort.Valid() || (page.Sort == core.SortRelevance && f.Query == "") {
+		return nil, "", core.ErrInvalidInput
+	}
+
+	projects, next, err := s.repo.GetList(ctx, page, f)
+	if err != nil || next == nil {
+		return projects, "", err
+	}
+	return projects, next.Encode(), nil
+}
+
+// Search ищет по словам; без поисковой строки искать нечего, для этого есть лента
+func (s *service) Search(ctx context.Context, limit, offset int, f core.ProjectFilter) ([]core.SearchResult, error) {
+	if f.Query == "" {
+		return nil, core.ErrInvalidInput
+	}
+	limit, offset = pageBounds(limit, offset)
+	return s.repo.Search(ctx, limit, offset, f)
+}
+
+func pageBounds(limit, offset int) (int, int) {
 	if limit <= 0 {
 		limit = 10
 	}
@@ -108,7 +205,7 @@ func (s *service) GetList(ctx context.Context, limit, offset int, monetizationTy
 	if offset < 0 {
 		offset = 0
 	}
-	return s.repo.GetList(ctx, limit, offset, monetizationType, searchQuery)
+	return limit, offset
 }
 
 func (s *service) GetByCreator(ctx context.Context, creatorID int) ([]core.Project, error) {
@@ -129,8 +226,8 @@ func (s *service) UpdatePicturePath(ctx context.Context, projectID int, pictureP
 	return s.repo.UpdatePicturePath(ctx, projectID, &picturePath)
 }
 
-func (s *service) BanProject(ctx context.Context, projectID int, banned bool) error {
-	return s.repo.BanProject(ctx, projectID, banned)
+func (s *service) BanProject(ctx context.Context, projectID int, banned bool, reason string, adminID int) error {
+	return s.repo.SetBanned(ctx, projectID, banned, reason, adminID)
 }
 
 func (s *service) ChangeProjectPublicity(ctx context.Context, projectID int, userID int, isPublic bool) error {
@@ -142,10 +239,15 @@ func (s *service) ChangeProjectPublicity(ctx context.Context, projectID int, use
 	if err != nil || !allowed {
 		return core.ErrNotAuthorized
 	}
+	if isPublic && !existingProject.Status.Approved() {
+		return core.ErrNotApproved
+	}
 	return s.repo.ChangeProjectPublicity(ctx, projectID, isPublic)
 }
 
-func (s *service) MarkProjectCompleted(ctx context.Context, projectID int, userID int, completed bool) error {
+// MarkProjectCompleted досрочно завершает сбор (active -> funded). Вернуть завершенный
+// проект в сбор нельзя.
+func (s *service) MarkProjectCompleted(ctx context.Context, projectID int, userID int, completed bool, reason string) error {
 	existingProject, err := s.repo.Get(ctx, projectID)
 	if err != nil {
 		return err
@@ -154,7 +256,10 @@ func (s *service) MarkProjectCompleted(ctx context.Context, projectID int, userI
 	if err != nil || !allowed {
 		return core.ErrNotAuthorized
 	}
-	return s.repo.MarkProjectCompleted(ctx, projectID, completed)
+	if !completed {
+		return core.ErrInvalidTransition
+	}
+	return s.repo.Transition(ctx, projectID, core.StatusFunded, reason, &userID)
 }
 
 func (s *service) StartPayback(ctx context.Context, projectID int, userID int) error {
@@ -177,64 +282,34 @@ func (s *service) StartPayback(ctx context.Context, projectID int, userID int) e
 		return core.ErrPaybackNotSupported
 	}
 
-	if !existingProject.PaybackStarted {
-		err = s.repo.StartPayback(ctx, projectID)
+	switch existingProject.Status {
+	case core.StatusFunded:
+		err = s.repo.Transition(ctx, projectID, core.StatusPaybackInProgress, "", &userID)
 		if err != nil {
 			s.log.Error("failed to start payback", "error", err)
 			return err
 		}
-	} else {
+	case core.StatusPaybackInProgress:
 		s.log.Info("payback already started, continuing payouts", "project_id", projectID)
+	default:
+		s.log.Warn("payback requires a funded project", "project_id", projectID, "status", existingProject.Status)
+		return core.ErrInvalidTransition
 	}
 
-	transactions, err := s.repo.GetProjectTransactions(ctx, projectID)
-	if err != nil {
-		s.log.Error("failed to get project transactions", "error", err)
-		return err
+	sg, err := s.sagas.StartAndRun(ctx, sagaPayback, strconv.Itoa(projectID), paybackPayload{ProjectID: projectID})
+	if errors.Is(err, saga.ErrAlreadyRunning) {
+		s.log.Warn("payback is already in progress", "project_id", projectID)
+		return core.ErrPaybackInProgress
 	}
-
-	investorPaybacks := s.calculatePaybacks(existingProject, transactions)
-	newMoneyRequired := existingProject.MoneyRequiredToPayback
-	defer func() {
-		_ = s.repo.UpdateMoneyRequiredToPayback(ctx, projectID, newMoneyRequired)
-	}()
-
-	for _, payback := range investorPaybacks {
-		amountRemaining := payback.PaybackAmount - payback.TotalReceived
-		if amountRemaining <= 0 {
-			s.log.Info("investor already fully paid", "project_id", projectID, "user_id", payback.UserID)
-			continue
-		}
-
-		amountToPay := amountRemaining
-		if existingProject.CurrentMoney < amountToPay {
-			amountToPay = existingProject.CurrentMoney
-			s.log.Info("partial payback - insufficient funds", "project_id", projectID, "user_id", payback.UserID, "amount_to_pay", amountToPay, "amount_remaining", amountRemaining)
-		}
-
-		if amountToPay > 0 {
-			err := s.transactionClient.Transfer(ctx, "project", projectID, "user", payback.UserID, amountToPay)
-			if err != nil {
-				s.log.Error("failed to create payback transaction", "error", err, "project_id", projectID, "user_id", payback.UserID, "amount", amountToPay)
-				return err
-			}
-			existingProject.CurrentMoney -= amountToPay
-			newMoneyRequired -= amountToPay
-			if newMoneyRequired < 0 {
-				newMoneyRequired = 0
-			}
-		}
-
-		if existingProject.CurrentMoney <= 0 {
-			s.log.Info("no more funds for payback", "project_id", projectID)
-			break
-		}
+	if sg != nil && sg.Status == saga.StatusRunning {
+		// выплаты продолжит воркер саг
+		s.log.Warn("payback interrupted, will be resumed", "project_id", projectID, "saga_id", sg.ID, "error", err)
+		return nil
 	}
-
-	return nil
+	return err
 }
 
-func (s *service) calculatePaybacks(project *core.Project, transactions []core.Transaction) []core.InvestorPayback {
+func (s *service) calculatePaybacks(project *core.Project, transactions []core.Transaction) ([]core.InvestorPayback, error) {
 	investorMap := make(map[int]*core.InvestorPayback)
 
 	for _, tx := range transactions {
@@ -251,9 +326,9 @@ func (s *service) calculatePaybacks(project *core.Project, transactions []core.T
 					Investments:   []core.Investment{},
 				}
 			}
-			investorMap[userID].TotalInvested += tx.Amount
+			investorMap[userID].TotalInvested += tx.Invested()
 			investorMap[userID].Investments = append(investorMap[userID].Investments, core.Investment{
-				Amount:     tx.Amount,
+				Amount:     tx.Invested(),
 				InvestedAt: tx.TimeAt,
 			})
 		} else if tx.Type == "project_to_user" && tx.FromID != nil && *tx.FromID == project.ID && tx.ReceiverID != nil {
@@ -269,35 +344,40 @@ func (s *service) calculatePaybacks(project *core.Project, transactions []core.T
 					Investments:   []core.Investment{},
 				}
 			}
-			investorMap[userID].TotalReceived += tx.Amount
+			investorMap[userID].TotalReceived += tx.Paid()
 		}
 	}
 
+	rate, err := money.PercentRate(project.Percent)
+	if err != nil {
+		return nil, err
+	}
 	result := make([]core.InvestorPayback, 0, len(investorMap))
 	for _, investor := range investorMap {
+		// сумма к выплате считается точно и округляется один раз на инвестора,
+		// чтобы копейки от отдельных вложений не накапливались
 		switch project.MonetizationType {
 		case "fixed_percent":
-			investor.PaybackAmount = investor.TotalInvested * (1 + project.Percent/100)
+			investor.PaybackAmount, err = investor.TotalInvested.MulRat(new(big.Rat).Add(big.NewRat(1, 1), rate), money.RoundHalfUp)
 		case "time_percent":
-			paybackAmount := investor.TotalInvested
+			paybackAmount := investor.TotalInvested.Rat()
 			now := time.Now()
 			for _, inv := range investor.Investments {
-				days := int(now.Sub(inv.InvestedAt).Hours() / 24)
-				paybackAmount += inv.Amount * (project.Percent / 100) * float64(days)
+				days := int64(now.Sub(inv.InvestedAt).Hours() / 24)
+				interest := new(big.Rat).Mul(inv.Amount.Rat(), rate)
+				paybackAmount.Add(paybackAmount, interest.Mul(interest, big.NewRat(days, 1)))
 			}
-			investor.PaybackAmount = paybackAmount
+			investor.PaybackAmount, err = money.FromRat(paybackAmount, money.RoundHalfUp)
+		}
+		if err != nil {
+			return nil, err
 		}
 		result = append(result, *investor)
 	}
 
-	return result
-}
-
-func (s *service) AddFunds(ctx context.Context, projectID int, amount float64) error {
-	// TODO add validations
-	return s.repo.AddFunds(ctx, projectID, amount)
+	return result, nil
 }
 
-func (s *service) UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, amount float64) error {
+func (s *service) UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, amount money.Amount) error {
 	return s.repo.UpdateMoneyRequiredToPayback(ctx, projectID, amount)
 }
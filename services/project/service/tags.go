@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Starostina-elena/investment_platform/services/project/core"
+)
+
+func (s *service) GetTags(ctx context.Context) ([]core.TagCount, error) {
+	return s.repo.GetTags(ctx)
+}
+
+func (s *service) CreateTag(ctx context.Context, t core.Tag) (*core.Tag, error) {
+	if err := s.repo.CreateTag(ctx, &t); err != nil {
+		return nil, err
+	}
+	s.log.Info("tag created", "id", t.ID, "name", t.Name)
+	return &t, nil
+}
+
+func (s *service) UpdateTag(ctx context.Context, t core.Tag) (*core.Tag, error) {
+	if err := s.repo.UpdateTag(ctx, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (s *service) DeleteTag(ctx context.Context, id int) error {
+	if err := s.repo.DeleteTag(ctx, id); err != nil {
+		return err
+	}
+	s.log.Info("tag deleted", "id", id)
+	return nil
+}
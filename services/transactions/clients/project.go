@@ -8,6 +8,8 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
 )
 
 type ProjectClient struct {
@@ -17,15 +19,22 @@ type ProjectClient struct {
 }
 
 type ProjectData struct {
-	ID                     int     `json:"id"`
-	Name                   string  `json:"name"`
-	MonetizationType       string  `json:"monetization_type"`
-	Percent                float64 `json:"percent"`
-	CurrentMoney           float64 `json:"current_money"`
-	WantedMoney            float64 `json:"wanted_money"`
-	MoneyRequiredToPayback float64 `json:"money_required_to_payback"`
-	CreatorID              int     `json:"creator_id"`
-	IsCompleted            bool    `json:"is_completed"`
+	ID                     int          `json:"id"`
+	Name                   string       `json:"name"`
+	MonetizationType       string       `json:"monetization_type"`
+	Percent                float64      `json:"percent"`
+	CurrentMoney           money.Amount `json:"current_money"`
+	WantedMoney            money.Amount `json:"wanted_money"`
+	MoneyRequiredToPayback money.Amount `json:"money_required_to_payback"`
+	CreatorID              int          `json:"creator_id"`
+	IsCompleted            bool         `json:"is_completed"`
+	Status                 string       `json:"status"`
+}
+
+// AcceptsInvestments — идет сбор: проект одобрен модератором и еще не завершен.
+// Пустой статус отдает проектный сервис без жизненного цикла, там смотрим только на is_completed.
+func (p *ProjectData) AcceptsInvestments() bool {
+	return !p.IsCompleted && (p.Status == "" || p.Status == "active")
 }
 
 func NewProjectClient(log slog.Logger) *ProjectClient {
@@ -41,7 +50,7 @@ func NewProjectClient(log slog.Logger) *ProjectClient {
 }
 
 func (pc *ProjectClient) GetProject(ctx context.Context, projectID int) (*ProjectData, error) {
-	url := fmt.Sprintf("%s/%d", pc.url, projectID)
+	url := fmt.Sprintf("%s/internal/projects/%d", pc.url, projectID)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -69,7 +78,7 @@ func (pc *ProjectClient) GetProject(ctx context.Context, projectID int) (*Projec
 	return &project, nil
 }
 
-func (pc *ProjectClient) UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, amount float64) error {
+func (pc *ProjectClient) UpdateMoneyRequiredToPayback(ctx context.Context, projectID int, amount money.Amount) error {
 	url := fmt.Sprintf("%s/%d/money-required-payback", pc.url, projectID)
 
 	reqBody, _ := json.Marshal(map[string]interface{}{"amount": amount})
@@ -13,9 +13,12 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fx"
 	"github.com/Starostina-elena/investment_platform/services/transactions/handler"
+	"github.com/Starostina-elena/investment_platform/services/transactions/outbox"
 	"github.com/Starostina-elena/investment_platform/services/transactions/repo"
 	"github.com/Starostina-elena/investment_platform/services/transactions/service"
 )
@@ -34,6 +37,21 @@ func openDB() *sqlx.DB {
 	return db
 }
 
+func openRedis() *redis.Client {
+	host := os.Getenv("REDIS_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := os.Getenv("REDIS_PORT")
+	if port == "" {
+		port = "6379"
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr: host + ":" + port,
+	})
+	return client
+}
+
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
@@ -47,11 +65,13 @@ func main() {
 		}
 	}(db)
 
+	redisClient := openRedis()
+	defer redisClient.Close()
+
 	repository := repo.NewRepo(db, *logger)
-	balanceClient := clients.NewBalanceClient(*logger)
 	projectClient := clients.NewProjectClient(*logger)
-	notificationClient := clients.NewNotificationClient(*logger)
-	svc := service.NewService(repository, balanceClient, projectClient, notificationClient, *logger)
+	orgClient := clients.NewOrgClient(os.Getenv("ORG_SERVICE_URL"), *logger)
+	svc := service.NewService(repository, projectClient, orgClient, *logger)
 	h := handler.NewHandler(svc, *logger)
 
 	router := getRouter(h)
@@ -63,6 +83,28 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	relay := outbox.NewRelay(db, redisClient, "transactions", *logger)
+	go relay.Run(ctx, time.Second)
+
+	if provider := fx.NewProviderFromEnv(); provider != nil {
+		updater := fx.NewUpdater(provider, repository, *logger)
+		go updater.Run(ctx, time.Hour)
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = svc.ExpireHolds(ctx)
+			}
+		}
+	}()
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("listen", "error", err)
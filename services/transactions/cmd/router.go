@@ -3,7 +3,9 @@ package main
 import (
 	"net/http"
 
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
 	"github.com/Starostina-elena/investment_platform/services/transactions/handler"
+	"github.com/Starostina-elena/investment_platform/services/transactions/middleware"
 )
 
 func getRouter(h *handler.Handler) *http.ServeMux {
@@ -11,6 +13,34 @@ func getRouter(h *handler.Handler) *http.ServeMux {
 
 	router.Handle("POST /transfer", handler.TransferHandler(h))
 
+	router.Handle("POST /internal/refunds", handler.RefundHandler(h))
+	router.Handle("POST /internal/holds", handler.CreateHoldHandler(h))
+	router.Handle("GET /internal/holds/{id}", handler.GetHoldHandler(h))
+	router.Handle("POST /internal/holds/{id}/capture", handler.CaptureHoldHandler(h))
+	router.Handle("POST /internal/holds/{id}/release", handler.ReleaseHoldHandler(h))
+
+	router.Handle("GET /history/user/{id}", middleware.AuthMiddleware(handler.HistoryHandler(h, clients.TypeUser)))
+	router.Handle("GET /history/org/{id}", middleware.AuthMiddleware(handler.HistoryHandler(h, clients.TypeOrg)))
+	router.Handle("GET /history/project/{id}", middleware.AuthMiddleware(handler.HistoryHandler(h, clients.TypeProject)))
+
+	router.Handle("GET /statement/user/{id}", middleware.AuthMiddleware(handler.StatementHandler(h, clients.TypeUser)))
+	router.Handle("GET /statement/org/{id}", middleware.AuthMiddleware(handler.StatementHandler(h, clients.TypeOrg)))
+
+	router.Handle("GET /wallets/user/{id}", middleware.AuthMiddleware(handler.WalletsHandler(h, clients.TypeUser)))
+	router.Handle("GET /wallets/org/{id}", middleware.AuthMiddleware(handler.WalletsHandler(h, clients.TypeOrg)))
+
+	router.Handle("GET /fx/rates", handler.RatesHandler(h))
+	router.Handle("POST /admin/fx/rates", middleware.AuthMiddleware(handler.SetRateHandler(h)))
+
+	router.Handle("POST /fees/quote", handler.QuoteHandler(h))
+	router.Handle("GET /admin/fees/rules", middleware.AuthMiddleware(handler.FeeRulesHandler(h)))
+	router.Handle("POST /admin/fees/rules", middleware.AuthMiddleware(handler.CreateFeeRuleHandler(h)))
+	router.Handle("POST /admin/fees/rules/{id}/disable", middleware.AuthMiddleware(handler.DisableFeeRuleHandler(h)))
+	router.Handle("GET /admin/fees/revenue", middleware.AuthMiddleware(handler.PlatformRevenueHandler(h)))
+
+	router.Handle("GET /admin/reconciliation", middleware.AuthMiddleware(handler.ReconciliationHandler(h)))
+	router.Handle("POST /admin/reconciliation/diffs/{id}/resolve", middleware.AuthMiddleware(handler.ResolveReconciliationDiffHandler(h)))
+
 	router.Handle("GET /ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("pong"))
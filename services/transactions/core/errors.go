@@ -0,0 +1,24 @@
+package core
+
+import "errors"
+
+var (
+	ErrInvalidAmount          = errors.New("amount must be positive")
+	ErrInsufficientFunds      = errors.New("insufficient funds")
+	ErrUnsupportedTransfer    = errors.New("unsupported transfer direction")
+	ErrEntityNotFound         = errors.New("entity not found")
+	ErrProjectCompleted       = errors.New("cannot transfer funds to completed project")
+	ErrProjectNotActive       = errors.New("project is not approved for fundraising yet")
+	ErrIdempotencyConflict    = errors.New("idempotency key reused with different payload")
+	ErrInvalidHistoryFilter   = errors.New("invalid history filter")
+	ErrNotAuthorized          = errors.New("not authorized")
+	ErrBalanceFrozen          = errors.New("balance is frozen pending reconciliation review")
+	ErrReconciliationNotFound = errors.New("reconciliation not found")
+	ErrCurrencyMismatch       = errors.New("transfer currency does not match project currency")
+	ErrRateUnavailable        = errors.New("fx rate is unavailable")
+	ErrHoldNotFound           = errors.New("hold not found")
+	ErrHoldExpired            = errors.New("hold expiry is in the past")
+	ErrHoldReleased           = errors.New("hold is already released")
+	ErrHoldCaptured           = errors.New("hold is already captured")
+	ErrFeeRuleNotFound        = errors.New("fee rule not found")
+)
@@ -0,0 +1,131 @@
+package fees
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+var ErrInvalidRule = errors.New("invalid fee rule")
+
+// Operation — вид перевода, с которого платформа берет комиссию
+type Operation string
+
+const (
+	OpDeposit    Operation = "deposit"    // пополнение кошелька с внешнего счета
+	OpInvestment Operation = "investment" // вложение в проект
+	OpPayback    Operation = "payback"    // выплата инвестору из проекта
+	// OpRefund — возврат вклада из провалившегося проекта. Правило для нее создать
+	// нельзя, поэтому возврат всегда идет без комиссии.
+	OpRefund Operation = "refund"
+)
+
+// OperationOf определяет вид операции по направлению перевода; пусто — перевод без комиссии
+func OperationOf(from, to clients.EntityType) Operation {
+	switch {
+	case from == clients.TypeExternal && (to == clients.TypeUser || to == clients.TypeOrg):
+		return OpDeposit
+	case (from == clients.TypeUser || from == clients.TypeOrg) && to == clients.TypeProject:
+		return OpInvestment
+	case from == clients.TypeProject && (to == clients.TypeUser || to == clients.TypeOrg):
+		return OpPayback
+	}
+	return ""
+}
+
+// Rule — правило комиссии: Percent процентов от зачисляемой суммы плюс Fixed,
+// но не меньше Min и не больше Max (0 — без ограничения). Пустые MonetizationType,
+// OrgType и Currency подходят к любому переводу. Fixed, Min и Max задаются
+// в валюте зачисления.
+type Rule struct {
+	ID               int64          `json:"id" db:"id"`
+	Operation        Operation      `json:"operation" db:"operation"`
+	MonetizationType string         `json:"monetization_type" db:"monetization_type"`
+	OrgType          string         `json:"org_type" db:"org_type"`
+	Currency         money.Currency `json:"currency" db:"currency"`
+	Percent          float64        `json:"percent" db:"percent"`
+	Fixed            money.Amount   `json:"fixed" db:"fixed"`
+	Min              money.Amount   `json:"min" db:"min_fee"`
+	Max              money.Amount   `json:"max" db:"max_fee"`
+	Active           bool           `json:"active" db:"active"`
+	CreatedBy        *int           `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+}
+
+func (r Rule) Validate() error {
+	switch r.Operation {
+	case OpDeposit, OpInvestment, OpPayback:
+	default:
+		return ErrInvalidRule
+	}
+	if r.Percent < 0 || r.Percent > 100 || r.Fixed.IsNegative() || r.Min.IsNegative() || r.Max.IsNegative() {
+		return ErrInvalidRule
+	}
+	if r.Max.IsPositive() && r.Min > r.Max {
+		return ErrInvalidRule
+	}
+	return nil
+}
+
+// Context — признаки перевода, по которым выбирается правило
+type Context struct {
+	Operation        Operation
+	MonetizationType string // тип монетизации проекта
+	OrgType          string // тип организации: получателя пополнения или владельца проекта
+	Currency         money.Currency
+}
+
+func (r Rule) matches(c Context) bool {
+	return r.Active && r.Operation == c.Operation &&
+		(r.MonetizationType == "" || r.MonetizationType == c.MonetizationType) &&
+		(r.OrgType == "" || r.OrgType == c.OrgType) &&
+		(r.Currency == "" || r.Currency == c.Currency)
+}
+
+func (r Rule) specificity() int {
+	n := 0
+	for _, set := range []bool{r.MonetizationType != "", r.OrgType != "", r.Currency != ""} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// Match выбирает самое точное из подходящих правил, при равенстве — более новое.
+// nil — комиссии нет.
+func Match(rules []Rule, c Context) *Rule {
+	var best *Rule
+	for i := range rules {
+		r := &rules[i]
+		if !r.matches(c) {
+			continue
+		}
+		if best == nil || r.specificity() > best.specificity() ||
+			(r.specificity() == best.specificity() && r.ID > best.ID) {
+			best = r
+		}
+	}
+	return best
+}
+
+// Fee считает комиссию с суммы amount. Комиссия не больше самой суммы.
+func (r Rule) Fee(amount money.Amount) (money.Amount, error) {
+	fee, err := amount.Percent(r.Percent, money.RoundHalfUp)
+	if err != nil {
+		return 0, err
+	}
+	fee += r.Fixed
+	if fee < r.Min {
+		fee = r.Min
+	}
+	if r.Max.IsPositive() && fee > r.Max {
+		fee = r.Max
+	}
+	if fee > amount {
+		fee = amount
+	}
+	return fee, nil
+}
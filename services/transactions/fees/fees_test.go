@@ -0,0 +1,95 @@
+package fees
+
+import (
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+func TestFee(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   Rule
+		amount money.Amount
+		want   money.Amount
+	}{
+		{"percent", Rule{Percent: 5}, money.MustParse("1000.00"), money.MustParse("50.00")},
+		{"percent rounds half up", Rule{Percent: 2.5}, money.MustParse("0.99"), money.MustParse("0.02")},
+		{"percent plus fixed", Rule{Percent: 1, Fixed: money.MustParse("30.00")}, money.MustParse("1000.00"), money.MustParse("40.00")},
+		{"min", Rule{Percent: 1, Min: money.MustParse("50.00")}, money.MustParse("1000.00"), money.MustParse("50.00")},
+		{"max", Rule{Percent: 10, Max: money.MustParse("500.00")}, money.MustParse("10000.00"), money.MustParse("500.00")},
+		{"not more than amount", Rule{Fixed: money.MustParse("100.00")}, money.MustParse("30.00"), money.MustParse("30.00")},
+	}
+	for _, tt := range tests {
+		if got, err := tt.rule.Fee(tt.amount); err != nil || got != tt.want {
+			t.Errorf("Fee() %s = %s, %v; want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	rules := []Rule{
+		{ID: 1, Operation: OpInvestment, Percent: 5, Active: true},
+		{ID: 2, Operation: OpInvestment, MonetizationType: "charity", Percent: 0, Active: true},
+		{ID: 3, Operation: OpInvestment, MonetizationType: "fixed_percent", OrgType: "jur", Percent: 3, Active: true},
+		{ID: 4, Operation: OpInvestment, Percent: 7, Active: false},
+		{ID: 5, Operation: OpDeposit, Currency: money.USD, Percent: 1, Active: true},
+	}
+	tests := []struct {
+		name string
+		ctx  Context
+		want int64
+	}{
+		{"default", Context{Operation: OpInvestment, MonetizationType: "custom", OrgType: "ip"}, 1},
+		{"by monetization type", Context{Operation: OpInvestment, MonetizationType: "charity", OrgType: "jur"}, 2},
+		{"most specific", Context{Operation: OpInvestment, MonetizationType: "fixed_percent", OrgType: "jur"}, 3},
+		{"by currency", Context{Operation: OpDeposit, Currency: money.USD}, 5},
+		{"no rule", Context{Operation: OpDeposit, Currency: money.RUB}, 0},
+	}
+	for _, tt := range tests {
+		var got int64
+		if r := Match(rules, tt.ctx); r != nil {
+			got = r.ID
+		}
+		if got != tt.want {
+			t.Errorf("Match() %s = rule %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOperationOf(t *testing.T) {
+	tests := []struct {
+		from, to clients.EntityType
+		want     Operation
+	}{
+		{clients.TypeExternal, clients.TypeUser, OpDeposit},
+		{clients.TypeUser, clients.TypeProject, OpInvestment},
+		{clients.TypeOrg, clients.TypeProject, OpInvestment},
+		{clients.TypeProject, clients.TypeUser, OpPayback},
+		{clients.TypeUser, clients.TypeExternal, ""},
+		{clients.TypeProject, clients.TypeOrg, OpPayback},
+	}
+	for _, tt := range tests {
+		if got := OperationOf(tt.from, tt.to); got != tt.want {
+			t.Errorf("OperationOf(%s, %s) = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := (Rule{Operation: OpPayback, Percent: 1}).Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	invalid := []Rule{
+		{Operation: "withdraw", Percent: 1},
+		{Operation: OpRefund, Percent: 1},
+		{Operation: OpDeposit, Percent: 101},
+		{Operation: OpDeposit, Min: money.MustParse("10.00"), Max: money.MustParse("5.00")},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Validate(%+v) error = nil, want ErrInvalidRule", r)
+		}
+	}
+}
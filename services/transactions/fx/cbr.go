@@ -0,0 +1,77 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+const cbrDailyURL = "https://www.cbr-xml-daily.ru/daily_json.js"
+
+// CBRProvider берет официальные курсы ЦБ РФ из ежедневной выгрузки
+type CBRProvider struct {
+	url    string
+	client *http.Client
+}
+
+func NewCBRProvider() *CBRProvider {
+	return &CBRProvider{
+		url:    cbrDailyURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *CBRProvider) Name() string {
+	return "cbr"
+}
+
+func (p *CBRProvider) Rates(ctx context.Context) ([]Rate, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cbr rates error: status %d", resp.StatusCode)
+	}
+
+	var daily struct {
+		Valute map[string]struct {
+			Nominal json.Number `json:"Nominal"`
+			Value   json.Number `json:"Value"`
+		} `json:"Valute"`
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&daily); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var rates []Rate
+	for _, currency := range []money.Currency{money.USD, money.EUR} {
+		v, ok := daily.Valute[currency.String()]
+		if !ok {
+			continue
+		}
+		value, ok1 := new(big.Rat).SetString(v.Value.String())
+		nominal, ok2 := new(big.Rat).SetString(v.Nominal.String())
+		if !ok1 || !ok2 || nominal.Sign() <= 0 {
+			return nil, ErrInvalidRate
+		}
+		// курс ЦБ дается за Nominal единиц валюты
+		rate := new(big.Rat).Quo(value, nominal)
+		rates = append(rates, Rate{Currency: currency, Rate: rate.FloatString(10), Source: p.Name(), FetchedAt: now})
+	}
+	return rates, nil
+}
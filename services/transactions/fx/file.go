@@ -0,0 +1,53 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+// FileProvider читает курсы из JSON-файла вида {"USD": "92.5", "EUR": "100.1"}.
+// Подходит для локального запуска и тестов.
+type FileProvider struct {
+	path string
+}
+
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+func (p *FileProvider) Rates(ctx context.Context) ([]Rate, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rates := make([]Rate, 0, len(raw))
+	for code, value := range raw {
+		currency, err := money.ParseCurrency(code)
+		if err != nil {
+			return nil, err
+		}
+		if currency == money.BaseCurrency {
+			continue
+		}
+		r := Rate{Currency: currency, Rate: value, Source: p.Name(), FetchedAt: now}
+		if _, err := r.Rat(); err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, nil
+}
@@ -0,0 +1,59 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+var ErrInvalidRate = errors.New("invalid fx rate")
+
+// MaxRateAge — насколько старым может быть курс, по которому проводится конвертация.
+// ЦБ не публикует курсы в выходные, поэтому запас больше суток.
+const MaxRateAge = 72 * time.Hour
+
+// Rate — курс валюты к базовой: 1 единица Currency стоит Rate единиц money.BaseCurrency.
+// Rate хранится десятичной строкой, чтобы не терять точность.
+type Rate struct {
+	ID        int64          `json:"id" db:"id"`
+	Currency  money.Currency `json:"currency" db:"currency"`
+	Rate      string         `json:"rate" db:"rate"`
+	Source    string         `json:"source" db:"source"`
+	FetchedAt time.Time      `json:"fetched_at" db:"fetched_at"`
+}
+
+func (r Rate) Rat() (*big.Rat, error) {
+	v, ok := new(big.Rat).SetString(r.Rate)
+	if !ok || v.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+	return v, nil
+}
+
+// Provider — источник курсов валют. Возвращает курсы к базовой валюте.
+type Provider interface {
+	Name() string
+	Rates(ctx context.Context) ([]Rate, error)
+}
+
+// CrossRate — сколько единиц to дают за единицу from, если известны курсы обеих валют к базовой.
+// Для базовой валюты курс передается nil.
+func CrossRate(from, to *big.Rat) *big.Rat {
+	one := big.NewRat(1, 1)
+	if from == nil {
+		from = one
+	}
+	if to == nil {
+		to = one
+	}
+	return new(big.Rat).Quo(from, to)
+}
+
+// Convert пересчитывает сумму по курсу. Доли копейки отбрасываются: получатель
+// не может получить больше, чем стоит списанная сумма.
+func Convert(amount money.Amount, rate *big.Rat) (money.Amount, error) {
+	return amount.MulRat(rate, money.RoundDown)
+}
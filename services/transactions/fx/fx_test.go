@@ -0,0 +1,56 @@
+package fx
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+func TestConvert(t *testing.T) {
+	usd := big.NewRat(925, 10)  // 92.5 RUB
+	eur := big.NewRat(1001, 10) // 100.1 RUB
+
+	tests := []struct {
+		name     string
+		amount   money.Amount
+		from, to *big.Rat
+		want     money.Amount
+	}{
+		{"usd to rub", money.MustParse("10.00"), usd, nil, money.MustParse("925.00")},
+		{"rub to usd rounds down", money.MustParse("100.00"), nil, usd, money.MustParse("1.08")},
+		{"usd to eur", money.MustParse("100.00"), usd, eur, money.MustParse("92.40")},
+		{"same currency", money.MustParse("1.23"), nil, nil, money.MustParse("1.23")},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.amount, CrossRate(tt.from, tt.to))
+		if err != nil || got != tt.want {
+			t.Errorf("Convert() %s = %s, %v; want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	if err := os.WriteFile(path, []byte(`{"USD": "92.5", "RUB": "1"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := NewFileProvider(path).Rates(context.Background())
+	if err != nil {
+		t.Fatalf("Rates() error = %v", err)
+	}
+	if len(rates) != 1 || rates[0].Currency != money.USD || rates[0].Rate != "92.5" {
+		t.Errorf("Rates() = %+v, want single USD rate 92.5", rates)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"GBP": "110"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileProvider(path).Rates(context.Background()); err == nil {
+		t.Errorf("Rates() with unknown currency: expected error")
+	}
+}
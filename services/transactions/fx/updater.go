@@ -0,0 +1,64 @@
+package fx
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+type Store interface {
+	SaveRates(ctx context.Context, rates []Rate) error
+}
+
+// NewProviderFromEnv выбирает источник курсов по FX_PROVIDER: "cbr" или "file"
+// (путь к файлу в FX_RATES_FILE). Без настройки курсы задает только администратор.
+func NewProviderFromEnv() Provider {
+	switch os.Getenv("FX_PROVIDER") {
+	case "cbr":
+		return NewCBRProvider()
+	case "file":
+		return NewFileProvider(os.Getenv("FX_RATES_FILE"))
+	}
+	return nil
+}
+
+// Updater периодически загружает курсы из провайдера и сохраняет их в fx_rates
+type Updater struct {
+	provider Provider
+	store    Store
+	log      slog.Logger
+}
+
+func NewUpdater(provider Provider, store Store, log slog.Logger) *Updater {
+	return &Updater{provider: provider, store: store, log: log}
+}
+
+func (u *Updater) Update(ctx context.Context) {
+	rates, err := u.provider.Rates(ctx)
+	if err != nil {
+		u.log.Error("failed to fetch fx rates", "provider", u.provider.Name(), "error", err)
+		return
+	}
+	if err := u.store.SaveRates(ctx, rates); err != nil {
+		u.log.Error("failed to save fx rates", "provider", u.provider.Name(), "error", err)
+		return
+	}
+	u.log.Info("fx rates updated", "provider", u.provider.Name(), "count", len(rates))
+}
+
+// Run обновляет курсы сразу и затем раз в interval, пока не отменен ctx
+func (u *Updater) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	u.Update(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.Update(ctx)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fees"
+	"github.com/Starostina-elena/investment_platform/services/transactions/middleware"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+// QuoteHandler показывает комиссию и сумму зачисления до перевода; тело как у POST /transfer
+func QuoteHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			FromType   string       `json:"from_type"`
+			FromID     int          `json:"from_id"`
+			ToType     string       `json:"to_type"`
+			ToID       int          `json:"to_id"`
+			Amount     money.Amount `json:"amount"`
+			Currency   string       `json:"currency"`
+			ToCurrency string       `json:"to_currency"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if errors.Is(err, money.ErrPrecision) {
+				http.Error(w, "Сумма указывается с точностью до копейки", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		currency, err := money.ParseCurrency(req.Currency)
+		if err != nil {
+			http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+			return
+		}
+		var toCurrency money.Currency
+		if req.ToCurrency != "" {
+			if toCurrency, err = money.ParseCurrency(req.ToCurrency); err != nil {
+				http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+				return
+			}
+		}
+
+		quote, err := h.service.Quote(r.Context(), clients.EntityType(req.FromType), clients.EntityType(req.ToType),
+			req.FromID, req.ToID, req.Amount, currency, toCurrency)
+		if err != nil {
+			switch err {
+			case core.ErrInvalidAmount:
+				http.Error(w, "Сумма перевода должна быть положительной", http.StatusBadRequest)
+			case core.ErrUnsupportedTransfer:
+				http.Error(w, "Такой перевод не поддерживается", http.StatusBadRequest)
+			case core.ErrEntityNotFound:
+				http.Error(w, "Участник перевода не найден", http.StatusNotFound)
+			case core.ErrCurrencyMismatch:
+				http.Error(w, "Проект принимает и выплачивает деньги только в своей валюте", http.StatusBadRequest)
+			case core.ErrRateUnavailable:
+				http.Error(w, "Нет актуального курса для конвертации", http.StatusServiceUnavailable)
+			default:
+				h.log.Error("quote error", "error", err)
+				http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(quote)
+	}
+}
+
+func FeeRulesHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireAdmin(w, r); !ok {
+			return
+		}
+
+		rules, err := h.service.GetFeeRules(r.Context())
+		if err != nil {
+			h.log.Error("failed to get fee rules", "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(rules)
+	}
+}
+
+// CreateFeeRuleHandler — новое правило комиссии: {"operation": "investment",
+// "monetization_type": "fixed_percent", "org_type": "", "currency": "", "percent": 3,
+// "fixed": 0, "min": 0, "max": 0}. Чтобы изменить правило, старое отключают и создают новое.
+func CreateFeeRuleHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		var rule fees.Rule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if rule.Currency != "" {
+			if _, err := money.ParseCurrency(string(rule.Currency)); err != nil {
+				http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+				return
+			}
+		}
+
+		err := h.service.CreateFeeRule(r.Context(), claims.UserID, &rule)
+		if err == fees.ErrInvalidRule {
+			http.Error(w, "Некорректное правило комиссии", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(rule)
+	}
+}
+
+func DisableFeeRuleHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := requireAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		err = h.service.DisableFeeRule(r.Context(), claims.UserID, id)
+		if err == core.ErrFeeRuleNotFound {
+			http.Error(w, "Правило не найдено или уже отключено", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.log.Error("failed to disable fee rule", "rule_id", id, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PlatformRevenueHandler — удержанные комиссии по валютам
+func PlatformRevenueHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireAdmin(w, r); !ok {
+			return
+		}
+
+		revenue, err := h.service.GetPlatformRevenue(r.Context())
+		if err != nil {
+			h.log.Error("failed to get platform revenue", "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(revenue)
+	}
+}
+
+func requireAdmin(w http.ResponseWriter, r *http.Request) (*middleware.UserClaims, bool) {
+	claims := middleware.FromContext(r.Context())
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	if !claims.Admin || claims.Banned {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return claims, true
+}
@@ -2,10 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
 	"github.com/Starostina-elena/investment_platform/services/transactions/service"
 )
 
@@ -21,34 +24,152 @@ func NewHandler(s service.Service, log slog.Logger) *Handler {
 func TransferHandler(h *Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
-			FromType string  `json:"from_type"` // "user", "org", "project"
-			FromID   int     `json:"from_id"`
-			ToType   string  `json:"to_type"`
-			ToID     int     `json:"to_id"`
-			Amount   float64 `json:"amount"`
+			FromType string       `json:"from_type"` // "user", "org", "project"
+			FromID   int          `json:"from_id"`
+			ToType   string       `json:"to_type"`
+			ToID     int          `json:"to_id"`
+			Amount   money.Amount `json:"amount"`
+			// Currency — валюта списания, ToCurrency — валюта зачисления (для проекта — его валюта)
+			Currency   string `json:"currency"`
+			ToCurrency string `json:"to_currency"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if errors.Is(err, money.ErrPrecision) {
+				http.Error(w, "Сумма указывается с точностью до копейки", http.StatusBadRequest)
+				return
+			}
 			http.Error(w, "bad request", http.StatusBadRequest)
 			return
 		}
 
-		tx, err := h.service.Transfer(
+		currency, err := money.ParseCurrency(req.Currency)
+		if err != nil {
+			http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+			return
+		}
+		var toCurrency money.Currency
+		if req.ToCurrency != "" {
+			if toCurrency, err = money.ParseCurrency(req.ToCurrency); err != nil {
+				http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+				return
+			}
+		}
+
+		idempotencyKey := r.Header.Get("Idempotency-Key")
+		if len(idempotencyKey) > 255 {
+			http.Error(w, "Слишком длинный Idempotency-Key", http.StatusBadRequest)
+			return
+		}
+
+		tx, replayed, err := h.service.Transfer(
 			r.Context(),
+			idempotencyKey,
 			clients.EntityType(req.FromType),
 			clients.EntityType(req.ToType),
 			req.FromID,
 			req.ToID,
 			req.Amount,
+			currency,
+			toCurrency,
 		)
 
 		if err != nil {
 			h.log.Error("transfer error", "error", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			switch err {
+			case core.ErrInvalidAmount:
+				http.Error(w, "Сумма перевода должна быть положительной", http.StatusBadRequest)
+			case core.ErrInsufficientFunds:
+				http.Error(w, "Недостаточно средств", http.StatusBadRequest)
+			case core.ErrUnsupportedTransfer:
+				http.Error(w, "Такой перевод не поддерживается", http.StatusBadRequest)
+			case core.ErrEntityNotFound:
+				http.Error(w, "Участник перевода не найден", http.StatusNotFound)
+			case core.ErrProjectCompleted:
+				http.Error(w, "Проект уже завершен", http.StatusBadRequest)
+			case core.ErrProjectNotActive:
+				http.Error(w, "Проект еще не прошел модерацию", http.StatusBadRequest)
+			case core.ErrBalanceFrozen:
+				http.Error(w, "Баланс заморожен до проверки расхождения", http.StatusConflict)
+			case core.ErrCurrencyMismatch:
+				http.Error(w, "Проект принимает и выплачивает деньги только в своей валюте", http.StatusBadRequest)
+			case core.ErrRateUnavailable:
+				http.Error(w, "Нет актуального курса для конвертации", http.StatusServiceUnavailable)
+			case core.ErrIdempotencyConflict:
+				http.Error(w, "Idempotency-Key уже использован с другими параметрами перевода", http.StatusConflict)
+			default:
+				http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if replayed {
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_ = json.NewEncoder(w).Encode(tx)
+	}
+}
+
+// RefundHandler — возврат вклада из провалившегося проекта, его вызывает daemon.
+// Idempotency-Key обязателен: возврат повторяется, пока не пройдут все переводы проекта.
+func RefundHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ProjectID int          `json:"project_id"`
+			ToType    string       `json:"to_type"` // "user", "org"
+			ToID      int          `json:"to_id"`
+			Amount    money.Amount `json:"amount"`
+			// FeeReturned — часть amount, которую возвращает платформа из удержанной комиссии
+			FeeReturned money.Amount `json:"fee_returned"`
+			Currency    string       `json:"currency"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		currency, err := money.ParseCurrency(req.Currency)
+		if err != nil {
+			http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+			return
+		}
+		idempotencyKey := r.Header.Get("Idempotency-Key")
+		if idempotencyKey == "" || len(idempotencyKey) > 255 {
+			http.Error(w, "Нужен Idempotency-Key не длиннее 255 символов", http.StatusBadRequest)
+			return
+		}
+
+		tx, replayed, err := h.service.Refund(r.Context(), idempotencyKey, req.ProjectID,
+			clients.EntityType(req.ToType), req.ToID, req.Amount, req.FeeReturned, currency)
+		if err != nil {
+			switch err {
+			case core.ErrInvalidAmount:
+				http.Error(w, "Сумма возврата должна быть больше возвращаемой комиссии", http.StatusBadRequest)
+			case core.ErrInsufficientFunds:
+				http.Error(w, "Недостаточно средств", http.StatusBadRequest)
+			case core.ErrUnsupportedTransfer:
+				http.Error(w, "Такой перевод не поддерживается", http.StatusBadRequest)
+			case core.ErrEntityNotFound:
+				http.Error(w, "Участник перевода не найден", http.StatusNotFound)
+			case core.ErrBalanceFrozen:
+				http.Error(w, "Баланс заморожен до проверки расхождения", http.StatusConflict)
+			case core.ErrIdempotencyConflict:
+				http.Error(w, "Idempotency-Key уже использован с другими параметрами перевода", http.StatusConflict)
+			default:
+				http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			}
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
+		if replayed {
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
 		_ = json.NewEncoder(w).Encode(tx)
 	}
 }
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/middleware"
+	"github.com/Starostina-elena/investment_platform/services/transactions/repo"
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+type HistoryResponse struct {
+	Items      []repo.HistoryEntry `json:"items"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+func HistoryHandler(h *Handler, entityType clients.EntityType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		idStr := r.PathValue("id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := parseHistoryFilter(r)
+		if err != nil {
+			http.Error(w, "Некорректные параметры фильтра", http.StatusBadRequest)
+			return
+		}
+
+		items, nextCursor, err := h.service.GetHistory(r.Context(), claims.UserID, claims.Admin, entityType, id, filter)
+		if err != nil {
+			switch err {
+			case core.ErrNotAuthorized:
+				http.Error(w, "Нет прав для просмотра истории операций", http.StatusForbidden)
+			case core.ErrInvalidHistoryFilter:
+				http.Error(w, "Некорректный тип транзакции", http.StatusBadRequest)
+			default:
+				h.log.Error("failed to get history", "entity_type", entityType, "entity_id", id, "error", err)
+				http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		resp := HistoryResponse{Items: items}
+		if nextCursor > 0 {
+			resp.NextCursor = strconv.Itoa(nextCursor)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// parseHistoryFilter читает параметры limit, cursor, type (через запятую), from и to.
+// Даты принимаются в виде 2006-01-02 или RFC 3339; дата без времени в to включает весь день.
+func parseHistoryFilter(r *http.Request) (repo.HistoryFilter, error) {
+	q := r.URL.Query()
+	f := repo.HistoryFilter{Limit: defaultHistoryLimit}
+
+	if limitStr := q.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return f, core.ErrInvalidHistoryFilter
+		}
+		f.Limit = min(limit, maxHistoryLimit)
+	}
+
+	if cursorStr := q.Get("cursor"); cursorStr != "" {
+		cursor, err := strconv.Atoi(cursorStr)
+		if err != nil || cursor <= 0 {
+			return f, core.ErrInvalidHistoryFilter
+		}
+		f.Cursor = cursor
+	}
+
+	if typesStr := q.Get("type"); typesStr != "" {
+		for _, t := range strings.Split(typesStr, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				f.Types = append(f.Types, t)
+			}
+		}
+	}
+
+	if fromStr := q.Get("from"); fromStr != "" {
+		from, _, err := parseHistoryDate(fromStr)
+		if err != nil {
+			return f, err
+		}
+		f.From = &from
+	}
+
+	if toStr := q.Get("to"); toStr != "" {
+		to, dateOnly, err := parseHistoryDate(toStr)
+		if err != nil {
+			return f, err
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		f.To = &to
+	}
+
+	return f, nil
+}
+
+func parseHistoryDate(s string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false, core.ErrInvalidHistoryFilter
+	}
+	return t, false, nil
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+// CreateHoldHandler резервирует деньги под будущий перевод. Idempotency-Key обязателен:
+// по нему повторный запрос возвращает уже созданный холд.
+func CreateHoldHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			FromType string       `json:"from_type"`
+			FromID   int          `json:"from_id"`
+			ToType   string       `json:"to_type"`
+			ToID     int          `json:"to_id"`
+			Amount   money.Amount `json:"amount"`
+			Currency string       `json:"currency"`
+			// TTLSeconds — срок холда; 0 — срок по умолчанию (для проекта — до его дедлайна).
+			// Холд на внешний счет (выплата) не истекает, срок для него не учитывается.
+			TTLSeconds int `json:"ttl_seconds"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			if errors.Is(err, money.ErrPrecision) {
+				http.Error(w, "Сумма указывается с точностью до копейки", http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if req.TTLSeconds < 0 {
+			http.Error(w, "Срок холда не может быть отрицательным", http.StatusBadRequest)
+			return
+		}
+
+		currency, err := money.ParseCurrency(req.Currency)
+		if err != nil {
+			http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+			return
+		}
+
+		reference := r.Header.Get("Idempotency-Key")
+		if reference == "" {
+			http.Error(w, "Для холда нужен Idempotency-Key", http.StatusBadRequest)
+			return
+		}
+		if len(reference) > 255 {
+			http.Error(w, "Слишком длинный Idempotency-Key", http.StatusBadRequest)
+			return
+		}
+
+		hold, existing, err := h.service.CreateHold(
+			r.Context(),
+			reference,
+			clients.EntityType(req.FromType),
+			clients.EntityType(req.ToType),
+			req.FromID,
+			req.ToID,
+			req.Amount,
+			currency,
+			time.Duration(req.TTLSeconds)*time.Second,
+		)
+		if err != nil {
+			h.log.Error("create hold error", "error", err)
+			writeHoldError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if existing {
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_ = json.NewEncoder(w).Encode(hold)
+	}
+}
+
+func GetHoldHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		hold, err := h.service.GetHold(r.Context(), id)
+		if err != nil {
+			writeHoldError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(hold)
+	}
+}
+
+// CaptureHoldHandler списывает холд и возвращает проведенный перевод
+func CaptureHoldHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		tx, replayed, err := h.service.CaptureHold(r.Context(), id)
+		if err != nil {
+			writeHoldError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if replayed {
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+		_ = json.NewEncoder(w).Encode(tx)
+	}
+}
+
+func ReleaseHoldHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.service.ReleaseHold(r.Context(), id); err != nil {
+			writeHoldError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeHoldError(w http.ResponseWriter, err error) {
+	switch err {
+	case core.ErrHoldNotFound:
+		http.Error(w, "Холд не найден", http.StatusNotFound)
+	case core.ErrHoldReleased:
+		http.Error(w, "Холд уже освобожден", http.StatusConflict)
+	case core.ErrHoldCaptured:
+		http.Error(w, "Холд уже списан", http.StatusConflict)
+	case core.ErrHoldExpired:
+		http.Error(w, "Срок холда уже истек", http.StatusBadRequest)
+	case core.ErrInvalidAmount:
+		http.Error(w, "Сумма холда должна быть положительной", http.StatusBadRequest)
+	case core.ErrInsufficientFunds:
+		http.Error(w, "Недостаточно средств", http.StatusBadRequest)
+	case core.ErrUnsupportedTransfer:
+		http.Error(w, "Такой перевод не поддерживается", http.StatusBadRequest)
+	case core.ErrEntityNotFound:
+		http.Error(w, "Участник перевода не найден", http.StatusNotFound)
+	case core.ErrProjectCompleted:
+		http.Error(w, "Проект уже завершен", http.StatusBadRequest)
+	case core.ErrProjectNotActive:
+		http.Error(w, "Проект еще не прошел модерацию", http.StatusBadRequest)
+	case core.ErrBalanceFrozen:
+		http.Error(w, "Баланс заморожен до проверки расхождения", http.StatusConflict)
+	case core.ErrCurrencyMismatch:
+		http.Error(w, "Проект принимает деньги только в своей валюте", http.StatusBadRequest)
+	case core.ErrRateUnavailable:
+		http.Error(w, "Нет актуального курса для конвертации", http.StatusServiceUnavailable)
+	case core.ErrIdempotencyConflict:
+		http.Error(w, "Idempotency-Key уже использован с другими параметрами холда", http.StatusConflict)
+	default:
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/middleware"
+)
+
+func ReconciliationHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !claims.Admin || claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		runID := 0
+		if v := r.URL.Query().Get("run_id"); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil || id <= 0 {
+				http.Error(w, "Некорректный run_id", http.StatusBadRequest)
+				return
+			}
+			runID = id
+		}
+
+		run, err := h.service.GetReconciliationRun(r.Context(), runID)
+		if err == core.ErrReconciliationNotFound {
+			http.Error(w, "Сверка не найдена", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(run)
+	}
+}
+
+func ResolveReconciliationDiffHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !claims.Admin || claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		diffID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		err = h.service.ResolveReconciliationDiff(r.Context(), diffID, claims.UserID)
+		if err == core.ErrReconciliationNotFound {
+			http.Error(w, "Расхождение не найдено или уже разобрано", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			h.log.Error("failed to resolve reconciliation diff", "diff_id", diffID, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/middleware"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+// StatementHandler отдает выписку за период: from и to — даты 2006-01-02 включительно,
+// format — csv (по умолчанию) или pdf, currency — валюта кошелька (по умолчанию RUB).
+func StatementHandler(h *Handler, entityType clients.EntityType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		idStr := r.PathValue("id")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		from, err := time.Parse(time.DateOnly, r.URL.Query().Get("from"))
+		if err != nil {
+			http.Error(w, "Некорректная дата начала периода", http.StatusBadRequest)
+			return
+		}
+		lastDay, err := time.Parse(time.DateOnly, r.URL.Query().Get("to"))
+		if err != nil || lastDay.Before(from) {
+			http.Error(w, "Некорректная дата конца периода", http.StatusBadRequest)
+			return
+		}
+		to := lastDay.AddDate(0, 0, 1)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "pdf" {
+			http.Error(w, "Поддерживаются форматы csv и pdf", http.StatusBadRequest)
+			return
+		}
+
+		currency, err := money.ParseCurrency(r.URL.Query().Get("currency"))
+		if err != nil {
+			http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+			return
+		}
+
+		st, err := h.service.GetStatement(r.Context(), claims.UserID, claims.Admin, entityType, id, currency, from, to)
+		if err != nil {
+			if err == core.ErrNotAuthorized {
+				http.Error(w, "Нет прав для просмотра выписки", http.StatusForbidden)
+				return
+			}
+			h.log.Error("failed to build statement", "entity_type", entityType, "entity_id", id, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		// документ собирается в память целиком, чтобы ошибка рендера не оборвала ответ на середине
+		var buf bytes.Buffer
+		contentType := "text/csv; charset=utf-8"
+		if format == "pdf" {
+			contentType = "application/pdf"
+			err = st.WritePDF(&buf)
+		} else {
+			err = st.WriteCSV(&buf)
+		}
+		if err != nil {
+			h.log.Error("failed to render statement", "entity_type", entityType, "entity_id", id, "format", format, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("statement_%s_%d_%s_%s.%s", entityType, id, from.Format(time.DateOnly), lastDay.Format(time.DateOnly), format)
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("X-Statement-Checksum", st.Checksum())
+		_, _ = w.Write(buf.Bytes())
+	}
+}
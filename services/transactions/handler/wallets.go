@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fx"
+	"github.com/Starostina-elena/investment_platform/services/transactions/middleware"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+func WalletsHandler(h *Handler, entityType clients.EntityType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Некорректный id", http.StatusBadRequest)
+			return
+		}
+
+		wallets, err := h.service.GetWallets(r.Context(), claims.UserID, claims.Admin, entityType, id)
+		if err != nil {
+			switch err {
+			case core.ErrNotAuthorized:
+				http.Error(w, "Нет прав для просмотра баланса", http.StatusForbidden)
+			case core.ErrEntityNotFound:
+				http.Error(w, "Владелец кошелька не найден", http.StatusNotFound)
+			default:
+				h.log.Error("failed to get wallets", "entity_type", entityType, "entity_id", id, "error", err)
+				http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(wallets)
+	}
+}
+
+func RatesHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rates, err := h.service.GetRates(r.Context())
+		if err != nil {
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"base":  money.BaseCurrency,
+			"rates": rates,
+		})
+	}
+}
+
+// SetRateHandler — ручной курс от администратора: {"currency": "USD", "rate": "92.5"}
+func SetRateHandler(h *Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := middleware.FromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !claims.Admin || claims.Banned {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var req struct {
+			Currency string `json:"currency"`
+			Rate     string `json:"rate"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		currency, err := money.ParseCurrency(req.Currency)
+		if err != nil || req.Currency == "" {
+			http.Error(w, "Неизвестная валюта", http.StatusBadRequest)
+			return
+		}
+
+		rate, err := h.service.SetRate(r.Context(), claims.UserID, currency, req.Rate)
+		if err == fx.ErrInvalidRate {
+			http.Error(w, "Курс должен быть положительным числом и задаваться для небазовой валюты", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			h.log.Error("failed to set fx rate", "currency", currency, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(rate)
+	}
+}
@@ -0,0 +1,44 @@
+package money
+
+import (
+	"errors"
+	"strings"
+)
+
+// Currency — код валюты ISO 4217. Суммы во всех поддерживаемых валютах
+// хранятся с точностью до сотой доли (копейки, центы).
+type Currency string
+
+const (
+	RUB Currency = "RUB"
+	USD Currency = "USD"
+	EUR Currency = "EUR"
+)
+
+// BaseCurrency — валюта, в которой ведутся основные балансы и курсы
+const BaseCurrency = RUB
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+var supportedCurrencies = map[Currency]bool{
+	RUB: true,
+	USD: true,
+	EUR: true,
+}
+
+// ParseCurrency разбирает код валюты без учета регистра. Пустая строка означает базовую валюту.
+func ParseCurrency(s string) (Currency, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return BaseCurrency, nil
+	}
+	c := Currency(s)
+	if !supportedCurrencies[c] {
+		return "", ErrUnknownCurrency
+	}
+	return c, nil
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
@@ -0,0 +1,172 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]Amount{
+		"0":       0,
+		"1500":    150000,
+		"99.9":    9990,
+		"0.01":    1,
+		"-12.05":  -1205,
+		"1e3":     100000,
+		" 7.50 ":  750,
+		"1234.56": 123456,
+	}
+	for in, want := range cases {
+		got, err := Parse(in)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Parse(%q) = %d, want %d", in, got, want)
+		}
+	}
+
+	if _, err := Parse("0.001"); !errors.Is(err, ErrPrecision) {
+		t.Errorf("Parse(0.001) error = %v, want ErrPrecision", err)
+	}
+	if _, err := Parse("abc"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Parse(abc) error = %v, want ErrInvalid", err)
+	}
+}
+
+func TestString(t *testing.T) {
+	cases := map[Amount]string{
+		0:      "0.00",
+		1:      "0.01",
+		-1:     "-0.01",
+		150000: "1500.00",
+		-1205:  "-12.05",
+	}
+	for in, want := range cases {
+		if got := in.String(); got != want {
+			t.Errorf("Amount(%d).String() = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPercentRounding(t *testing.T) {
+	percent := func(a Amount, p float64, mode RoundingMode) Amount {
+		t.Helper()
+		got, err := a.Percent(p, mode)
+		if err != nil {
+			t.Fatalf("Percent(%v) error = %v", p, err)
+		}
+		return got
+	}
+
+	// 0.10 * 5% = 0.005 — ровно половина копейки
+	a := MustParse("0.10")
+	if got := percent(a, 5, RoundHalfUp); got != 1 {
+		t.Errorf("Percent(RoundHalfUp) = %s, want 0.01", got)
+	}
+	if got := percent(a, 5, RoundHalfEven); got != 0 {
+		t.Errorf("Percent(RoundHalfEven) = %s, want 0.00", got)
+	}
+	if got := percent(a, 5, RoundDown); got != 0 {
+		t.Errorf("Percent(RoundDown) = %s, want 0.00", got)
+	}
+	if got := percent(MustParse("0.01"), 1, RoundUp); got != 1 {
+		t.Errorf("Percent(RoundUp) = %s, want 0.01", got)
+	}
+	if got := percent(MustParse("-0.10"), 5, RoundHalfUp); got != -1 {
+		t.Errorf("negative Percent(RoundHalfUp) = %s, want -0.01", got)
+	}
+
+	// 1000 * 12.3% во float64 дает 122.99999999999999
+	if got := percent(MustParse("1000"), 12.3, RoundDown); got != MustParse("123") {
+		t.Errorf("Percent(12.3) = %s, want 123.00", got)
+	}
+}
+
+func TestPercentRateInvalid(t *testing.T) {
+	for _, p := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := PercentRate(p); !errors.Is(err, ErrInvalid) {
+			t.Errorf("PercentRate(%v) error = %v, want ErrInvalid", p, err)
+		}
+		if _, err := MustParse("100").Percent(p, RoundHalfUp); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Percent(%v) error = %v, want ErrInvalid", p, err)
+		}
+	}
+}
+
+func TestFromRatOverflow(t *testing.T) {
+	max := big.NewRat(math.MaxInt64, 100)
+	if got, err := FromRat(max, RoundDown); err != nil || got != Amount(math.MaxInt64) {
+		t.Errorf("FromRat(max) = %s, %v; want the largest amount", got, err)
+	}
+	over := new(big.Rat).Add(max, big.NewRat(1, 100))
+	if _, err := FromRat(over, RoundDown); !errors.Is(err, ErrOverflow) {
+		t.Errorf("FromRat(max + 0.01) error = %v, want ErrOverflow", err)
+	}
+	if _, err := MustParse("1000000000000").Percent(1e10, RoundHalfUp); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Percent(1e10) error = %v, want ErrOverflow", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		Amount Amount `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 0.3}`), &v); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if v.Amount != 30 {
+		t.Errorf("Unmarshal() = %d, want 30", v.Amount)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": "10.10"}`), &v); err != nil || v.Amount != 1010 {
+		t.Errorf("Unmarshal(string) = %d, %v", v.Amount, err)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `{"amount":10.10}` {
+		t.Errorf("Marshal() = %s", data)
+	}
+}
+
+func TestScan(t *testing.T) {
+	var a Amount
+	if err := a.Scan([]byte("1234.56")); err != nil || a != 123456 {
+		t.Errorf("Scan([]byte) = %d, %v", a, err)
+	}
+	if err := a.Scan([]byte("0.125")); err != nil || a != 13 {
+		t.Errorf("Scan(0.125) = %d, %v", a, err)
+	}
+	if err := a.Scan(nil); err != nil || a != 0 {
+		t.Errorf("Scan(nil) = %d, %v", a, err)
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Currency
+		wantErr bool
+	}{
+		{"", BaseCurrency, false},
+		{"usd", USD, false},
+		{" EUR ", EUR, false},
+		{"GBP", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCurrency(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCurrency(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCurrency(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
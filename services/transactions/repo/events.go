@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/Starostina-elena/investment_platform/services/transactions/outbox"
+	"github.com/jmoiron/sqlx"
+)
+
+const outboxService = "transactions"
+
+type TransferCompletedEvent struct {
+	TransactionID int                `json:"transaction_id"`
+	Type          string             `json:"type"`
+	FromType      clients.EntityType `json:"from_type"`
+	FromID        int                `json:"from_id"`
+	ToType        clients.EntityType `json:"to_type"`
+	ToID          int                `json:"to_id"`
+	Amount        money.Amount       `json:"amount"`
+	Currency      money.Currency     `json:"currency"`
+	ToAmount      money.Amount       `json:"to_amount"`
+	ToCurrency    money.Currency     `json:"to_currency"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+type GoalReachedInvestor struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// GoalReachedEvent несет адреса инвесторов, чтобы уведомления можно было
+// отправить без обращения к БД
+type GoalReachedEvent struct {
+	ProjectID    int                   `json:"project_id"`
+	ProjectName  string                `json:"project_name"`
+	WantedMoney  money.Amount          `json:"wanted_money"`
+	CurrentMoney money.Amount          `json:"current_money"`
+	Investors    []GoalReachedInvestor `json:"investors"`
+}
+
+// addGoalReachedEvent пишет project.goal_reached, если перевод впервые довел
+// сбор проекта до цели. Проверка идет по балансу до и после перевода под
+// блокировкой строки проекта, поэтому событие появляется ровно один раз.
+func addGoalReachedEvent(ctx context.Context, tx *sqlx.Tx, projectID int, before, after money.Amount) error {
+	var project struct {
+		Name        string       `db:"name"`
+		WantedMoney money.Amount `db:"wanted_money"`
+	}
+	if err := tx.GetContext(ctx, &project, `SELECT name, wanted_money FROM projects WHERE id = $1`, projectID); err != nil {
+		return err
+	}
+	if before >= project.WantedMoney || after < project.WantedMoney {
+		return nil
+	}
+
+	investors, err := projectInvestors(ctx, tx, projectID)
+	if err != nil {
+		return err
+	}
+	event := GoalReachedEvent{
+		ProjectID:    projectID,
+		ProjectName:  project.Name,
+		WantedMoney:  project.WantedMoney,
+		CurrentMoney: after,
+		Investors:    make([]GoalReachedInvestor, 0, len(investors)),
+	}
+	for _, inv := range investors {
+		event.Investors = append(event.Investors, GoalReachedInvestor{UserID: inv.UserID, Email: inv.UserEmail})
+	}
+	return outbox.Add(ctx, tx, outboxService, outbox.TypeProjectGoalReached, event)
+}
@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fees"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/jmoiron/sqlx"
+)
+
+// applyFee удерживает комиссию платформы из суммы зачисления. Вызывается после
+// resolveConversion, поэтому комиссия считается в валюте получателя.
+func applyFee(ctx context.Context, tx *sqlx.Tx, t *Transaction) error {
+	t.Fee = money.Zero
+	t.FeeRuleID = nil
+
+	fc, err := feeContext(ctx, tx, t)
+	if err != nil || fc.Operation == "" {
+		return err
+	}
+	if fc.Operation == fees.OpRefund {
+		// правил для возврата нет: платформа отдает удержанную с вклада комиссию,
+		// и получатель получает больше, чем списано с проекта
+		t.Fee = -t.FeeReturned
+		t.ToAmount += t.FeeReturned
+		return nil
+	}
+
+	var rules []fees.Rule
+	err = tx.SelectContext(ctx, &rules, `SELECT * FROM fee_rules WHERE operation = $1 AND active`, fc.Operation)
+	if err != nil {
+		return err
+	}
+	rule := fees.Match(rules, fc)
+	if rule == nil {
+		return nil
+	}
+
+	if t.Fee, err = rule.Fee(t.ToAmount); err != nil {
+		return core.ErrInvalidAmount
+	}
+	t.ToAmount -= t.Fee
+	t.FeeRuleID = &rule.ID
+	return nil
+}
+
+// feeContext собирает признаки перевода для выбора правила: тип монетизации проекта
+// и тип организации — получателя пополнения или владельца проекта
+func feeContext(ctx context.Context, tx *sqlx.Tx, t *Transaction) (fees.Context, error) {
+	fc := fees.Context{Operation: fees.OperationOf(t.FromType, t.ToType), Currency: t.ToCurrency}
+	if t.Refund {
+		fc.Operation = fees.OpRefund
+	}
+
+	var err error
+	switch fc.Operation {
+	case fees.OpDeposit:
+		if t.ToType == clients.TypeOrg {
+			err = tx.GetContext(ctx, &fc.OrgType,
+				`SELECT COALESCE(type::text, '') FROM organizations WHERE id = $1`, t.ToID)
+		}
+	case fees.OpInvestment, fees.OpPayback:
+		projectID := t.ToID
+		if fc.Operation == fees.OpPayback {
+			projectID = t.FromID
+		}
+		err = tx.QueryRowxContext(ctx, `
+			SELECT p.monetization_type::text, COALESCE(o.type::text, '')
+			FROM projects p
+			LEFT JOIN organizations o ON o.id = p.creator_id
+			WHERE p.id = $1`, projectID).Scan(&fc.MonetizationType, &fc.OrgType)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return fc, core.ErrEntityNotFound
+	}
+	return fc, err
+}
+
+// Quote считает перевод так же, как Transfer, но ничего не проводит: сколько получит
+// получатель, какой будет курс и комиссия
+func (r *Repo) Quote(ctx context.Context, t *Transaction) error {
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if !supportedTransactionTypes[transactionType(t)] {
+		return core.ErrUnsupportedTransfer
+	}
+	if err := resolveConversion(ctx, tx, t); err != nil {
+		return err
+	}
+	return applyFee(ctx, tx, t)
+}
+
+// GetFeeRules возвращает все правила, включая отключенные
+func (r *Repo) GetFeeRules(ctx context.Context) ([]fees.Rule, error) {
+	rules := []fees.Rule{}
+	err := r.db.SelectContext(ctx, &rules, `SELECT * FROM fee_rules ORDER BY operation, id`)
+	return rules, err
+}
+
+func (r *Repo) CreateFeeRule(ctx context.Context, rule *fees.Rule) error {
+	return r.db.QueryRowxContext(ctx, `
+		INSERT INTO fee_rules (operation, monetization_type, org_type, currency, percent, fixed, min_fee, max_fee, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, active, created_at`,
+		rule.Operation, rule.MonetizationType, rule.OrgType, rule.Currency, rule.Percent,
+		rule.Fixed, rule.Min, rule.Max, rule.CreatedBy).Scan(&rule.ID, &rule.Active, &rule.CreatedAt)
+}
+
+// DisableFeeRule отключает правило. Правила не удаляются: на них ссылаются проведенные переводы.
+func (r *Repo) DisableFeeRule(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE fee_rules SET active = FALSE WHERE id = $1 AND active`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return core.ErrFeeRuleNotFound
+	}
+	return nil
+}
+
+// PlatformRevenue — накопленные комиссии по валютам, из леджера
+func (r *Repo) PlatformRevenue(ctx context.Context) ([]WalletBalance, error) {
+	revenue := []WalletBalance{}
+	err := r.db.SelectContext(ctx, &revenue, `
+		SELECT a.currency, COALESCE(SUM(e.amount), 0) AS balance, 0 AS held
+		FROM ledger_accounts a
+		LEFT JOIN ledger_entries e ON e.account_id = a.id
+		WHERE a.entity_type = $1
+		GROUP BY a.currency
+		ORDER BY a.currency`, clients.TypePlatform)
+	return revenue, err
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fx"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/jmoiron/sqlx"
+)
+
+func (r *Repo) SaveRates(ctx context.Context, rates []fx.Rate) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, rate := range rates {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO fx_rates (currency, rate, source, fetched_at) VALUES ($1, $2, $3, $4)`,
+			rate.Currency, rate.Rate, rate.Source, rate.FetchedAt)
+		if err != nil {
+			r.log.Error("failed to save fx rate", "currency", rate.Currency, "error", err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LatestRates возвращает последний курс каждой валюты
+func (r *Repo) LatestRates(ctx context.Context) ([]fx.Rate, error) {
+	rates := []fx.Rate{}
+	err := r.db.SelectContext(ctx, &rates, `
+		SELECT DISTINCT ON (currency) id, currency, rate, source, fetched_at
+		FROM fx_rates ORDER BY currency, fetched_at DESC, id DESC`)
+	if err != nil {
+		r.log.Error("failed to get fx rates", "error", err)
+		return nil, err
+	}
+	return rates, nil
+}
+
+// rateToBase — последний курс валюты к базовой; для базовой валюты nil
+func rateToBase(ctx context.Context, tx *sqlx.Tx, currency money.Currency) (*big.Rat, error) {
+	if currency == money.BaseCurrency {
+		return nil, nil
+	}
+	var rate fx.Rate
+	err := tx.GetContext(ctx, &rate, `
+		SELECT id, currency, rate, source, fetched_at
+		FROM fx_rates WHERE currency = $1 ORDER BY fetched_at DESC, id DESC LIMIT 1`, currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrRateUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(rate.FetchedAt) > fx.MaxRateAge {
+		return nil, core.ErrRateUnavailable
+	}
+	return rate.Rat()
+}
+
+// conversionRate — курс from→to по последним сохраненным курсам к базовой валюте
+func conversionRate(ctx context.Context, tx *sqlx.Tx, from, to money.Currency) (*big.Rat, error) {
+	fromRate, err := rateToBase(ctx, tx, from)
+	if err != nil {
+		return nil, err
+	}
+	toRate, err := rateToBase(ctx, tx, to)
+	if err != nil {
+		return nil, err
+	}
+	return fx.CrossRate(fromRate, toRate), nil
+}
@@ -0,0 +1,247 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/lib/pq"
+)
+
+const (
+	DirectionIn  = "in"
+	DirectionOut = "out"
+)
+
+// HistoryEntry — движение денег с точки зрения одной сущности
+type HistoryEntry struct {
+	ID               int                `json:"id"`
+	Type             string             `json:"type"`
+	Direction        string             `json:"direction"`
+	CounterpartyType clients.EntityType `json:"counterparty_type"`
+	CounterpartyID   int                `json:"counterparty_id"`
+	Amount           money.Amount       `json:"amount"`
+	Currency         money.Currency     `json:"currency"`
+	BalanceAfter     *money.Amount      `json:"balance_after"`
+	CreatedAt        time.Time          `json:"created_at"`
+}
+
+type HistoryFilter struct {
+	Types []string
+	From  *time.Time
+	To    *time.Time
+	// Cursor — id последней записи предыдущей страницы, 0 для первой страницы
+	Cursor int
+	Limit  int
+}
+
+// типы транзакций, в которых сущность выступает отправителем и получателем
+var outgoingTypes = map[clients.EntityType][]string{
+	clients.TypeUser:    {"user_to_project", "user_to_org", "user_withdraw"},
+	clients.TypeOrg:     {"org_to_project", "org_withdraw"},
+	clients.TypeProject: {"project_to_user", "project_to_org"},
+}
+
+var incomingTypes = map[clients.EntityType][]string{
+	clients.TypeUser:    {"project_to_user", "user_deposit"},
+	clients.TypeOrg:     {"project_to_org", "user_to_org", "org_deposit"},
+	clients.TypeProject: {"user_to_project", "org_to_project"},
+}
+
+type historyRow struct {
+	ID               int            `db:"id"`
+	Type             string         `db:"type"`
+	FromID           sql.NullInt64  `db:"from_id"`
+	ReceiverID       sql.NullInt64  `db:"reciever_id"`
+	Amount           money.Amount   `db:"amount"`
+	Currency         money.Currency `db:"currency"`
+	ToAmount         money.Amount   `db:"to_amount"`
+	ToCurrency       money.Currency `db:"to_currency"`
+	CumSumOfSender   *money.Amount  `db:"cum_sum_of_sender"`
+	CumSumOfReceiver *money.Amount  `db:"cum_sum_of_reciever"`
+	TimeAt           time.Time      `db:"time_at"`
+}
+
+// historySelect выбирает транзакции, где сущность $1 — отправитель с типом из $2
+// или получатель с типом из $3
+const historySelect = `SELECT id, type, from_id, reciever_id, amount, currency, COALESCE(to_amount, amount) AS to_amount,
+	       to_currency, cum_sum_of_sender, cum_sum_of_reciever, time_at
+	FROM transactions
+	WHERE ((from_id = $1 AND type::text = ANY($2)) OR (reciever_id = $1 AND type::text = ANY($3)))`
+
+// currencyFilter оставляет движения в валюте $4: у исходящих это валюта списания,
+// у входящих — валюта зачисления
+const currencyFilter = ` AND CASE WHEN from_id = $1 AND type::text = ANY($2) THEN currency ELSE to_currency END = $4`
+
+// GetHistory возвращает страницу движений сущности от новых к старым и id
+// последней записи, если за ней есть еще записи (иначе 0).
+func (r *Repo) GetHistory(ctx context.Context, entityType clients.EntityType, entityID int, f HistoryFilter) ([]HistoryEntry, int, error) {
+	outgoing, incoming, err := filterTypes(entityType, f.Types)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := historySelect
+	args := []interface{}{entityID, pq.Array(outgoing), pq.Array(incoming)}
+	argIndex := 4
+
+	if f.From != nil {
+		query += " AND time_at >= $" + fmt.Sprintf("%d", argIndex)
+		args = append(args, *f.From)
+		argIndex++
+	}
+
+	if f.To != nil {
+		query += " AND time_at < $" + fmt.Sprintf("%d", argIndex)
+		args = append(args, *f.To)
+		argIndex++
+	}
+
+	if f.Cursor > 0 {
+		query += " AND id < $" + fmt.Sprintf("%d", argIndex)
+		args = append(args, f.Cursor)
+		argIndex++
+	}
+
+	// запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	query += " ORDER BY id DESC LIMIT $" + fmt.Sprintf("%d", argIndex)
+	args = append(args, f.Limit+1)
+
+	var rows []historyRow
+	if err := r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		r.log.Error("failed to get history", "entity_type", entityType, "entity_id", entityID, "error", err)
+		return nil, 0, err
+	}
+
+	nextCursor := 0
+	if len(rows) > f.Limit {
+		rows = rows[:f.Limit]
+		nextCursor = rows[len(rows)-1].ID
+	}
+
+	entries := make([]HistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, toHistoryEntry(entityType, entityID, row))
+	}
+	return entries, nextCursor, nil
+}
+
+// GetMovements возвращает все движения сущности в валюте currency за период [from, to)
+// в хронологическом порядке
+func (r *Repo) GetMovements(ctx context.Context, entityType clients.EntityType, entityID int, currency money.Currency, from, to time.Time) ([]HistoryEntry, error) {
+	outgoing, incoming, err := filterTypes(entityType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []historyRow
+	err = r.db.SelectContext(ctx, &rows, historySelect+currencyFilter+` AND time_at >= $5 AND time_at < $6 ORDER BY id`,
+		entityID, pq.Array(outgoing), pq.Array(incoming), currency, from, to)
+	if err != nil {
+		r.log.Error("failed to get movements", "entity_type", entityType, "entity_id", entityID, "error", err)
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, toHistoryEntry(entityType, entityID, row))
+	}
+	return entries, nil
+}
+
+// GetBalanceAt возвращает остаток сущности в валюте currency на момент at по последней
+// транзакции до него. Если транзакций до at не было, остаток нулевой.
+func (r *Repo) GetBalanceAt(ctx context.Context, entityType clients.EntityType, entityID int, currency money.Currency, at time.Time) (money.Amount, error) {
+	outgoing, incoming, err := filterTypes(entityType, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows []historyRow
+	err = r.db.SelectContext(ctx, &rows, historySelect+currencyFilter+` AND time_at < $5 ORDER BY id DESC LIMIT 1`,
+		entityID, pq.Array(outgoing), pq.Array(incoming), currency, at)
+	if err != nil {
+		r.log.Error("failed to get balance", "entity_type", entityType, "entity_id", entityID, "error", err)
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	entry := toHistoryEntry(entityType, entityID, rows[0])
+	if entry.BalanceAfter == nil {
+		return 0, nil
+	}
+	return *entry.BalanceAfter, nil
+}
+
+func filterTypes(entityType clients.EntityType, types []string) ([]string, []string, error) {
+	outgoing, ok := outgoingTypes[entityType]
+	if !ok {
+		return nil, nil, core.ErrUnsupportedTransfer
+	}
+	incoming := incomingTypes[entityType]
+	if len(types) == 0 {
+		return outgoing, incoming, nil
+	}
+
+	var out, in []string
+	for _, t := range types {
+		known := false
+		if slices.Contains(outgoing, t) {
+			out = append(out, t)
+			known = true
+		}
+		if slices.Contains(incoming, t) {
+			in = append(in, t)
+			known = true
+		}
+		if !known {
+			return nil, nil, core.ErrInvalidHistoryFilter
+		}
+	}
+	return out, in, nil
+}
+
+func toHistoryEntry(entityType clients.EntityType, entityID int, row historyRow) HistoryEntry {
+	e := HistoryEntry{
+		ID:        row.ID,
+		Type:      row.Type,
+		Amount:    row.Amount,
+		CreatedAt: row.TimeAt,
+	}
+
+	if row.FromID.Valid && int(row.FromID.Int64) == entityID && slices.Contains(outgoingTypes[entityType], row.Type) {
+		e.Direction = DirectionOut
+		e.Currency = row.Currency
+		e.BalanceAfter = row.CumSumOfSender
+		e.CounterpartyType, e.CounterpartyID = counterparty(row.Type, DirectionOut, row.ReceiverID)
+	} else {
+		e.Direction = DirectionIn
+		e.Amount = row.ToAmount
+		e.Currency = row.ToCurrency
+		e.BalanceAfter = row.CumSumOfReceiver
+		e.CounterpartyType, e.CounterpartyID = counterparty(row.Type, DirectionIn, row.FromID)
+	}
+	return e
+}
+
+// counterparty определяет вторую сторону по типу транзакции: "<from>_to_<to>",
+// "<to>_deposit" и "<from>_withdraw" (у двух последних вторая сторона внешняя)
+func counterparty(txType, direction string, id sql.NullInt64) (clients.EntityType, int) {
+	from, to, ok := strings.Cut(txType, "_to_")
+	if !ok {
+		return clients.TypeExternal, 0
+	}
+	other := to
+	if direction == DirectionIn {
+		other = from
+	}
+	return clients.EntityType(other), int(id.Int64)
+}
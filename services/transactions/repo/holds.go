@@ -0,0 +1,341 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/jmoiron/sqlx"
+)
+
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldReleased HoldStatus = "released"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold — резерв средств на балансе. Пока холд активен, деньги остаются на балансе
+// отправителя, но не доступны для других переводов и холдов. При списании (capture)
+// проводится обычный перевод получателю ToType/ToID, при освобождении (release)
+// или истечении ExpiresAt резерв просто снимается. Холд без ExpiresAt не истекает.
+type Hold struct {
+	ID            int64              `json:"id" db:"id"`
+	EntityType    clients.EntityType `json:"entity_type" db:"entity_type"`
+	EntityID      int                `json:"entity_id" db:"entity_id"`
+	Currency      money.Currency     `json:"currency" db:"currency"`
+	Amount        money.Amount       `json:"amount" db:"amount"`
+	ToType        clients.EntityType `json:"to_type" db:"to_type"`
+	ToID          int                `json:"to_id" db:"to_id"`
+	Status        HoldStatus         `json:"status" db:"status"`
+	Reference     string             `json:"reference" db:"reference"`
+	TransactionID *int               `json:"transaction_id,omitempty" db:"transaction_id"`
+	ExpiresAt     *time.Time         `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
+}
+
+const holdColumns = `id, entity_type, entity_id, currency, amount, to_type, to_id, status, reference,
+	transaction_id, expires_at, created_at`
+
+func (h *Hold) transaction() *Transaction {
+	return &Transaction{
+		FromType: h.EntityType,
+		FromID:   h.EntityID,
+		ToType:   h.ToType,
+		ToID:     h.ToID,
+		Amount:   h.Amount,
+		Currency: h.Currency,
+	}
+}
+
+// ReplayHold ищет холд, уже созданный с h.Reference, без блокировок и подставляет его в h.
+// existing = false, если такого холда нет; тогда его создает CreateHold.
+func (r *Repo) ReplayHold(ctx context.Context, h *Hold) (existing bool, err error) {
+	return loadHoldByReference(ctx, r.db, h)
+}
+
+// loadHoldByReference подставляет в h холд с тем же reference. Холд с другими
+// параметрами — ErrIdempotencyConflict.
+func loadHoldByReference(ctx context.Context, q sqlx.QueryerContext, h *Hold) (bool, error) {
+	var stored Hold
+	err := sqlx.GetContext(ctx, q, &stored, `SELECT `+holdColumns+` FROM balance_holds WHERE reference = $1`, h.Reference)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if stored.EntityType != h.EntityType || stored.EntityID != h.EntityID || stored.Currency != h.Currency ||
+		stored.Amount != h.Amount || stored.ToType != h.ToType || stored.ToID != h.ToID {
+		return false, core.ErrIdempotencyConflict
+	}
+	*h = stored
+	return true, nil
+}
+
+// CreateHold резервирует h.Amount на балансе h.EntityType/h.EntityID. Reference —
+// ключ идемпотентности: повторный запрос с тем же reference и теми же параметрами
+// возвращает уже созданный холд и existing = true. Холд на проект (обещание
+// инвестиции) истекает не позже дедлайна проекта и только в валюте проекта.
+func (r *Repo) CreateHold(ctx context.Context, h *Hold) (existing bool, err error) {
+	if !supportedTransactionTypes[transactionType(h.transaction())] {
+		return false, core.ErrUnsupportedTransfer
+	}
+	if _, ok := balanceProjections[h.EntityType]; !ok {
+		return false, core.ErrUnsupportedTransfer
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// блокировка баланса сериализует холды и переводы одной сущности
+	balance, err := lockBalance(ctx, tx, h.EntityType, h.EntityID, h.Currency)
+	if err != nil {
+		return false, err
+	}
+
+	existing, err = loadHoldByReference(ctx, tx, h)
+	if err != nil || existing {
+		return existing, err
+	}
+
+	if h.ToType == clients.TypeProject {
+		var project struct {
+			Currency     money.Currency `db:"currency"`
+			ActivatedAt  *time.Time     `db:"activated_at"`
+			DurationDays *int           `db:"duration_days"`
+		}
+		err := tx.GetContext(ctx, &project, `SELECT currency, activated_at, duration_days FROM projects WHERE id = $1`, h.ToID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, core.ErrEntityNotFound
+		}
+		if err != nil {
+			return false, err
+		}
+		if project.Currency != h.Currency {
+			return false, core.ErrCurrencyMismatch
+		}
+		if h.ExpiresAt, err = pledgeExpiry(h.ExpiresAt, project.ActivatedAt, project.DurationDays); err != nil {
+			return false, err
+		}
+	}
+	if h.ExpiresAt != nil && !h.ExpiresAt.After(time.Now()) {
+		return false, core.ErrHoldExpired
+	}
+
+	frozen, err := isFrozen(ctx, tx, h.EntityType, h.EntityID)
+	if err != nil {
+		return false, err
+	}
+	if frozen {
+		return false, core.ErrBalanceFrozen
+	}
+	held, err := heldAmount(ctx, tx, h.EntityType, h.EntityID, h.Currency, 0)
+	if err != nil {
+		return false, err
+	}
+	if balance-held < h.Amount {
+		return false, core.ErrInsufficientFunds
+	}
+
+	err = tx.QueryRowxContext(ctx, `
+		INSERT INTO balance_holds (entity_type, entity_id, currency, amount, to_type, to_id, reference, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, status, created_at`,
+		h.EntityType, h.EntityID, h.Currency, h.Amount, h.ToType, h.ToID, h.Reference, h.ExpiresAt,
+	).Scan(&h.ID, &h.Status, &h.CreatedAt)
+	if err != nil {
+		r.log.Error("failed to insert hold", "reference", h.Reference, "error", err)
+		return false, err
+	}
+
+	return false, tx.Commit()
+}
+
+// pledgeExpiry — срок обещания инвестиции: не позже дедлайна сбора. Сбор идет
+// duration_days с одобрения модератором (activated_at), как и в daemon, который
+// завершает проекты. Проект, не начавший сбор, обещаний не принимает.
+func pledgeExpiry(requested, activatedAt *time.Time, durationDays *int) (*time.Time, error) {
+	if activatedAt == nil || durationDays == nil {
+		return nil, core.ErrProjectNotActive
+	}
+	deadline := activatedAt.AddDate(0, 0, *durationDays)
+	if requested == nil || requested.After(deadline) {
+		return &deadline, nil
+	}
+	return requested, nil
+}
+
+func (r *Repo) GetHold(ctx context.Context, id int64) (*Hold, error) {
+	var h Hold
+	err := r.db.GetContext(ctx, &h, `SELECT `+holdColumns+` FROM balance_holds WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// CaptureHold списывает холд: проводит перевод получателю холда в той же транзакции БД,
+// в которой холд помечается списанным. Истекший холд тоже можно списать, если денег
+// все еще хватает: выплата могла пройти у провайдера позже срока холда.
+// Повторное списание возвращает исходный перевод и replayed = true.
+func (r *Repo) CaptureHold(ctx context.Context, id int64) (t *Transaction, replayed bool, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var h Hold
+	err = tx.GetContext(ctx, &h, `SELECT `+holdColumns+` FROM balance_holds WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, core.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	t = h.transaction()
+	if h.Status == HoldCaptured && h.TransactionID != nil {
+		if err := loadTransaction(ctx, tx, *h.TransactionID, t); err != nil {
+			return nil, false, err
+		}
+		return t, true, nil
+	}
+	if h.Status == HoldReleased {
+		return nil, false, core.ErrHoldReleased
+	}
+
+	t.CreatedAt = time.Now()
+	txID, err := r.post(ctx, tx, t, transactionType(t), h.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE balance_holds SET status = $1, transaction_id = $2, updated_at = NOW() WHERE id = $3`,
+		HoldCaptured, txID, h.ID)
+	if err != nil {
+		r.log.Error("failed to mark hold captured", "hold_id", h.ID, "error", err)
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Error("failed to commit hold capture", "hold_id", h.ID, "error", err)
+		return nil, false, err
+	}
+	t.ID = txID
+	return t, false, nil
+}
+
+// ReleaseHold снимает резерв. Повторное освобождение проходит без ошибки,
+// освободить списанный холд нельзя.
+func (r *Repo) ReleaseHold(ctx context.Context, id int64) error {
+	var status HoldStatus
+	err := r.db.GetContext(ctx, &status, `
+		UPDATE balance_holds SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status IN ($3, $4)
+		RETURNING status`, HoldReleased, id, HoldActive, HoldExpired)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	err = r.db.GetContext(ctx, &status, `SELECT status FROM balance_holds WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return core.ErrHoldNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status == HoldCaptured {
+		return core.ErrHoldCaptured
+	}
+	return nil
+}
+
+// ExpireHolds помечает истекшими активные холды с прошедшим expires_at; холды без срока не трогает.
+// Доступный остаток не зависит от этой пометки: просроченный холд не учитывается и до нее.
+func (r *Repo) ExpireHolds(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE balance_holds SET status = $1, updated_at = NOW()
+		WHERE status = $2 AND expires_at <= NOW()`, HoldExpired, HoldActive)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FundedPledges возвращает активные обещания инвестиций в проект, если вместе с уже
+// собранными деньгами они покрывают цель проекта, иначе пустой список
+func (r *Repo) FundedPledges(ctx context.Context, projectID int) ([]int64, error) {
+	ids := []int64{}
+	err := r.db.SelectContext(ctx, &ids, `
+		WITH pledges AS (
+			SELECT id, amount FROM balance_holds
+			WHERE to_type = $1 AND to_id = $2 AND status = $3 AND (expires_at IS NULL OR expires_at > NOW())
+		)
+		SELECT pledges.id FROM pledges, projects p
+		WHERE p.id = $2
+		  AND COALESCE(p.current_money, 0) + (SELECT SUM(amount) FROM pledges) >= p.wanted_money
+		ORDER BY pledges.id`, clients.TypeProject, projectID, HoldActive)
+	if err != nil {
+		r.log.Error("failed to get funded pledges", "project_id", projectID, "error", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// heldAmount — сумма активных холдов сущности в валюте, кроме холда exclude
+func heldAmount(ctx context.Context, q sqlx.QueryerContext, entityType clients.EntityType, id int, currency money.Currency, exclude int64) (money.Amount, error) {
+	var held money.Amount
+	err := sqlx.GetContext(ctx, q, &held, `
+		SELECT COALESCE(SUM(amount), 0) FROM balance_holds
+		WHERE entity_type = $1 AND entity_id = $2 AND currency = $3
+		  AND status = $4 AND (expires_at IS NULL OR expires_at > NOW()) AND id <> $5`,
+		entityType, id, currency, HoldActive, exclude)
+	return held, err
+}
+
+func loadTransaction(ctx context.Context, q sqlx.QueryerContext, id int, t *Transaction) error {
+	var stored struct {
+		TimeAt     time.Time      `db:"time_at"`
+		ToAmount   money.Amount   `db:"to_amount"`
+		ToCurrency money.Currency `db:"to_currency"`
+		FXRate     *string        `db:"fx_rate"`
+		Fee        money.Amount   `db:"fee"`
+		FeeRuleID  *int64         `db:"fee_rule_id"`
+	}
+	err := sqlx.GetContext(ctx, q, &stored, `
+		SELECT time_at, COALESCE(to_amount, amount) AS to_amount, to_currency, fx_rate, fee, fee_rule_id
+		FROM transactions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	t.CreatedAt = stored.TimeAt
+	t.ToAmount = stored.ToAmount
+	t.ToCurrency = stored.ToCurrency
+	t.FXRate = stored.FXRate
+	t.Fee = stored.Fee
+	t.FeeRuleID = stored.FeeRuleID
+	return nil
+}
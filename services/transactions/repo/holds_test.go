@@ -0,0 +1,41 @@
+package repo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+)
+
+func TestPledgeExpiry(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	days := 30
+
+	// проект создан за 60 дней до одобрения и начал сбор вчера:
+	// срок обещания считается от одобрения, а не от создания
+	activated := now.AddDate(0, 0, -1)
+	got, err := pledgeExpiry(nil, &activated, &days)
+	if err != nil {
+		t.Fatalf("pledgeExpiry() error = %v", err)
+	}
+	if want := now.AddDate(0, 0, 29); !got.Equal(want) {
+		t.Errorf("pledgeExpiry() = %s, want %s", got, want)
+	}
+	if !got.After(now) {
+		t.Errorf("pledgeExpiry() = %s is already past", got)
+	}
+
+	requested := now.AddDate(0, 0, 3)
+	if got, _ := pledgeExpiry(&requested, &activated, &days); !got.Equal(requested) {
+		t.Errorf("pledgeExpiry(before deadline) = %s, want requested %s", got, requested)
+	}
+	late := now.AddDate(0, 0, 90)
+	if got, _ := pledgeExpiry(&late, &activated, &days); !got.Equal(now.AddDate(0, 0, 29)) {
+		t.Errorf("pledgeExpiry(after deadline) = %s, want the deadline", got)
+	}
+
+	if _, err := pledgeExpiry(nil, nil, &days); !errors.Is(err, core.ErrProjectNotActive) {
+		t.Errorf("pledgeExpiry(not activated) error = %v, want ErrProjectNotActive", err)
+	}
+}
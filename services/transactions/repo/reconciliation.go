@@ -0,0 +1,105 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/jmoiron/sqlx"
+)
+
+// ReconciliationRun — результат сверки балансов, выполненной демоном
+type ReconciliationRun struct {
+	ID              int                  `db:"id" json:"id"`
+	StartedAt       time.Time            `db:"started_at" json:"started_at"`
+	FinishedAt      *time.Time           `db:"finished_at" json:"finished_at"`
+	EntitiesChecked int                  `db:"entities_checked" json:"entities_checked"`
+	DriftCount      int                  `db:"drift_count" json:"drift_count"`
+	TotalDrift      money.Amount         `db:"total_drift" json:"total_drift"`
+	Diffs           []ReconciliationDiff `db:"-" json:"diffs"`
+}
+
+type ReconciliationDiff struct {
+	ID              int64        `db:"id" json:"id"`
+	EntityType      string       `db:"entity_type" json:"entity_type"`
+	EntityID        int          `db:"entity_id" json:"entity_id"`
+	Currency        string       `db:"currency" json:"currency"`
+	StoredBalance   money.Amount `db:"stored_balance" json:"stored_balance"`
+	ComputedBalance money.Amount `db:"computed_balance" json:"computed_balance"`
+	Diff            money.Amount `db:"diff" json:"diff"`
+	Frozen          bool         `db:"frozen" json:"frozen"`
+	ResolvedAt      *time.Time   `db:"resolved_at" json:"resolved_at"`
+	ResolvedBy      *int         `db:"resolved_by" json:"resolved_by"`
+}
+
+// GetReconciliationRun возвращает сверку с расхождениями; runID = 0 — последнюю
+func (r *Repo) GetReconciliationRun(ctx context.Context, runID int) (*ReconciliationRun, error) {
+	var run ReconciliationRun
+	query := `SELECT id, started_at, finished_at, entities_checked, drift_count, total_drift FROM reconciliation_runs`
+	var err error
+	if runID == 0 {
+		err = r.db.GetContext(ctx, &run, query+` ORDER BY id DESC LIMIT 1`)
+	} else {
+		err = r.db.GetContext(ctx, &run, query+` WHERE id = $1`, runID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, core.ErrReconciliationNotFound
+	}
+	if err != nil {
+		r.log.Error("failed to get reconciliation run", "run_id", runID, "error", err)
+		return nil, err
+	}
+
+	run.Diffs = []ReconciliationDiff{}
+	err = r.db.SelectContext(ctx, &run.Diffs, `
+		SELECT id, entity_type, entity_id, currency, stored_balance, computed_balance, diff, frozen, resolved_at, resolved_by
+		FROM reconciliation_diffs WHERE run_id = $1 ORDER BY id`, run.ID)
+	if err != nil {
+		r.log.Error("failed to get reconciliation diffs", "run_id", run.ID, "error", err)
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ResolveReconciliationDiff отмечает расхождение разобранным и снимает заморозку баланса
+func (r *Repo) ResolveReconciliationDiff(ctx context.Context, diffID int64, adminID int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var diff ReconciliationDiff
+	err = tx.GetContext(ctx, &diff, `
+		UPDATE reconciliation_diffs SET resolved_at = NOW(), resolved_by = $1
+		WHERE id = $2 AND resolved_at IS NULL
+		RETURNING id, entity_type, entity_id, stored_balance, computed_balance, diff, frozen, resolved_at, resolved_by`,
+		adminID, diffID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return core.ErrReconciliationNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM frozen_balances WHERE entity_type = $1 AND entity_id = $2`, diff.EntityType, diff.EntityID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isFrozen — заморожен ли баланс сущности до разбора расхождения
+func isFrozen(ctx context.Context, tx *sqlx.Tx, entityType clients.EntityType, id int) (bool, error) {
+	var frozen bool
+	err := tx.GetContext(ctx, &frozen,
+		`SELECT EXISTS (SELECT 1 FROM frozen_balances WHERE entity_type = $1 AND entity_id = $2)`, entityType, id)
+	return frozen, err
+}
@@ -2,23 +2,43 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"time"
 
 	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fx"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/Starostina-elena/investment_platform/services/transactions/outbox"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
+// Transaction — перевод. Amount списывается с отправителя в Currency, получателю
+// зачисляется ToAmount в ToCurrency по курсу FXRate (nil, если валюты совпадают).
+// Комиссия платформы Fee в ToCurrency удерживается из зачисления по правилу FeeRuleID.
 type Transaction struct {
-	ID        int                `json:"id"`
-	FromType  clients.EntityType `json:"from_type"`
-	FromID    int                `json:"from_id"`
-	ToType    clients.EntityType `json:"to_type"`
-	ToID      int                `json:"to_id"`
-	Amount    float64            `json:"amount"`
-	CreatedAt time.Time          `json:"created_at"`
+	ID         int                `json:"id"`
+	FromType   clients.EntityType `json:"from_type"`
+	FromID     int                `json:"from_id"`
+	ToType     clients.EntityType `json:"to_type"`
+	ToID       int                `json:"to_id"`
+	Amount     money.Amount       `json:"amount"`
+	Currency   money.Currency     `json:"currency"`
+	ToAmount   money.Amount       `json:"to_amount"`
+	ToCurrency money.Currency     `json:"to_currency"`
+	FXRate     *string            `json:"fx_rate,omitempty"`
+	Fee        money.Amount       `json:"fee"`
+	FeeRuleID  *int64             `json:"fee_rule_id,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	// Refund — возврат вклада из провалившегося проекта. Комиссия с него не берется,
+	// а удержанную с вклада комиссию FeeReturned платформа доплачивает получателю.
+	Refund      bool         `json:"-"`
+	FeeReturned money.Amount `json:"-"`
 }
 
 type Investor struct {
@@ -26,6 +46,47 @@ type Investor struct {
 	UserEmail string `db:"user_email"`
 }
 
+// projection — колонка, в которой хранится баланс сущности, выведенный из леджера.
+// Если currencyColumn пустая, колонка хранит баланс в базовой валюте, а остатки
+// в остальных валютах лежат в wallet_balances. Иначе у сущности одна валюта из currencyColumn.
+type projection struct {
+	table          string
+	column         string
+	currencyColumn string
+}
+
+var balanceProjections = map[clients.EntityType]projection{
+	clients.TypeUser:    {table: "users", column: "balance"},
+	clients.TypeOrg:     {table: "organizations", column: "balance"},
+	clients.TypeProject: {table: "projects", column: "current_money", currencyColumn: "currency"},
+}
+
+// значения enum transaction_type из миграции 0001
+var supportedTransactionTypes = map[string]bool{
+	"org_to_project":  true,
+	"project_to_org":  true,
+	"user_to_project": true,
+	"project_to_user": true,
+	"user_to_org":     true,
+	"user_deposit":    true,
+	"user_withdraw":   true,
+	"org_deposit":     true,
+	"org_withdraw":    true,
+}
+
+type posting struct {
+	entityType clients.EntityType
+	entityID   int
+	currency   money.Currency
+	amount     money.Amount
+}
+
+type accountKey struct {
+	entityType clients.EntityType
+	entityID   int
+	currency   money.Currency
+}
+
 type Repo struct {
 	db  *sqlx.DB
 	log slog.Logger
@@ -35,27 +96,401 @@ func NewRepo(db *sqlx.DB, log slog.Logger) *Repo {
 	return &Repo{db: db, log: log}
 }
 
-func (r *Repo) Create(ctx context.Context, t *Transaction) (int, error) {
-	txType := fmt.Sprintf("%s_to_%s", t.FromType, t.ToType)
+func transactionType(t *Transaction) string {
+	switch {
+	case t.FromType == clients.TypeExternal:
+		return fmt.Sprintf("%s_deposit", t.ToType)
+	case t.ToType == clients.TypeExternal:
+		return fmt.Sprintf("%s_withdraw", t.FromType)
+	}
+	return fmt.Sprintf("%s_to_%s", t.FromType, t.ToType)
+}
 
-	if t.FromID == 0 {
-		txType = fmt.Sprintf("%s_deposit", t.ToType)
+// Transfer проводит перевод одной транзакцией БД: запись в transactions,
+// проводки в леджере и обновление балансов обеих сторон. Если валюта получателя
+// отличается от валюты перевода, сумма конвертируется по последнему сохраненному курсу.
+// Если передан ключ идемпотентности и он уже использован с тем же requestHash,
+// перевод не повторяется: в t подставляются id и время исходной транзакции,
+// а replayed = true.
+func (r *Repo) Transfer(ctx context.Context, t *Transaction, idempotencyKey, requestHash string) (replayed bool, err error) {
+	txType := transactionType(t)
+	if !supportedTransactionTypes[txType] {
+		return false, core.ErrUnsupportedTransfer
 	}
 
-	var id int
-	row := r.db.QueryRowxContext(ctx,
-		`INSERT INTO transactions (from_id, reciever_id, type, amount, time_at) 
-		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
-		t.FromID, t.ToID, txType, t.Amount, t.CreatedAt)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if idempotencyKey != "" {
+		claimed, err := claimIdempotencyKey(ctx, tx, idempotencyKey, requestHash)
+		if err != nil {
+			r.log.Error("failed to claim idempotency key", "key", idempotencyKey, "error", err)
+			return false, err
+		}
+		if !claimed {
+			if err := loadReplayedTransaction(ctx, tx, idempotencyKey, requestHash, t); err != nil {
+				return false, err
+			}
+			r.log.Info("transfer replayed by idempotency key", "key", idempotencyKey, "transaction_id", t.ID)
+			return true, nil
+		}
+	}
+
+	id, err := r.post(ctx, tx, t, txType, 0)
+	if err != nil {
+		return false, err
+	}
+
+	if idempotencyKey != "" {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE transfer_idempotency_keys SET transaction_id = $1 WHERE key = $2`, id, idempotencyKey)
+		if err != nil {
+			r.log.Error("failed to save idempotency key result", "key", idempotencyKey, "error", err)
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.Error("failed to commit transfer", "transaction_id", id, "error", err)
+		return false, err
+	}
+	t.ID = id
+	return false, nil
+}
+
+// post проводит перевод внутри открытой транзакции БД и возвращает его id.
+// capturedHold — списываемый холд: его сумма не уменьшает доступный остаток отправителя.
+func (r *Repo) post(ctx context.Context, tx *sqlx.Tx, t *Transaction, txType string, capturedHold int64) (int, error) {
+	if err := resolveConversion(ctx, tx, t); err != nil {
+		return 0, err
+	}
+	if err := applyFee(ctx, tx, t); err != nil {
+		r.log.Error("failed to calculate fee", "error", err)
+		return 0, err
+	}
+
+	postings := []posting{
+		{entityType: t.FromType, entityID: t.FromID, currency: t.Currency, amount: -t.Amount},
+		{entityType: t.ToType, entityID: t.ToID, currency: t.ToCurrency, amount: t.ToAmount},
+	}
+	// отрицательная комиссия — возврат, который платформа доплачивает получателю
+	if !t.Fee.IsZero() {
+		postings = append(postings,
+			posting{entityType: clients.TypePlatform, currency: t.ToCurrency, amount: t.Fee})
+	}
+	if t.Currency != t.ToCurrency {
+		// конвертация проходит через счета обменника, чтобы журнал сходился в каждой валюте
+		postings = append(postings,
+			posting{entityType: clients.TypeFX, currency: t.Currency, amount: t.Amount},
+			posting{entityType: clients.TypeFX, currency: t.ToCurrency, amount: -(t.ToAmount + t.Fee)},
+		)
+	}
+
+	// строки блокируются в одном и том же порядке, чтобы встречные переводы не ловили дедлок
+	locked := make([]posting, len(postings))
+	copy(locked, postings)
+	sort.Slice(locked, func(i, j int) bool {
+		if locked[i].entityType != locked[j].entityType {
+			return locked[i].entityType < locked[j].entityType
+		}
+		if locked[i].entityID != locked[j].entityID {
+			return locked[i].entityID < locked[j].entityID
+		}
+		return locked[i].currency < locked[j].currency
+	})
+
+	balances := make(map[accountKey]money.Amount, len(locked))
+	for _, p := range locked {
+		balance, err := lockBalance(ctx, tx, p.entityType, p.entityID, p.currency)
+		if err != nil {
+			r.log.Error("failed to lock balance", "entity_type", p.entityType, "entity_id", p.entityID, "currency", p.currency, "error", err)
+			return 0, err
+		}
+		balances[accountKey{p.entityType, p.entityID, p.currency}] = balance
+	}
+
+	if _, ok := balanceProjections[t.FromType]; ok {
+		// замороженный сверкой баланс может принимать деньги, но не отдавать их
+		frozen, err := isFrozen(ctx, tx, t.FromType, t.FromID)
+		if err != nil {
+			return 0, err
+		}
+		if frozen {
+			return 0, core.ErrBalanceFrozen
+		}
+		held, err := heldAmount(ctx, tx, t.FromType, t.FromID, t.Currency, capturedHold)
+		if err != nil {
+			return 0, err
+		}
+		if balances[accountKey{t.FromType, t.FromID, t.Currency}]-held < t.Amount {
+			return 0, core.ErrInsufficientFunds
+		}
+	}
+
+	for _, p := range postings {
+		if err := changeBalance(ctx, tx, p.entityType, p.entityID, p.currency, p.amount); err != nil {
+			r.log.Error("failed to update balance", "entity_type", p.entityType, "entity_id", p.entityID, "error", err)
+			return 0, err
+		}
+	}
+
+	// остатки после перевода сохраняются в транзакции, из них строится история с балансом
+	senderBalance := balanceAfter(balances, t.FromType, t.FromID, t.Currency, -t.Amount)
+	receiverBalance := balanceAfter(balances, t.ToType, t.ToID, t.ToCurrency, t.ToAmount)
 
-	if err := row.Scan(&id); err != nil {
+	var id int
+	err := tx.QueryRowxContext(ctx,
+		`INSERT INTO transactions (from_id, reciever_id, type, amount, cum_sum_of_sender, cum_sum_of_reciever, time_at,
+		                           currency, to_amount, to_currency, fx_rate, fee, fee_rule_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+		t.FromID, t.ToID, txType, t.Amount, senderBalance, receiverBalance, t.CreatedAt,
+		t.Currency, t.ToAmount, t.ToCurrency, t.FXRate, t.Fee, t.FeeRuleID).Scan(&id)
+	if err != nil {
 		r.log.Error("failed to insert tx", "error", err)
 		return 0, err
 	}
+
+	if err := postJournal(ctx, tx, id, "transfer", postings); err != nil {
+		r.log.Error("failed to post ledger entries", "transaction_id", id, "error", err)
+		return 0, err
+	}
+
+	err = outbox.Add(ctx, tx, outboxService, outbox.TypeTransferCompleted, TransferCompletedEvent{
+		TransactionID: id,
+		Type:          txType,
+		FromType:      t.FromType,
+		FromID:        t.FromID,
+		ToType:        t.ToType,
+		ToID:          t.ToID,
+		Amount:        t.Amount,
+		Currency:      t.Currency,
+		ToAmount:      t.ToAmount,
+		ToCurrency:    t.ToCurrency,
+		CreatedAt:     t.CreatedAt,
+	})
+	if err != nil {
+		r.log.Error("failed to add transfer event", "transaction_id", id, "error", err)
+		return 0, err
+	}
+
+	if t.ToType == clients.TypeProject {
+		before := balances[accountKey{t.ToType, t.ToID, t.ToCurrency}]
+		if err := addGoalReachedEvent(ctx, tx, t.ToID, before, *receiverBalance); err != nil {
+			r.log.Error("failed to add goal reached event", "project_id", t.ToID, "error", err)
+			return 0, err
+		}
+	}
+
 	return id, nil
 }
 
-func (r *Repo) GetProjectInvestors(ctx context.Context, projectID int) ([]Investor, error) {
+// claimIdempotencyKey занимает ключ в рамках текущей транзакции. Параллельный запрос
+// с тем же ключом ждет на вставке, пока первый не завершится.
+func claimIdempotencyKey(ctx context.Context, tx *sqlx.Tx, key, requestHash string) (bool, error) {
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO transfer_idempotency_keys (key, request_hash) VALUES ($1, $2)
+		 ON CONFLICT (key) DO NOTHING`, key, requestHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// ReplayTransfer ищет перевод, уже проведенный с этим ключом, без блокировок: ответ на
+// повтор не должен зависеть от проверок, которые с тех пор могли измениться (проект завершен).
+// replayed = false, если ключ еще не использован; тогда перевод проводит Transfer.
+func (r *Repo) ReplayTransfer(ctx context.Context, t *Transaction, idempotencyKey, requestHash string) (replayed bool, err error) {
+	err = loadReplayedTransaction(ctx, r.db, idempotencyKey, requestHash, t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.log.Info("transfer replayed by idempotency key", "key", idempotencyKey, "transaction_id", t.ID)
+	return true, nil
+}
+
+func loadReplayedTransaction(ctx context.Context, q sqlx.QueryerContext, key, requestHash string, t *Transaction) error {
+	var stored struct {
+		RequestHash   string          `db:"request_hash"`
+		TransactionID sql.NullInt64   `db:"transaction_id"`
+		TimeAt        sql.NullTime    `db:"time_at"`
+		ToAmount      *money.Amount   `db:"to_amount"`
+		ToCurrency    *money.Currency `db:"to_currency"`
+		FXRate        *string         `db:"fx_rate"`
+		Fee           *money.Amount   `db:"fee"`
+		FeeRuleID     *int64          `db:"fee_rule_id"`
+	}
+	err := sqlx.GetContext(ctx, q, &stored, `
+		SELECT k.request_hash, k.transaction_id, t.time_at,
+		       COALESCE(t.to_amount, t.amount) AS to_amount, t.to_currency, t.fx_rate, t.fee, t.fee_rule_id
+		FROM transfer_idempotency_keys k
+		LEFT JOIN transactions t ON t.id = k.transaction_id
+		WHERE k.key = $1`, key)
+	if err != nil {
+		return err
+	}
+	if stored.RequestHash != requestHash {
+		return core.ErrIdempotencyConflict
+	}
+	if !stored.TransactionID.Valid {
+		return fmt.Errorf("idempotency key %q has no transaction", key)
+	}
+	t.ID = int(stored.TransactionID.Int64)
+	t.CreatedAt = stored.TimeAt.Time
+	if stored.ToAmount != nil && stored.ToCurrency != nil {
+		t.ToAmount = *stored.ToAmount
+		t.ToCurrency = *stored.ToCurrency
+	}
+	t.FXRate = stored.FXRate
+	if stored.Fee != nil {
+		t.Fee = *stored.Fee
+	}
+	t.FeeRuleID = stored.FeeRuleID
+	return nil
+}
+
+// resolveConversion определяет валюту получателя и сумму зачисления. Проект принимает
+// деньги только в своей валюте; остальным получателям по умолчанию зачисляется валюта перевода.
+func resolveConversion(ctx context.Context, tx *sqlx.Tx, t *Transaction) error {
+	if t.ToType == clients.TypeProject {
+		var projectCurrency money.Currency
+		err := tx.GetContext(ctx, &projectCurrency, `SELECT currency FROM projects WHERE id = $1`, t.ToID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return core.ErrEntityNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if t.ToCurrency != "" && t.ToCurrency != projectCurrency {
+			return core.ErrCurrencyMismatch
+		}
+		t.ToCurrency = projectCurrency
+	}
+	if t.ToCurrency == "" {
+		t.ToCurrency = t.Currency
+	}
+
+	if t.ToCurrency == t.Currency {
+		t.ToAmount = t.Amount
+		t.FXRate = nil
+		return nil
+	}
+
+	rate, err := conversionRate(ctx, tx, t.Currency, t.ToCurrency)
+	if err != nil {
+		return err
+	}
+	t.ToAmount, err = fx.Convert(t.Amount, rate)
+	if err != nil || !t.ToAmount.IsPositive() {
+		return core.ErrInvalidAmount
+	}
+	fxRate := rate.FloatString(10)
+	t.FXRate = &fxRate
+	return nil
+}
+
+func balanceAfter(balances map[accountKey]money.Amount, entityType clients.EntityType, id int, currency money.Currency, delta money.Amount) *money.Amount {
+	if _, ok := balanceProjections[entityType]; !ok {
+		return nil
+	}
+	balance := balances[accountKey{entityType, id, currency}] + delta
+	return &balance
+}
+
+func lockBalance(ctx context.Context, tx *sqlx.Tx, entityType clients.EntityType, id int, currency money.Currency) (money.Amount, error) {
+	p, ok := balanceProjections[entityType]
+	if !ok {
+		// у внешнего счета и обменника нет проекции, их баланс не ограничен
+		return 0, nil
+	}
+	if p.currencyColumn == "" && currency != money.BaseCurrency {
+		return lockWalletBalance(ctx, tx, p, entityType, id, currency)
+	}
+
+	currencyExpr := p.currencyColumn
+	if currencyExpr == "" {
+		currencyExpr = fmt.Sprintf("'%s'", money.BaseCurrency)
+	}
+
+	var balance money.Amount
+	var stored money.Currency
+	query := fmt.Sprintf("SELECT COALESCE(%s, 0), %s FROM %s WHERE id = $1 FOR UPDATE", p.column, currencyExpr, p.table)
+	if err := tx.QueryRowxContext(ctx, query, id).Scan(&balance, &stored); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, core.ErrEntityNotFound
+		}
+		return 0, err
+	}
+	if stored != currency {
+		return 0, core.ErrCurrencyMismatch
+	}
+	return balance, nil
+}
+
+func changeBalance(ctx context.Context, tx *sqlx.Tx, entityType clients.EntityType, id int, currency money.Currency, delta money.Amount) error {
+	p, ok := balanceProjections[entityType]
+	if !ok {
+		return nil
+	}
+	if p.currencyColumn == "" && currency != money.BaseCurrency {
+		return changeWalletBalance(ctx, tx, entityType, id, currency, delta)
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s = COALESCE(%s, 0) + $1 WHERE id = $2", p.table, p.column, p.column)
+	_, err := tx.ExecContext(ctx, query, delta, id)
+	return err
+}
+
+func accountID(ctx context.Context, tx *sqlx.Tx, entityType clients.EntityType, id int, currency money.Currency) (int, error) {
+	var accID int
+	err := tx.QueryRowxContext(ctx, `
+		WITH created AS (
+			INSERT INTO ledger_accounts (entity_type, entity_id, currency) VALUES ($1, $2, $3)
+			ON CONFLICT (entity_type, entity_id, currency) DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM created
+		UNION ALL
+		SELECT id FROM ledger_accounts WHERE entity_type = $1 AND entity_id = $2 AND currency = $3
+		LIMIT 1`, entityType, id, currency).Scan(&accID)
+	return accID, err
+}
+
+func postJournal(ctx context.Context, tx *sqlx.Tx, transactionID int, kind string, postings []posting) error {
+	var journalID int64
+	err := tx.QueryRowxContext(ctx,
+		`INSERT INTO ledger_journals (transaction_id, kind) VALUES ($1, $2) RETURNING id`,
+		transactionID, kind).Scan(&journalID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range postings {
+		accID, err := accountID(ctx, tx, p.entityType, p.entityID, p.currency)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO ledger_entries (journal_id, account_id, amount) VALUES ($1, $2, $3)`,
+			journalID, accID, p.amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func projectInvestors(ctx context.Context, q sqlx.QueryerContext, projectID int) ([]Investor, error) {
 	var investors []Investor
 	query := `
 		SELECT DISTINCT ON (t.from_id) t.from_id as user_id, u.email as user_email
@@ -63,10 +498,6 @@ func (r *Repo) GetProjectInvestors(ctx context.Context, projectID int) ([]Invest
 		JOIN users u ON t.from_id = u.id
 		WHERE t.reciever_id = $1 AND t.type = 'user_to_project'
 	`
-	err := r.db.SelectContext(ctx, &investors, query, projectID)
-	if err != nil {
-		r.log.Error("failed to get project investors", "error", err, "project_id", projectID)
-		return nil, err
-	}
-	return investors, nil
+	err := sqlx.SelectContext(ctx, q, &investors, query, projectID)
+	return investors, err
 }
@@ -0,0 +1,94 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/jmoiron/sqlx"
+)
+
+// WalletBalance — остаток сущности в одной валюте. Held — сумма активных холдов,
+// доступно для переводов Balance - Held.
+type WalletBalance struct {
+	Currency money.Currency `json:"currency" db:"currency"`
+	Balance  money.Amount   `json:"balance" db:"balance"`
+	Held     money.Amount   `json:"held" db:"held"`
+}
+
+// GetWallets возвращает остатки пользователя или организации во всех валютах
+// вместе с зарезервированными суммами, первым — в базовой
+func (r *Repo) GetWallets(ctx context.Context, entityType clients.EntityType, id int) ([]WalletBalance, error) {
+	p, ok := balanceProjections[entityType]
+	if !ok || p.currencyColumn != "" {
+		return nil, core.ErrUnsupportedTransfer
+	}
+
+	var base money.Amount
+	query := fmt.Sprintf("SELECT COALESCE(%s, 0) FROM %s WHERE id = $1", p.column, p.table)
+	if err := r.db.QueryRowxContext(ctx, query, id).Scan(&base); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, core.ErrEntityNotFound
+		}
+		r.log.Error("failed to get base balance", "entity_type", entityType, "entity_id", id, "error", err)
+		return nil, err
+	}
+
+	var others []WalletBalance
+	err := r.db.SelectContext(ctx, &others, `
+		SELECT currency, balance FROM wallet_balances
+		WHERE entity_type = $1 AND entity_id = $2 AND currency <> $3
+		ORDER BY currency`, entityType, id, money.BaseCurrency)
+	if err != nil {
+		r.log.Error("failed to get wallet balances", "entity_type", entityType, "entity_id", id, "error", err)
+		return nil, err
+	}
+
+	wallets := append([]WalletBalance{{Currency: money.BaseCurrency, Balance: base}}, others...)
+	for i := range wallets {
+		held, err := heldAmount(ctx, r.db, entityType, id, wallets[i].Currency, 0)
+		if err != nil {
+			r.log.Error("failed to get held amount", "entity_type", entityType, "entity_id", id, "error", err)
+			return nil, err
+		}
+		wallets[i].Held = held
+	}
+	return wallets, nil
+}
+
+// lockWalletBalance блокирует остаток в небазовой валюте. Сначала блокируется строка
+// самой сущности, поэтому порядок блокировок тот же, что и для базовой валюты.
+func lockWalletBalance(ctx context.Context, tx *sqlx.Tx, p projection, entityType clients.EntityType, id int, currency money.Currency) (money.Amount, error) {
+	var exists int
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE id = $1 FOR UPDATE", p.table)
+	if err := tx.QueryRowxContext(ctx, query, id).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, core.ErrEntityNotFound
+		}
+		return 0, err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO wallet_balances (entity_type, entity_id, currency) VALUES ($1, $2, $3)
+		ON CONFLICT (entity_type, entity_id, currency) DO NOTHING`, entityType, id, currency)
+	if err != nil {
+		return 0, err
+	}
+
+	var balance money.Amount
+	err = tx.QueryRowxContext(ctx, `
+		SELECT balance FROM wallet_balances
+		WHERE entity_type = $1 AND entity_id = $2 AND currency = $3 FOR UPDATE`, entityType, id, currency).Scan(&balance)
+	return balance, err
+}
+
+func changeWalletBalance(ctx context.Context, tx *sqlx.Tx, entityType clients.EntityType, id int, currency money.Currency, delta money.Amount) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE wallet_balances SET balance = balance + $1, updated_at = NOW()
+		WHERE entity_type = $2 AND entity_id = $3 AND currency = $4`, delta, entityType, id, currency)
+	return err
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fees"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/Starostina-elena/investment_platform/services/transactions/repo"
+)
+
+// Quote — расчет перевода до подтверждения: сколько спишется, сколько получит
+// получатель и сколько удержит платформа
+type Quote struct {
+	Amount     money.Amount   `json:"amount"`
+	Currency   money.Currency `json:"currency"`
+	ToAmount   money.Amount   `json:"to_amount"`
+	ToCurrency money.Currency `json:"to_currency"`
+	FXRate     *string        `json:"fx_rate,omitempty"`
+	Fee        money.Amount   `json:"fee"`
+	FeeRuleID  *int64         `json:"fee_rule_id,omitempty"`
+}
+
+// Quote считает комиссию и конвертацию по тем же правилам, что и Transfer.
+// Курс и правила могут измениться до самого перевода.
+func (s *service) Quote(ctx context.Context, fromType, toType clients.EntityType, fromID, toID int, amount money.Amount, currency, toCurrency money.Currency) (*Quote, error) {
+	if !amount.IsPositive() {
+		return nil, core.ErrInvalidAmount
+	}
+	t := &Transaction{
+		FromType:   fromType,
+		FromID:     fromID,
+		ToType:     toType,
+		ToID:       toID,
+		Amount:     amount,
+		Currency:   currency,
+		ToCurrency: toCurrency,
+	}
+	if err := s.repo.Quote(ctx, t); err != nil {
+		return nil, err
+	}
+	return &Quote{
+		Amount:     t.Amount,
+		Currency:   t.Currency,
+		ToAmount:   t.ToAmount,
+		ToCurrency: t.ToCurrency,
+		FXRate:     t.FXRate,
+		Fee:        t.Fee,
+		FeeRuleID:  t.FeeRuleID,
+	}, nil
+}
+
+func (s *service) GetFeeRules(ctx context.Context) ([]fees.Rule, error) {
+	return s.repo.GetFeeRules(ctx)
+}
+
+func (s *service) CreateFeeRule(ctx context.Context, adminID int, rule *fees.Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	rule.CreatedBy = &adminID
+	if err := s.repo.CreateFeeRule(ctx, rule); err != nil {
+		s.log.Error("failed to create fee rule", "error", err)
+		return err
+	}
+	s.log.Info("fee rule created", "rule_id", rule.ID, "operation", rule.Operation, "percent", rule.Percent, "admin_id", adminID)
+	return nil
+}
+
+func (s *service) DisableFeeRule(ctx context.Context, adminID int, id int64) error {
+	if err := s.repo.DisableFeeRule(ctx, id); err != nil {
+		return err
+	}
+	s.log.Info("fee rule disabled", "rule_id", id, "admin_id", adminID)
+	return nil
+}
+
+func (s *service) GetPlatformRevenue(ctx context.Context) ([]repo.WalletBalance, error) {
+	return s.repo.PlatformRevenue(ctx)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/Starostina-elena/investment_platform/services/transactions/repo"
+)
+
+// DefaultHoldTTL — срок холда, если вызывающий его не задал. Обещание инвестиции
+// в проект по умолчанию действует до дедлайна проекта, а резерв под выплату на внешний
+// счет — пока его не спишут или не освободят.
+const DefaultHoldTTL = 72 * time.Hour
+
+// holdExpiry — срок холда по ttl вызывающего. nil — холд не истекает сам
+// (или, для проекта, истекает в дедлайн, его подставит repo).
+func holdExpiry(toType clients.EntityType, ttl time.Duration, now time.Time) *time.Time {
+	if toType == clients.TypeExternal {
+		return nil
+	}
+	if ttl <= 0 {
+		if toType == clients.TypeProject {
+			return nil
+		}
+		ttl = DefaultHoldTTL
+	}
+	expiresAt := now.Add(ttl)
+	return &expiresAt
+}
+
+// CreateHold резервирует деньги под будущий перевод. Второе значение — true, если холд
+// с этим reference уже существовал. Обещание инвестиции в проект списывается сразу,
+// как только обещания вместе с собранными деньгами покрывают цель проекта.
+func (s *service) CreateHold(ctx context.Context, reference string, fromType, toType clients.EntityType, fromID, toID int, amount money.Amount, currency money.Currency, ttl time.Duration) (*repo.Hold, bool, error) {
+	if !amount.IsPositive() {
+		return nil, false, core.ErrInvalidAmount
+	}
+
+	h := &repo.Hold{
+		EntityType: fromType,
+		EntityID:   fromID,
+		Currency:   currency,
+		Amount:     amount,
+		ToType:     toType,
+		ToID:       toID,
+		Reference:  reference,
+		ExpiresAt:  holdExpiry(toType, ttl, time.Now()),
+	}
+
+	// повтор с тем же reference отдает созданный холд до проверки проекта, как и Transfer
+	existing, err := s.repo.ReplayHold(ctx, h)
+	if err != nil {
+		s.log.Error("failed to look up hold", "error", err, "reference", reference)
+		return nil, false, err
+	}
+	if existing {
+		return h, true, nil
+	}
+
+	if toType == clients.TypeProject {
+		if err := s.checkProjectOpen(ctx, toID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	existing, err = s.repo.CreateHold(ctx, h)
+	if err != nil {
+		s.log.Error("failed to create hold", "error", err, "from", fromType, "from_id", fromID, "reference", reference)
+		return nil, false, err
+	}
+	if existing {
+		return h, true, nil
+	}
+	s.log.Info("hold created", "hold_id", h.ID, "from", fromType, "from_id", fromID, "to", toType, "to_id", toID, "amount", amount, "currency", currency)
+
+	if toType == clients.TypeProject {
+		s.capturePledges(ctx, toID)
+	}
+	return h, false, nil
+}
+
+func (s *service) GetHold(ctx context.Context, id int64) (*repo.Hold, error) {
+	return s.repo.GetHold(ctx, id)
+}
+
+// CaptureHold списывает холд переводом его получателю. Второе значение — true,
+// если холд уже был списан и возвращен исходный перевод.
+func (s *service) CaptureHold(ctx context.Context, id int64) (*Transaction, bool, error) {
+	t, replayed, err := s.repo.CaptureHold(ctx, id)
+	if err != nil {
+		s.log.Error("failed to capture hold", "error", err, "hold_id", id)
+		return nil, false, err
+	}
+	if replayed {
+		return t, true, nil
+	}
+	s.log.Info("hold captured", "hold_id", id, "transaction_id", t.ID)
+
+	if t.ToType == clients.TypeProject {
+		s.handleProjectPayment(ctx, t.ToID, t.ToAmount+t.Fee)
+	}
+	return t, false, nil
+}
+
+func (s *service) ReleaseHold(ctx context.Context, id int64) error {
+	if err := s.repo.ReleaseHold(ctx, id); err != nil {
+		s.log.Error("failed to release hold", "error", err, "hold_id", id)
+		return err
+	}
+	s.log.Info("hold released", "hold_id", id)
+	return nil
+}
+
+func (s *service) ExpireHolds(ctx context.Context) error {
+	n, err := s.repo.ExpireHolds(ctx)
+	if err != nil {
+		s.log.Error("failed to expire holds", "error", err)
+		return err
+	}
+	if n > 0 {
+		s.log.Info("holds expired", "count", n)
+	}
+	return nil
+}
+
+// capturePledges списывает все обещания инвестиций в проект, если их хватает до цели.
+// Ошибка одного списания не мешает остальным: холд останется активным, и его спишет
+// следующий перевод или обещание в этот проект.
+func (s *service) capturePledges(ctx context.Context, projectID int) {
+	ids, err := s.repo.FundedPledges(ctx, projectID)
+	if err != nil {
+		return
+	}
+	for _, id := range ids {
+		if _, _, err := s.CaptureHold(ctx, id); err != nil {
+			s.log.Error("failed to capture pledge", "error", err, "hold_id", id, "project_id", projectID)
+		}
+	}
+}
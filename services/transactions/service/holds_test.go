@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+)
+
+func TestHoldExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		toType clients.EntityType
+		ttl    time.Duration
+		want   *time.Time
+	}{
+		{name: "withdrawal without ttl never expires", toType: clients.TypeExternal, ttl: 0, want: nil},
+		{name: "withdrawal ignores ttl", toType: clients.TypeExternal, ttl: time.Hour, want: nil},
+		{name: "pledge without ttl is left to the project deadline", toType: clients.TypeProject, ttl: 0, want: nil},
+		{name: "pledge with ttl", toType: clients.TypeProject, ttl: time.Hour, want: ptr(now.Add(time.Hour))},
+		{name: "org hold with ttl", toType: clients.TypeOrg, ttl: 2 * time.Hour, want: ptr(now.Add(2 * time.Hour))},
+		{name: "org hold without ttl gets default", toType: clients.TypeOrg, ttl: 0, want: ptr(now.Add(DefaultHoldTTL))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := holdExpiry(tt.toType, tt.ttl, now)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil:
+				t.Errorf("holdExpiry() = %v, want %v", got, tt.want)
+			case !got.Equal(*tt.want):
+				t.Errorf("holdExpiry() = %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
@@ -2,105 +2,329 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fees"
+	"github.com/Starostina-elena/investment_platform/services/transactions/fx"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
 	"github.com/Starostina-elena/investment_platform/services/transactions/repo"
+	"github.com/Starostina-elena/investment_platform/services/transactions/statement"
 )
 
 type Transaction = repo.Transaction
 
 type Repo interface {
-	Create(ctx context.Context, t *Transaction) (int, error)
-	GetProjectInvestors(ctx context.Context, projectID int) ([]repo.Investor, error)
+	Transfer(ctx context.Context, t *Transaction, idempotencyKey, requestHash string) (bool, error)
+	ReplayTransfer(ctx context.Context, t *Transaction, idempotencyKey, requestHash string) (bool, error)
+	GetHistory(ctx context.Context, entityType clients.EntityType, entityID int, f repo.HistoryFilter) ([]repo.HistoryEntry, int, error)
+	GetMovements(ctx context.Context, entityType clients.EntityType, entityID int, currency money.Currency, from, to time.Time) ([]repo.HistoryEntry, error)
+	GetBalanceAt(ctx context.Context, entityType clients.EntityType, entityID int, currency money.Currency, at time.Time) (money.Amount, error)
+	GetWallets(ctx context.Context, entityType clients.EntityType, id int) ([]repo.WalletBalance, error)
+	LatestRates(ctx context.Context) ([]fx.Rate, error)
+	SaveRates(ctx context.Context, rates []fx.Rate) error
+	GetReconciliationRun(ctx context.Context, runID int) (*repo.ReconciliationRun, error)
+	ResolveReconciliationDiff(ctx context.Context, diffID int64, adminID int) error
+	CreateHold(ctx context.Context, h *repo.Hold) (bool, error)
+	ReplayHold(ctx context.Context, h *repo.Hold) (bool, error)
+	GetHold(ctx context.Context, id int64) (*repo.Hold, error)
+	CaptureHold(ctx context.Context, id int64) (*Transaction, bool, error)
+	ReleaseHold(ctx context.Context, id int64) error
+	ExpireHolds(ctx context.Context) (int64, error)
+	FundedPledges(ctx context.Context, projectID int) ([]int64, error)
+	Quote(ctx context.Context, t *Transaction) error
+	GetFeeRules(ctx context.Context) ([]fees.Rule, error)
+	CreateFeeRule(ctx context.Context, rule *fees.Rule) error
+	DisableFeeRule(ctx context.Context, id int64) error
+	PlatformRevenue(ctx context.Context) ([]repo.WalletBalance, error)
 }
 
 type Service interface {
-	Transfer(ctx context.Context, fromType, toType clients.EntityType, fromID, toID int, amount float64) (*Transaction, error)
+	Transfer(ctx context.Context, idempotencyKey string, fromType, toType clients.EntityType, fromID, toID int, amount money.Amount, currency, toCurrency money.Currency) (*Transaction, bool, error)
+	Refund(ctx context.Context, idempotencyKey string, projectID int, toType clients.EntityType, toID int, amount, feeReturned money.Amount, currency money.Currency) (*Transaction, bool, error)
+	GetHistory(ctx context.Context, userID int, isAdmin bool, entityType clients.EntityType, entityID int, f repo.HistoryFilter) ([]repo.HistoryEntry, int, error)
+	GetStatement(ctx context.Context, userID int, isAdmin bool, entityType clients.EntityType, entityID int, currency money.Currency, from, to time.Time) (*statement.Statement, error)
+	GetWallets(ctx context.Context, userID int, isAdmin bool, entityType clients.EntityType, entityID int) ([]repo.WalletBalance, error)
+	GetRates(ctx context.Context) ([]fx.Rate, error)
+	SetRate(ctx context.Context, adminID int, currency money.Currency, rate string) (*fx.Rate, error)
+	GetReconciliationRun(ctx context.Context, runID int) (*repo.ReconciliationRun, error)
+	ResolveReconciliationDiff(ctx context.Context, diffID int64, adminID int) error
+	CreateHold(ctx context.Context, reference string, fromType, toType clients.EntityType, fromID, toID int, amount money.Amount, currency money.Currency, ttl time.Duration) (*repo.Hold, bool, error)
+	GetHold(ctx context.Context, id int64) (*repo.Hold, error)
+	CaptureHold(ctx context.Context, id int64) (*Transaction, bool, error)
+	ReleaseHold(ctx context.Context, id int64) error
+	ExpireHolds(ctx context.Context) error
+	Quote(ctx context.Context, fromType, toType clients.EntityType, fromID, toID int, amount money.Amount, currency, toCurrency money.Currency) (*Quote, error)
+	GetFeeRules(ctx context.Context) ([]fees.Rule, error)
+	CreateFeeRule(ctx context.Context, adminID int, rule *fees.Rule) error
+	DisableFeeRule(ctx context.Context, adminID int, id int64) error
+	GetPlatformRevenue(ctx context.Context) ([]repo.WalletBalance, error)
 }
 
 type service struct {
-	repo               Repo
-	balanceClient      *clients.BalanceClient
-	projectClient      *clients.ProjectClient
-	notificationClient *clients.NotificationClient
-	log                slog.Logger
+	repo          Repo
+	projectClient *clients.ProjectClient
+	orgClient     *clients.OrgClient
+	log           slog.Logger
 }
 
-func NewService(repo Repo, bc *clients.BalanceClient, pc *clients.ProjectClient, nc *clients.NotificationClient, log slog.Logger) Service {
+func NewService(repo Repo, pc *clients.ProjectClient, oc *clients.OrgClient, log slog.Logger) Service {
 	return &service{
-		repo:               repo,
-		balanceClient:      bc,
-		projectClient:      pc,
-		notificationClient: nc,
-		log:                log,
+		repo:          repo,
+		projectClient: pc,
+		orgClient:     oc,
+		log:           log,
 	}
 }
 
-func (s *service) Transfer(ctx context.Context, fromType, toType clients.EntityType, fromID, toID int, amount float64) (*Transaction, error) {
-	if amount <= 0 {
-		return nil, fmt.Errorf("amount must be positive")
+// Transfer возвращает вторым значением true, если перевод с этим ключом идемпотентности
+// уже был проведен и вместо нового возвращена исходная транзакция.
+// toCurrency может быть пустой: тогда получатель получает валюту перевода (проект — свою валюту).
+func (s *service) Transfer(ctx context.Context, idempotencyKey string, fromType, toType clients.EntityType, fromID, toID int, amount money.Amount, currency, toCurrency money.Currency) (*Transaction, bool, error) {
+	if !amount.IsPositive() {
+		return nil, false, core.ErrInvalidAmount
 	}
 
-	s.log.Info("starting transfer", "from", fromType, "from_id", fromID, "to", toType, "to_id", toID, "amount", amount)
+	s.log.Info("starting transfer", "from", fromType, "from_id", fromID, "to", toType, "to_id", toID, "amount", amount, "currency", currency)
 
-	if toType == clients.TypeProject {
-		project, err := s.projectClient.GetProject(ctx, toID)
+	t := &Transaction{
+		FromType:   fromType,
+		FromID:     fromID,
+		ToType:     toType,
+		ToID:       toID,
+		Amount:     amount,
+		Currency:   currency,
+		ToCurrency: toCurrency,
+		CreatedAt:  time.Now(),
+	}
+
+	// повтор уже проведенного перевода отдает исходную транзакцию, даже если проект
+	// с тех пор завершился: иначе клиент, не получивший ответ, решит, что денег не списали
+	if idempotencyKey != "" {
+		replayed, err := s.repo.ReplayTransfer(ctx, t, idempotencyKey, requestHash(t))
 		if err != nil {
-			s.log.Error("failed to check project status", "error", err, "project_id", toID)
-			return nil, fmt.Errorf("failed to check project status: %v", err)
+			s.log.Error("failed to look up idempotency key", "error", err, "key", idempotencyKey)
+			return nil, false, err
 		}
-		if project.IsCompleted {
-			s.log.Warn("cannot transfer to completed project", "project_id", toID)
-			return nil, fmt.Errorf("cannot transfer funds to completed project")
+		if replayed {
+			return t, true, nil
 		}
 	}
 
-	err := s.balanceClient.ChangeBalance(ctx, fromType, fromID, -amount)
+	if toType == clients.TypeProject {
+		if err := s.checkProjectOpen(ctx, toID); err != nil {
+			return nil, false, err
+		}
+	}
+
+	replayed, err := s.repo.Transfer(ctx, t, idempotencyKey, requestHash(t))
+	if err != nil {
+		s.log.Error("transfer failed", "error", err, "from", fromType, "from_id", fromID, "to", toType, "to_id", toID)
+		return nil, false, err
+	}
+	if replayed {
+		return t, true, nil
+	}
+
+	if toType == clients.TypeProject {
+		// payback считается в валюте проекта от всей суммы вложения: комиссию платформы
+		// платит проект, а не инвестор
+		s.log.Info("processing project payment", "project_id", toID, "amount", t.ToAmount, "fee", t.Fee, "currency", t.ToCurrency)
+		s.handleProjectPayment(ctx, toID, t.ToAmount+t.Fee)
+		s.capturePledges(ctx, toID)
+	}
+
+	return t, false, nil
+}
+
+// Refund возвращает инвестору вклад из провалившегося проекта. amount — сколько получит
+// инвестор, вместе с комиссией, удержанной при вложении; эту часть, feeReturned,
+// возвращает платформа, а остальное списывается с проекта.
+func (s *service) Refund(ctx context.Context, idempotencyKey string, projectID int, toType clients.EntityType, toID int, amount, feeReturned money.Amount, currency money.Currency) (*Transaction, bool, error) {
+	if feeReturned.IsNegative() || !(amount - feeReturned).IsPositive() {
+		return nil, false, core.ErrInvalidAmount
+	}
+
+	s.log.Info("starting refund", "project_id", projectID, "to", toType, "to_id", toID, "amount", amount, "fee_returned", feeReturned, "currency", currency)
+
+	t := &Transaction{
+		FromType:    clients.TypeProject,
+		FromID:      projectID,
+		ToType:      toType,
+		ToID:        toID,
+		Amount:      amount - feeReturned,
+		Currency:    currency,
+		ToCurrency:  currency,
+		Refund:      true,
+		FeeReturned: feeReturned,
+		CreatedAt:   time.Now(),
+	}
+	replayed, err := s.repo.Transfer(ctx, t, idempotencyKey, requestHash(t))
 	if err != nil {
-		s.log.Error("failed to deduct funds", "error", err)
-		return nil, fmt.Errorf("transaction failed: %v", err)
+		s.log.Error("refund failed", "error", err, "project_id", projectID, "to", toType, "to_id", toID)
+		return nil, false, err
 	}
+	return t, replayed, nil
+}
 
-	err = s.balanceClient.ChangeBalance(ctx, toType, toID, amount)
+// checkProjectOpen пропускает деньги только в проект, который идет сбор
+func (s *service) checkProjectOpen(ctx context.Context, projectID int) error {
+	project, err := s.projectClient.GetProject(ctx, projectID)
 	if err != nil {
-		s.log.Error("failed to add funds, starting rollback", "error", err)
+		s.log.Error("failed to check project status", "error", err, "project_id", projectID)
+		return fmt.Errorf("failed to check project status: %v", err)
+	}
+	if project.IsCompleted {
+		s.log.Warn("cannot transfer to completed project", "project_id", projectID)
+		return core.ErrProjectCompleted
+	}
+	if !project.AcceptsInvestments() {
+		s.log.Warn("cannot transfer to project that is not collecting funds", "project_id", projectID, "status", project.Status)
+		return core.ErrProjectNotActive
+	}
+	return nil
+}
 
-		rbErr := s.balanceClient.ChangeBalance(ctx, fromType, fromID, amount)
-		if rbErr != nil {
-			s.log.Error("CRITICAL: ROLLBACK FAILED", "from_type", fromType, "from_id", fromID, "amount", amount, "error", rbErr)
-			return nil, fmt.Errorf("system error: money stuck, contact support")
+func requestHash(t *Transaction) string {
+	data := fmt.Sprintf("%s:%d:%s:%d:%s:%s:%s", t.FromType, t.FromID, t.ToType, t.ToID, t.Amount, t.Currency, t.ToCurrency)
+	if t.Refund {
+		data += fmt.Sprintf(":refund:%s", t.FeeReturned)
+	}
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetHistory отдает историю пользователю-владельцу, сотруднику организации с правом
+// money_management (для организации и ее проектов) и администраторам.
+func (s *service) GetHistory(ctx context.Context, userID int, isAdmin bool, entityType clients.EntityType, entityID int, f repo.HistoryFilter) ([]repo.HistoryEntry, int, error) {
+	if !isAdmin {
+		if err := s.authorizeHistory(ctx, userID, entityType, entityID); err != nil {
+			return nil, 0, err
 		}
+	}
+	return s.repo.GetHistory(ctx, entityType, entityID, f)
+}
 
-		return nil, fmt.Errorf("transaction failed at destination: %v", err)
+// GetStatement собирает выписку по кошельку в валюте currency за период [from, to)
+// с теми же правами доступа, что и история
+func (s *service) GetStatement(ctx context.Context, userID int, isAdmin bool, entityType clients.EntityType, entityID int, currency money.Currency, from, to time.Time) (*statement.Statement, error) {
+	if !isAdmin {
+		if err := s.authorizeHistory(ctx, userID, entityType, entityID); err != nil {
+			return nil, err
+		}
 	}
 
-	t := &Transaction{
-		FromType:  fromType,
-		FromID:    fromID,
-		ToType:    toType,
-		ToID:      toID,
-		Amount:    amount,
-		CreatedAt: time.Now(),
+	opening, err := s.repo.GetBalanceAt(ctx, entityType, entityID, currency, from)
+	if err != nil {
+		return nil, err
 	}
 
-	id, err := s.repo.Create(ctx, t)
+	entries, err := s.repo.GetMovements(ctx, entityType, entityID, currency, from, to)
 	if err != nil {
-		s.log.Error("transaction successful but failed to save record", "error", err)
+		return nil, err
 	}
-	t.ID = id
 
-	if toType == clients.TypeProject {
-		s.log.Info("processing project payment", "project_id", toID, "amount", amount)
-		s.handleProjectPayment(ctx, toID, amount)
+	movements := make([]statement.Movement, 0, len(entries))
+	for _, e := range entries {
+		amount := e.Amount
+		if e.Direction == repo.DirectionOut {
+			amount = -amount
+		}
+		counterparty := "внешний счет"
+		if e.CounterpartyType != clients.TypeExternal {
+			counterparty = fmt.Sprintf("%s #%d", e.CounterpartyType, e.CounterpartyID)
+		}
+		movements = append(movements, statement.Movement{
+			ID:           e.ID,
+			Date:         e.CreatedAt,
+			Type:         e.Type,
+			Counterparty: counterparty,
+			Amount:       amount,
+		})
 	}
 
-	return t, nil
+	st := statement.New(string(entityType), entityID, from, to, opening, movements)
+	st.Currency = currency
+	return st, nil
 }
 
-func (s *service) handleProjectPayment(ctx context.Context, projectID int, amount float64) {
+// GetWallets отдает остатки во всех валютах с теми же правами доступа, что и история
+func (s *service) GetWallets(ctx context.Context, userID int, isAdmin bool, entityType clients.EntityType, entityID int) ([]repo.WalletBalance, error) {
+	if !isAdmin {
+		if err := s.authorizeHistory(ctx, userID, entityType, entityID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetWallets(ctx, entityType, entityID)
+}
+
+func (s *service) GetRates(ctx context.Context) ([]fx.Rate, error) {
+	return s.repo.LatestRates(ctx)
+}
+
+// SetRate сохраняет курс, заданный администратором. Он действует, пока провайдер
+// не загрузит более свежий.
+func (s *service) SetRate(ctx context.Context, adminID int, currency money.Currency, rate string) (*fx.Rate, error) {
+	if currency == money.BaseCurrency {
+		return nil, fx.ErrInvalidRate
+	}
+	r := fx.Rate{Currency: currency, Rate: rate, Source: "manual", FetchedAt: time.Now()}
+	if _, err := r.Rat(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveRates(ctx, []fx.Rate{r}); err != nil {
+		return nil, err
+	}
+	s.log.Info("fx rate set manually", "currency", currency, "rate", rate, "admin_id", adminID)
+	return &r, nil
+}
+
+func (s *service) GetReconciliationRun(ctx context.Context, runID int) (*repo.ReconciliationRun, error) {
+	return s.repo.GetReconciliationRun(ctx, runID)
+}
+
+func (s *service) ResolveReconciliationDiff(ctx context.Context, diffID int64, adminID int) error {
+	if err := s.repo.ResolveReconciliationDiff(ctx, diffID, adminID); err != nil {
+		return err
+	}
+	s.log.Info("reconciliation diff resolved", "diff_id", diffID, "admin_id", adminID)
+	return nil
+}
+
+func (s *service) authorizeHistory(ctx context.Context, userID int, entityType clients.EntityType, entityID int) error {
+	orgID := entityID
+	switch entityType {
+	case clients.TypeUser:
+		if userID != entityID {
+			return core.ErrNotAuthorized
+		}
+		return nil
+	case clients.TypeProject:
+		project, err := s.projectClient.GetProject(ctx, entityID)
+		if err != nil {
+			s.log.Error("failed to get project for history", "error", err, "project_id", entityID)
+			return err
+		}
+		orgID = project.CreatorID
+	}
+
+	allowed, err := s.orgClient.CheckUserOrgPermission(ctx, orgID, userID, "money_management")
+	if err != nil {
+		s.log.Error("failed to check money_management permission", "error", err, "org_id", orgID, "user_id", userID)
+		return err
+	}
+	if !allowed {
+		return core.ErrNotAuthorized
+	}
+	return nil
+}
+
+func (s *service) handleProjectPayment(ctx context.Context, projectID int, amount money.Amount) {
 	project, err := s.projectClient.GetProject(ctx, projectID)
 	if err != nil {
 		s.log.Error("failed to get project data", "error", err, "project_id", projectID)
@@ -109,14 +333,18 @@ func (s *service) handleProjectPayment(ctx context.Context, projectID int, amoun
 
 	s.log.Info("got project data", "project_id", projectID, "monetization_type", project.MonetizationType)
 
-	var paybackDelta float64
+	var paybackDelta money.Amount
 	switch project.MonetizationType {
 	case "fixed_percent":
-		paybackDelta = amount * (project.Percent / 100)
+		paybackDelta, err = amount.Percent(project.Percent, money.RoundHalfUp)
+		if err != nil {
+			s.log.Error("failed to calculate payback", "error", err, "project_id", projectID, "percent", project.Percent)
+			return
+		}
 		s.log.Info("fixed_percent payback calculation", "amount", amount, "percent", project.Percent, "payback_delta", paybackDelta)
 
 	case "time_percent":
-		paybackDelta = 0
+		paybackDelta = money.Zero
 		s.log.Info("time_percent payback calculation - will be calculated at payback time", "amount", amount)
 
 	default:
@@ -130,35 +358,4 @@ func (s *service) handleProjectPayment(ctx context.Context, projectID int, amoun
 	} else {
 		s.log.Info("updated money required to payback", "project_id", projectID, "new_amount", newMoneyRequired)
 	}
-
-	updatedProject, err := s.projectClient.GetProject(ctx, projectID)
-	if err != nil {
-		s.log.Error("failed to get updated project data for goal check", "error", err, "project_id", projectID)
-		return
-	}
-
-	if updatedProject.CurrentMoney >= updatedProject.WantedMoney {
-		s.log.Info("project goal reached!", "project_id", projectID, "current_money", updatedProject.CurrentMoney, "wanted_money", updatedProject.WantedMoney)
-		s.sendGoalReachedNotifications(ctx, projectID, updatedProject)
-	}
-}
-
-func (s *service) sendGoalReachedNotifications(ctx context.Context, projectID int, project *clients.ProjectData) {
-	investors, err := s.repo.GetProjectInvestors(ctx, projectID)
-	if err != nil {
-		s.log.Error("failed to get project investors", "error", err, "project_id", projectID)
-		return
-	}
-
-	s.log.Info("sending goal reached notifications", "project_id", projectID, "investor_count", len(investors))
-
-	for _, investor := range investors {
-		if err := s.notificationClient.SendEmail(investor.UserEmail, "project_goal_reached", project.Name, 0); err != nil {
-			s.log.Error("failed to send goal reached email", "error", err, "user_id", investor.UserID, "email", investor.UserEmail)
-		} else {
-			s.log.Info("sent goal reached email", "user_id", investor.UserID, "email", investor.UserEmail)
-		}
-	}
-
-	s.log.Info("goal reached notifications sent for project", "project_id", projectID, "project_name", project.Name)
 }
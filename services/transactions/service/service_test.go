@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/clients"
+	"github.com/Starostina-elena/investment_platform/services/transactions/core"
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+	"github.com/Starostina-elena/investment_platform/services/transactions/repo"
+)
+
+// replayRepo помнит один уже проведенный перевод и один холд
+type replayRepo struct {
+	Repo
+	transferKey  string
+	holdRef      string
+	transferred  bool
+	holdsCreated bool
+}
+
+func (r *replayRepo) ReplayTransfer(ctx context.Context, t *Transaction, key, hash string) (bool, error) {
+	if key != r.transferKey {
+		return false, nil
+	}
+	t.ID = 42
+	return true, nil
+}
+
+func (r *replayRepo) Transfer(ctx context.Context, t *Transaction, key, hash string) (bool, error) {
+	r.transferred = true
+	t.ID = 43
+	return false, nil
+}
+
+func (r *replayRepo) ReplayHold(ctx context.Context, h *repo.Hold) (bool, error) {
+	if h.Reference != r.holdRef {
+		return false, nil
+	}
+	h.ID = 7
+	return true, nil
+}
+
+func (r *replayRepo) CreateHold(ctx context.Context, h *repo.Hold) (bool, error) {
+	r.holdsCreated = true
+	return false, nil
+}
+
+// completedProject поднимает проектный сервис, где проект 1 уже завершен
+func completedProject(t *testing.T) *clients.ProjectClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(clients.ProjectData{ID: 1, IsCompleted: true, Status: "completed"})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("PROJECT_SERVICE_URL", srv.URL)
+	return clients.NewProjectClient(*slog.Default())
+}
+
+func TestTransferReplayAfterProjectCompleted(t *testing.T) {
+	r := &replayRepo{transferKey: "invest-1"}
+	s := NewService(r, completedProject(t), nil, *slog.Default())
+	ctx := context.Background()
+
+	tx, replayed, err := s.Transfer(ctx, "invest-1", clients.TypeUser, clients.TypeProject, 5, 1, money.FromRubles(100), money.RUB, "")
+	if err != nil {
+		t.Fatalf("Transfer() replay error = %v", err)
+	}
+	if !replayed || tx.ID != 42 {
+		t.Errorf("Transfer() = %d, replayed %v; want the original transaction 42", tx.ID, replayed)
+	}
+
+	_, _, err = s.Transfer(ctx, "invest-2", clients.TypeUser, clients.TypeProject, 5, 1, money.FromRubles(100), money.RUB, "")
+	if !errors.Is(err, core.ErrProjectCompleted) {
+		t.Errorf("Transfer() with a new key error = %v, want ErrProjectCompleted", err)
+	}
+	if r.transferred {
+		t.Error("Transfer() posted a transfer to a completed project")
+	}
+}
+
+func TestCreateHoldReplayAfterProjectCompleted(t *testing.T) {
+	r := &replayRepo{holdRef: "pledge-1"}
+	s := NewService(r, completedProject(t), nil, *slog.Default())
+	ctx := context.Background()
+
+	h, existing, err := s.CreateHold(ctx, "pledge-1", clients.TypeUser, clients.TypeProject, 5, 1, money.FromRubles(100), money.RUB, 0)
+	if err != nil {
+		t.Fatalf("CreateHold() replay error = %v", err)
+	}
+	if !existing || h.ID != 7 {
+		t.Errorf("CreateHold() = %d, existing %v; want the original hold 7", h.ID, existing)
+	}
+
+	_, _, err = s.CreateHold(ctx, "pledge-2", clients.TypeUser, clients.TypeProject, 5, 1, money.FromRubles(100), money.RUB, 0)
+	if !errors.Is(err, core.ErrProjectCompleted) {
+		t.Errorf("CreateHold() with a new reference error = %v, want ErrProjectCompleted", err)
+	}
+	if r.holdsCreated {
+		t.Error("CreateHold() created a hold on a completed project")
+	}
+}
+
+func TestRefundRequestHash(t *testing.T) {
+	transfer := &Transaction{FromType: clients.TypeProject, FromID: 1, ToType: clients.TypeUser, ToID: 5,
+		Amount: money.FromRubles(95), Currency: money.RUB, ToCurrency: money.RUB}
+	refund := *transfer
+	refund.Refund, refund.FeeReturned = true, money.FromRubles(5)
+
+	if requestHash(transfer) == requestHash(&refund) {
+		t.Error("refund and payback with the same amount share a request hash")
+	}
+	other := refund
+	other.FeeReturned = money.FromRubles(4)
+	if requestHash(&refund) == requestHash(&other) {
+		t.Error("refunds with different returned fee share a request hash")
+	}
+}
+
+func TestRefundRejectsFeeAboveAmount(t *testing.T) {
+	s := &service{repo: &replayRepo{}, log: *slog.Default()}
+	for _, fee := range []money.Amount{money.FromRubles(100), money.FromRubles(-1)} {
+		_, _, err := s.Refund(context.Background(), "refund-1", 1, clients.TypeUser, 5, money.FromRubles(100), fee, money.RUB)
+		if !errors.Is(err, core.ErrInvalidAmount) {
+			t.Errorf("Refund(fee %s) error = %v, want ErrInvalidAmount", fee, err)
+		}
+	}
+}
+
+func TestRefundChargesProjectNetOfReturnedFee(t *testing.T) {
+	r := &replayRepo{}
+	s := &service{repo: r, log: *slog.Default()}
+	tx, _, err := s.Refund(context.Background(), "refund-1", 1, clients.TypeUser, 5, money.FromRubles(100), money.FromRubles(5), money.RUB)
+	if err != nil {
+		t.Fatalf("Refund() error = %v", err)
+	}
+	if !r.transferred || !tx.Refund || tx.Amount != money.FromRubles(95) || tx.FeeReturned != money.FromRubles(5) {
+		t.Errorf("Refund() = %+v, want 95 from the project and 5 returned by the platform", tx)
+	}
+}
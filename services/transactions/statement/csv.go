@@ -0,0 +1,20 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV пишет выписку и последней строкой ее контрольную сумму.
+// Для проверки достаточно посчитать SHA-256 от файла без последней строки.
+func (s *Statement) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(s.rows()); err != nil {
+		return err
+	}
+	if err := cw.Write([]string{"SHA-256", s.Checksum()}); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,101 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+const pdfFont = "go"
+
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"ID", 14, "L"},
+	{"Дата", 36, "L"},
+	{"Тип", 38, "L"},
+	{"Контрагент", 34, "L"},
+	{"Сумма", 34, "R"},
+	{"Остаток", 34, "R"},
+}
+
+// WritePDF рисует выписку на A4. Шрифты Go встроены в бинарник и содержат кириллицу,
+// поэтому внешние файлы и рендереры не нужны. В подвале каждой страницы —
+// та же контрольная сумма, что и в CSV.
+func (s *Statement) WritePDF(w io.Writer) error {
+	checksum := s.Checksum()
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetCreationDate(s.GeneratedAt)
+	pdf.SetModificationDate(s.GeneratedAt)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", gobold.TTF)
+	pdf.SetTitle(fmt.Sprintf("Выписка: %s", s.accountLabel()), true)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(pdfFont, "", 7)
+		pdf.CellFormat(0, 4, "SHA-256: "+checksum, "", 1, "L", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("Страница %d из {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.CellFormat(0, 8, "Выписка по счету", "", 1, "L", false, 0, "")
+
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.CellFormat(0, 6, s.accountLabel(), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, fmt.Sprintf("Период: %s — %s", s.From.Format(time.DateOnly), s.lastDay().Format(time.DateOnly)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Валюта: "+s.Currency.String(), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Сформирована: "+s.GeneratedAt.UTC().Format("2006-01-02 15:04 UTC"), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	pdf.CellFormat(0, 6, "Входящий остаток: "+formatAmount(s.OpeningBalance), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	writeHeader := func() {
+		pdf.SetFont(pdfFont, "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for _, c := range pdfColumns {
+			pdf.CellFormat(c.width, 7, c.title, "1", 0, c.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont(pdfFont, "", 9)
+	}
+	writeHeader()
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottomMargin := pdf.GetMargins()
+	for _, m := range s.Movements {
+		if pdf.GetY()+6 > pageHeight-bottomMargin-10 {
+			pdf.AddPage()
+			writeHeader()
+		}
+		cells := []string{
+			fmt.Sprintf("%d", m.ID),
+			m.Date.UTC().Format("2006-01-02 15:04"),
+			m.Type,
+			m.Counterparty,
+			formatAmount(m.Amount),
+			formatAmount(m.BalanceAfter),
+		}
+		for i, c := range pdfColumns {
+			pdf.CellFormat(c.width, 6, cells[i], "1", 0, c.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	if len(s.Movements) == 0 {
+		pdf.CellFormat(0, 6, "Операций за период нет", "1", 1, "C", false, 0, "")
+	}
+
+	pdf.Ln(2)
+	pdf.SetFont(pdfFont, "B", 10)
+	pdf.CellFormat(0, 6, "Исходящий остаток: "+formatAmount(s.ClosingBalance), "", 1, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,110 @@
+package statement
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+// Movement — одна операция в выписке, сумма со знаком: приход положительный, расход отрицательный
+type Movement struct {
+	ID           int
+	Date         time.Time
+	Type         string
+	Counterparty string
+	Amount       money.Amount
+	BalanceAfter money.Amount
+}
+
+// Statement — выписка по счету пользователя или организации за период [From, To)
+type Statement struct {
+	EntityType     string
+	EntityID       int
+	Currency       money.Currency
+	From           time.Time
+	To             time.Time
+	GeneratedAt    time.Time
+	OpeningBalance money.Amount
+	ClosingBalance money.Amount
+	Movements      []Movement
+}
+
+// New собирает выписку в базовой валюте и пересчитывает остатки от входящего остатка,
+// поэтому итог всегда сходится с суммой операций. Валюту другого кошелька задает Currency.
+func New(entityType string, entityID int, from, to time.Time, openingBalance money.Amount, movements []Movement) *Statement {
+	s := &Statement{
+		EntityType:     entityType,
+		EntityID:       entityID,
+		Currency:       money.BaseCurrency,
+		From:           from,
+		To:             to,
+		GeneratedAt:    time.Now(),
+		OpeningBalance: openingBalance,
+		Movements:      make([]Movement, len(movements)),
+	}
+
+	balance := openingBalance
+	for i, m := range movements {
+		balance += m.Amount
+		m.BalanceAfter = balance
+		s.Movements[i] = m
+	}
+	s.ClosingBalance = balance
+	return s
+}
+
+func (s *Statement) accountLabel() string {
+	switch s.EntityType {
+	case "user":
+		return fmt.Sprintf("Пользователь #%d", s.EntityID)
+	case "org":
+		return fmt.Sprintf("Организация #%d", s.EntityID)
+	}
+	return fmt.Sprintf("%s #%d", s.EntityType, s.EntityID)
+}
+
+// lastDay — последний день периода включительно
+func (s *Statement) lastDay() time.Time {
+	return s.To.AddDate(0, 0, -1)
+}
+
+func formatAmount(v money.Amount) string {
+	return v.String()
+}
+
+func (s *Statement) rows() [][]string {
+	rows := [][]string{
+		{"Счет", s.accountLabel(), s.Currency.String()},
+		{"Период", s.From.Format(time.DateOnly), s.lastDay().Format(time.DateOnly)},
+		{"Входящий остаток", formatAmount(s.OpeningBalance)},
+		{"ID", "Дата", "Тип", "Контрагент", "Сумма", "Остаток"},
+	}
+	for _, m := range s.Movements {
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", m.ID),
+			m.Date.UTC().Format(time.RFC3339),
+			m.Type,
+			m.Counterparty,
+			formatAmount(m.Amount),
+			formatAmount(m.BalanceAfter),
+		})
+	}
+	rows = append(rows, []string{"Исходящий остаток", formatAmount(s.ClosingBalance)})
+	return rows
+}
+
+// Checksum — SHA-256 от содержимого выписки без строки с контрольной суммой.
+// Время формирования в нее не входит, так что выписки за один период совпадают,
+// а CSV и PDF одной выписки несут одну и ту же сумму.
+func (s *Statement) Checksum() string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.WriteAll(s.rows())
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package statement
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Starostina-elena/investment_platform/services/transactions/money"
+)
+
+func testStatement() *Statement {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	return New("org", 7, from, to, money.MustParse("1000"), []Movement{
+		{ID: 11, Date: from.Add(time.Hour), Type: "org_deposit", Counterparty: "внешний счет", Amount: money.MustParse("500")},
+		{ID: 12, Date: from.Add(48 * time.Hour), Type: "org_to_project", Counterparty: "project #3", Amount: money.MustParse("-1200.50")},
+	})
+}
+
+func TestNew_RunningBalances(t *testing.T) {
+	s := testStatement()
+
+	if s.Movements[0].BalanceAfter != money.MustParse("1500") {
+		t.Errorf("New() first balance = %v, want 1500", s.Movements[0].BalanceAfter)
+	}
+	if s.Movements[1].BalanceAfter != money.MustParse("299.50") {
+		t.Errorf("New() second balance = %v, want 299.5", s.Movements[1].BalanceAfter)
+	}
+	if s.ClosingBalance != money.MustParse("299.50") {
+		t.Errorf("New() closing balance = %v, want 299.5", s.ClosingBalance)
+	}
+}
+
+func TestNew_Empty(t *testing.T) {
+	s := New("user", 1, time.Now(), time.Now(), money.MustParse("42"), nil)
+	if s.ClosingBalance != money.MustParse("42") {
+		t.Errorf("New() closing balance = %v, want opening 42", s.ClosingBalance)
+	}
+}
+
+func TestChecksum_IgnoresGeneratedAt(t *testing.T) {
+	a := testStatement()
+	b := testStatement()
+	b.GeneratedAt = a.GeneratedAt.Add(time.Hour)
+
+	if a.Checksum() != b.Checksum() {
+		t.Error("Checksum() differs for the same period")
+	}
+
+	b.Movements[0].Amount = money.MustParse("501")
+	if a.Checksum() == b.Checksum() {
+		t.Error("Checksum() did not change after movement change")
+	}
+}
+
+func TestWriteCSV_ChecksumMatchesBody(t *testing.T) {
+	s := testStatement()
+
+	var buf bytes.Buffer
+	if err := s.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	out := buf.String()
+	idx := strings.LastIndex(strings.TrimSuffix(out, "\n"), "\n")
+	body, footer := out[:idx+1], out[idx+1:]
+
+	sum := sha256.Sum256([]byte(body))
+	if footer != "SHA-256,"+hex.EncodeToString(sum[:])+"\n" {
+		t.Errorf("WriteCSV() footer = %q, does not match body checksum", footer)
+	}
+
+	r := csv.NewReader(strings.NewReader(out))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("WriteCSV() produced invalid csv: %v", err)
+	}
+	if got := records[2][1]; got != "1000.00" {
+		t.Errorf("WriteCSV() opening balance = %q, want 1000.00", got)
+	}
+	if got := records[len(records)-2][1]; got != "299.50" {
+		t.Errorf("WriteCSV() closing balance = %q, want 299.50", got)
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	s := testStatement()
+
+	var buf bytes.Buffer
+	if err := s.WritePDF(&buf); err != nil {
+		t.Fatalf("WritePDF() error = %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Error("WritePDF() output is not a pdf")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/Starostina-elena/investment_platform/services/user/core"
+	"github.com/Starostina-elena/investment_platform/services/user/money"
 )
 
 func TestCreateUserHandler_Success(t *testing.T) {
@@ -149,10 +150,6 @@ func (m *mockService) RevokeAllRefreshTokens(ctx context.Context, userID int) er
 	return nil
 }
 
-func (m *mockService) ChangeBalance(ctx context.Context, userID int, delta float64) error {
-	return nil
-}
-
 func (m *mockService) GetActiveInvestments(ctx context.Context, userID int) ([]core.UserProjectInvestment, error) {
 	return []core.UserProjectInvestment{
 		{
@@ -160,8 +157,8 @@ func (m *mockService) GetActiveInvestments(ctx context.Context, userID int) ([]c
 			ProjectName:      "Project A",
 			QuickPeek:        "A great project",
 			MonetizationType: "Equity",
-			TotalInvested:    1000.0,
-			TotalReceived:    100.0,
+			TotalInvested:    money.FromRubles(1000),
+			TotalReceived:    money.FromRubles(100),
 			CreatedAt:        time.Now().AddDate(0, -1, 0),
 			IsCompleted:      false,
 			IsBanned:         false,
@@ -176,8 +173,8 @@ func (m *mockService) GetArchivedInvestments(ctx context.Context, userID int) ([
 			ProjectName:      "Project B",
 			QuickPeek:        "An archived project",
 			MonetizationType: "Revenue Share",
-			TotalInvested:    2000.0,
-			TotalReceived:    500.0,
+			TotalInvested:    money.FromRubles(2000),
+			TotalReceived:    money.FromRubles(500),
 			CreatedAt:        time.Now().AddDate(-1, 0, 0),
 			IsCompleted:      true,
 			IsBanned:         false,
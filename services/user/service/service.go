@@ -9,6 +9,7 @@ import (
 
 	"github.com/Starostina-elena/investment_platform/services/user/auth"
 	"github.com/Starostina-elena/investment_platform/services/user/core"
+	"github.com/Starostina-elena/investment_platform/services/user/money"
 	"github.com/Starostina-elena/investment_platform/services/user/repo"
 	"github.com/Starostina-elena/investment_platform/services/user/storage"
 	"github.com/lib/pq"
@@ -31,7 +32,6 @@ type Service interface {
 	ChangePassword(ctx context.Context, userID int, oldPassword string, newPassword string) (*core.User, error)
 	GetActiveInvestments(ctx context.Context, userID int) ([]core.UserProjectInvestment, error)
 	GetArchivedInvestments(ctx context.Context, userID int) ([]core.UserProjectInvestment, error)
-	ChangeBalance(ctx context.Context, userID int, delta float64) error
 }
 
 type service struct {
@@ -55,7 +55,7 @@ func (s *service) Create(ctx context.Context, user core.User) (*core.User, error
 	user.CreatedAt = time.Now()
 	user.IsAdmin = false
 	user.IsBanned = false
-	user.Balance = 0.0
+	user.Balance = money.Zero
 	id, err := s.repo.Create(ctx, &user)
 	if err != nil {
 		var pqErr *pq.Error
@@ -228,7 +228,3 @@ func (s *service) GetArchivedInvestments(ctx context.Context, userID int) ([]cor
 	}
 	return investments, nil
 }
-
-func (s *service) ChangeBalance(ctx context.Context, userID int, delta float64) error {
-	return s.repo.ChangeBalance(ctx, userID, delta)
-}